@@ -1,17 +1,32 @@
 "use client";
 
 import { useState, useEffect, useRef } from "react";
-import { Camera, Save, Loader2, PlusCircle } from "lucide-react";
+import { Camera, Save, Loader2, PlusCircle, MessageSquareText } from "lucide-react";
 import { apiRequest } from "@/lib/api";
 import { useRouter } from "next/navigation";
 import Link from "next/link";
 import { toast } from "sonner";
 
+// 解析ジョブの状態を確認する間隔
+const ANALYSIS_POLL_INTERVAL_MS = 1500;
+
 interface Group {
   id: string;
   name: string;
 }
 
+// AI解析の結果から作成された、確定前のレシートの下書き
+interface ReceiptDraft {
+  id: string;
+  date: string | null;
+  shop: string;
+  item: string;
+  amount: number;
+  payment_method: string;
+  needs_review: string[] | null;
+  created_at: string;
+}
+
 export default function Register() {
   const router = useRouter();
   const fileInputRef = useRef<HTMLInputElement>(null);
@@ -26,7 +41,15 @@ export default function Register() {
   });
   const [loading, setLoading] = useState(false);
   const [analyzing, setAnalyzing] = useState(false);
+  // 「昨日サイゼリヤで3200円、折半」のような文からの入力
+  const [receiptText, setReceiptText] = useState("");
+  const [parsingText, setParsingText] = useState(false);
+  // AI解析の確からしさが低く、確認が必要な項目（date, shop, item, amount）
+  const [needsReview, setNeedsReview] = useState<string[]>([]);
   const [groups, setGroups] = useState<Group[]>([]);
+  // 編集中の下書き（保存時は下書きを確定する）と、未確定の下書き一覧
+  const [draftId, setDraftId] = useState<string | null>(null);
+  const [drafts, setDrafts] = useState<ReceiptDraft[]>([]);
   const [fetchingGroups, setFetchingGroups] = useState(true);
 
   useEffect(() => {
@@ -38,8 +61,12 @@ export default function Register() {
 
     async function fetchGroups() {
       try {
-        const myGroups = await apiRequest("/api/groups");
+        const [myGroups, myDrafts] = await Promise.all([
+          apiRequest("/api/groups"),
+          apiRequest("/api/receipt-drafts"),
+        ]);
         setGroups(myGroups);
+        setDrafts(myDrafts || []);
       } catch (err) {
         console.error("Failed to fetch groups:", err);
       } finally {
@@ -60,12 +87,22 @@ export default function Register() {
     setAnalyzing(true);
     const formDataBody = new FormData();
     formDataBody.append("image", file);
+    if (groups.length > 0) formDataBody.append("group_id", groups[0].id);
 
     try {
-      const data = await apiRequest("/api/receipts/analyze", {
+      // 解析はジョブとして受け付けられるため、完了するまで状態を確認する
+      let job = await apiRequest("/api/analysis-jobs", {
         method: "POST",
         body: formDataBody,
       });
+      while (job.status === "queued" || job.status === "processing") {
+        await new Promise((resolve) => setTimeout(resolve, ANALYSIS_POLL_INTERVAL_MS));
+        job = await apiRequest(`/api/analysis-jobs/${job.id}`);
+      }
+      if (job.status !== "succeeded" || !job.result) {
+        throw new Error(job.error || "analysis failed");
+      }
+      const data = job.result;
 
       setFormData((prev) => {
         const newDate = data.date || prev.date;
@@ -78,6 +115,15 @@ export default function Register() {
           amount: data.amount || prev.amount,
         };
       });
+      const reviewFields: string[] = data.needs_review || [];
+      setNeedsReview(reviewFields);
+      setDraftId(data.draft_id || null);
+      if (reviewFields.length > 0) {
+        toast.warning("読み取りに自信のない項目があります。黄色の項目を確認してください。");
+      }
+      if (data.corrected && data.corrected.length > 0) {
+        toast.info("過去の修正をもとに店舗名・品名を補正しました。");
+      }
     } catch (err) {
       console.error("Failed to analyze receipt:", err);
       toast.error("解析に失敗しました。手動で入力してください。");
@@ -87,6 +133,68 @@ export default function Register() {
     }
   };
 
+  // 文から読み取った内容をフォームに入力する（下書きは作成しない）
+  const handleParseText = async () => {
+    if (!receiptText.trim() || groups.length === 0) return;
+
+    setParsingText(true);
+    try {
+      const data = await apiRequest("/api/receipts/parse-text", {
+        method: "POST",
+        body: JSON.stringify({ group_id: groups[0].id, text: receiptText }),
+      });
+      const receipt = data.receipt;
+      const newDate = receipt.date.slice(0, 10);
+      setFormData((prev) => ({
+        ...prev,
+        date: newDate,
+        settlement_month: newDate.slice(0, 7),
+        shop: receipt.shop || prev.shop,
+        item: receipt.item || prev.item,
+        amount: receipt.amount || prev.amount,
+        payer_id: receipt.payer_id,
+        payment_method: receipt.payment_method || prev.payment_method,
+      }));
+      const reviewFields: string[] = data.needs_review || [];
+      setNeedsReview(reviewFields);
+      setDraftId(null);
+      if (reviewFields.length > 0) {
+        toast.warning("読み取れなかった項目があります。黄色の項目を確認してください。");
+      }
+    } catch (err) {
+      console.error("Failed to parse receipt text:", err);
+      toast.error("読み取りに失敗しました。手動で入力してください。");
+    } finally {
+      setParsingText(false);
+    }
+  };
+
+  // 未確定の下書きをフォームに読み込む
+  const loadDraft = (draft: ReceiptDraft) => {
+    const newDate = draft.date ? draft.date.slice(0, 10) : formData.date;
+    setFormData({
+      ...formData,
+      date: newDate,
+      settlement_month: newDate.slice(0, 7),
+      shop: draft.shop,
+      item: draft.item,
+      amount: draft.amount,
+      payment_method: draft.payment_method || formData.payment_method,
+    });
+    setNeedsReview(draft.needs_review || []);
+    setDraftId(draft.id);
+    setDrafts((prev) => prev.filter((d) => d.id !== draft.id));
+  };
+
+  // 入力欄の枠の色（確認が必要な項目は黄色で強調する）
+  const fieldClass = (field: string) =>
+    needsReview.includes(field) ? "bg-amber-50 border border-amber-400" : "bg-gray-50 border border-gray-200";
+
+  // 確認が必要な項目は、利用者が編集した時点で強調を解除する
+  const markReviewed = (field: string) => {
+    setNeedsReview((prev) => prev.filter((f) => f !== field));
+  };
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     if (groups.length === 0) return;
@@ -100,13 +208,14 @@ export default function Register() {
     try {
       const user = JSON.parse(localStorage.getItem("user") || "{}");
       const [sYear, sMonth] = formData.settlement_month.split('-').map(Number);
-      await apiRequest("/api/receipts", {
+      // 下書きを編集している場合は下書きを確定する（確定した下書きは削除される）
+      await apiRequest(draftId ? `/api/receipt-drafts/${draftId}/confirm` : "/api/receipts", {
         method: "POST",
         body: JSON.stringify({
           ...formData,
           amount: Number(formData.amount),
           group_id: groups[0].id,
-          payer_id: user.id || "",
+          payer_id: formData.payer_id || user.id || "",
           date: new Date(formData.date).toISOString(),
           settlement_year: sYear,
           settlement_month: sMonth,
@@ -157,7 +266,7 @@ export default function Register() {
         <section>
           <input 
             type="file" 
-            accept="image/*" 
+            accept="image/*,.heic,.heif,application/pdf" 
             capture="environment" 
             className="hidden" 
             ref={fileInputRef}
@@ -184,16 +293,57 @@ export default function Register() {
           </button>
         </section>
 
+        <section className="space-y-2">
+          <label className="text-sm font-semibold text-gray-800">文章で入力</label>
+          <textarea
+            placeholder="例：昨日サイゼリヤで夕食3200円、折半"
+            className="w-full p-3 bg-gray-50 border border-gray-200 rounded-xl focus:outline-none focus:ring-2 focus:ring-blue-500 text-gray-900"
+            rows={2}
+            maxLength={500}
+            value={receiptText}
+            onChange={(e) => setReceiptText(e.target.value)}
+          />
+          <button
+            type="button"
+            onClick={handleParseText}
+            disabled={parsingText || analyzing || !receiptText.trim()}
+            className="w-full py-3 bg-blue-50 text-blue-600 rounded-xl font-semibold flex items-center justify-center gap-2 active:bg-blue-100 disabled:opacity-50"
+          >
+            {parsingText ? <Loader2 size={18} className="animate-spin" /> : <MessageSquareText size={18} />}
+            {parsingText ? "読み取り中..." : "文章から入力"}
+          </button>
+        </section>
+
+        {drafts.length > 0 && (
+          <section className="space-y-2">
+            <h2 className="text-sm font-semibold text-gray-800">未確定の下書き</h2>
+            {drafts.map((draft) => (
+              <button
+                key={draft.id}
+                type="button"
+                onClick={() => loadDraft(draft)}
+                className="w-full p-3 flex justify-between items-center bg-gray-50 border border-gray-200 rounded-xl text-left text-sm text-gray-900 active:bg-gray-100"
+              >
+                <span>
+                  {draft.date ? draft.date.slice(0, 10) : "日付不明"} {draft.shop || "店舗不明"}
+                </span>
+                <span className="font-bold">¥{draft.amount.toLocaleString()}</span>
+              </button>
+            ))}
+          </section>
+        )}
+
         <form onSubmit={handleSubmit} className="space-y-4">
           <div className="grid grid-cols-2 gap-4">
             <div className="space-y-1">
               <label className="text-sm font-semibold text-gray-800">購入日</label>
               <input 
                 type="date" 
-                className="w-full p-3 bg-gray-50 border border-gray-200 rounded-xl focus:outline-none focus:ring-2 focus:ring-blue-500 text-gray-900" 
+                className={`w-full p-3 ${fieldClass("date")} rounded-xl focus:outline-none focus:ring-2 focus:ring-blue-500 text-gray-900`}
                 value={formData.date}
                 onChange={(e) => {
                   const newDate = e.target.value;
+                  markReviewed("date");
                   setFormData({...formData, date: newDate, settlement_month: newDate.slice(0, 7)});
                 }}
                 required
@@ -216,9 +366,12 @@ export default function Register() {
             <input 
               type="text" 
               placeholder="お店の名前を入力" 
-              className="w-full p-3 bg-gray-50 border border-gray-200 rounded-xl focus:outline-none focus:ring-2 focus:ring-blue-500 text-gray-900" 
+              className={`w-full p-3 ${fieldClass("shop")} rounded-xl focus:outline-none focus:ring-2 focus:ring-blue-500 text-gray-900`}
               value={formData.shop}
-              onChange={(e) => setFormData({...formData, shop: e.target.value})}
+              onChange={(e) => {
+                markReviewed("shop");
+                setFormData({...formData, shop: e.target.value});
+              }}
             />
           </div>
 
@@ -227,9 +380,12 @@ export default function Register() {
             <input 
               type="text" 
               placeholder="例：夕食の買い物" 
-              className="w-full p-3 bg-gray-50 border border-gray-200 rounded-xl focus:outline-none focus:ring-2 focus:ring-blue-500 text-gray-900" 
+              className={`w-full p-3 ${fieldClass("item")} rounded-xl focus:outline-none focus:ring-2 focus:ring-blue-500 text-gray-900`}
               value={formData.item}
-              onChange={(e) => setFormData({...formData, item: e.target.value})}
+              onChange={(e) => {
+                markReviewed("item");
+                setFormData({...formData, item: e.target.value});
+              }}
             />
           </div>
 
@@ -240,9 +396,12 @@ export default function Register() {
               <input 
                 type="number" 
                 placeholder="0" 
-                className="w-full p-3 pl-8 bg-gray-50 border border-gray-200 rounded-xl focus:outline-none focus:ring-2 focus:ring-blue-500 font-bold text-lg text-gray-900" 
+                className={`w-full p-3 pl-8 ${fieldClass("amount")} rounded-xl focus:outline-none focus:ring-2 focus:ring-blue-500 font-bold text-lg text-gray-900`}
                 value={formData.amount || ""}
-                onChange={(e) => setFormData({...formData, amount: Number(e.target.value)})}
+                onChange={(e) => {
+                  markReviewed("amount");
+                  setFormData({...formData, amount: Number(e.target.value)});
+                }}
                 required
                 min="1"
               />
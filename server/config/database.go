@@ -1,34 +1,152 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"net/url"
 	"os"
+	"receipt/server/internal/migration"
 	"receipt/server/internal/models"
+	"time"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
+// データベースの種類（DB_DRIVER）
+const (
+	DBDriverMySQL    = "mysql"    // MySQL / MariaDB（既定）
+	DBDriverPostgres = "postgres" // PostgreSQL
+	DBDriverSQLite   = "sqlite"   // SQLite（ファイル1つで動作する。ローカル環境・テスト向け）
+)
+
+// defaultSQLitePath DB_PATH が未指定の場合のSQLiteのファイル
+const defaultSQLitePath = "receipt.db"
+
+// DBConfig データベースの接続設定
+type DBConfig struct {
+	Driver   string // mysql / postgres / sqlite（空の場合は mysql）
+	Host     string
+	Port     string // 空の場合はデータベースの種類ごとの既定のポート
+	User     string
+	Password string
+	Name     string
+	SSLMode  string // postgres: 空の場合は disable
+	Path     string // sqlite: データベースのファイル（空の場合は receipt.db）
+}
+
+// DBConfigFromEnv 環境変数からデータベースの接続設定を読み込む
+func DBConfigFromEnv() DBConfig {
+	return DBConfig{
+		Driver:   os.Getenv("DB_DRIVER"),
+		Host:     os.Getenv("DB_HOST"),
+		Port:     os.Getenv("DB_PORT"),
+		User:     os.Getenv("DB_USER"),
+		Password: os.Getenv("DB_PASSWORD"),
+		Name:     os.Getenv("DB_NAME"),
+		SSLMode:  os.Getenv("DB_SSLMODE"),
+		Path:     os.Getenv("DB_PATH"),
+	}
+}
+
+// InitDB データベースに接続し、適用していないマイグレーションを適用する。
+// DB_REQUIRE_MIGRATED=true の場合は適用せず、スキーマが最新でなければ起動しない（migrate up を別に実行する構成向け）
 func InitDB() {
-	user := os.Getenv("DB_USER")
-	pass := os.Getenv("DB_PASSWORD")
-	host := os.Getenv("DB_HOST")
-	port := "3306"
-	dbname := os.Getenv("DB_NAME")
-
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", user, pass, host, port, dbname)
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	ConnectDB()
+
+	migrator, err := migration.New(DB)
 	if err != nil {
-		panic("failed to connect database")
+		panic("failed to load migrations: " + err.Error())
+	}
+	if os.Getenv("DB_REQUIRE_MIGRATED") == "true" {
+		if err := migrator.Check(); err != nil {
+			panic("database schema is not up to date (run `migrate up`): " + err.Error())
+		}
+		return
 	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		panic("failed to migrate database: " + err.Error())
+	}
+}
 
-	// オートマイグレーション
-	err = db.AutoMigrate(&models.User{}, &models.Group{}, &models.Receipt{}, &models.Settlement{})
+// ConnectDB 環境変数の設定でデータベースに接続する（マイグレーションは行わない）
+func ConnectDB() {
+	db, err := OpenDB(DBConfigFromEnv())
 	if err != nil {
-		panic("failed to migrate database")
+		panic("failed to connect database: " + err.Error())
 	}
-
 	DB = db
 }
+
+// OpenDB 設定されたデータベースに接続する（マイグレーションは行わない）
+func OpenDB(cfg DBConfig) (*gorm.DB, error) {
+	dialector, err := newDialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Driver == DBDriverSQLite {
+		// SQLite は同時に1つの接続しか書き込めないため、接続を1つにしてロック待ちのエラーを避ける
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+
+	// group_members にロールを持たせるため、結合テーブルのモデルを登録
+	if err := db.SetupJoinTable(&models.Group{}, "Members", &models.GroupMember{}); err != nil {
+		return nil, fmt.Errorf("failed to set up group_members join table: %w", err)
+	}
+	return db, nil
+}
+
+func newDialector(cfg DBConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case "", DBDriverMySQL:
+		port := cfg.Port
+		if port == "" {
+			port = "3306"
+		}
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", cfg.User, cfg.Password, cfg.Host, port, cfg.Name)
+		return mysql.Open(dsn), nil
+	case DBDriverPostgres:
+		port := cfg.Port
+		if port == "" {
+			port = "5432"
+		}
+		sslMode := cfg.SSLMode
+		if sslMode == "" {
+			sslMode = "disable"
+		}
+		params := url.Values{"sslmode": {sslMode}}
+		// MySQL の loc=Local と同様に、日付をサーバーのタイムゾーン（環境変数 TZ）で扱う
+		if tz := time.Local.String(); tz != "Local" {
+			params.Set("TimeZone", tz)
+		}
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(cfg.User, cfg.Password),
+			Host:     cfg.Host + ":" + port,
+			Path:     "/" + cfg.Name,
+			RawQuery: params.Encode(),
+		}
+		return postgres.Open(dsn.String()), nil
+	case DBDriverSQLite:
+		path := cfg.Path
+		if path == "" {
+			path = defaultSQLitePath
+		}
+		// 外部キー制約はSQLiteでは接続ごとに有効にする必要がある
+		return sqlite.Open(path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"), nil
+	}
+	return nil, fmt.Errorf("unknown database driver: %s", cfg.Driver)
+}
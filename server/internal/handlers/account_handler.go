@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"receipt/server/internal/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DeleteMeInput 退会用入力
+type DeleteMeInput struct {
+	Password              string `json:"password"`               // パスワードを設定していない（外部IDプロバイダーのみの）ユーザーは不要
+	Code                  string `json:"code"`                   // 2段階認証が有効なユーザーのみ（TOTPコードまたはリカバリーコード）
+	ReauthenticationToken string `json:"reauthentication_token"` // パスワード・2段階認証のないユーザーのみ（直前のパスキー・外部IDプロバイダーでのログイン結果）
+}
+
+// AccountHandler 退会・データエクスポート関連ハンドラー
+type AccountHandler struct {
+	accountService service.AccountService
+}
+
+// NewAccountHandler AccountHandlerを作成
+func NewAccountHandler(as service.AccountService) *AccountHandler {
+	return &AccountHandler{accountService: as}
+}
+
+// DeleteMe 退会（アカウント削除）
+func (h *AccountHandler) DeleteMe(c *gin.Context) {
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	var input DeleteMeInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := h.accountService.DeleteAccount(userID, input.Password, input.Code, input.ReauthenticationToken); err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to delete account")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
+}
+
+// ExportMe 自分に関するデータをZIPアーカイブでダウンロード
+func (h *AccountHandler) ExportMe(c *gin.Context) {
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	archive, err := h.accountService.ExportAccount(userID)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to export account data")
+		}
+		return
+	}
+
+	filename := fmt.Sprintf("receipt-export-%s.zip", time.Now().Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, "application/zip", archive)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"receipt/server/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AIUsageHandler AIの利用状況関連ハンドラー
+type AIUsageHandler struct {
+	usageService service.AIUsageService
+}
+
+// NewAIUsageHandler AIUsageHandlerを作成
+func NewAIUsageHandler(us service.AIUsageService) *AIUsageHandler {
+	return &AIUsageHandler{usageService: us}
+}
+
+// GetMyUsage 自分のAIの利用状況取得（days: 集計日数）
+func (h *AIUsageHandler) GetMyUsage(c *gin.Context) {
+	days, ok := parseUsageDays(c)
+	if !ok {
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	report, err := h.usageService.GetUserUsage(userID, days)
+	if err != nil {
+		respondInternalError(c, "Failed to get AI usage")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetGroupUsage グループのAIの利用状況取得（days: 集計日数）
+func (h *AIUsageHandler) GetGroupUsage(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_id format"})
+		return
+	}
+
+	days, ok := parseUsageDays(c)
+	if !ok {
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	report, err := h.usageService.GetGroupUsage(groupID, userID, days)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to get AI usage")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// parseUsageDays クエリの days を読み込む（省略時は0）。不正な場合はエラーを応答して false を返す
+func parseUsageDays(c *gin.Context) (int, bool) {
+	daysStr := c.Query("days")
+	if daysStr == "" {
+		return 0, true
+	}
+	days, err := strconv.Atoi(daysStr)
+	if err != nil || days <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be a positive integer"})
+		return 0, false
+	}
+	return days, true
+}
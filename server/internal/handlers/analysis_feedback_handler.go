@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"receipt/server/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AnalysisFeedbackHandler AI解析の精度関連ハンドラー
+type AnalysisFeedbackHandler struct {
+	feedbackService service.AnalysisFeedbackService
+}
+
+// NewAnalysisFeedbackHandler AnalysisFeedbackHandlerを作成
+func NewAnalysisFeedbackHandler(fs service.AnalysisFeedbackService) *AnalysisFeedbackHandler {
+	return &AnalysisFeedbackHandler{feedbackService: fs}
+}
+
+// GetAccuracy グループのAI解析の精度取得（months: 集計月数）
+func (h *AnalysisFeedbackHandler) GetAccuracy(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_id format"})
+		return
+	}
+
+	months := 0
+	if monthsStr := c.Query("months"); monthsStr != "" {
+		if months, err = strconv.Atoi(monthsStr); err != nil || months <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "months must be a positive integer"})
+			return
+		}
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	report, err := h.feedbackService.GetAccuracy(groupID, userID, months)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to get analysis accuracy")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"receipt/server/internal/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// analysisJobRefreshInterval SSEで状態の通知がない間にジョブを再取得する間隔（他のプロセスで解析された場合に備える）
+const analysisJobRefreshInterval = 3 * time.Second
+
+// AnalysisJobHandler レシート解析ジョブ関連ハンドラー
+type AnalysisJobHandler struct {
+	analysisJobService service.AnalysisJobService
+	imageProcessor     service.ReceiptImageProcessor
+}
+
+// NewAnalysisJobHandler AnalysisJobHandlerを作成
+func NewAnalysisJobHandler(ajs service.AnalysisJobService, ip service.ReceiptImageProcessor) *AnalysisJobHandler {
+	return &AnalysisJobHandler{
+		analysisJobService: ajs,
+		imageProcessor:     ip,
+	}
+}
+
+// SubmitJob レシートの画像を解析待ちとして登録し、すぐにジョブIDを返す（受け付ける形式は AnalyzeReceipt と同じ）
+func (h *AnalysisJobHandler) SubmitJob(c *gin.Context) {
+	img, groupID, ok := readReceiptUpload(c, h.imageProcessor)
+	if !ok {
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	job, err := h.analysisJobService.SubmitJob(userID, groupID, img)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to submit analysis job")
+		}
+		return
+	}
+
+	c.Header("Location", "/api/analysis-jobs/"+job.ID.String())
+	c.JSON(http.StatusAccepted, job)
+}
+
+// SubmitBatch 複数のレシートの画像（フォーム項目 images を複数、または ZIP ファイルの archive）をまとめて解析待ちにする。
+// 解析結果は GetBatch でレシートの下書きとして取得し、確認後に POST /receipts/batch でまとめて登録する
+func (h *AnalysisJobHandler) SubmitBatch(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxReceiptArchiveSize+multipartOverhead)
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondWithServiceError(c, service.ErrImageTooLarge)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "images or archive is required"})
+		return
+	}
+
+	var groupID *uuid.UUID
+	if values := form.Value["group_id"]; len(values) > 0 && values[0] != "" {
+		parsed, err := uuid.Parse(values[0])
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_id format"})
+			return
+		}
+		groupID = &parsed
+	}
+
+	var files []service.ReceiptFile
+	for _, header := range form.File["images"] {
+		if len(files) >= service.MaxReceiptBatchSize {
+			respondWithServiceError(c, service.ErrReceiptBatchTooLarge)
+			return
+		}
+		data, err := readUploadedFile(header, service.MaxReceiptUploadSize)
+		if err != nil {
+			if !respondWithServiceErrorDetails(c, err, gin.H{"file": header.Filename}) {
+				respondInternalError(c, "Failed to read image")
+			}
+			return
+		}
+		files = append(files, service.ReceiptFile{Name: header.Filename, Data: data})
+	}
+	for _, header := range form.File["archive"] {
+		data, err := readUploadedFile(header, service.MaxReceiptArchiveSize)
+		if err != nil {
+			if !respondWithServiceError(c, err) {
+				respondInternalError(c, "Failed to read archive")
+			}
+			return
+		}
+		extracted, err := service.ExtractReceiptArchive(data)
+		if err != nil {
+			if !respondWithServiceError(c, err) {
+				respondInternalError(c, "Failed to read archive")
+			}
+			return
+		}
+		files = append(files, extracted...)
+	}
+	if len(files) > service.MaxReceiptBatchSize {
+		respondWithServiceError(c, service.ErrReceiptBatchTooLarge)
+		return
+	}
+
+	uploads := make([]service.ReceiptUpload, 0, len(files))
+	for _, file := range files {
+		img, err := h.imageProcessor.Prepare(c.Request.Context(), file.Data)
+		if err != nil {
+			// どのファイルを読み込めなかったかを file で返す
+			if !respondWithServiceErrorDetails(c, err, gin.H{"file": file.Name}) {
+				respondInternalError(c, "Failed to process image")
+			}
+			return
+		}
+		uploads = append(uploads, service.ReceiptUpload{FileName: file.Name, Image: img})
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	batch, err := h.analysisJobService.SubmitBatch(userID, groupID, uploads)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to submit analysis batch")
+		}
+		return
+	}
+
+	c.Header("Location", "/api/analysis-batches/"+batch.ID.String())
+	c.JSON(http.StatusAccepted, batch)
+}
+
+// GetBatch まとめて解析したレシートの状態と下書きを取得
+func (h *AnalysisJobHandler) GetBatch(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid analysis batch id"})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	batch, err := h.analysisJobService.GetBatch(id, userID)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to get analysis batch")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+// readUploadedFile アップロードされたファイルを最大 limit バイトまで読み込む
+func readUploadedFile(header *multipart.FileHeader, limit int64) ([]byte, error) {
+	if header.Size > limit {
+		return nil, service.ErrImageTooLarge
+	}
+
+	src, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, service.ErrImageTooLarge
+	}
+	return data, nil
+}
+
+// GetJob 解析ジョブの状態と結果を取得
+func (h *AnalysisJobHandler) GetJob(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid analysis job id"})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	job, err := h.analysisJobService.GetJob(id, userID)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to get analysis job")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// StreamJob 解析ジョブの状態を Server-Sent Events（status イベント）で送り、解析が終わったら終了する
+func (h *AnalysisJobHandler) StreamJob(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid analysis job id"})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	// 購読を始めてから現在の状態を取得し、その間の変化を取りこぼさないようにする
+	updates, unsubscribe, err := h.analysisJobService.WatchJob(id, userID)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to watch analysis job")
+		}
+		return
+	}
+	defer unsubscribe()
+
+	job, err := h.analysisJobService.GetJob(id, userID)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to get analysis job")
+		}
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	send := func(job *service.AnalysisJobStatus) {
+		c.SSEvent("status", job)
+		c.Writer.Flush()
+	}
+	send(job)
+
+	ticker := time.NewTicker(analysisJobRefreshInterval)
+	defer ticker.Stop()
+
+	for !job.Done() {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case update := <-updates:
+			job = &update
+			send(job)
+		case <-ticker.C:
+			latest, err := h.analysisJobService.GetJob(id, userID)
+			if err != nil {
+				return
+			}
+			if latest.Status != job.Status || latest.Attempts != job.Attempts {
+				job = latest
+				send(job)
+			}
+		}
+	}
+}
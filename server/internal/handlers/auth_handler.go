@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"receipt/server/internal/service"
+	"receipt/server/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -21,6 +23,23 @@ type LoginInput struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// VerifyTwoFactorInput 2段階認証コード検証用入力
+type VerifyTwoFactorInput struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"` // TOTPコードまたはリカバリーコード
+}
+
+// EnableTwoFactorInput 2段階認証有効化用入力
+type EnableTwoFactorInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactorInput 2段階認証無効化用入力（パスワード、またはTOTPコード・リカバリーコードのいずれか）
+type DisableTwoFactorInput struct {
+	Password string `json:"password" binding:"required_without=Code"`
+	Code     string `json:"code" binding:"required_without=Password"`
+}
+
 // UpdateMeInput ユーザー情報更新用入力
 type UpdateMeInput struct {
 	Email    string `json:"email"`
@@ -62,15 +81,41 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, user, err := h.userService.Login(input.Email, input.Password)
+	result, err := h.userService.Login(input.Email, input.Password)
 	if err != nil {
-		if !respondWithServiceError(c, err) {
-			respondInternalError(c, "Failed to login")
-		}
+		respondLoginError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token, "user": user})
+	c.JSON(http.StatusOK, result)
+}
+
+// VerifyTwoFactor ログインの2段階目（TOTPコード・リカバリーコードの検証）
+func (h *UserHandler) VerifyTwoFactor(c *gin.Context) {
+	var input VerifyTwoFactorInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.userService.VerifyTwoFactorLogin(input.ChallengeToken, input.Code)
+	if err != nil {
+		respondLoginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// respondLoginError ログイン処理のエラーレスポンスを返す（ロック中は Retry-After を付与）
+func respondLoginError(c *gin.Context, err error) {
+	var lockedErr *service.AccountLockedError
+	if errors.As(err, &lockedErr) {
+		c.Header("Retry-After", utils.RetryAfterSeconds(lockedErr.RetryAfter))
+	}
+	if !respondWithServiceError(c, err) {
+		respondInternalError(c, "Failed to login")
+	}
 }
 
 // GetMe 現在のユーザー情報取得
@@ -110,3 +155,62 @@ func (h *UserHandler) UpdateMe(c *gin.Context) {
 
 	c.JSON(http.StatusOK, user)
 }
+
+// SetupTwoFactor 2段階認証のセットアップ開始（シークレットとプロビジョニングURIの発行）
+func (h *UserHandler) SetupTwoFactor(c *gin.Context) {
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	setup, err := h.userService.SetupTwoFactor(userID)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to set up two-factor authentication")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+// EnableTwoFactor 2段階認証の有効化（リカバリーコードを返す）
+func (h *UserHandler) EnableTwoFactor(c *gin.Context) {
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	var input EnableTwoFactorInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recoveryCodes, err := h.userService.EnableTwoFactor(userID, input.Code)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to enable two-factor authentication")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": recoveryCodes})
+}
+
+// DisableTwoFactor 2段階認証の無効化
+func (h *UserHandler) DisableTwoFactor(c *gin.Context) {
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	var input DisableTwoFactorInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.userService.DisableTwoFactor(userID, input.Password, input.Code); err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to disable two-factor authentication")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
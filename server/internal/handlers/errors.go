@@ -17,23 +17,79 @@ var serviceErrorMapping = []struct {
 	// User
 	{service.ErrInvalidCredentials, http.StatusUnauthorized, "Invalid email or password"},
 	{service.ErrUserNotFound, http.StatusUnauthorized, "User record not found"},
+	{service.ErrInvalidTwoFactorCode, http.StatusUnauthorized, "認証コードが正しくありません"},
+	{service.ErrInvalidChallenge, http.StatusUnauthorized, "認証の有効期限が切れました。もう一度ログインしてください"},
+	{service.ErrReauthenticationRequired, http.StatusUnauthorized, "本人確認のため、もう一度ログインしてから操作してください"},
+	{service.ErrTwoFactorAlreadyEnabled, http.StatusConflict, "2段階認証は既に有効です"},
+	{service.ErrTwoFactorNotSetup, http.StatusBadRequest, "2段階認証のセットアップを先に行ってください"},
+	{service.ErrAccountLocked, http.StatusTooManyRequests, "ログイン失敗が続いたため、アカウントを一時的にロックしています。しばらくしてから再度お試しください"},
+
+	// Passkey
+	{service.ErrPasskeyNotFound, http.StatusNotFound, "Passkey not found"},
+	{service.ErrInvalidPasskeySession, http.StatusBadRequest, "パスキーの操作の有効期限が切れました。もう一度お試しください"},
+	{service.ErrPasskeyVerificationFailed, http.StatusUnauthorized, "パスキーを確認できませんでした"},
+
+	// OpenID Connect
+	{service.ErrOIDCNotConfigured, http.StatusNotFound, "外部アカウントでのログインは設定されていません"},
+	{service.ErrInvalidOIDCState, http.StatusBadRequest, "ログインの有効期限が切れました。もう一度お試しください"},
+	{service.ErrOIDCAuthenticationFailed, http.StatusUnauthorized, "外部アカウントでの認証に失敗しました"},
+	{service.ErrOIDCEmailNotVerified, http.StatusForbidden, "外部アカウントのメールアドレスが確認されていません"},
 
 	// Group
 	{service.ErrGroupNotFound, http.StatusNotFound, "Group not found"},
-	{service.ErrNotOwner, http.StatusForbidden, "Only group owner can perform this action"},
+	{service.ErrPermissionDenied, http.StatusForbidden, "この操作を行う権限がありません"},
+	{service.ErrInvalidRole, http.StatusBadRequest, "Invalid group role"},
+	{service.ErrCannotChangeOwnerRole, http.StatusBadRequest, "Owner's role cannot be changed"},
 	{service.ErrInviteUserNotFound, http.StatusNotFound, service.ErrInviteUserNotFound.Error()},
 	{service.ErrAlreadyMember, http.StatusBadRequest, "User is already a member of this group"},
 	{service.ErrOwnerCannotBeRemoved, http.StatusBadRequest, "Owner cannot be removed from the group"},
 	{service.ErrMemberNotFound, http.StatusNotFound, "User to remove not found"},
+	{service.ErrOwnerCannotLeave, http.StatusBadRequest, "オーナーは退出する前にオーナー権限を他のメンバーに譲渡してください"},
+	{service.ErrOutstandingBalance, http.StatusConflict, "未精算の残高があるため退出できません。精算を済ませるか、強制退出を選択してください"},
+	{service.ErrAlreadyOwner, http.StatusBadRequest, "User is already the owner of this group"},
+	{service.ErrGroupArchived, http.StatusConflict, "アーカイブされたグループは閲覧のみです。変更するにはアーカイブを解除してください"},
+	{service.ErrRestoreWindowExpired, http.StatusGone, "復元できる期間を過ぎたため、グループを復元できません"},
+	{service.ErrPurgeConfirmationMismatch, http.StatusBadRequest, "確認のため、グループ名を正確に入力してください"},
 
 	// Receipt
 	{service.ErrReceiptNotFound, http.StatusNotFound, "Receipt not found"},
 	{service.ErrNotCreator, http.StatusForbidden, "Only the creator can modify this receipt"},
 	{service.ErrAlreadySettled, http.StatusForbidden, "精算済みのレシートは変更できません"},
 	{service.ErrInvalidAmount, http.StatusBadRequest, "金額は1円以上にしてください"},
+	{service.ErrPaymentMethodRequired, http.StatusBadRequest, "支払い方法を指定してください"},
+	{service.ErrImageTooLarge, http.StatusRequestEntityTooLarge, "ファイルのサイズまたは画像の解像度が大きすぎます（20MBまで）"},
+	{service.ErrUnsupportedImageType, http.StatusUnsupportedMediaType, "対応していないファイル形式です（JPEG・PNG・HEIC・PDF などを選択してください）"},
+	{service.ErrImageProcessingFailed, http.StatusUnprocessableEntity, "画像を読み込めませんでした"},
+	{service.ErrAnalysisJobNotFound, http.StatusNotFound, "Analysis job not found"},
+	{service.ErrReceiptDraftNotFound, http.StatusNotFound, "Receipt draft not found"},
+	{service.ErrDraftGroupRequired, http.StatusBadRequest, "登録先のグループを指定してください"},
+	{service.ErrDraftDateRequired, http.StatusBadRequest, "購入日を入力してください"},
+	{service.ErrEmptyReceiptBatch, http.StatusBadRequest, "レシートを1件以上指定してください"},
+	{service.ErrReceiptBatchTooLarge, http.StatusBadRequest, "一度に扱えるレシートは50件までです"},
+	{service.ErrInvalidReceiptArchive, http.StatusBadRequest, "ZIPファイルを読み込めませんでした"},
+	{service.ErrAIQuotaExceeded, http.StatusTooManyRequests, "本日のAI解析の利用上限に達しました。手動で入力するか、明日以降に再度お試しください"},
+	{service.ErrInvalidReceiptText, http.StatusBadRequest, "レシートの内容を500文字以内で入力してください"},
+	{service.ErrInvalidSpendingQuestion, http.StatusBadRequest, "質問を500文字以内で入力してください"},
+
+	// Shop
+	{service.ErrShopNotFound, http.StatusNotFound, "Shop not found"},
+	{service.ErrInvalidShopName, http.StatusBadRequest, "店舗名を入力してください"},
+	{service.ErrShopAlreadyExists, http.StatusConflict, "同じ名前の店舗が既に登録されています"},
+	{service.ErrInvalidPaymentMethod, http.StatusBadRequest, "支払い方法が正しくありません"},
+	{service.ErrInvalidShopMerge, http.StatusBadRequest, "統合する店舗には、同じグループの別の店舗を指定してください"},
 
 	// Settlement
 	{service.ErrInvalidSettlementAmount, http.StatusBadRequest, "精算金額は1円以上にしてください"},
+	{service.ErrInvalidSettlementRecipient, http.StatusBadRequest, "精算の受取人はグループの他のメンバーを指定してください"},
+	{service.ErrSettlementExceedsBalance, http.StatusBadRequest, "精算金額が未精算の累積残高を超えています"},
+
+	// Report
+	{service.ErrInvalidReportRange, http.StatusBadRequest, "集計期間が正しくありません（開始日は終了日以前、期間は5年以内にしてください）"},
+	{service.ErrInvalidReportGranularity, http.StatusBadRequest, "集計単位は month または week を指定してください"},
+
+	// Insight
+	{service.ErrInvalidShopAlias, http.StatusBadRequest, "別名と正式な店舗名を入力してください"},
+	{service.ErrShopAliasNotFound, http.StatusNotFound, "Shop alias not found"},
 }
 
 // respondWithServiceError Service層のエラーに応じたHTTPレスポンスを返す。
@@ -41,9 +97,18 @@ var serviceErrorMapping = []struct {
 // 一致しない場合は 500 Internal Server Error を返す。
 // 戻り値：エラーがマッピングに一致した場合はtrue
 func respondWithServiceError(c *gin.Context, err error) bool {
+	return respondWithServiceErrorDetails(c, err, nil)
+}
+
+// respondWithServiceErrorDetails respondWithServiceError と同様に返し、レスポンスに details の項目を追加する
+func respondWithServiceErrorDetails(c *gin.Context, err error, details gin.H) bool {
 	for _, m := range serviceErrorMapping {
 		if errors.Is(err, m.err) {
-			c.JSON(m.status, gin.H{"error": m.msg})
+			body := gin.H{"error": m.msg}
+			for k, v := range details {
+				body[k] = v
+			}
+			c.JSON(m.status, body)
 			return true
 		}
 	}
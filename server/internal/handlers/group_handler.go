@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"receipt/server/internal/models"
 	"receipt/server/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -23,6 +24,26 @@ type InviteMemberInput struct {
 	Email string `json:"email" binding:"required,email"`
 }
 
+// ChangeMemberRoleInput メンバーのロール変更用入力
+type ChangeMemberRoleInput struct {
+	Role string `json:"role" binding:"required"` // admin / member / viewer
+}
+
+// TransferOwnershipInput オーナー権限譲渡用入力
+type TransferOwnershipInput struct {
+	UserID uuid.UUID `json:"user_id" binding:"required"`
+}
+
+// LeaveGroupInput グループ退出用入力
+type LeaveGroupInput struct {
+	Force bool `json:"force"` // 未精算の残高があっても退出する
+}
+
+// PurgeGroupInput グループ完全削除用入力
+type PurgeGroupInput struct {
+	Confirmation string `json:"confirmation" binding:"required"` // 確認のためのグループ名
+}
+
 // GroupHandler グループ関連ハンドラー
 type GroupHandler struct {
 	groupService service.GroupService
@@ -115,7 +136,10 @@ func (h *GroupHandler) GetMyGroups(c *gin.Context) {
 	userIDVal, _ := c.Get("userID")
 	userID := userIDVal.(uuid.UUID)
 
-	groups, err := h.groupService.GetMyGroups(userID)
+	// アーカイブ済みのグループは include_archived=true の場合のみ含める
+	includeArchived := c.Query("include_archived") == "true"
+
+	groups, err := h.groupService.GetMyGroups(userID, includeArchived)
 	if err != nil {
 		respondInternalError(c, "Failed to fetch groups")
 		return
@@ -174,3 +198,201 @@ func (h *GroupHandler) DeleteGroup(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Group deleted successfully"})
 }
+
+// ChangeMemberRole メンバーのロール変更
+func (h *GroupHandler) ChangeMemberRole(c *gin.Context) {
+	groupIDStr := c.Param("id")
+	groupID, err := uuid.Parse(groupIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_id format"})
+		return
+	}
+
+	memberIDStr := c.Param("userId")
+	memberID, err := uuid.Parse(memberIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid member user_id format"})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	var input ChangeMemberRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := h.groupService.ChangeMemberRole(groupID, userID, memberID, input.Role)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to change member role")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// TransferOwnership オーナー権限を他のメンバーに譲渡
+func (h *GroupHandler) TransferOwnership(c *gin.Context) {
+	groupIDStr := c.Param("id")
+	groupID, err := uuid.Parse(groupIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_id format"})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	var input TransferOwnershipInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := h.groupService.TransferOwnership(groupID, userID, input.UserID)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to transfer ownership")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// LeaveGroup グループから退出
+func (h *GroupHandler) LeaveGroup(c *gin.Context) {
+	groupIDStr := c.Param("id")
+	groupID, err := uuid.Parse(groupIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_id format"})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	// ボディは省略可能（省略時は強制退出しない）
+	var input LeaveGroupInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := h.groupService.LeaveGroup(groupID, userID, input.Force); err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to leave group")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Left the group successfully"})
+}
+
+// ArchiveGroup グループをアーカイブ（閲覧のみ）にする
+func (h *GroupHandler) ArchiveGroup(c *gin.Context) {
+	h.setArchived(c, true)
+}
+
+// UnarchiveGroup グループのアーカイブを解除
+func (h *GroupHandler) UnarchiveGroup(c *gin.Context) {
+	h.setArchived(c, false)
+}
+
+func (h *GroupHandler) setArchived(c *gin.Context, archived bool) {
+	groupIDStr := c.Param("id")
+	groupID, err := uuid.Parse(groupIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_id format"})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	var group *models.Group
+	if archived {
+		group, err = h.groupService.ArchiveGroup(groupID, userID)
+	} else {
+		group, err = h.groupService.UnarchiveGroup(groupID, userID)
+	}
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to update archive status")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// GetDeletedGroups 復元可能な削除済みグループ一覧取得
+func (h *GroupHandler) GetDeletedGroups(c *gin.Context) {
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	groups, err := h.groupService.GetDeletedGroups(userID)
+	if err != nil {
+		respondInternalError(c, "Failed to fetch deleted groups")
+		return
+	}
+
+	c.JSON(http.StatusOK, groups)
+}
+
+// RestoreGroup 削除済みグループを復元
+func (h *GroupHandler) RestoreGroup(c *gin.Context) {
+	groupIDStr := c.Param("id")
+	groupID, err := uuid.Parse(groupIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_id format"})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	group, err := h.groupService.RestoreGroup(groupID, userID)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to restore group")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// PurgeGroup グループを履歴ごと完全削除（バックグラウンドで実行）
+func (h *GroupHandler) PurgeGroup(c *gin.Context) {
+	groupIDStr := c.Param("id")
+	groupID, err := uuid.Parse(groupIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_id format"})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	var input PurgeGroupInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.groupService.PurgeGroup(groupID, userID, input.Confirmation); err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to purge group")
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Group purge has been scheduled"})
+}
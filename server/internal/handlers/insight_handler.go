@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"net/http"
+	"receipt/server/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateShopAliasInput 店舗名の別名登録用入力
+type CreateShopAliasInput struct {
+	Alias         string `json:"alias" binding:"required"`          // 例: 7-Eleven
+	CanonicalName string `json:"canonical_name" binding:"required"` // 例: セブンイレブン
+}
+
+// InsightHandler 店舗別・支払者別インサイト関連ハンドラー
+type InsightHandler struct {
+	insightService service.InsightService
+}
+
+// NewInsightHandler InsightHandlerを作成
+func NewInsightHandler(is service.InsightService) *InsightHandler {
+	return &InsightHandler{insightService: is}
+}
+
+// GetInsights グループのインサイト取得（months: 集計月数、limit: 上位店舗の件数）
+func (h *InsightHandler) GetInsights(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_id format"})
+		return
+	}
+
+	months, limit := 0, 0
+	if monthsStr := c.Query("months"); monthsStr != "" {
+		if months, err = strconv.Atoi(monthsStr); err != nil || months <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "months must be a positive integer"})
+			return
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err = strconv.Atoi(limitStr); err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	insights, err := h.insightService.GetInsights(groupID, userID, months, limit)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to get insights")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, insights)
+}
+
+// GetShopAliases グループで使われる店舗名の別名一覧取得（全グループ共通の別名を含む）
+func (h *InsightHandler) GetShopAliases(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_id format"})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	aliases, err := h.insightService.GetShopAliases(groupID, userID)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to get shop aliases")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, aliases)
+}
+
+// CreateShopAlias グループの店舗名の別名登録
+func (h *InsightHandler) CreateShopAlias(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_id format"})
+		return
+	}
+
+	var input CreateShopAliasInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	alias, err := h.insightService.CreateShopAlias(groupID, userID, input.Alias, input.CanonicalName)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to create shop alias")
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, alias)
+}
+
+// DeleteShopAlias グループの店舗名の別名削除
+func (h *InsightHandler) DeleteShopAlias(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_id format"})
+		return
+	}
+
+	aliasID, err := uuid.Parse(c.Param("aliasId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid alias id format"})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	if err := h.insightService.DeleteShopAlias(groupID, userID, aliasID); err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to delete shop alias")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Shop alias deleted successfully"})
+}
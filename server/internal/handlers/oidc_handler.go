@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"receipt/server/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FinishOIDCLoginInput OIDCログイン完了用入力（IDプロバイダーからのコールバック内容）
+type FinishOIDCLoginInput struct {
+	SessionToken string `json:"session_token" binding:"required"`
+	Code         string `json:"code" binding:"required"`
+	State        string `json:"state" binding:"required"`
+}
+
+// OIDCHandler OpenID Connect ログイン関連ハンドラー
+type OIDCHandler struct {
+	oidcService service.OIDCService
+}
+
+// NewOIDCHandler OIDCHandlerを作成
+func NewOIDCHandler(oidcSvc service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{oidcService: oidcSvc}
+}
+
+// BeginLogin OIDCログインの開始（認可URLの発行）
+func (h *OIDCHandler) BeginLogin(c *gin.Context) {
+	authorization, err := h.oidcService.BeginLogin(c.Request.Context())
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to begin OpenID Connect login")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, authorization)
+}
+
+// FinishLogin OIDCログインの完了（認可コードの交換とユーザーの紐付け）
+func (h *OIDCHandler) FinishLogin(c *gin.Context) {
+	var input FinishOIDCLoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.oidcService.FinishLogin(c.Request.Context(), input.SessionToken, input.Code, input.State)
+	if err != nil {
+		respondLoginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
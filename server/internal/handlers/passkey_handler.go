@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"receipt/server/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// FinishPasskeyRegistrationInput パスキー登録完了用入力
+type FinishPasskeyRegistrationInput struct {
+	SessionToken string          `json:"session_token" binding:"required"`
+	Name         string          `json:"name"`
+	Credential   json.RawMessage `json:"credential" binding:"required"` // navigator.credentials.create() の結果
+}
+
+// FinishPasskeyLoginInput パスキーログイン完了用入力
+type FinishPasskeyLoginInput struct {
+	SessionToken string          `json:"session_token" binding:"required"`
+	Credential   json.RawMessage `json:"credential" binding:"required"` // navigator.credentials.get() の結果
+}
+
+// PasskeyHandler パスキー（WebAuthn）関連ハンドラー
+type PasskeyHandler struct {
+	passkeyService service.PasskeyService
+}
+
+// NewPasskeyHandler PasskeyHandlerを作成
+func NewPasskeyHandler(ps service.PasskeyService) *PasskeyHandler {
+	return &PasskeyHandler{passkeyService: ps}
+}
+
+// BeginRegistration パスキー登録の開始
+func (h *PasskeyHandler) BeginRegistration(c *gin.Context) {
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	ceremony, err := h.passkeyService.BeginRegistration(userID)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to begin passkey registration")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, ceremony)
+}
+
+// FinishRegistration パスキー登録の完了
+func (h *PasskeyHandler) FinishRegistration(c *gin.Context) {
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	var input FinishPasskeyRegistrationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	credential, err := h.passkeyService.FinishRegistration(userID, input.SessionToken, input.Name, input.Credential)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to register passkey")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, credential)
+}
+
+// BeginLogin パスキーログインの開始
+func (h *PasskeyHandler) BeginLogin(c *gin.Context) {
+	ceremony, err := h.passkeyService.BeginLogin()
+	if err != nil {
+		respondInternalError(c, "Failed to begin passkey login")
+		return
+	}
+
+	c.JSON(http.StatusOK, ceremony)
+}
+
+// FinishLogin パスキーログインの完了
+func (h *PasskeyHandler) FinishLogin(c *gin.Context) {
+	var input FinishPasskeyLoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.passkeyService.FinishLogin(input.SessionToken, input.Credential)
+	if err != nil {
+		respondLoginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetPasskeys 登録済みパスキー一覧取得
+func (h *PasskeyHandler) GetPasskeys(c *gin.Context) {
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	passkeys, err := h.passkeyService.GetPasskeys(userID)
+	if err != nil {
+		respondInternalError(c, "Failed to fetch passkeys")
+		return
+	}
+
+	c.JSON(http.StatusOK, passkeys)
+}
+
+// DeletePasskey パスキー削除
+func (h *PasskeyHandler) DeletePasskey(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid passkey id"})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	if err := h.passkeyService.DeletePasskey(userID, id); err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to delete passkey")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Passkey deleted successfully"})
+}
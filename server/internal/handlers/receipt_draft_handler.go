@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"net/http"
+	"receipt/server/internal/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ConfirmReceiptDraftInput 下書きの確定用入力（省略した項目は下書きの値を使う）
+type ConfirmReceiptDraftInput struct {
+	GroupID         uuid.UUID  `json:"group_id"`
+	Date            time.Time  `json:"date"`
+	SettlementYear  int        `json:"settlement_year"`
+	SettlementMonth int        `json:"settlement_month"`
+	Shop            string     `json:"shop"`
+	ShopID          *uuid.UUID `json:"shop_id"`
+	Category        string     `json:"category"`
+	Item            string     `json:"item"`
+	Amount          int        `json:"amount"`
+	PayerID         uuid.UUID  `json:"payer_id"` // 省略時は確定したユーザー
+	PaymentMethod   string     `json:"payment_method"`
+}
+
+// ReceiptDraftHandler レシートの下書き関連ハンドラー
+type ReceiptDraftHandler struct {
+	draftService service.ReceiptDraftService
+}
+
+// NewReceiptDraftHandler ReceiptDraftHandlerを作成
+func NewReceiptDraftHandler(ds service.ReceiptDraftService) *ReceiptDraftHandler {
+	return &ReceiptDraftHandler{draftService: ds}
+}
+
+// GetDrafts 自分の下書き一覧取得（画像は含めない）
+func (h *ReceiptDraftHandler) GetDrafts(c *gin.Context) {
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	drafts, err := h.draftService.GetDrafts(userID)
+	if err != nil {
+		respondInternalError(c, "Failed to fetch receipt drafts")
+		return
+	}
+
+	c.JSON(http.StatusOK, drafts)
+}
+
+// GetDraft 下書き詳細取得
+func (h *ReceiptDraftHandler) GetDraft(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid receipt draft id"})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	draft, err := h.draftService.GetDraft(id, userID)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to get receipt draft")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, draft)
+}
+
+// GetDraftImage 下書きのレシート画像（JPEG または PDF）取得
+func (h *ReceiptDraftHandler) GetDraftImage(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid receipt draft id"})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	draft, err := h.draftService.GetDraft(id, userID)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to get receipt draft")
+		}
+		return
+	}
+	if len(draft.ImageData) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Receipt draft has no image"})
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.Data(http.StatusOK, draft.MIMEType, draft.ImageData)
+}
+
+// ConfirmDraft 下書きをレシートとして登録
+func (h *ReceiptDraftHandler) ConfirmDraft(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid receipt draft id"})
+		return
+	}
+
+	var input ConfirmReceiptDraftInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	params := &service.CreateReceiptParams{
+		GroupID:         input.GroupID,
+		Date:            input.Date,
+		SettlementYear:  input.SettlementYear,
+		SettlementMonth: input.SettlementMonth,
+		Shop:            input.Shop,
+		ShopID:          input.ShopID,
+		Category:        input.Category,
+		Item:            input.Item,
+		Amount:          input.Amount,
+		PayerID:         input.PayerID,
+		PaymentMethod:   input.PaymentMethod,
+	}
+
+	receipt, err := h.draftService.ConfirmDraft(id, userID, params)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to confirm receipt draft")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, receipt)
+}
+
+// DeleteDraft 下書きを破棄
+func (h *ReceiptDraftHandler) DeleteDraft(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid receipt draft id"})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	if err := h.draftService.DeleteDraft(id, userID); err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to delete receipt draft")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Receipt draft deleted successfully"})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -22,20 +23,53 @@ type CreateReceiptInput struct {
 	Item            string    `json:"item"`
 	Amount          int       `json:"amount" binding:"required"`
 	PayerID         uuid.UUID `json:"payer_id" binding:"required"`
-	PaymentMethod   string    `json:"payment_method" binding:"required"`
+	PaymentMethod   string    `json:"payment_method"` // 省略時は店舗の既定の支払い方法
+
+	ShopID   *uuid.UUID `json:"shop_id"`  // 店舗マスタの店舗（省略時は店舗名から照合）
+	Category string     `json:"category"` // 省略時は店舗の既定の分類
+}
+
+// CreateReceiptsInput レシートのまとめて登録用入力
+type CreateReceiptsInput struct {
+	GroupID         uuid.UUID           `json:"group_id" binding:"required"`
+	Receipts        []BatchReceiptInput `json:"receipts" binding:"required,dive"`
+	AllowDuplicates bool                `json:"allow_duplicates"` // true の場合は重複の疑いがあるレシートも登録する
+}
+
+// BatchReceiptInput まとめて登録するレシート1件分の入力（グループは CreateReceiptsInput で指定する）
+type BatchReceiptInput struct {
+	Date            time.Time  `json:"date" binding:"required"`
+	SettlementYear  int        `json:"settlement_year"`
+	SettlementMonth int        `json:"settlement_month"`
+	Shop            string     `json:"shop"`
+	ShopID          *uuid.UUID `json:"shop_id"`
+	Category        string     `json:"category"`
+	Item            string     `json:"item"`
+	Amount          int        `json:"amount" binding:"required"`
+	PayerID         uuid.UUID  `json:"payer_id" binding:"required"`
+	PaymentMethod   string     `json:"payment_method"`
 }
 
+// multipartOverhead 解析用アップロードのリクエストで、ファイル以外に許容するサイズ
+const multipartOverhead = 1 << 20
+
 // ReceiptHandler レシート関連ハンドラー
 type ReceiptHandler struct {
-	receiptService service.ReceiptService
-	aiAnalyzer     service.AIAnalyzer
+	receiptService  service.ReceiptService
+	feedbackService service.AnalysisFeedbackService
+	draftService    service.ReceiptDraftService
+	usageService    service.AIUsageService
+	imageProcessor  service.ReceiptImageProcessor
 }
 
 // NewReceiptHandler ReceiptHandlerを作成
-func NewReceiptHandler(rs service.ReceiptService, ai service.AIAnalyzer) *ReceiptHandler {
+func NewReceiptHandler(rs service.ReceiptService, fs service.AnalysisFeedbackService, ds service.ReceiptDraftService, us service.AIUsageService, ip service.ReceiptImageProcessor) *ReceiptHandler {
 	return &ReceiptHandler{
-		receiptService: rs,
-		aiAnalyzer:     ai,
+		receiptService:  rs,
+		feedbackService: fs,
+		draftService:    ds,
+		usageService:    us,
+		imageProcessor:  ip,
 	}
 }
 
@@ -53,6 +87,9 @@ func (h *ReceiptHandler) GetReceipts(c *gin.Context) {
 		return
 	}
 
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
 	yearStr := c.Query("year")
 	monthStr := c.Query("month")
 
@@ -65,9 +102,11 @@ func (h *ReceiptHandler) GetReceipts(c *gin.Context) {
 		monthPtr = &month
 	}
 
-	receipts, err := h.receiptService.GetReceipts(groupID, yearPtr, monthPtr)
+	receipts, err := h.receiptService.GetReceipts(groupID, userID, yearPtr, monthPtr)
 	if err != nil {
-		respondInternalError(c, "Failed to fetch receipts")
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to fetch receipts")
+		}
 		return
 	}
 
@@ -91,6 +130,8 @@ func (h *ReceiptHandler) CreateReceipt(c *gin.Context) {
 		SettlementYear:  input.SettlementYear,
 		SettlementMonth: input.SettlementMonth,
 		Shop:            input.Shop,
+		ShopID:          input.ShopID,
+		Category:        input.Category,
 		Item:            input.Item,
 		Amount:          input.Amount,
 		PayerID:         input.PayerID,
@@ -108,6 +149,52 @@ func (h *ReceiptHandler) CreateReceipt(c *gin.Context) {
 	c.JSON(http.StatusOK, receipt)
 }
 
+// CreateReceipts 解析結果の下書きなど、複数のレシートをまとめて登録する。
+// 重複の疑いがあるレシートは allow_duplicates を指定しない限り登録せず、duplicates で返す
+func (h *ReceiptHandler) CreateReceipts(c *gin.Context) {
+	var input CreateReceiptsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	params := make([]*service.CreateReceiptParams, len(input.Receipts))
+	for i, r := range input.Receipts {
+		params[i] = &service.CreateReceiptParams{
+			GroupID:         input.GroupID,
+			Date:            r.Date,
+			SettlementYear:  r.SettlementYear,
+			SettlementMonth: r.SettlementMonth,
+			Shop:            r.Shop,
+			ShopID:          r.ShopID,
+			Category:        r.Category,
+			Item:            r.Item,
+			Amount:          r.Amount,
+			PayerID:         r.PayerID,
+			PaymentMethod:   r.PaymentMethod,
+		}
+	}
+
+	result, err := h.receiptService.CreateReceipts(input.GroupID, params, userID, input.AllowDuplicates)
+	if err != nil {
+		// 不正な入力が何番目のレシートかを index で返す
+		var details gin.H
+		var batchErr *service.ReceiptBatchError
+		if errors.As(err, &batchErr) {
+			details = gin.H{"index": batchErr.Index}
+		}
+		if !respondWithServiceErrorDetails(c, err, details) {
+			respondInternalError(c, "Failed to create receipts")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // GetReceipt レシート詳細取得
 func (h *ReceiptHandler) GetReceipt(c *gin.Context) {
 	idStr := c.Param("id")
@@ -117,7 +204,10 @@ func (h *ReceiptHandler) GetReceipt(c *gin.Context) {
 		return
 	}
 
-	receipt, err := h.receiptService.GetReceipt(id)
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	receipt, err := h.receiptService.GetReceipt(id, userID)
 	if err != nil {
 		if !respondWithServiceError(c, err) {
 			respondInternalError(c, "Failed to get receipt")
@@ -152,6 +242,8 @@ func (h *ReceiptHandler) UpdateReceipt(c *gin.Context) {
 		SettlementYear:  input.SettlementYear,
 		SettlementMonth: input.SettlementMonth,
 		Shop:            input.Shop,
+		ShopID:          input.ShopID,
+		Category:        input.Category,
 		Item:            input.Item,
 		Amount:          input.Amount,
 		PayerID:         input.PayerID,
@@ -191,32 +283,95 @@ func (h *ReceiptHandler) DeleteReceipt(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Receipt deleted successfully"})
 }
 
-// AnalyzeReceipt レシートAI解析
+// AnalyzeReceipt レシートAI解析（group_id を指定した場合は店舗名をグループの店舗マスタと照合し、
+// グループで過去に修正された店舗名・品名を補正する）。
+// JPEG・PNG・GIF・WebP・HEIC の画像と PDF（複数ページ可）を受け付ける。
+// 解析が終わるまで応答を返さないため、通信が不安定な環境では解析ジョブ（POST /analysis-jobs）を使う
 func (h *ReceiptHandler) AnalyzeReceipt(c *gin.Context) {
+	img, groupID, ok := readReceiptUpload(c, h.imageProcessor)
+	if !ok {
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	result, err := h.usageService.AnalyzeReceipt(c.Request.Context(), userID, groupID, img)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to analyze receipt: %v", err)})
+		}
+		return
+	}
+
+	if groupID != nil {
+		if err := h.feedbackService.RefineAnalysis(*groupID, userID, result); err != nil {
+			if !respondWithServiceError(c, err) {
+				respondInternalError(c, "Failed to match shop")
+			}
+			return
+		}
+	}
+
+	// 画面を離れても結果を失わないよう、下書きとして保存する（result.draft_id で確定できる）
+	if _, err := h.draftService.CreateDraft(userID, groupID, img, result); err != nil {
+		respondInternalError(c, "Failed to save receipt draft")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// readReceiptUpload 解析用にアップロードされたレシートの画像（フォーム項目 image）と group_id を読み込み、正規化する。
+// 読み込めなかった場合はエラーを応答して false を返す
+func readReceiptUpload(c *gin.Context, imageProcessor service.ReceiptImageProcessor) (*service.ReceiptImage, *uuid.UUID, bool) {
+	// multipart のヘッダー分の余裕を持たせてリクエスト全体のサイズを制限する
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxReceiptUploadSize+multipartOverhead)
+
 	file, err := c.FormFile("image")
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondWithServiceError(c, service.ErrImageTooLarge)
+			return nil, nil, false
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Image is required"})
-		return
+		return nil, nil, false
+	}
+	if file.Size > service.MaxReceiptUploadSize {
+		respondWithServiceError(c, service.ErrImageTooLarge)
+		return nil, nil, false
+	}
+
+	var groupID *uuid.UUID
+	if groupIDStr := c.PostForm("group_id"); groupIDStr != "" {
+		parsed, err := uuid.Parse(groupIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_id format"})
+			return nil, nil, false
+		}
+		groupID = &parsed
 	}
 
 	src, err := file.Open()
 	if err != nil {
 		respondInternalError(c, "Failed to open image")
-		return
+		return nil, nil, false
 	}
 	defer src.Close()
 
-	imgData, err := io.ReadAll(src)
+	imgData, err := io.ReadAll(io.LimitReader(src, service.MaxReceiptUploadSize+1))
 	if err != nil {
 		respondInternalError(c, "Failed to read image")
-		return
+		return nil, nil, false
 	}
 
-	result, err := h.aiAnalyzer.AnalyzeReceipt(c.Request.Context(), imgData)
+	img, err := imageProcessor.Prepare(c.Request.Context(), imgData)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to analyze receipt: %v", err)})
-		return
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to process image")
+		}
+		return nil, nil, false
 	}
-
-	c.JSON(http.StatusOK, result)
+	return img, groupID, true
 }
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"receipt/server/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ReceiptTextHandler 自然文からのレシートの入力関連ハンドラー
+type ReceiptTextHandler struct {
+	textService service.ReceiptTextService
+}
+
+// NewReceiptTextHandler ReceiptTextHandlerを作成
+func NewReceiptTextHandler(ts service.ReceiptTextService) *ReceiptTextHandler {
+	return &ReceiptTextHandler{textService: ts}
+}
+
+// ParseReceiptTextInput 自然文からのレシートの入力用入力
+type ParseReceiptTextInput struct {
+	GroupID uuid.UUID `json:"group_id" binding:"required"`
+	Text    string    `json:"text" binding:"required"`
+}
+
+// ParseReceiptTextResponse 自然文から作成したレシートの登録内容（receipt をそのまま POST /receipts に送れる）
+type ParseReceiptTextResponse struct {
+	Receipt     CreateReceiptInput `json:"receipt"`
+	NeedsReview []string           `json:"needs_review"`
+}
+
+// ParseText 「昨日サイゼリヤで3200円、折半」のような文をレシートの登録内容に変換する（登録はしない）
+func (h *ReceiptTextHandler) ParseText(c *gin.Context) {
+	var input ParseReceiptTextInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	parsed, err := h.textService.ParseText(c.Request.Context(), input.GroupID, userID, input.Text)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to parse receipt text")
+		}
+		return
+	}
+
+	params := parsed.Params
+	needsReview := parsed.NeedsReview
+	if needsReview == nil {
+		needsReview = []string{}
+	}
+	c.JSON(http.StatusOK, ParseReceiptTextResponse{
+		Receipt: CreateReceiptInput{
+			GroupID:         params.GroupID,
+			Date:            params.Date,
+			SettlementYear:  params.SettlementYear,
+			SettlementMonth: params.SettlementMonth,
+			Shop:            params.Shop,
+			Item:            params.Item,
+			Amount:          params.Amount,
+			PayerID:         params.PayerID,
+			PaymentMethod:   params.PaymentMethod,
+			ShopID:          params.ShopID,
+			Category:        params.Category,
+		},
+		NeedsReview: needsReview,
+	})
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"receipt/server/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// reportDateLayout レポート期間の日付形式
+const reportDateLayout = "2006-01-02"
+
+// ReportHandler 年次・任意期間レポート関連ハンドラー
+type ReportHandler struct {
+	reportService service.ReportService
+}
+
+// NewReportHandler ReportHandlerを作成
+func NewReportHandler(rs service.ReportService) *ReportHandler {
+	return &ReportHandler{reportService: rs}
+}
+
+// GetReport 任意期間のレポート取得（from, to は YYYY-MM-DD、両端を含む）
+func (h *ReportHandler) GetReport(c *gin.Context) {
+	groupIDStr := c.Query("group_id")
+	fromStr := c.Query("from")
+	toStr := c.Query("to")
+
+	if groupIDStr == "" || fromStr == "" || toStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_id, from, and to are required"})
+		return
+	}
+
+	groupID, err := uuid.Parse(groupIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_id format"})
+		return
+	}
+	from, err := time.ParseInLocation(reportDateLayout, fromStr, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from format (YYYY-MM-DD)"})
+		return
+	}
+	to, err := time.ParseInLocation(reportDateLayout, toStr, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to format (YYYY-MM-DD)"})
+		return
+	}
+
+	h.respondReport(c, groupID, from, to)
+}
+
+// GetYearlyReport 年次レポート取得（1月1日〜12月31日）
+func (h *ReportHandler) GetYearlyReport(c *gin.Context) {
+	groupIDStr := c.Query("group_id")
+	yearStr := c.Query("year")
+
+	if groupIDStr == "" || yearStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_id and year are required"})
+		return
+	}
+
+	groupID, err := uuid.Parse(groupIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_id format"})
+		return
+	}
+	year, err := strconv.Atoi(yearStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid year format"})
+		return
+	}
+
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(year, time.December, 31, 0, 0, 0, 0, time.Local)
+
+	h.respondReport(c, groupID, from, to)
+}
+
+func (h *ReportHandler) respondReport(c *gin.Context, groupID uuid.UUID, from time.Time, to time.Time) {
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	result, err := h.reportService.GetReport(groupID, userID, from, to, c.Query("granularity"))
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to get report")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
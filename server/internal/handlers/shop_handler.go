@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"net/http"
+	"receipt/server/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ShopInput 店舗の作成・更新用入力
+type ShopInput struct {
+	Name                 string   `json:"name" binding:"required"`
+	Aliases              []string `json:"aliases"` // 作成時のみ使用
+	DefaultCategory      string   `json:"default_category"`
+	DefaultPaymentMethod string   `json:"default_payment_method"`
+}
+
+// AddShopAliasInput 店舗の別名追加用入力
+type AddShopAliasInput struct {
+	Alias string `json:"alias" binding:"required"`
+}
+
+// MergeShopsInput 店舗統合用入力
+type MergeShopsInput struct {
+	SourceIDs []uuid.UUID `json:"source_ids"` // 統合先にまとめる店舗（空の場合は未紐付けのレシートの紐付けのみ）
+}
+
+// ShopHandler 店舗マスタ関連ハンドラー
+type ShopHandler struct {
+	shopService service.ShopService
+}
+
+// NewShopHandler ShopHandlerを作成
+func NewShopHandler(ss service.ShopService) *ShopHandler {
+	return &ShopHandler{shopService: ss}
+}
+
+// GetShops グループの店舗一覧取得
+func (h *ShopHandler) GetShops(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_id format"})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	shops, err := h.shopService.GetShops(groupID, userID)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to get shops")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, shops)
+}
+
+// CreateShop グループの店舗登録
+func (h *ShopHandler) CreateShop(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_id format"})
+		return
+	}
+
+	var input ShopInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	params := &service.ShopParams{
+		GroupID:              groupID,
+		Name:                 input.Name,
+		Aliases:              input.Aliases,
+		DefaultCategory:      input.DefaultCategory,
+		DefaultPaymentMethod: input.DefaultPaymentMethod,
+	}
+
+	shop, err := h.shopService.CreateShop(params, userID)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to create shop")
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, shop)
+}
+
+// UpdateShop 店舗名・既定値の更新
+func (h *ShopHandler) UpdateShop(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid shop id format"})
+		return
+	}
+
+	var input ShopInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	params := &service.ShopParams{
+		Name:                 input.Name,
+		DefaultCategory:      input.DefaultCategory,
+		DefaultPaymentMethod: input.DefaultPaymentMethod,
+	}
+
+	shop, err := h.shopService.UpdateShop(id, params, userID)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to update shop")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, shop)
+}
+
+// DeleteShop 店舗の削除（紐付いていたレシートは入力された店舗名のまま残る）
+func (h *ShopHandler) DeleteShop(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid shop id format"})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	if err := h.shopService.DeleteShop(id, userID); err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to delete shop")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Shop deleted successfully"})
+}
+
+// AddShopAlias 店舗の別名追加
+func (h *ShopHandler) AddShopAlias(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid shop id format"})
+		return
+	}
+
+	var input AddShopAliasInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	alias, err := h.shopService.AddShopAlias(id, userID, input.Alias)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to add shop alias")
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, alias)
+}
+
+// MergeShops 店舗の統合と、店舗名が一致するレシートの紐付け
+func (h *ShopHandler) MergeShops(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid shop id format"})
+		return
+	}
+
+	var input MergeShopsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	result, err := h.shopService.MergeShops(id, userID, input.SourceIDs)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to merge shops")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"receipt/server/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SpendingQuestionHandler 支出に関する質問関連ハンドラー
+type SpendingQuestionHandler struct {
+	questionService service.SpendingQuestionService
+}
+
+// NewSpendingQuestionHandler SpendingQuestionHandlerを作成
+func NewSpendingQuestionHandler(qs service.SpendingQuestionService) *SpendingQuestionHandler {
+	return &SpendingQuestionHandler{questionService: qs}
+}
+
+// AskInput 支出に関する質問用入力
+type AskInput struct {
+	Question string `json:"question" binding:"required"`
+}
+
+// Ask 「夏に外食にいくら使った？」のような質問に、集計した値と集計に使ったレシートで答える
+func (h *SpendingQuestionHandler) Ask(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_id format"})
+		return
+	}
+
+	var input AskInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	answer, err := h.questionService.Ask(c.Request.Context(), groupID, userID, input.Question)
+	if err != nil {
+		if !respondWithServiceError(c, err) {
+			respondInternalError(c, "Failed to answer the question")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, answer)
+}
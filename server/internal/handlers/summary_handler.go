@@ -15,6 +15,9 @@ type CreateSettlementInput struct {
 	Year    int       `json:"year" binding:"required"`
 	Month   int       `json:"month" binding:"required"`
 	Amount  int       `json:"amount" binding:"required"`
+
+	RecipientID *uuid.UUID `json:"recipient_id"` // 精算を受け取るメンバー（2人のグループでは省略可）
+	Cumulative  bool       `json:"cumulative"`   // 指定月までの累積残高をまとめて精算する
 }
 
 // SummaryHandler 集計・精算関連ハンドラー
@@ -46,7 +49,10 @@ func (h *SummaryHandler) GetMonthlySummary(c *gin.Context) {
 	year, _ := strconv.Atoi(yearStr)
 	month, _ := strconv.Atoi(monthStr)
 
-	result, err := h.summaryService.GetMonthlySummary(groupID, year, month)
+	userIDVal, _ := c.Get("userID")
+	userID := userIDVal.(uuid.UUID)
+
+	result, err := h.summaryService.GetMonthlySummary(groupID, userID, year, month)
 	if err != nil {
 		if !respondWithServiceError(c, err) {
 			respondInternalError(c, "Failed to get monthly summary")
@@ -68,7 +74,16 @@ func (h *SummaryHandler) CreateSettlement(c *gin.Context) {
 	userIDVal, _ := c.Get("userID")
 	userID := userIDVal.(uuid.UUID)
 
-	settlement, err := h.summaryService.CreateSettlement(input.GroupID, input.Year, input.Month, input.Amount, userID)
+	params := &service.CreateSettlementParams{
+		GroupID:     input.GroupID,
+		Year:        input.Year,
+		Month:       input.Month,
+		Amount:      input.Amount,
+		RecipientID: input.RecipientID,
+		Cumulative:  input.Cumulative,
+	}
+
+	settlement, err := h.summaryService.CreateSettlement(params, userID)
 	if err != nil {
 		if !respondWithServiceError(c, err) {
 			respondInternalError(c, "Failed to create settlement")
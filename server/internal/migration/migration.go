@@ -0,0 +1,456 @@
+package migration
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultLockTimeout 他のサーバーがマイグレーション中の場合に、ロックの解放を待つ既定の時間
+	defaultLockTimeout = 2 * time.Minute
+	// lockRetryInterval ロックを取得し直す間隔
+	lockRetryInterval = time.Second
+	// staleLockAge これより長く更新されていないロックは、取得したサーバーが停止したものとみなして解除する
+	staleLockAge = 10 * time.Minute
+	// lockHeartbeatInterval ロックを持っている間、ロックの更新日時（locked_at）を更新する間隔
+	lockHeartbeatInterval = time.Minute
+)
+
+var (
+	// ErrLockTimeout 他のサーバーのマイグレーションが終わらず、ロックを取得できなかった場合のエラー
+	ErrLockTimeout = errors.New("timed out waiting for the migration lock")
+	// ErrSchemaBehind 適用していないマイグレーションがある場合のエラー
+	ErrSchemaBehind = errors.New("database schema is behind")
+	// ErrUnknownVersion 元に戻すマイグレーションがこのバイナリに含まれていない場合のエラー
+	ErrUnknownVersion = errors.New("migration is not included in this binary")
+	// ErrIrreversible 元に戻せないマイグレーション（初期スキーマなど）を元に戻そうとした場合のエラー
+	ErrIrreversible = errors.New("migration cannot be reverted")
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationFilePattern マイグレーションのファイル名（例: 0001_initial_schema.up.sql）
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// irreversibleMarker down のSQLにこの行がある場合、そのマイグレーションは元に戻せない
+const irreversibleMarker = "-- irreversible"
+
+// 追加するものが既にある場合は実行しない文（objectExists）
+var (
+	addColumnPattern     = regexp.MustCompile("(?i)^ALTER TABLE [`\"]?(\\w+)[`\"]? ADD COLUMN [`\"]?(\\w+)")
+	createIndexPattern   = regexp.MustCompile("(?i)^CREATE (?:UNIQUE )?INDEX [`\"]?(\\w+)[`\"]? ON [`\"]?(\\w+)")
+	addConstraintPattern = regexp.MustCompile("(?i)^ALTER TABLE [`\"]?(\\w+)[`\"]? ADD CONSTRAINT [`\"]?(\\w+)")
+)
+
+// bookkeepingTables 適用済みのマイグレーションとロックを記録するテーブル（データベースの種類ごと）
+var bookkeepingTables = map[string][]string{
+	"mysql": {
+		"CREATE TABLE IF NOT EXISTS `schema_migrations` (`version` bigint NOT NULL, `name` varchar(255) NOT NULL, `applied_at` datetime(3) NOT NULL, PRIMARY KEY (`version`))",
+		"CREATE TABLE IF NOT EXISTS `schema_migration_lock` (`id` int NOT NULL, `owner` varchar(100) NOT NULL, `locked_at` datetime(3) NOT NULL, PRIMARY KEY (`id`))",
+	},
+	"postgres": {
+		`CREATE TABLE IF NOT EXISTS "schema_migrations" ("version" bigint NOT NULL, "name" varchar(255) NOT NULL, "applied_at" timestamptz NOT NULL, PRIMARY KEY ("version"))`,
+		`CREATE TABLE IF NOT EXISTS "schema_migration_lock" ("id" int NOT NULL, "owner" varchar(100) NOT NULL, "locked_at" timestamptz NOT NULL, PRIMARY KEY ("id"))`,
+	},
+	"sqlite": {
+		"CREATE TABLE IF NOT EXISTS `schema_migrations` (`version` integer NOT NULL, `name` varchar(255) NOT NULL, `applied_at` datetime NOT NULL, PRIMARY KEY (`version`))",
+		"CREATE TABLE IF NOT EXISTS `schema_migration_lock` (`id` integer NOT NULL, `owner` varchar(100) NOT NULL, `locked_at` datetime NOT NULL, PRIMARY KEY (`id`))",
+	},
+}
+
+// Migration バージョン付きのスキーマ変更（Up で適用し、Down で元に戻す）
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Irreversible 元に戻せないマイグレーションかどうか（down のSQLに irreversibleMarker の行がある）
+func (m Migration) Irreversible() bool {
+	for _, line := range strings.Split(m.Down, "\n") {
+		if strings.TrimSpace(line) == irreversibleMarker {
+			return true
+		}
+	}
+	return false
+}
+
+// Status マイグレーションの適用状況
+type Status struct {
+	Version   int
+	Name      string     // このバイナリに含まれていない場合は空
+	AppliedAt *time.Time // 適用していない場合はnil
+}
+
+// appliedMigration schema_migrations の行
+type appliedMigration struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time
+}
+
+// Migrator バイナリに埋め込んだSQLのマイグレーションを適用する。
+// 複数のサーバーが同時に起動しても、ロックを取得した1台だけが適用する
+type Migrator struct {
+	db          *gorm.DB
+	dialect     string
+	migrations  []Migration
+	owner       string
+	lockTimeout time.Duration
+}
+
+// New 接続先のデータベースの種類のマイグレーションを読み込んだMigratorを作成
+func New(db *gorm.DB) (*Migrator, error) {
+	dialect := db.Dialector.Name()
+	if _, ok := bookkeepingTables[dialect]; !ok {
+		return nil, fmt.Errorf("migrations are not available for %s", dialect)
+	}
+	migrations, err := Load(dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	return &Migrator{
+		db:          db,
+		dialect:     dialect,
+		migrations:  migrations,
+		owner:       fmt.Sprintf("%s/%s", hostname, uuid.NewString()),
+		lockTimeout: defaultLockTimeout,
+	}, nil
+}
+
+// Load データベースの種類のマイグレーションをバージョン順に読み込む（up と down の両方が必要）
+func Load(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations for %s: %w", dialect, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		m := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		content, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has different names: %s and %s", version, migration.Name, m[2])
+		}
+		if m[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Status すべてのマイグレーションの適用状況（このバイナリに含まれていない適用済みのバージョンを含む）
+func (m *Migrator) Status() ([]Status, error) {
+	if err := m.ensureBookkeepingTables(); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if a, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &a.AppliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, a := range applied {
+		appliedAt := a.AppliedAt
+		statuses = append(statuses, Status{Version: a.Version, AppliedAt: &appliedAt})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Pending 適用していないマイグレーション。
+// AutoMigrate で作成した以前のバージョンのデータベースにも初期スキーマを適用し、不足しているテーブル・列を追加する
+func (m *Migrator) Pending() ([]Migration, error) {
+	if err := m.ensureBookkeepingTables(); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Check 適用していないマイグレーションがある場合に ErrSchemaBehind を返す
+func (m *Migrator) Check() error {
+	pending, err := m.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migration(s) from version %d", ErrSchemaBehind, len(pending), pending[0].Version)
+	}
+	return nil
+}
+
+// Up 適用していないマイグレーションをバージョン順にすべて適用し、適用したマイグレーションを返す
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func() error {
+		pending, err := m.Pending()
+		if err != nil {
+			return err
+		}
+		for _, migration := range pending {
+			if err := m.run(migration.Up, func(tx *gorm.DB) error {
+				return tx.Table("schema_migrations").Create(map[string]interface{}{
+					"version":    migration.Version,
+					"name":       migration.Name,
+					"applied_at": time.Now(),
+				}).Error
+			}); err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			log.Printf("applied migration %d_%s", migration.Version, migration.Name)
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down 適用済みのマイグレーションを新しい順に steps 件元に戻し、元に戻したマイグレーションを返す。
+// 元に戻せないマイグレーションが含まれる場合は、何も元に戻さずに ErrIrreversible を返す
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func() error {
+		var versions []int
+		err := m.db.Table("schema_migrations").Order("version desc").Limit(steps).Pluck("version", &versions).Error
+		if err != nil {
+			return err
+		}
+
+		migrations := make([]Migration, 0, len(versions))
+		for _, version := range versions {
+			migration, ok := m.find(version)
+			if !ok {
+				return fmt.Errorf("%w: version %d", ErrUnknownVersion, version)
+			}
+			if migration.Irreversible() {
+				return fmt.Errorf("%w: %d_%s", ErrIrreversible, migration.Version, migration.Name)
+			}
+			migrations = append(migrations, migration)
+		}
+
+		for _, migration := range migrations {
+			if err := m.run(migration.Down, func(tx *gorm.DB) error {
+				return tx.Exec("DELETE FROM schema_migrations WHERE version = ?", migration.Version).Error
+			}); err != nil {
+				return fmt.Errorf("failed to revert migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			log.Printf("reverted migration %d_%s", migration.Version, migration.Name)
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// run マイグレーションのSQLと記録を1つのトランザクションで実行する。
+// MySQL ではDDLが暗黙的にコミットされるため、途中で失敗した場合は実行済みの文が残る（マイグレーションは適用済みとして記録しない）。
+// PostgreSQL・SQLite ではDDLもロールバックされる
+func (m *Migrator) run(sql string, record func(tx *gorm.DB) error) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range splitStatements(sql) {
+			exists, err := objectExists(tx, statement)
+			if err != nil {
+				return err
+			}
+			if exists {
+				continue
+			}
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return record(tx)
+	})
+}
+
+// withLock マイグレーションのロックを取得して fn を実行する。他のサーバーがロックを持っている場合は解放されるまで待つ。
+// 時間のかかるマイグレーションの途中で停止したものとみなされないよう、fn の実行中はロックの更新日時を更新し続ける
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	if err := m.ensureBookkeepingTables(); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(m.lockTimeout)
+	for {
+		err := m.db.Exec("INSERT INTO schema_migration_lock (id, owner, locked_at) VALUES (1, ?, ?)", m.owner, time.Now()).Error
+		if err == nil {
+			break
+		}
+		if err := m.db.Exec("DELETE FROM schema_migration_lock WHERE id = 1 AND locked_at < ?", time.Now().Add(-staleLockAge)).Error; err != nil {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return ErrLockTimeout
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+	defer func() {
+		if err := m.db.Exec("DELETE FROM schema_migration_lock WHERE id = 1 AND owner = ?", m.owner).Error; err != nil {
+			log.Printf("failed to release migration lock: %v", err)
+		}
+	}()
+
+	stop := make(chan struct{})
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		m.heartbeat(stop)
+	}()
+	defer func() {
+		close(stop)
+		<-heartbeatDone
+	}()
+	return fn()
+}
+
+// heartbeat stop が閉じられるまで、持っているロックの更新日時を一定間隔で更新する
+func (m *Migrator) heartbeat(stop <-chan struct{}) {
+	ticker := time.NewTicker(lockHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			result := m.db.Exec("UPDATE schema_migration_lock SET locked_at = ? WHERE id = 1 AND owner = ?", time.Now(), m.owner)
+			if result.Error != nil {
+				log.Printf("failed to refresh migration lock: %v", result.Error)
+			} else if result.RowsAffected == 0 {
+				log.Printf("migration lock was taken over by another server")
+			}
+		}
+	}
+}
+
+func (m *Migrator) ensureBookkeepingTables() error {
+	for _, statement := range bookkeepingTables[m.dialect] {
+		if err := m.db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("failed to create migration tables: %w", err)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) applied() (map[int]appliedMigration, error) {
+	var rows []appliedMigration
+	if err := m.db.Table("schema_migrations").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int]appliedMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+func (m *Migrator) find(version int) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// objectExists 列・インデックス・制約を追加する文で、追加するものが既にあるかどうか。
+// どのデータベースでも使える IF NOT EXISTS がないため、AutoMigrate で作成済みのデータベースに適用する場合に備えて確認する
+func objectExists(tx *gorm.DB, statement string) (bool, error) {
+	if m := addColumnPattern.FindStringSubmatch(statement); m != nil {
+		return tx.Migrator().HasColumn(m[1], m[2]), nil
+	}
+	if m := createIndexPattern.FindStringSubmatch(statement); m != nil {
+		return tx.Migrator().HasIndex(m[2], m[1]), nil
+	}
+	if m := addConstraintPattern.FindStringSubmatch(statement); m != nil {
+		return tx.Migrator().HasConstraint(m[1], m[2]), nil
+	}
+	return false, nil
+}
+
+// splitStatements マイグレーションのSQLを文ごとに分割する（行末の「;」で区切り、「--」で始まる行は読み飛ばす）
+func splitStatements(sql string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(sql, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
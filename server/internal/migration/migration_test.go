@@ -0,0 +1,313 @@
+package migration_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"receipt/server/config"
+	"receipt/server/internal/migration"
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestLoad(t *testing.T) {
+	// データベースの種類ごとの識別子の引用符
+	dialects := map[string]string{"mysql": "`", "postgres": `"`, "sqlite": "`"}
+
+	var names []string
+	for dialect, quote := range dialects {
+		migrations, err := migration.Load(dialect)
+		if err != nil {
+			t.Fatalf("Load(%s) failed: %v", dialect, err)
+		}
+		if len(migrations) == 0 {
+			t.Fatalf("expected embedded migrations for %s", dialect)
+		}
+
+		for i, m := range migrations {
+			if m.Version != i+1 {
+				t.Errorf("%s: expected version %d, got %d (%s)", dialect, i+1, m.Version, m.Name)
+			}
+			if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+				t.Errorf("%s: expected up and down SQL for %d_%s", dialect, m.Version, m.Name)
+			}
+		}
+
+		// どの種類のデータベースでも同じバージョンのマイグレーションがそろっている
+		var dialectNames []string
+		for _, m := range migrations {
+			dialectNames = append(dialectNames, m.Name)
+		}
+		if names == nil {
+			names = dialectNames
+		} else if strings.Join(names, ",") != strings.Join(dialectNames, ",") {
+			t.Errorf("%s: expected migrations %v, got %v", dialect, names, dialectNames)
+		}
+
+		// 初期スキーマはモデルのすべてのテーブルを作成する
+		for _, table := range []string{"users", "groups", "group_members", "group_membership_periods", "receipts", "settlements", "shops", "shop_aliases", "rate_limit_buckets", "recovery_codes", "credentials", "external_identities", "analysis_jobs", "receipt_drafts", "analysis_feedbacks", "ai_usages", "analysis_caches"} {
+			if !strings.Contains(migrations[0].Up, "CREATE TABLE IF NOT EXISTS "+quote+table+quote) {
+				t.Errorf("%s: expected initial schema to create %s", dialect, table)
+			}
+		}
+		// 初期スキーマは既存のデータベースを引き継ぐため、元に戻せない（テーブルを削除しない）
+		if !migrations[0].Irreversible() || strings.Contains(migrations[0].Down, "DROP TABLE") {
+			t.Errorf("%s: expected initial schema to be irreversible", dialect)
+		}
+	}
+}
+
+func TestMigratorSQLite(t *testing.T) {
+	db, err := config.OpenDB(config.DBConfig{Driver: config.DBDriverSQLite, Path: filepath.Join(t.TempDir(), "receipt.db")})
+	if err != nil {
+		t.Fatalf("OpenDB failed: %v", err)
+	}
+	migrator, err := migration.New(db)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := migrator.Check(); !errors.Is(err, migration.ErrSchemaBehind) {
+		t.Errorf("expected ErrSchemaBehind before migrating, got %v", err)
+	}
+
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	all, _ := migration.Load("sqlite")
+	if len(applied) != len(all) {
+		t.Errorf("expected %d applied migrations, got %d", len(all), len(applied))
+	}
+	if err := migrator.Check(); err != nil {
+		t.Errorf("expected schema to be up to date, got %v", err)
+	}
+	if again, err := migrator.Up(context.Background()); err != nil || len(again) != 0 {
+		t.Errorf("expected nothing to apply, got %d (%v)", len(again), err)
+	}
+
+	// 初期スキーマまで元に戻そうとした場合は、何も元に戻さない
+	if reverted, err := migrator.Down(context.Background(), len(all)); !errors.Is(err, migration.ErrIrreversible) || len(reverted) != 0 {
+		t.Errorf("expected ErrIrreversible without reverting anything, got %d (%v)", len(reverted), err)
+	}
+	if err := migrator.Check(); err != nil {
+		t.Errorf("expected schema to stay up to date, got %v", err)
+	}
+
+	// 初期スキーマ以降は元に戻し、もう一度適用できる
+	reverted, err := migrator.Down(context.Background(), len(all)-1)
+	if err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if len(reverted) != len(all)-1 {
+		t.Errorf("expected %d reverted migrations, got %d", len(all)-1, len(reverted))
+	}
+	if !db.Migrator().HasTable("users") {
+		t.Error("expected users table to be kept")
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Up after Down failed: %v", err)
+	}
+
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			t.Errorf("expected %04d_%s to be applied", s.Version, s.Name)
+		}
+	}
+}
+
+func TestLoadUnknownDialect(t *testing.T) {
+	if _, err := migration.Load("oracle"); err == nil {
+		t.Error("expected error for a database without migrations")
+	}
+}
+
+// TestMigratorUpgradesAutoMigrateSchema マイグレーション導入前に AutoMigrate で作成したデータベースに適用できる
+func TestMigratorUpgradesAutoMigrateSchema(t *testing.T) {
+	// マイグレーション導入前の最初のバージョンのモデル（User, Group, Receipt, Settlement のみを AutoMigrate していた）
+	type User struct {
+		ID           uuid.UUID `gorm:"type:char(36);primaryKey"`
+		Email        string    `gorm:"type:varchar(255);uniqueIndex;not null"`
+		PasswordHash string    `gorm:"type:varchar(255);not null"`
+		Nickname     string    `gorm:"type:varchar(100);not null"`
+		CreatedAt    time.Time
+		UpdatedAt    time.Time
+		DeletedAt    gorm.DeletedAt `gorm:"index"`
+	}
+	type Group struct {
+		ID        uuid.UUID `gorm:"type:char(36);primaryKey"`
+		Name      string    `gorm:"type:varchar(100);not null"`
+		OwnerID   uuid.UUID `gorm:"type:char(36);not null"`
+		CreatedAt time.Time
+		UpdatedAt time.Time
+		DeletedAt gorm.DeletedAt `gorm:"index"`
+		Members   []User         `gorm:"many2many:group_members;"`
+		Owner     User           `gorm:"foreignKey:OwnerID"`
+	}
+	type Receipt struct {
+		ID              uuid.UUID `gorm:"type:char(36);primaryKey"`
+		GroupID         uuid.UUID `gorm:"type:char(36);not null"`
+		UserID          uuid.UUID `gorm:"type:char(36);not null"`
+		Date            time.Time `gorm:"not null"`
+		SettlementYear  int       `gorm:"not null"`
+		SettlementMonth int       `gorm:"not null"`
+		Shop            string    `gorm:"type:varchar(255)"`
+		Item            string    `gorm:"type:varchar(255)"`
+		Amount          int       `gorm:"not null"`
+		PayerID         uuid.UUID `gorm:"type:char(36);not null"`
+		PaymentMethod   string    `gorm:"type:varchar(50);not null"`
+		SettledAt       *time.Time
+		CreatedAt       time.Time
+		UpdatedAt       time.Time
+		DeletedAt       gorm.DeletedAt `gorm:"index"`
+
+		Group Group `gorm:"foreignKey:GroupID"`
+		User  User  `gorm:"foreignKey:UserID"`
+		Payer User  `gorm:"foreignKey:PayerID"`
+	}
+	type Settlement struct {
+		ID        uuid.UUID `gorm:"type:char(36);primaryKey"`
+		GroupID   uuid.UUID `gorm:"type:char(36);not null"`
+		Year      int       `gorm:"not null"`
+		Month     int       `gorm:"not null"`
+		Amount    int       `gorm:"not null"`
+		SettledBy uuid.UUID `gorm:"type:char(36);not null"`
+		CreatedAt time.Time
+
+		Group         Group `gorm:"foreignKey:GroupID"`
+		SettledByUser User  `gorm:"foreignKey:SettledBy"`
+	}
+
+	db, err := config.OpenDB(config.DBConfig{Driver: config.DBDriverSQLite, Path: filepath.Join(t.TempDir(), "receipt.db")})
+	if err != nil {
+		t.Fatalf("OpenDB failed: %v", err)
+	}
+	legacy := db.Session(&gorm.Session{NewDB: true})
+	if err := legacy.AutoMigrate(&User{}, &Group{}, &Receipt{}, &Settlement{}); err != nil {
+		t.Fatalf("AutoMigrate failed: %v", err)
+	}
+
+	owner := User{ID: uuid.New(), Email: "owner@example.com", PasswordHash: "hash", Nickname: "owner"}
+	member := User{ID: uuid.New(), Email: "member@example.com", PasswordHash: "hash", Nickname: "member"}
+	group := Group{ID: uuid.New(), Name: "家計", OwnerID: owner.ID, Members: []User{owner, member}}
+	receipt := Receipt{ID: uuid.New(), GroupID: group.ID, UserID: owner.ID, Date: time.Now(), SettlementYear: 2024, SettlementMonth: 1, Amount: 1000, PayerID: owner.ID, PaymentMethod: "half"}
+	for _, v := range []interface{}{&owner, &member, &group, &receipt} {
+		if err := legacy.Create(v).Error; err != nil {
+			t.Fatalf("failed to create legacy data: %v", err)
+		}
+	}
+
+	migrator, err := migration.New(db)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := migrator.Check(); !errors.Is(err, migration.ErrSchemaBehind) {
+		t.Errorf("expected ErrSchemaBehind before migrating, got %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if err := migrator.Check(); err != nil {
+		t.Errorf("expected schema to be up to date, got %v", err)
+	}
+
+	// 後から追加したテーブル・列がそろっている
+	for _, table := range []string{"group_membership_periods", "shops", "shop_aliases", "rate_limit_buckets", "recovery_codes", "credentials", "external_identities", "analysis_jobs", "receipt_drafts", "analysis_feedbacks", "ai_usages", "analysis_caches"} {
+		if !db.Migrator().HasTable(table) {
+			t.Errorf("expected %s to be created", table)
+		}
+	}
+	for table, columns := range map[string][]string{
+		"users":         {"failed_login_count", "locked_until", "totp_secret", "totp_enabled", "totp_last_used_step"},
+		"groups":        {"archived_at", "purge_requested_at"},
+		"group_members": {"role", "created_at"},
+		"receipts":      {"shop_id", "category"},
+		"settlements":   {"recipient_id", "cumulative"},
+	} {
+		for _, column := range columns {
+			if !db.Migrator().HasColumn(table, column) {
+				t.Errorf("expected %s.%s to be added", table, column)
+			}
+		}
+	}
+	if !db.Migrator().HasIndex("receipts", "idx_receipts_shop_id") {
+		t.Error("expected idx_receipts_shop_id to be created")
+	}
+
+	// 既存のメンバーにロールと在籍期間が補完される
+	role, _ := repository.NewGroupRepository(db).GetMemberRole(group.ID, owner.ID)
+	if role != models.GroupRoleOwner {
+		t.Errorf("expected owner role, got %q", role)
+	}
+	periods, err := repository.NewGroupRepository(db).GetMembershipPeriods(group.ID)
+	if err != nil || len(periods) != 2 {
+		t.Errorf("expected 2 membership periods, got %d (%v)", len(periods), err)
+	}
+
+	// 現在のモデルで読み書きできる
+	var receipts []models.Receipt
+	if err := db.Where("group_id = ?", group.ID).Find(&receipts).Error; err != nil || len(receipts) != 1 {
+		t.Fatalf("expected the legacy receipt, got %d (%v)", len(receipts), err)
+	}
+	receipts[0].Category = "食費"
+	if err := db.Save(&receipts[0]).Error; err != nil {
+		t.Errorf("failed to update receipt with the current model: %v", err)
+	}
+	if err := db.Create(&models.Settlement{GroupID: group.ID, Year: 2024, Month: 1, Amount: 500, SettledBy: owner.ID, Cumulative: true}).Error; err != nil {
+		t.Errorf("failed to create settlement with the current model: %v", err)
+	}
+
+	// 元に戻しても、以前から記録していたデータは削除されない（down を繰り返しても初期スキーマで止まる）
+	all, _ := migration.Load("sqlite")
+	for i := 0; i < len(all)+1; i++ {
+		_, err := migrator.Down(context.Background(), 1)
+		if i < len(all)-1 && err != nil {
+			t.Fatalf("Down failed: %v", err)
+		}
+		if i >= len(all)-1 && !errors.Is(err, migration.ErrIrreversible) {
+			t.Errorf("expected ErrIrreversible for the initial schema, got %v", err)
+		}
+	}
+	var users, groups, receiptCount int64
+	db.Table("users").Count(&users)
+	db.Table("groups").Count(&groups)
+	db.Table("receipts").Count(&receiptCount)
+	if users != 2 || groups != 1 || receiptCount != 1 {
+		t.Errorf("expected legacy data to be kept, got %d users, %d groups and %d receipts", users, groups, receiptCount)
+	}
+}
+
+// TestMigratorUpgradesCurrentAutoMigrateSchema マイグレーション導入直前のバージョン（すべてのモデルを AutoMigrate していた）のデータベースに適用できる
+func TestMigratorUpgradesCurrentAutoMigrateSchema(t *testing.T) {
+	db, err := config.OpenDB(config.DBConfig{Driver: config.DBDriverSQLite, Path: filepath.Join(t.TempDir(), "receipt.db")})
+	if err != nil {
+		t.Fatalf("OpenDB failed: %v", err)
+	}
+	err = db.AutoMigrate(&models.User{}, &models.Group{}, &models.GroupMember{}, &models.GroupMembershipPeriod{}, &models.Receipt{}, &models.Settlement{},
+		&models.Shop{}, &models.ShopAlias{}, &models.RateLimitBucket{}, &models.RecoveryCode{}, &models.Credential{}, &models.ExternalIdentity{},
+		&models.AnalysisJob{}, &models.ReceiptDraft{}, &models.AnalysisFeedback{}, &models.AIUsage{}, &models.AnalysisCache{})
+	if err != nil {
+		t.Fatalf("AutoMigrate failed: %v", err)
+	}
+
+	migrator, err := migration.New(db)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if err := migrator.Check(); err != nil {
+		t.Errorf("expected schema to be up to date, got %v", err)
+	}
+}
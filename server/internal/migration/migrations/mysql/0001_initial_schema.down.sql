@@ -0,0 +1,4 @@
+-- irreversible
+-- 初期スキーマは元に戻せない。
+-- AutoMigrate で作成した以前のバージョンのデータベースも、既存のテーブルを引き継いでこのマイグレーションを適用済みとして記録するため、
+-- テーブルを削除すると以前から記録していたデータまで失われる
@@ -0,0 +1,291 @@
+-- 初期スキーマ（AutoMigrate で作成していた時点のテーブル）
+-- AutoMigrate で作成した以前のバージョンのデータベースにも適用できるよう、既にあるテーブルは作成せず、
+-- 不足している列・インデックス・制約だけを追加する（既にある列・インデックス・制約を追加する文は実行しない）
+
+CREATE TABLE IF NOT EXISTS "users" (
+  "id" char(36),
+  "email" varchar(255) NOT NULL,
+  "password_hash" varchar(255),
+  "nickname" varchar(100) NOT NULL,
+  "failed_login_count" bigint NOT NULL DEFAULT 0,
+  "locked_until" timestamptz,
+  "totp_secret" varchar(64),
+  "totp_enabled" boolean NOT NULL DEFAULT false,
+  "totp_last_used_step" bigint NOT NULL DEFAULT 0,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  "deleted_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+-- 以前のバージョンで作成したテーブルに不足している列
+ALTER TABLE "users" ADD COLUMN "failed_login_count" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "users" ADD COLUMN "locked_until" timestamptz;
+ALTER TABLE "users" ADD COLUMN "totp_secret" varchar(64);
+ALTER TABLE "users" ADD COLUMN "totp_enabled" boolean NOT NULL DEFAULT false;
+ALTER TABLE "users" ADD COLUMN "totp_last_used_step" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "users" ALTER COLUMN "password_hash" DROP NOT NULL;
+CREATE INDEX "idx_users_deleted_at" ON "users" ("deleted_at");
+CREATE UNIQUE INDEX "idx_users_email" ON "users" ("email");
+
+CREATE TABLE IF NOT EXISTS "groups" (
+  "id" char(36),
+  "name" varchar(100) NOT NULL,
+  "owner_id" char(36) NOT NULL,
+  "archived_at" timestamptz,
+  "purge_requested_at" timestamptz,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  "deleted_at" timestamptz,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_groups_owner" FOREIGN KEY ("owner_id") REFERENCES "users"("id")
+);
+-- 以前のバージョンで作成したテーブルに不足している列
+ALTER TABLE "groups" ADD COLUMN "archived_at" timestamptz;
+ALTER TABLE "groups" ADD COLUMN "purge_requested_at" timestamptz;
+CREATE INDEX "idx_groups_deleted_at" ON "groups" ("deleted_at");
+CREATE INDEX "idx_groups_purge_requested_at" ON "groups" ("purge_requested_at");
+
+CREATE TABLE IF NOT EXISTS "group_members" (
+  "group_id" char(36),
+  "user_id" char(36),
+  "role" varchar(20) NOT NULL DEFAULT 'member',
+  "created_at" timestamptz,
+  PRIMARY KEY ("group_id","user_id"),
+  CONSTRAINT "fk_groups_memberships" FOREIGN KEY ("group_id") REFERENCES "groups"("id"),
+  CONSTRAINT "fk_group_members_group" FOREIGN KEY ("group_id") REFERENCES "groups"("id"),
+  CONSTRAINT "fk_group_members_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+-- 以前のバージョンで作成したテーブルに不足している列
+ALTER TABLE "group_members" ADD COLUMN "role" varchar(20) NOT NULL DEFAULT 'member';
+ALTER TABLE "group_members" ADD COLUMN "created_at" timestamptz;
+
+CREATE TABLE IF NOT EXISTS "group_membership_periods" (
+  "id" char(36),
+  "group_id" char(36) NOT NULL,
+  "user_id" char(36) NOT NULL,
+  "joined_at" timestamptz NOT NULL,
+  "left_at" timestamptz,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_group_membership_periods_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX "idx_membership_period_group_user" ON "group_membership_periods" ("group_id","user_id");
+
+CREATE TABLE IF NOT EXISTS "receipts" (
+  "id" char(36),
+  "group_id" char(36) NOT NULL,
+  "user_id" char(36) NOT NULL,
+  "date" timestamptz NOT NULL,
+  "settlement_year" bigint NOT NULL,
+  "settlement_month" bigint NOT NULL,
+  "shop" varchar(255),
+  "shop_id" char(36),
+  "category" varchar(50),
+  "item" varchar(255),
+  "amount" bigint NOT NULL,
+  "payer_id" char(36) NOT NULL,
+  "payment_method" varchar(50) NOT NULL,
+  "settled_at" timestamptz,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  "deleted_at" timestamptz,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_receipts_group" FOREIGN KEY ("group_id") REFERENCES "groups"("id"),
+  CONSTRAINT "fk_receipts_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+  CONSTRAINT "fk_receipts_payer" FOREIGN KEY ("payer_id") REFERENCES "users"("id")
+);
+-- 以前のバージョンで作成したテーブルに不足している列
+ALTER TABLE "receipts" ADD COLUMN "shop_id" char(36);
+ALTER TABLE "receipts" ADD COLUMN "category" varchar(50);
+CREATE INDEX "idx_receipts_deleted_at" ON "receipts" ("deleted_at");
+CREATE INDEX "idx_receipts_shop_id" ON "receipts" ("shop_id");
+
+CREATE TABLE IF NOT EXISTS "settlements" (
+  "id" char(36),
+  "group_id" char(36) NOT NULL,
+  "year" bigint NOT NULL,
+  "month" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "settled_by" char(36) NOT NULL,
+  "recipient_id" char(36),
+  "cumulative" boolean NOT NULL DEFAULT false,
+  "created_at" timestamptz,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_settlements_group" FOREIGN KEY ("group_id") REFERENCES "groups"("id"),
+  CONSTRAINT "fk_settlements_settled_by_user" FOREIGN KEY ("settled_by") REFERENCES "users"("id")
+);
+-- 以前のバージョンで作成したテーブルに不足している列
+ALTER TABLE "settlements" ADD COLUMN "recipient_id" char(36);
+ALTER TABLE "settlements" ADD COLUMN "cumulative" boolean NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS "shops" (
+  "id" char(36),
+  "group_id" char(36) NOT NULL,
+  "name" varchar(255) NOT NULL,
+  "default_category" varchar(50),
+  "default_payment_method" varchar(50),
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_shops_group_id" ON "shops" ("group_id");
+
+CREATE TABLE IF NOT EXISTS "shop_aliases" (
+  "id" char(36),
+  "group_id" char(36),
+  "shop_id" char(36),
+  "alias" varchar(255) NOT NULL,
+  "canonical_name" varchar(255) NOT NULL,
+  "created_at" timestamptz,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_shops_aliases" FOREIGN KEY ("shop_id") REFERENCES "shops"("id")
+);
+-- 以前のバージョンで作成したテーブルに不足している列
+ALTER TABLE "shop_aliases" ADD COLUMN "shop_id" char(36);
+ALTER TABLE "shop_aliases" ADD CONSTRAINT "fk_shops_aliases" FOREIGN KEY ("shop_id") REFERENCES "shops"("id");
+CREATE INDEX "idx_shop_aliases_shop_id" ON "shop_aliases" ("shop_id");
+CREATE INDEX "idx_shop_aliases_group_id" ON "shop_aliases" ("group_id");
+
+CREATE TABLE IF NOT EXISTS "rate_limit_buckets" (
+  "key" varchar(255),
+  "tokens" decimal NOT NULL,
+  "updated_at" timestamptz NOT NULL,
+  PRIMARY KEY ("key")
+);
+
+CREATE TABLE IF NOT EXISTS "recovery_codes" (
+  "id" char(36),
+  "user_id" char(36) NOT NULL,
+  "code_hash" char(64) NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_recovery_codes_user_id" ON "recovery_codes" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "credentials" (
+  "id" char(36),
+  "user_id" char(36) NOT NULL,
+  "credential_id" varchar(255) NOT NULL,
+  "name" varchar(100),
+  "data" text NOT NULL,
+  "last_used_at" timestamptz,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_credentials_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE UNIQUE INDEX "idx_credentials_credential_id" ON "credentials" ("credential_id");
+CREATE INDEX "idx_credentials_user_id" ON "credentials" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "external_identities" (
+  "id" char(36),
+  "user_id" char(36) NOT NULL,
+  "issuer" varchar(255) NOT NULL,
+  "subject" varchar(255) NOT NULL,
+  "email" varchar(255),
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_external_identities_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE UNIQUE INDEX "idx_external_identity_subject" ON "external_identities" ("issuer","subject");
+CREATE INDEX "idx_external_identities_user_id" ON "external_identities" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "analysis_jobs" (
+  "id" char(36),
+  "user_id" char(36) NOT NULL,
+  "group_id" char(36),
+  "batch_id" char(36),
+  "file_name" varchar(255),
+  "status" varchar(20) NOT NULL,
+  "mime_type" varchar(100) NOT NULL,
+  "image_data" bytea,
+  "preview_data" bytea,
+  "page_count" bigint NOT NULL DEFAULT 0,
+  "attempts" bigint NOT NULL DEFAULT 0,
+  "result" text,
+  "error" varchar(1000),
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  "started_at" timestamptz,
+  "completed_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+-- 以前のバージョンで作成したテーブルに不足している列
+ALTER TABLE "analysis_jobs" ADD COLUMN "batch_id" char(36);
+ALTER TABLE "analysis_jobs" ADD COLUMN "file_name" varchar(255);
+CREATE INDEX "idx_analysis_jobs_status" ON "analysis_jobs" ("status");
+CREATE INDEX "idx_analysis_jobs_batch_id" ON "analysis_jobs" ("batch_id");
+CREATE INDEX "idx_analysis_jobs_user_id" ON "analysis_jobs" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "receipt_drafts" (
+  "id" char(36),
+  "user_id" char(36) NOT NULL,
+  "group_id" char(36),
+  "date" timestamptz,
+  "shop" varchar(255),
+  "analyzed_shop" varchar(255),
+  "shop_id" char(36),
+  "category" varchar(100),
+  "item" varchar(255),
+  "amount" bigint NOT NULL DEFAULT 0,
+  "payment_method" varchar(50),
+  "needs_review" varchar(100),
+  "mime_type" varchar(100),
+  "image_data" bytea,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+-- 以前のバージョンで作成したテーブルに不足している列
+ALTER TABLE "receipt_drafts" ADD COLUMN "analyzed_shop" varchar(255);
+CREATE INDEX "idx_receipt_drafts_expires_at" ON "receipt_drafts" ("expires_at");
+CREATE INDEX "idx_receipt_drafts_user_id" ON "receipt_drafts" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "analysis_feedbacks" (
+  "id" char(36),
+  "group_id" char(36) NOT NULL,
+  "user_id" char(36) NOT NULL,
+  "receipt_id" char(36) NOT NULL,
+  "proposed_date" timestamptz,
+  "analyzed_shop" varchar(255),
+  "proposed_shop" varchar(255),
+  "proposed_item" varchar(255),
+  "proposed_amount" bigint NOT NULL DEFAULT 0,
+  "final_shop" varchar(255),
+  "final_item" varchar(255),
+  "date_edited" boolean NOT NULL DEFAULT false,
+  "shop_edited" boolean NOT NULL DEFAULT false,
+  "item_edited" boolean NOT NULL DEFAULT false,
+  "amount_edited" boolean NOT NULL DEFAULT false,
+  "created_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_analysis_feedbacks_created_at" ON "analysis_feedbacks" ("created_at");
+CREATE INDEX "idx_analysis_feedbacks_analyzed_shop" ON "analysis_feedbacks" ("analyzed_shop");
+CREATE INDEX "idx_analysis_feedbacks_group_id" ON "analysis_feedbacks" ("group_id");
+
+CREATE TABLE IF NOT EXISTS "ai_usages" (
+  "id" char(36),
+  "user_id" char(36) NOT NULL,
+  "group_id" char(36),
+  "kind" varchar(50) NOT NULL,
+  "cached" boolean NOT NULL DEFAULT false,
+  "prompt_tokens" bigint NOT NULL DEFAULT 0,
+  "output_tokens" bigint NOT NULL DEFAULT 0,
+  "total_tokens" bigint NOT NULL DEFAULT 0,
+  "created_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_ai_usage_group_created" ON "ai_usages" ("group_id","created_at");
+CREATE INDEX "idx_ai_usage_user_created" ON "ai_usages" ("user_id","created_at");
+
+CREATE TABLE IF NOT EXISTS "analysis_caches" (
+  "content_hash" char(64),
+  "result" text NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz,
+  PRIMARY KEY ("content_hash")
+);
+CREATE INDEX "idx_analysis_caches_expires_at" ON "analysis_caches" ("expires_at");
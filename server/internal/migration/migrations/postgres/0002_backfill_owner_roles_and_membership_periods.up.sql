@@ -0,0 +1,18 @@
+-- ロール導入前から存在するオーナーの紐付けにオーナーロールを設定する
+UPDATE "group_members"
+SET "role" = 'owner'
+FROM "groups"
+WHERE "groups"."id" = "group_members"."group_id"
+  AND "groups"."owner_id" = "group_members"."user_id"
+  AND "group_members"."role" <> 'owner';
+
+-- 在籍期間の導入前から存在するメンバーに、グループ作成時からの在籍期間を補完する
+INSERT INTO "group_membership_periods" ("id", "group_id", "user_id", "joined_at")
+SELECT gen_random_uuid()::text, "group_members"."group_id", "group_members"."user_id", COALESCE("groups"."created_at", NOW())
+FROM "group_members"
+JOIN "groups" ON "groups"."id" = "group_members"."group_id"
+WHERE NOT EXISTS (
+  SELECT 1 FROM "group_membership_periods"
+  WHERE "group_membership_periods"."group_id" = "group_members"."group_id"
+    AND "group_membership_periods"."user_id" = "group_members"."user_id"
+);
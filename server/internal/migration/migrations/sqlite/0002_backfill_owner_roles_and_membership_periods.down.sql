@@ -0,0 +1 @@
+-- 補完したデータは以前から存在したデータと区別できないため、元に戻さない
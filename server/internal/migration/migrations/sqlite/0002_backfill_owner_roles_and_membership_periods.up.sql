@@ -0,0 +1,23 @@
+-- ロール導入前から存在するオーナーの紐付けにオーナーロールを設定する
+UPDATE `group_members`
+SET `role` = 'owner'
+WHERE `role` <> 'owner'
+  AND EXISTS (
+    SELECT 1 FROM `groups`
+    WHERE `groups`.`id` = `group_members`.`group_id`
+      AND `groups`.`owner_id` = `group_members`.`user_id`
+  );
+
+-- 在籍期間の導入前から存在するメンバーに、グループ作成時からの在籍期間を補完する（IDはUUID v4の形式で生成する）
+INSERT INTO `group_membership_periods` (`id`, `group_id`, `user_id`, `joined_at`)
+SELECT
+  lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' ||
+    substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))),
+  `group_members`.`group_id`, `group_members`.`user_id`, COALESCE(`groups`.`created_at`, CURRENT_TIMESTAMP)
+FROM `group_members`
+JOIN `groups` ON `groups`.`id` = `group_members`.`group_id`
+WHERE NOT EXISTS (
+  SELECT 1 FROM `group_membership_periods`
+  WHERE `group_membership_periods`.`group_id` = `group_members`.`group_id`
+    AND `group_membership_periods`.`user_id` = `group_members`.`user_id`
+);
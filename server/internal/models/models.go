@@ -9,13 +9,18 @@ import (
 
 // User ユーザー情報
 type User struct {
-	ID           uuid.UUID      `gorm:"type:char(36);primaryKey" json:"id"`
-	Email        string         `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
-	PasswordHash string         `gorm:"type:varchar(255);not null" json:"-"`
-	Nickname     string         `gorm:"type:varchar(100);not null" json:"nickname"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               uuid.UUID      `gorm:"type:char(36);primaryKey" json:"id"`
+	Email            string         `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
+	PasswordHash     string         `gorm:"type:varchar(255)" json:"-"` // 外部IDプロバイダーのみでログインするユーザーは空
+	Nickname         string         `gorm:"type:varchar(100);not null" json:"nickname"`
+	FailedLoginCount int            `gorm:"not null;default:0" json:"-"` // 連続ログイン失敗回数
+	LockedUntil      *time.Time     `json:"-"`                           // アカウントロックの解除日時
+	TOTPSecret       string         `gorm:"type:varchar(64)" json:"-"`   // 2段階認証（TOTP）の共有シークレット
+	TOTPEnabled      bool           `gorm:"not null;default:false" json:"totp_enabled"`
+	TOTPLastUsedStep int64          `gorm:"not null;default:0" json:"-"` // 最後に使用されたTOTPのタイムステップ（再利用防止）
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
@@ -25,16 +30,77 @@ func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
 	return
 }
 
+// RecoveryCode 2段階認証のリカバリーコード（ハッシュ化して保存）
+type RecoveryCode struct {
+	ID        uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:char(36);not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"type:char(64);not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (r *RecoveryCode) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == uuid.Nil {
+		r.ID, err = uuid.NewV7()
+	}
+	return
+}
+
+// ExternalIdentity 外部IDプロバイダー（OpenID Connect）のアカウントとユーザーの紐付け
+type ExternalIdentity struct {
+	ID        uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID    uuid.UUID `gorm:"type:char(36);not null;index" json:"user_id"`
+	Issuer    string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_external_identity_subject" json:"issuer"`
+	Subject   string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_external_identity_subject" json:"-"`
+	Email     string    `gorm:"type:varchar(255)" json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (e *ExternalIdentity) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.ID == uuid.Nil {
+		e.ID, err = uuid.NewV7()
+	}
+	return
+}
+
+// Credential パスキー（WebAuthn）の認証情報
+type Credential struct {
+	ID           uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID       uuid.UUID  `gorm:"type:char(36);not null;index" json:"user_id"`
+	CredentialID string     `gorm:"type:varchar(255);uniqueIndex;not null" json:"-"` // 認証器が発行したクレデンシャルID（base64url）
+	Name         string     `gorm:"type:varchar(100)" json:"name"`                   // 利用者が識別するための表示名（例: "iPhone"）
+	Data         string     `gorm:"type:text;not null" json:"-"`                     // 公開鍵・署名カウンタ等（webauthn.Credential のJSON）
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (c *Credential) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == uuid.Nil {
+		c.ID, err = uuid.NewV7()
+	}
+	return
+}
+
 // Group 夫婦・家族などのグループ
 type Group struct {
-	ID        uuid.UUID      `gorm:"type:char(36);primaryKey" json:"id"`
-	Name      string         `gorm:"type:varchar(100);not null" json:"name"`
-	OwnerID   uuid.UUID      `gorm:"type:char(36);not null" json:"owner_id"` // グループ管理者（作成者）
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
-	Members   []User         `gorm:"many2many:group_members;" json:"members"`
-	Owner     User           `gorm:"foreignKey:OwnerID" json:"-"`
+	ID               uuid.UUID      `gorm:"type:char(36);primaryKey" json:"id"`
+	Name             string         `gorm:"type:varchar(100);not null" json:"name"`
+	OwnerID          uuid.UUID      `gorm:"type:char(36);not null" json:"owner_id"` // グループ管理者（作成者）
+	ArchivedAt       *time.Time     `json:"archived_at"`                            // アーカイブ日時（アーカイブ中は閲覧のみ）
+	PurgeRequestedAt *time.Time     `gorm:"index" json:"-"`                         // 完全削除の要求日時（バックグラウンドで履歴ごと削除）
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+	Members          []User         `gorm:"many2many:group_members;" json:"members"`
+	Owner            User           `gorm:"foreignKey:OwnerID" json:"-"`
+
+	Memberships []GroupMember `gorm:"foreignKey:GroupID" json:"memberships"` // メンバーごとのロール
 }
 
 func (g *Group) BeforeCreate(tx *gorm.DB) (err error) {
@@ -44,6 +110,55 @@ func (g *Group) BeforeCreate(tx *gorm.DB) (err error) {
 	return
 }
 
+// Group Roles
+const (
+	GroupRoleOwner  = "owner"  // オーナー（全操作が可能）
+	GroupRoleAdmin  = "admin"  // 管理者（メンバー管理・グループ設定が可能）
+	GroupRoleMember = "member" // メンバー（レシート登録・精算が可能）
+	GroupRoleViewer = "viewer" // 閲覧のみ
+)
+
+// GroupMember グループとメンバーの紐付け（group_members テーブル）
+type GroupMember struct {
+	GroupID   uuid.UUID `gorm:"type:char(36);primaryKey" json:"group_id"`
+	UserID    uuid.UUID `gorm:"type:char(36);primaryKey" json:"user_id"`
+	Role      string    `gorm:"type:varchar(20);not null;default:member" json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (m *GroupMember) BeforeCreate(tx *gorm.DB) (err error) {
+	if m.Role == "" {
+		m.Role = GroupRoleMember
+	}
+	return
+}
+
+// GroupMembershipPeriod グループへの在籍期間。退出・再参加のたびに期間が追加され、過去の割り勘計算に使う
+type GroupMembershipPeriod struct {
+	ID       uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+	GroupID  uuid.UUID  `gorm:"type:char(36);not null;index:idx_membership_period_group_user" json:"group_id"`
+	UserID   uuid.UUID  `gorm:"type:char(36);not null;index:idx_membership_period_group_user" json:"user_id"`
+	JoinedAt time.Time  `gorm:"not null" json:"joined_at"`
+	LeftAt   *time.Time `json:"left_at"` // 在籍中の場合はnull
+
+	User User `gorm:"foreignKey:UserID" json:"user"`
+}
+
+func (p *GroupMembershipPeriod) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.ID == uuid.Nil {
+		p.ID, err = uuid.NewV7()
+	}
+	return
+}
+
+// IsActiveBetween 指定期間 [from, to] のいずれかの時点で在籍していたかどうか
+func (p *GroupMembershipPeriod) IsActiveBetween(from time.Time, to time.Time) bool {
+	if p.JoinedAt.After(to) {
+		return false
+	}
+	return p.LeftAt == nil || !p.LeftAt.Before(from)
+}
+
 // Receipt レシート明細
 type Receipt struct {
 	ID             uuid.UUID      `gorm:"type:char(36);primaryKey" json:"id"`
@@ -53,6 +168,8 @@ type Receipt struct {
 	SettlementYear int            `gorm:"not null" json:"settlement_year"`
 	SettlementMonth int           `gorm:"not null" json:"settlement_month"`
 	Shop           string         `gorm:"type:varchar(255)" json:"shop"`
+	ShopID         *uuid.UUID     `gorm:"type:char(36);index" json:"shop_id"` // 店舗マスタに紐付いている場合の店舗
+	Category       string         `gorm:"type:varchar(50)" json:"category"`
 	Item           string         `gorm:"type:varchar(255)" json:"item"`
 	Amount         int            `gorm:"not null" json:"amount"`
 	PayerID        uuid.UUID      `gorm:"type:char(36);not null" json:"payer_id"` // 実際に支払ったユーザー
@@ -81,6 +198,43 @@ const (
 	PaymentMethodOther = "other" // 全額相手負担
 )
 
+// Shop グループごとの店舗マスタ。レシート登録時の既定の分類・支払い方法を持つ
+type Shop struct {
+	ID                   uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+	GroupID              uuid.UUID `gorm:"type:char(36);not null;index" json:"group_id"`
+	Name                 string    `gorm:"type:varchar(255);not null" json:"name"`
+	DefaultCategory      string    `gorm:"type:varchar(50)" json:"default_category"`
+	DefaultPaymentMethod string    `gorm:"type:varchar(50)" json:"default_payment_method"`
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+
+	Aliases []ShopAlias `gorm:"foreignKey:ShopID" json:"aliases"`
+}
+
+func (s *Shop) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == uuid.Nil {
+		s.ID, err = uuid.NewV7()
+	}
+	return
+}
+
+// ShopAlias 店舗名の別名。正規化した店舗名が Alias で始まるレシートを CanonicalName の店舗として集計する
+type ShopAlias struct {
+	ID            uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+	GroupID       *uuid.UUID `gorm:"type:char(36);index" json:"group_id"` // nullの場合は全グループ共通
+	ShopID        *uuid.UUID `gorm:"type:char(36);index" json:"shop_id"`  // 店舗マスタの別名の場合の店舗（CanonicalName は店舗名）
+	Alias         string     `gorm:"type:varchar(255);not null" json:"alias"`
+	CanonicalName string     `gorm:"type:varchar(255);not null" json:"canonical_name"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func (a *ShopAlias) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ID == uuid.Nil {
+		a.ID, err = uuid.NewV7()
+	}
+	return
+}
+
 // Settlement 精算情報
 type Settlement struct {
 	ID        uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
@@ -89,6 +243,8 @@ type Settlement struct {
 	Month     int       `gorm:"not null" json:"month"`
 	Amount    int       `gorm:"not null" json:"amount"`
 	SettledBy uuid.UUID `gorm:"type:char(36);not null" json:"settled_by"`
+	RecipientID *uuid.UUID `gorm:"type:char(36)" json:"recipient_id"` // 受け取ったメンバー（未設定の場合は他のメンバーで均等に受け取ったものとする）
+	Cumulative bool     `gorm:"not null;default:false" json:"cumulative"` // 指定月までの累積残高をまとめて精算したかどうか
 	CreatedAt time.Time `json:"created_at"`
 
 	Group     Group     `gorm:"foreignKey:GroupID" json:"-"`
@@ -101,3 +257,136 @@ func (s *Settlement) BeforeCreate(tx *gorm.DB) (err error) {
 	}
 	return
 }
+
+// ReceiptDraft AI解析の結果から作成した、確定前のレシートの下書き（レシートとは別に保存し、集計・精算には含めない）
+type ReceiptDraft struct {
+	ID            uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID        uuid.UUID  `gorm:"type:char(36);not null;index" json:"user_id"`
+	GroupID       *uuid.UUID `gorm:"type:char(36)" json:"group_id"`
+	Date          *time.Time `json:"date"` // 読み取れなかった場合はnull
+	Shop          string     `gorm:"type:varchar(255)" json:"shop"`
+	AnalyzedShop  string     `gorm:"type:varchar(255)" json:"analyzed_shop"` // 店舗マスタとの照合前・過去の修正による補正前の、AIが読み取った店舗名
+	ShopID        *uuid.UUID `gorm:"type:char(36)" json:"shop_id"`
+	Category      string     `gorm:"type:varchar(100)" json:"category"`
+	Item          string     `gorm:"type:varchar(255)" json:"item"`
+	Amount        int        `gorm:"not null;default:0" json:"amount"`
+	PaymentMethod string     `gorm:"type:varchar(50)" json:"payment_method"`
+	NeedsReview   []string   `gorm:"type:varchar(100);serializer:json" json:"needs_review"` // 確認が必要な項目（date, shop, item, amount）
+	MIMEType      string     `gorm:"type:varchar(100)" json:"mime_type"`
+	ImageData     []byte     `json:"-"`
+	ExpiresAt     time.Time  `gorm:"not null;index" json:"expires_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (d *ReceiptDraft) BeforeCreate(tx *gorm.DB) (err error) {
+	if d.ID == uuid.Nil {
+		d.ID, err = uuid.NewV7()
+	}
+	return
+}
+
+// AnalysisFeedback AI解析の提案（下書き）と、利用者が確定したレシートの差分。次回以降の解析の補正と精度の集計に使う
+type AnalysisFeedback struct {
+	ID             uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+	GroupID        uuid.UUID  `gorm:"type:char(36);not null;index" json:"group_id"`
+	UserID         uuid.UUID  `gorm:"type:char(36);not null" json:"user_id"`
+	ReceiptID      uuid.UUID  `gorm:"type:char(36);not null" json:"receipt_id"`
+	ProposedDate   *time.Time `json:"proposed_date"`
+	AnalyzedShop   string     `gorm:"type:varchar(255);index" json:"analyzed_shop"` // AIが読み取った店舗名（補正の照合に使う）
+	ProposedShop   string     `gorm:"type:varchar(255)" json:"proposed_shop"`       // 利用者に提案した店舗名（店舗マスタとの照合・補正後）
+	ProposedItem   string     `gorm:"type:varchar(255)" json:"proposed_item"`
+	ProposedAmount int        `gorm:"not null;default:0" json:"proposed_amount"`
+	FinalShop      string     `gorm:"type:varchar(255)" json:"final_shop"`
+	FinalItem      string     `gorm:"type:varchar(255)" json:"final_item"`
+	DateEdited     bool       `gorm:"not null;default:false" json:"date_edited"`
+	ShopEdited     bool       `gorm:"not null;default:false" json:"shop_edited"`
+	ItemEdited     bool       `gorm:"not null;default:false" json:"item_edited"`
+	AmountEdited   bool       `gorm:"not null;default:false" json:"amount_edited"`
+	CreatedAt      time.Time  `gorm:"index" json:"created_at"`
+}
+
+func (f *AnalysisFeedback) BeforeCreate(tx *gorm.DB) (err error) {
+	if f.ID == uuid.Nil {
+		f.ID, err = uuid.NewV7()
+	}
+	return
+}
+
+// Analysis Job Statuses
+const (
+	AnalysisJobQueued     = "queued"     // 解析待ち
+	AnalysisJobProcessing = "processing" // 解析中
+	AnalysisJobSucceeded  = "succeeded"  // 解析完了
+	AnalysisJobFailed     = "failed"     // 解析失敗
+)
+
+// AnalysisJob レシート画像の非同期解析ジョブ。サーバーを再起動しても解析待ちのジョブを引き継げるよう画像ごと保存する
+type AnalysisJob struct {
+	ID          uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:char(36);not null;index" json:"user_id"`
+	GroupID     *uuid.UUID `gorm:"type:char(36)" json:"group_id"`       // 指定された場合は店舗名をグループの店舗マスタと照合する
+	BatchID     *uuid.UUID `gorm:"type:char(36);index" json:"batch_id"` // まとめて解析する場合のバッチ
+	FileName    string     `gorm:"type:varchar(255)" json:"file_name"`
+	Status      string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	MIMEType    string     `gorm:"type:varchar(100);not null" json:"-"`
+	ImageData   []byte     `json:"-"` // 正規化済みの画像（解析が終わったら削除する）
+	PreviewData []byte     `json:"-"` // PDFの1ページ目のJPEG
+	PageCount   int        `gorm:"not null;default:0" json:"-"`
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	Result      string     `gorm:"type:text" json:"-"` // 解析結果（JSON）
+	Error       string     `gorm:"type:varchar(1000)" json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	StartedAt   *time.Time `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+func (j *AnalysisJob) BeforeCreate(tx *gorm.DB) (err error) {
+	if j.ID == uuid.Nil {
+		j.ID, err = uuid.NewV7()
+	}
+	return
+}
+
+// RateLimitBucket レート制限用のトークンバケット（DB保存用）
+type RateLimitBucket struct {
+	Key       string    `gorm:"type:varchar(255);primaryKey" json:"key"`
+	Tokens    float64   `gorm:"not null" json:"tokens"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime:false;not null" json:"updated_at"`
+}
+
+// AI Usage Kinds
+const (
+	AIUsageAnalyzeReceipt = "analyze_receipt" // レシート画像の解析
+	AIUsageParseText      = "parse_text"      // 自然文からのレシートの入力
+	AIUsageAskQuestion    = "ask_question"    // 支出に関する質問への回答
+)
+
+// AIUsage AIの呼び出し1回分の記録。1日あたりの利用上限の確認と利用状況の集計に使う
+type AIUsage struct {
+	ID           uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID       uuid.UUID  `gorm:"type:char(36);not null;index:idx_ai_usage_user_created" json:"user_id"`
+	GroupID      *uuid.UUID `gorm:"type:char(36);index:idx_ai_usage_group_created" json:"group_id"`
+	Kind         string     `gorm:"type:varchar(50);not null" json:"kind"`
+	Cached       bool       `gorm:"not null;default:false" json:"cached"` // 保存済みの解析結果を返した（モデルを呼び出していない）
+	PromptTokens int        `gorm:"not null;default:0" json:"prompt_tokens"`
+	OutputTokens int        `gorm:"not null;default:0" json:"output_tokens"`
+	TotalTokens  int        `gorm:"not null;default:0" json:"total_tokens"`
+	CreatedAt    time.Time  `gorm:"index:idx_ai_usage_user_created;index:idx_ai_usage_group_created" json:"created_at"`
+}
+
+func (u *AIUsage) BeforeCreate(tx *gorm.DB) (err error) {
+	if u.ID == uuid.Nil {
+		u.ID, err = uuid.NewV7()
+	}
+	return
+}
+
+// AnalysisCache 画像の内容のハッシュごとの解析結果。同じ画像が再度アップロードされた場合はモデルを呼び出さずに返す
+type AnalysisCache struct {
+	ContentHash string    `gorm:"type:char(64);primaryKey" json:"content_hash"` // 正規化済みの画像の SHA-256（16進数）
+	Result      string    `gorm:"type:text;not null" json:"-"`                  // 解析結果（JSON）
+	ExpiresAt   time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"receipt/server/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AIUsageTotals 種類ごとのAIの呼び出し回数とトークン数の集計
+type AIUsageTotals struct {
+	Kind         string `gorm:"column:kind"`
+	Calls        int    `gorm:"column:calls"`
+	CachedCalls  int    `gorm:"column:cached_calls"`
+	PromptTokens int    `gorm:"column:prompt_tokens"`
+	OutputTokens int    `gorm:"column:output_tokens"`
+	TotalTokens  int    `gorm:"column:total_tokens"`
+}
+
+// AIUsageRepository AIの利用記録関連データ操作インターフェース
+type AIUsageRepository interface {
+	Create(usage *models.AIUsage) error
+	// Reserve ユーザー（と usage.GroupID のグループ）の since 以降の呼び出し回数が上限未満であれば usage を記録し、記録した場合は true を返す。
+	// 上限が0以下の場合は数えない
+	Reserve(usage *models.AIUsage, since time.Time, userLimit int, groupLimit int) (bool, error)
+	// UpdateTokens 記録済みの呼び出しにトークン数を記録する
+	UpdateTokens(usage *models.AIUsage) error
+	Delete(id uuid.UUID) error
+	// CountByUserSince since 以降にユーザーがモデルを呼び出した回数（保存済みの結果を返した分を除く）
+	CountByUserSince(userID uuid.UUID, since time.Time) (int, error)
+	// CountByGroupSince since 以降にグループでモデルを呼び出した回数（保存済みの結果を返した分を除く）
+	CountByGroupSince(groupID uuid.UUID, since time.Time) (int, error)
+	GetTotalsByUser(userID uuid.UUID, since time.Time) ([]AIUsageTotals, error)
+	GetTotalsByGroup(groupID uuid.UUID, since time.Time) ([]AIUsageTotals, error)
+}
+
+type gormAIUsageRepository struct {
+	db *gorm.DB
+}
+
+// NewAIUsageRepository AIUsageRepositoryの実装を作成
+func NewAIUsageRepository(db *gorm.DB) AIUsageRepository {
+	return &gormAIUsageRepository{db: db}
+}
+
+func (r *gormAIUsageRepository) Create(usage *models.AIUsage) error {
+	return r.db.Create(usage).Error
+}
+
+// Reserve 同時に呼び出されても上限を超えて記録しないよう、ユーザー（とグループ）の行を更新してロックしてから数える
+// （SQLite では更新した時点でデータベース全体の書き込みロックを取得する）
+func (r *gormAIUsageRepository) Reserve(usage *models.AIUsage, since time.Time, userLimit int, groupLimit int) (bool, error) {
+	reserved := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		limits := []struct {
+			model  interface{}
+			column string
+			id     *uuid.UUID
+			limit  int
+		}{
+			{&models.User{}, "user_id", &usage.UserID, userLimit},
+			{&models.Group{}, "group_id", usage.GroupID, groupLimit},
+		}
+		for _, l := range limits {
+			if l.id == nil || l.limit <= 0 {
+				continue
+			}
+			if err := tx.Model(l.model).Where("id = ?", *l.id).UpdateColumn("id", gorm.Expr("id")).Error; err != nil {
+				return err
+			}
+			used, err := countSince(tx, l.column, *l.id, since)
+			if err != nil {
+				return err
+			}
+			if used >= l.limit {
+				return nil
+			}
+		}
+
+		if err := tx.Create(usage).Error; err != nil {
+			return err
+		}
+		reserved = true
+		return nil
+	})
+	return reserved, err
+}
+
+func (r *gormAIUsageRepository) UpdateTokens(usage *models.AIUsage) error {
+	return r.db.Model(usage).UpdateColumns(map[string]interface{}{
+		"prompt_tokens": usage.PromptTokens,
+		"output_tokens": usage.OutputTokens,
+		"total_tokens":  usage.TotalTokens,
+	}).Error
+}
+
+func (r *gormAIUsageRepository) Delete(id uuid.UUID) error {
+	return r.db.Delete(&models.AIUsage{}, "id = ?", id).Error
+}
+
+func (r *gormAIUsageRepository) CountByUserSince(userID uuid.UUID, since time.Time) (int, error) {
+	return countSince(r.db, "user_id", userID, since)
+}
+
+func (r *gormAIUsageRepository) CountByGroupSince(groupID uuid.UUID, since time.Time) (int, error) {
+	return countSince(r.db, "group_id", groupID, since)
+}
+
+// countSince since 以降にモデルを呼び出した回数（保存済みの結果を返した分を除く）
+func countSince(db *gorm.DB, column string, id uuid.UUID, since time.Time) (int, error) {
+	var count int64
+	err := db.Model(&models.AIUsage{}).
+		Where(column+" = ? AND created_at >= ? AND cached = ?", id, since, false).
+		Count(&count).Error
+	return int(count), err
+}
+
+func (r *gormAIUsageRepository) GetTotalsByUser(userID uuid.UUID, since time.Time) ([]AIUsageTotals, error) {
+	return r.totalsSince("user_id", userID, since)
+}
+
+func (r *gormAIUsageRepository) GetTotalsByGroup(groupID uuid.UUID, since time.Time) ([]AIUsageTotals, error) {
+	return r.totalsSince("group_id", groupID, since)
+}
+
+func (r *gormAIUsageRepository) totalsSince(column string, id uuid.UUID, since time.Time) ([]AIUsageTotals, error) {
+	columns := []string{
+		"kind",
+		"COUNT(*) AS calls",
+		"COALESCE(SUM(CASE WHEN cached THEN 1 ELSE 0 END), 0) AS cached_calls",
+	}
+	for _, column := range []string{"prompt_tokens", "output_tokens", "total_tokens"} {
+		columns = append(columns, "COALESCE(SUM("+column+"), 0) AS "+column)
+	}
+
+	var totals []AIUsageTotals
+	err := r.db.Model(&models.AIUsage{}).
+		Select(strings.Join(columns, ", ")).
+		Where(column+" = ? AND created_at >= ?", id, since).
+		Group("kind").
+		Order("kind").
+		Scan(&totals).Error
+	return totals, err
+}
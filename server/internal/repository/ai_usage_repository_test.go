@@ -0,0 +1,126 @@
+package repository_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+func TestAIUsageRepositoryCounts(t *testing.T) {
+	db := newTestDB(t)
+	repo := repository.NewAIUsageRepository(db)
+
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	group := newTestGroup(t, db, alice)
+	since := time.Now().Add(-time.Hour)
+
+	usages := []models.AIUsage{
+		{UserID: alice.ID, GroupID: &group.ID, Kind: models.AIUsageAnalyzeReceipt, PromptTokens: 100, OutputTokens: 20, TotalTokens: 120},
+		{UserID: alice.ID, GroupID: &group.ID, Kind: models.AIUsageAnalyzeReceipt, Cached: true},
+		{UserID: alice.ID, Kind: models.AIUsageParseText, PromptTokens: 30, OutputTokens: 10, TotalTokens: 40},
+		{UserID: bob.ID, GroupID: &group.ID, Kind: models.AIUsageAskQuestion, PromptTokens: 50, OutputTokens: 5, TotalTokens: 55},
+		{UserID: alice.ID, GroupID: &group.ID, Kind: models.AIUsageAnalyzeReceipt, CreatedAt: since.Add(-time.Hour)},
+	}
+	for i := range usages {
+		if err := repo.Create(&usages[i]); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	// 保存済みの結果を返した呼び出しと期間外の呼び出しは数えない
+	if count, err := repo.CountByUserSince(alice.ID, since); err != nil || count != 2 {
+		t.Errorf("expected 2 calls by alice, got %d (%v)", count, err)
+	}
+	if count, err := repo.CountByGroupSince(group.ID, since); err != nil || count != 2 {
+		t.Errorf("expected 2 calls in the group, got %d (%v)", count, err)
+	}
+
+	totals, err := repo.GetTotalsByUser(alice.ID, since)
+	if err != nil {
+		t.Fatalf("GetTotalsByUser failed: %v", err)
+	}
+	want := []repository.AIUsageTotals{
+		{Kind: models.AIUsageAnalyzeReceipt, Calls: 2, CachedCalls: 1, PromptTokens: 100, OutputTokens: 20, TotalTokens: 120},
+		{Kind: models.AIUsageParseText, Calls: 1, PromptTokens: 30, OutputTokens: 10, TotalTokens: 40},
+	}
+	if len(totals) != len(want) {
+		t.Fatalf("expected %+v, got %+v", want, totals)
+	}
+	for i := range want {
+		if totals[i] != want[i] {
+			t.Errorf("expected %+v, got %+v", want[i], totals[i])
+		}
+	}
+}
+
+func TestAIUsageRepositoryReserve(t *testing.T) {
+	db := newTestDB(t)
+	repo := repository.NewAIUsageRepository(db)
+
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	group := newTestGroup(t, db, alice)
+	since := time.Now().Add(-time.Hour)
+
+	// 同時に確保しても、ユーザーの上限を超えて記録しない
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reserved := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := repo.Reserve(&models.AIUsage{UserID: alice.ID, Kind: models.AIUsageParseText}, since, 3, 0)
+			if err != nil {
+				t.Errorf("Reserve failed: %v", err)
+			}
+			if ok {
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if count, _ := repo.CountByUserSince(alice.ID, since); reserved != 3 || count != 3 {
+		t.Errorf("expected 3 reserved calls, got %d (%d recorded)", reserved, count)
+	}
+
+	// グループの上限は、グループの他のメンバーの呼び出しも数える
+	for i, user := range []uuid.UUID{bob.ID, bob.ID, alice.ID} {
+		usage := &models.AIUsage{UserID: user, GroupID: &group.ID, Kind: models.AIUsageAnalyzeReceipt}
+		ok, err := repo.Reserve(usage, since, 0, 2)
+		if err != nil {
+			t.Fatalf("Reserve failed: %v", err)
+		}
+		if want := i < 2; ok != want {
+			t.Errorf("call %d: expected reserved=%v, got %v", i+1, want, ok)
+		}
+	}
+
+	// トークン数は呼び出し後に記録し、取り消した確保は数えない
+	usage := &models.AIUsage{UserID: bob.ID, Kind: models.AIUsageAskQuestion}
+	if ok, err := repo.Reserve(usage, since, 0, 0); err != nil || !ok {
+		t.Fatalf("expected the call to be reserved, got %v (%v)", ok, err)
+	}
+	usage.PromptTokens, usage.OutputTokens, usage.TotalTokens = 50, 5, 55
+	if err := repo.UpdateTokens(usage); err != nil {
+		t.Fatalf("UpdateTokens failed: %v", err)
+	}
+	totals, _ := repo.GetTotalsByUser(bob.ID, since)
+	if len(totals) != 2 || totals[1].Kind != models.AIUsageAskQuestion || totals[1].TotalTokens != 55 {
+		t.Errorf("expected tokens to be recorded, got %+v", totals)
+	}
+	if err := repo.Delete(usage.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if count, _ := repo.CountByUserSince(bob.ID, since); count != 2 {
+		t.Errorf("expected the released call not to be counted, got %d", count)
+	}
+}
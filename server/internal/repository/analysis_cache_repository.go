@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"time"
+
+	"receipt/server/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AnalysisCacheRepository 画像ごとの解析結果の保存に関するデータ操作インターフェース
+type AnalysisCacheRepository interface {
+	// Get 有効期限内の解析結果を取得する
+	Get(contentHash string, now time.Time) (*models.AnalysisCache, error)
+	// Save 解析結果を保存する（同じ画像の結果が既にある場合は置き換える）
+	Save(entry *models.AnalysisCache) error
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+type gormAnalysisCacheRepository struct {
+	db *gorm.DB
+}
+
+// NewAnalysisCacheRepository AnalysisCacheRepositoryの実装を作成
+func NewAnalysisCacheRepository(db *gorm.DB) AnalysisCacheRepository {
+	return &gormAnalysisCacheRepository{db: db}
+}
+
+func (r *gormAnalysisCacheRepository) Get(contentHash string, now time.Time) (*models.AnalysisCache, error) {
+	var entry models.AnalysisCache
+	if err := r.db.Where("content_hash = ? AND expires_at > ?", contentHash, now).First(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *gormAnalysisCacheRepository) Save(entry *models.AnalysisCache) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "content_hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"result", "expires_at", "created_at"}),
+	}).Create(entry).Error
+}
+
+// DeleteExpired 有効期限を過ぎた解析結果を削除し、削除した件数を返す
+func (r *gormAnalysisCacheRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now).Delete(&models.AnalysisCache{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"receipt/server/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AnalysisAccuracyTotals AI解析の提案が確定までに修正された件数の集計
+type AnalysisAccuracyTotals struct {
+	Total        int `gorm:"column:total"`
+	DateEdited   int `gorm:"column:date_edited"`
+	ShopEdited   int `gorm:"column:shop_edited"`
+	ItemEdited   int `gorm:"column:item_edited"`
+	AmountEdited int `gorm:"column:amount_edited"`
+}
+
+// AnalysisFeedbackRepository AI解析の修正履歴関連データ操作インターフェース
+type AnalysisFeedbackRepository interface {
+	Create(feedback *models.AnalysisFeedback) error
+	GetRecentByGroupID(groupID uuid.UUID, limit int) ([]models.AnalysisFeedback, error)
+	GetAccuracyTotals(groupID uuid.UUID, from time.Time) (*AnalysisAccuracyTotals, error)
+}
+
+type gormAnalysisFeedbackRepository struct {
+	db *gorm.DB
+}
+
+// NewAnalysisFeedbackRepository AnalysisFeedbackRepositoryの実装を作成
+func NewAnalysisFeedbackRepository(db *gorm.DB) AnalysisFeedbackRepository {
+	return &gormAnalysisFeedbackRepository{db: db}
+}
+
+func (r *gormAnalysisFeedbackRepository) Create(feedback *models.AnalysisFeedback) error {
+	return r.db.Create(feedback).Error
+}
+
+// GetRecentByGroupID グループの修正履歴を新しい順に最大 limit 件取得する
+func (r *gormAnalysisFeedbackRepository) GetRecentByGroupID(groupID uuid.UUID, limit int) ([]models.AnalysisFeedback, error) {
+	var feedback []models.AnalysisFeedback
+	err := r.db.Where("group_id = ?", groupID).
+		Order("created_at desc").
+		Limit(limit).
+		Find(&feedback).Error
+	return feedback, err
+}
+
+// GetAccuracyTotals from 以降に確定された提案の件数と、項目ごとの修正件数を集計する
+func (r *gormAnalysisFeedbackRepository) GetAccuracyTotals(groupID uuid.UUID, from time.Time) (*AnalysisAccuracyTotals, error) {
+	columns := []string{"COUNT(*) AS total"}
+	for _, column := range []string{"date_edited", "shop_edited", "item_edited", "amount_edited"} {
+		columns = append(columns, "COALESCE(SUM(CASE WHEN "+column+" THEN 1 ELSE 0 END), 0) AS "+column)
+	}
+
+	var totals AnalysisAccuracyTotals
+	err := r.db.Model(&models.AnalysisFeedback{}).
+		Select(strings.Join(columns, ", ")).
+		Where("group_id = ? AND created_at >= ?", groupID, from).
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return &totals, nil
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"time"
+
+	"receipt/server/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AnalysisJobRepository レシート解析ジョブ関連データ操作インターフェース
+type AnalysisJobRepository interface {
+	Create(job *models.AnalysisJob) error
+	CreateBatch(jobs []*models.AnalysisJob) error
+	GetByID(id uuid.UUID) (*models.AnalysisJob, error)
+	GetByBatchID(batchID uuid.UUID) ([]models.AnalysisJob, error)
+	ClaimNext(now time.Time) (*models.AnalysisJob, error)
+	Requeue(job *models.AnalysisJob) error
+	Finish(job *models.AnalysisJob) error
+	RequeueInterrupted(startedBefore time.Time, maxAttempts int) (requeued int64, failed int64, err error)
+}
+
+// interruptedAnalysisJobError 中断を繰り返して失敗にしたジョブのエラー
+const interruptedAnalysisJobError = "analysis was interrupted too many times"
+
+type gormAnalysisJobRepository struct {
+	db *gorm.DB
+}
+
+// NewAnalysisJobRepository AnalysisJobRepositoryの実装を作成
+func NewAnalysisJobRepository(db *gorm.DB) AnalysisJobRepository {
+	return &gormAnalysisJobRepository{db: db}
+}
+
+func (r *gormAnalysisJobRepository) Create(job *models.AnalysisJob) error {
+	return r.db.Create(job).Error
+}
+
+// CreateBatch まとめて解析するジョブを登録する（1件でも失敗した場合はすべて登録しない）
+func (r *gormAnalysisJobRepository) CreateBatch(jobs []*models.AnalysisJob) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&jobs).Error
+	})
+}
+
+// GetByID ジョブを取得する（画像は読み込まない）
+func (r *gormAnalysisJobRepository) GetByID(id uuid.UUID) (*models.AnalysisJob, error) {
+	var job models.AnalysisJob
+	err := r.db.Omit("image_data", "preview_data").First(&job, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetByBatchID バッチのジョブを登録順に取得する（画像は読み込まない）
+func (r *gormAnalysisJobRepository) GetByBatchID(batchID uuid.UUID) ([]models.AnalysisJob, error) {
+	var jobs []models.AnalysisJob
+	err := r.db.Omit("image_data", "preview_data").
+		Where("batch_id = ?", batchID).
+		Order("created_at asc, id asc").
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// ClaimNext 最も古い解析待ちのジョブを解析中にして取得する。解析待ちのジョブがない場合は nil を返す。
+// 他のワーカーと同じジョブを取り合った場合は、状態の条件付き更新に成功した方だけが取得する
+func (r *gormAnalysisJobRepository) ClaimNext(now time.Time) (*models.AnalysisJob, error) {
+	for {
+		var job models.AnalysisJob
+		err := r.db.Where("status = ?", models.AnalysisJobQueued).
+			Order("created_at asc").
+			Take(&job).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		result := r.db.Model(&models.AnalysisJob{}).
+			Where("id = ? AND status = ?", job.ID, models.AnalysisJobQueued).
+			Updates(map[string]interface{}{
+				"status":     models.AnalysisJobProcessing,
+				"attempts":   gorm.Expr("attempts + 1"),
+				"started_at": now,
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		job.Status = models.AnalysisJobProcessing
+		job.Attempts++
+		job.StartedAt = &now
+		return &job, nil
+	}
+}
+
+// Requeue 解析に失敗したジョブ・停止で中断したジョブを解析待ちに戻す（試行回数も保存する）
+func (r *gormAnalysisJobRepository) Requeue(job *models.AnalysisJob) error {
+	return r.db.Model(job).Updates(map[string]interface{}{
+		"status":   models.AnalysisJobQueued,
+		"error":    job.Error,
+		"attempts": job.Attempts,
+	}).Error
+}
+
+// Finish 解析が終わったジョブの結果を保存し、不要になった画像を削除する
+func (r *gormAnalysisJobRepository) Finish(job *models.AnalysisJob) error {
+	return r.db.Model(job).Updates(map[string]interface{}{
+		"status":       job.Status,
+		"result":       job.Result,
+		"error":        job.Error,
+		"completed_at": job.CompletedAt,
+		"image_data":   nil,
+		"preview_data": nil,
+	}).Error
+}
+
+// RequeueInterrupted サーバーの停止で中断された解析中のジョブを解析待ちに戻し、戻した件数と失敗にした件数を返す。
+// 解析を始めてから startedBefore までに終わっていないジョブのみを中断されたものとし（他のサーバーで解析中のジョブは戻さない）、
+// 試行回数が maxAttempts に達しているジョブは失敗にする
+func (r *gormAnalysisJobRepository) RequeueInterrupted(startedBefore time.Time, maxAttempts int) (requeued int64, failed int64, err error) {
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.AnalysisJob{}).
+			Where("status = ? AND started_at < ? AND attempts >= ?", models.AnalysisJobProcessing, startedBefore, maxAttempts).
+			Updates(map[string]interface{}{
+				"status":       models.AnalysisJobFailed,
+				"error":        interruptedAnalysisJobError,
+				"completed_at": time.Now(),
+				"image_data":   nil,
+				"preview_data": nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		failed = result.RowsAffected
+
+		result = tx.Model(&models.AnalysisJob{}).
+			Where("status = ? AND started_at < ?", models.AnalysisJobProcessing, startedBefore).
+			Update("status", models.AnalysisJobQueued)
+		if result.Error != nil {
+			return result.Error
+		}
+		requeued = result.RowsAffected
+		return nil
+	})
+	return requeued, failed, err
+}
@@ -0,0 +1,64 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+)
+
+func TestAnalysisJobRepositoryRequeueInterrupted(t *testing.T) {
+	db := newTestDB(t)
+	repo := repository.NewAnalysisJobRepository(db)
+	user := newTestUser(t, db, "alice")
+
+	now := time.Now()
+	stale := now.Add(-time.Hour)
+	newJob := func(status string, attempts int, startedAt *time.Time) *models.AnalysisJob {
+		t.Helper()
+		job := &models.AnalysisJob{UserID: user.ID, Status: status, MIMEType: "image/jpeg", ImageData: []byte("image"), Attempts: attempts, StartedAt: startedAt}
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+		return job
+	}
+	interrupted := newJob(models.AnalysisJobProcessing, 1, &stale)
+	exhausted := newJob(models.AnalysisJobProcessing, 3, &stale)
+	running := newJob(models.AnalysisJobProcessing, 1, &now)
+	queued := newJob(models.AnalysisJobQueued, 0, nil)
+
+	requeued, failed, err := repo.RequeueInterrupted(now.Add(-time.Minute), 3)
+	if err != nil {
+		t.Fatalf("RequeueInterrupted failed: %v", err)
+	}
+	if requeued != 1 || failed != 1 {
+		t.Errorf("expected 1 requeued and 1 failed job, got %d and %d", requeued, failed)
+	}
+
+	for _, tc := range []struct {
+		job  *models.AnalysisJob
+		want string
+	}{
+		{interrupted, models.AnalysisJobQueued},
+		{exhausted, models.AnalysisJobFailed},
+		{running, models.AnalysisJobProcessing},
+		{queued, models.AnalysisJobQueued},
+	} {
+		got, err := repo.GetByID(tc.job.ID)
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		if got.Status != tc.want {
+			t.Errorf("expected status %s, got %s", tc.want, got.Status)
+		}
+	}
+
+	var withImage int64
+	if err := db.Model(&models.AnalysisJob{}).Where("id = ? AND image_data IS NOT NULL", exhausted.ID).Count(&withImage).Error; err != nil {
+		t.Fatalf("failed to count jobs: %v", err)
+	}
+	if withImage != 0 {
+		t.Error("expected the failed job's image to be deleted")
+	}
+}
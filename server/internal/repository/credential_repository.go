@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"receipt/server/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CredentialRepository パスキー（WebAuthn）認証情報のデータ操作インターフェース
+type CredentialRepository interface {
+	Create(credential *models.Credential) error
+	GetByID(id uuid.UUID) (*models.Credential, error)
+	GetByCredentialID(credentialID string) (*models.Credential, error)
+	GetByUserID(userID uuid.UUID) ([]models.Credential, error)
+	Update(credential *models.Credential) error
+	Delete(credential *models.Credential) error
+}
+
+type gormCredentialRepository struct {
+	db *gorm.DB
+}
+
+// NewCredentialRepository CredentialRepositoryの実装を作成
+func NewCredentialRepository(db *gorm.DB) CredentialRepository {
+	return &gormCredentialRepository{db: db}
+}
+
+func (r *gormCredentialRepository) Create(credential *models.Credential) error {
+	return r.db.Create(credential).Error
+}
+
+func (r *gormCredentialRepository) GetByID(id uuid.UUID) (*models.Credential, error) {
+	var credential models.Credential
+	if err := r.db.First(&credential, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (r *gormCredentialRepository) GetByCredentialID(credentialID string) (*models.Credential, error) {
+	var credential models.Credential
+	if err := r.db.Where("credential_id = ?", credentialID).First(&credential).Error; err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (r *gormCredentialRepository) GetByUserID(userID uuid.UUID) ([]models.Credential, error) {
+	var credentials []models.Credential
+	err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&credentials).Error
+	return credentials, err
+}
+
+func (r *gormCredentialRepository) Update(credential *models.Credential) error {
+	return r.db.Save(credential).Error
+}
+
+func (r *gormCredentialRepository) Delete(credential *models.Credential) error {
+	return r.db.Delete(credential).Error
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"receipt/server/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ExternalIdentityRepository 外部IDプロバイダーのアカウント紐付けに関するデータ操作インターフェース
+type ExternalIdentityRepository interface {
+	GetByIssuerAndSubject(issuer, subject string) (*models.ExternalIdentity, error)
+	Create(identity *models.ExternalIdentity) error
+	CreateUserWithIdentity(user *models.User, identity *models.ExternalIdentity) error
+}
+
+type gormExternalIdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewExternalIdentityRepository ExternalIdentityRepositoryの実装を作成
+func NewExternalIdentityRepository(db *gorm.DB) ExternalIdentityRepository {
+	return &gormExternalIdentityRepository{db: db}
+}
+
+func (r *gormExternalIdentityRepository) GetByIssuerAndSubject(issuer, subject string) (*models.ExternalIdentity, error) {
+	var identity models.ExternalIdentity
+	if err := r.db.Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error; err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *gormExternalIdentityRepository) Create(identity *models.ExternalIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+func (r *gormExternalIdentityRepository) CreateUserWithIdentity(user *models.User, identity *models.ExternalIdentity) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"receipt/server/internal/models"
 
 	"github.com/google/uuid"
@@ -18,6 +20,16 @@ type GroupRepository interface {
 	AddMember(group *models.Group, user *models.User) error
 	RemoveMember(group *models.Group, user *models.User) error
 	IsMember(groupID uuid.UUID, userID uuid.UUID) (bool, error)
+	GetMemberRole(groupID uuid.UUID, userID uuid.UUID) (string, error)
+	SetMemberRole(groupID uuid.UUID, userID uuid.UUID, role string) error
+	TransferOwnership(group *models.Group, newOwnerID uuid.UUID) error
+	GetMembershipPeriods(groupID uuid.UUID) ([]models.GroupMembershipPeriod, error)
+	GetDeletedByID(id uuid.UUID) (*models.Group, error)
+	GetDeletedGroupsByOwner(ownerID uuid.UUID, deletedSince time.Time) ([]models.Group, error)
+	Restore(group *models.Group) error
+	RequestPurge(group *models.Group) error
+	GetGroupIDsDueForPurge(deletedBefore time.Time) ([]uuid.UUID, error)
+	Purge(groupID uuid.UUID) error
 }
 
 type gormGroupRepository struct {
@@ -43,7 +55,7 @@ func (r *gormGroupRepository) GetByID(id uuid.UUID) (*models.Group, error) {
 
 func (r *gormGroupRepository) GetByIDWithMembers(id uuid.UUID) (*models.Group, error) {
 	var group models.Group
-	if err := r.db.Preload("Members").First(&group, "id = ?", id).Error; err != nil {
+	if err := r.db.Preload("Members").Preload("Memberships").First(&group, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	return &group, nil
@@ -53,51 +65,189 @@ func (r *gormGroupRepository) Update(group *models.Group) error {
 	return r.db.Save(group).Error
 }
 
+// Delete グループを論理削除する。レシート・精算履歴・メンバーは復元できるよう残す
 func (r *gormGroupRepository) Delete(group *models.Group) error {
+	return r.db.Delete(group).Error
+}
+
+// GetDeletedByID 論理削除済みのグループを取得する
+func (r *gormGroupRepository) GetDeletedByID(id uuid.UUID) (*models.Group, error) {
+	var group models.Group
+	if err := r.db.Unscoped().Where("deleted_at IS NOT NULL").First(&group, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// GetDeletedGroupsByOwner 指定日時以降に論理削除された、オーナーのグループ一覧を取得する（完全削除要求済みは除く）
+func (r *gormGroupRepository) GetDeletedGroupsByOwner(ownerID uuid.UUID, deletedSince time.Time) ([]models.Group, error) {
+	var groups []models.Group
+	err := r.db.Unscoped().
+		Where("owner_id = ? AND deleted_at >= ? AND purge_requested_at IS NULL", ownerID, deletedSince).
+		Order("deleted_at DESC").
+		Find(&groups).Error
+	return groups, err
+}
+
+// Restore 論理削除されたグループを復元する
+func (r *gormGroupRepository) Restore(group *models.Group) error {
+	return r.db.Unscoped().Model(group).Update("deleted_at", nil).Error
+}
+
+// RequestPurge 完全削除を要求する（まだ削除されていない場合は論理削除も行う）
+func (r *gormGroupRepository) RequestPurge(group *models.Group) error {
+	now := time.Now()
+	return r.db.Unscoped().Model(&models.Group{}).Where("id = ?", group.ID).
+		Updates(map[string]interface{}{
+			"purge_requested_at": now,
+			"deleted_at":         gorm.Expr("COALESCE(deleted_at, ?)", now),
+		}).Error
+}
+
+// GetGroupIDsDueForPurge 完全削除の対象（要求済み、または復元期限切れ）のグループIDを取得する
+func (r *gormGroupRepository) GetGroupIDsDueForPurge(deletedBefore time.Time) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Unscoped().Model(&models.Group{}).
+		Where("purge_requested_at IS NOT NULL OR (deleted_at IS NOT NULL AND deleted_at < ?)", deletedBefore).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// Purge グループとその履歴（レシート・精算・メンバー・在籍期間）と、グループに紐付くデータ
+// （店舗マスタ・別名・解析ジョブ・下書き・解析の修正履歴）を物理削除する。
+// AIの利用記録はユーザーごとの利用上限の集計に使うため削除せず、グループとの紐付けだけを外す
+func (r *gormGroupRepository) Purge(groupID uuid.UUID) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
-		// 1. レシートを削除
-		if err := tx.Where("group_id = ?", group.ID).Delete(&models.Receipt{}).Error; err != nil {
+		// 1. レシートの解析に関するデータを削除（AIの利用記録はグループとの紐付けを外して残す）
+		for _, model := range []interface{}{&models.AnalysisFeedback{}, &models.ReceiptDraft{}, &models.AnalysisJob{}} {
+			if err := tx.Where("group_id = ?", groupID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.AIUsage{}).Where("group_id = ?", groupID).Update("group_id", nil).Error; err != nil {
 			return err
 		}
 
-		// 2. 精算履歴を削除
-		if err := tx.Where("group_id = ?", group.ID).Delete(&models.Settlement{}).Error; err != nil {
+		// 2. レシートを削除
+		if err := tx.Unscoped().Where("group_id = ?", groupID).Delete(&models.Receipt{}).Error; err != nil {
 			return err
 		}
 
-		// 3. メンバーとの紐付けを解除
-		if err := tx.Model(group).Association("Members").Clear(); err != nil {
+		// 3. 精算履歴を削除
+		if err := tx.Where("group_id = ?", groupID).Delete(&models.Settlement{}).Error; err != nil {
 			return err
 		}
 
-		// 4. グループ自体を削除
-		if err := tx.Delete(group).Error; err != nil {
+		// 4. 店舗の別名と店舗マスタを削除
+		if err := tx.Where("group_id = ?", groupID).Delete(&models.ShopAlias{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", groupID).Delete(&models.Shop{}).Error; err != nil {
+			return err
+		}
+
+		// 5. メンバーとの紐付けと在籍期間を削除
+		if err := tx.Where("group_id = ?", groupID).Delete(&models.GroupMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", groupID).Delete(&models.GroupMembershipPeriod{}).Error; err != nil {
 			return err
 		}
 
-		return nil
+		// 6. グループ自体を削除
+		return tx.Unscoped().Where("id = ?", groupID).Delete(&models.Group{}).Error
 	})
 }
 
+// GetGroupsByUserID ユーザーが参加しているグループを取得する。
+// アーカイブ済みのグループも含む（一覧で表示するかは呼び出し側で決める。退会・エクスポートでは含める）
 func (r *gormGroupRepository) GetGroupsByUserID(userID uuid.UUID) ([]models.Group, error) {
 	var groups []models.Group
-	err := r.db.Joins("JOIN group_members ON group_members.group_id = groups.id").
-		Where("group_members.user_id = ?", userID).
+	memberships := r.db.Model(&models.GroupMember{}).Select("group_id").Where("user_id = ?", userID)
+	err := r.db.Where("id IN (?)", memberships).
 		Preload("Members").
+		Preload("Memberships").
 		Find(&groups).Error
 	return groups, err
 }
 
+// AddMember メンバーを一般メンバー（member）ロールで追加し、在籍期間を開始する
 func (r *gormGroupRepository) AddMember(group *models.Group, user *models.User) error {
-	return r.db.Model(group).Association("Members").Append(user)
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.GroupMember{GroupID: group.ID, UserID: user.ID, Role: models.GroupRoleMember}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.GroupMembershipPeriod{GroupID: group.ID, UserID: user.ID, JoinedAt: time.Now()}).Error
+	})
 }
 
+// RemoveMember メンバーの紐付けを解除し、在籍期間を終了する（過去の期間は集計のために残す）
 func (r *gormGroupRepository) RemoveMember(group *models.Group, user *models.User) error {
-	return r.db.Model(group).Association("Members").Delete(user)
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(group).Association("Members").Delete(user); err != nil {
+			return err
+		}
+		return tx.Model(&models.GroupMembershipPeriod{}).
+			Where("group_id = ? AND user_id = ? AND left_at IS NULL", group.ID, user.ID).
+			Update("left_at", time.Now()).Error
+	})
 }
 
 func (r *gormGroupRepository) IsMember(groupID uuid.UUID, userID uuid.UUID) (bool, error) {
 	var count int64
-	err := r.db.Table("group_members").Where("group_id = ? AND user_id = ?", groupID, userID).Count(&count).Error
+	err := r.db.Model(&models.GroupMember{}).Where("group_id = ? AND user_id = ?", groupID, userID).Count(&count).Error
 	return count > 0, err
 }
+
+// GetMemberRole メンバーのロールを取得する。メンバーでない場合は空文字を返す
+func (r *gormGroupRepository) GetMemberRole(groupID uuid.UUID, userID uuid.UUID) (string, error) {
+	var members []models.GroupMember
+	err := r.db.Where("group_id = ? AND user_id = ?", groupID, userID).Limit(1).Find(&members).Error
+	if err != nil || len(members) == 0 {
+		return "", err
+	}
+	return members[0].Role, nil
+}
+
+func (r *gormGroupRepository) SetMemberRole(groupID uuid.UUID, userID uuid.UUID, role string) error {
+	return r.db.Model(&models.GroupMember{}).
+		Where("group_id = ? AND user_id = ?", groupID, userID).
+		Update("role", role).Error
+}
+
+// TransferOwnership オーナーを譲渡する。新オーナーのロールをownerにし、元オーナーは管理者（admin）になる
+func (r *gormGroupRepository) TransferOwnership(group *models.Group, newOwnerID uuid.UUID) error {
+	previousOwnerID := group.OwnerID
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Group{}).Where("id = ?", group.ID).Update("owner_id", newOwnerID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.GroupMember{}).
+			Where("group_id = ? AND user_id = ?", group.ID, previousOwnerID).
+			Update("role", models.GroupRoleAdmin).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.GroupMember{}).
+			Where("group_id = ? AND user_id = ?", group.ID, newOwnerID).
+			Update("role", models.GroupRoleOwner).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	group.OwnerID = newOwnerID
+	return nil
+}
+
+// GetMembershipPeriods グループの在籍期間を参加順に取得する（退会済みユーザーを含む）
+func (r *gormGroupRepository) GetMembershipPeriods(groupID uuid.UUID) ([]models.GroupMembershipPeriod, error) {
+	var periods []models.GroupMembershipPeriod
+	err := r.db.Preload("User", unscopedUser).
+		Where("group_id = ?", groupID).
+		Order("joined_at ASC").
+		Find(&periods).Error
+	return periods, err
+}
@@ -0,0 +1,203 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"receipt/server/config"
+	"receipt/server/internal/migration"
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// newTestDB テストごとのSQLiteのデータベースを作成し、マイグレーションを適用する
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := config.OpenDB(config.DBConfig{Driver: config.DBDriverSQLite, Path: filepath.Join(t.TempDir(), "receipt.db")})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	migrator, err := migration.New(db)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func newTestUser(t *testing.T, db *gorm.DB, nickname string) *models.User {
+	t.Helper()
+	user := &models.User{Email: nickname + "@example.com", Nickname: nickname}
+	if err := repository.NewUserRepository(db).Create(user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+func newTestGroup(t *testing.T, db *gorm.DB, owner *models.User) *models.Group {
+	t.Helper()
+	group := &models.Group{Name: "テスト", OwnerID: owner.ID, Members: []models.User{*owner}}
+	if err := repository.NewGroupRepository(db).Create(group); err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+	return group
+}
+
+func TestGroupRepositoryMembership(t *testing.T) {
+	db := newTestDB(t)
+	repo := repository.NewGroupRepository(db)
+
+	owner := newTestUser(t, db, "owner")
+	member := newTestUser(t, db, "member")
+	outsider := newTestUser(t, db, "outsider")
+	group := newTestGroup(t, db, owner)
+	other := newTestGroup(t, db, outsider)
+
+	if err := repo.AddMember(group, member); err != nil {
+		t.Fatalf("AddMember failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		user *models.User
+		want bool
+	}{
+		{owner, true},
+		{member, true},
+		{outsider, false},
+	} {
+		got, err := repo.IsMember(group.ID, tc.user.ID)
+		if err != nil {
+			t.Fatalf("IsMember failed: %v", err)
+		}
+		if got != tc.want {
+			t.Errorf("IsMember(%s) = %v, want %v", tc.user.Nickname, got, tc.want)
+		}
+	}
+	if ok, _ := repo.IsMember(uuid.New(), owner.ID); ok {
+		t.Error("expected no membership in an unknown group")
+	}
+
+	role, err := repo.GetMemberRole(group.ID, member.ID)
+	if err != nil || role != models.GroupRoleMember {
+		t.Errorf("expected member role, got %q (%v)", role, err)
+	}
+
+	groups, err := repo.GetGroupsByUserID(member.ID)
+	if err != nil {
+		t.Fatalf("GetGroupsByUserID failed: %v", err)
+	}
+	if len(groups) != 1 || groups[0].ID != group.ID {
+		t.Fatalf("expected only the joined group, got %+v", groups)
+	}
+	if len(groups[0].Members) != 2 {
+		t.Errorf("expected 2 members to be preloaded, got %d", len(groups[0].Members))
+	}
+	if groups, _ := repo.GetGroupsByUserID(outsider.ID); len(groups) != 1 || groups[0].ID != other.ID {
+		t.Errorf("expected outsider to see only their own group, got %+v", groups)
+	}
+
+	if err := repo.RemoveMember(group, member); err != nil {
+		t.Fatalf("RemoveMember failed: %v", err)
+	}
+	if ok, _ := repo.IsMember(group.ID, member.ID); ok {
+		t.Error("expected removed member not to be a member")
+	}
+	periods, err := repo.GetMembershipPeriods(group.ID)
+	if err != nil {
+		t.Fatalf("GetMembershipPeriods failed: %v", err)
+	}
+	var closed int
+	for _, p := range periods {
+		if p.UserID == member.ID && p.LeftAt != nil {
+			closed++
+		}
+	}
+	if closed != 1 {
+		t.Errorf("expected the removed member's period to be closed, got %+v", periods)
+	}
+}
+
+func TestGroupRepositoryPurge(t *testing.T) {
+	db := newTestDB(t)
+	repo := repository.NewGroupRepository(db)
+
+	owner := newTestUser(t, db, "owner")
+	group := newTestGroup(t, db, owner)
+	other := newTestGroup(t, db, owner)
+
+	// 完全削除するグループと、残すグループに同じデータを登録する
+	for _, g := range []*models.Group{group, other} {
+		groupID := g.ID
+		receipt := newTestReceipt(t, db, g, owner, "2026-07-01", "イオン", "食費", "野菜", 1000)
+		shop := &models.Shop{GroupID: groupID, Name: "イオン", Aliases: []models.ShopAlias{{GroupID: &groupID, Alias: "AEON", CanonicalName: "イオン"}}}
+		for _, record := range []interface{}{
+			&models.GroupMembershipPeriod{GroupID: groupID, UserID: owner.ID, JoinedAt: time.Now()},
+			&models.Settlement{GroupID: groupID, Year: 2026, Month: 7, Amount: 500, SettledBy: owner.ID},
+			shop,
+			&models.ShopAlias{GroupID: &groupID, Alias: "ｲｵﾝ", CanonicalName: "イオン"},
+			&models.AnalysisJob{UserID: owner.ID, GroupID: &groupID, Status: models.AnalysisJobSucceeded, MIMEType: "image/jpeg"},
+			&models.ReceiptDraft{UserID: owner.ID, GroupID: &groupID, ExpiresAt: time.Now().Add(time.Hour)},
+			&models.AnalysisFeedback{GroupID: groupID, UserID: owner.ID, ReceiptID: receipt.ID},
+			&models.AIUsage{UserID: owner.ID, GroupID: &groupID, Kind: models.AIUsageAnalyzeReceipt},
+		} {
+			if err := db.Create(record).Error; err != nil {
+				t.Fatalf("failed to create %T: %v", record, err)
+			}
+		}
+	}
+
+	if err := repo.Purge(group.ID); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		model interface{}
+		query string
+	}{
+		{&models.Group{}, "id = ?"},
+		{&models.Receipt{}, "group_id = ?"},
+		{&models.Settlement{}, "group_id = ?"},
+		{&models.GroupMember{}, "group_id = ?"},
+		{&models.GroupMembershipPeriod{}, "group_id = ?"},
+		{&models.Shop{}, "group_id = ?"},
+		{&models.ShopAlias{}, "group_id = ?"},
+		{&models.AnalysisJob{}, "group_id = ?"},
+		{&models.ReceiptDraft{}, "group_id = ?"},
+		{&models.AnalysisFeedback{}, "group_id = ?"},
+		{&models.AIUsage{}, "group_id = ?"},
+	} {
+		var purged, kept int64
+		if err := db.Unscoped().Model(tc.model).Where(tc.query, group.ID).Count(&purged).Error; err != nil {
+			t.Fatalf("failed to count %T: %v", tc.model, err)
+		}
+		if err := db.Unscoped().Model(tc.model).Where(tc.query, other.ID).Count(&kept).Error; err != nil {
+			t.Fatalf("failed to count %T: %v", tc.model, err)
+		}
+		if purged != 0 {
+			t.Errorf("expected %T of the purged group to be deleted, got %d", tc.model, purged)
+		}
+		if kept == 0 {
+			t.Errorf("expected %T of the other group to be kept", tc.model)
+		}
+	}
+	// AIの利用記録はユーザーの利用上限に数えるため、グループとの紐付けだけを外して残す
+	var usages int64
+	if err := db.Model(&models.AIUsage{}).Where("user_id = ? AND group_id IS NULL", owner.ID).Count(&usages).Error; err != nil {
+		t.Fatalf("failed to count AI usage: %v", err)
+	}
+	if usages != 1 {
+		t.Errorf("expected the purged group's AI usage to be kept without the group, got %d", usages)
+	}
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"receipt/server/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RateLimit トークンバケットの設定
+// Burst 個までのリクエストを連続で許可し、Interval ごとにトークンを1つ補充する
+type RateLimit struct {
+	Burst    int
+	Interval time.Duration
+}
+
+// RateLimitStore レート制限用トークンバケットの保存先インターフェース
+type RateLimitStore interface {
+	// Take key に対応するバケットからトークンを1つ消費する。
+	// トークンが不足している場合は allowed=false と、次のトークンが補充されるまでの待ち時間を返す。
+	Take(key string, limit RateLimit, now time.Time) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// refillBucket 経過時間に応じてトークンを補充し、1つ消費した結果を返す
+func refillBucket(tokens float64, last time.Time, limit RateLimit, now time.Time) (float64, bool, time.Duration) {
+	if elapsed := now.Sub(last); elapsed > 0 && limit.Interval > 0 {
+		tokens += float64(elapsed) / float64(limit.Interval)
+	}
+	if tokens > float64(limit.Burst) {
+		tokens = float64(limit.Burst)
+	}
+
+	if tokens < 1 {
+		retryAfter := time.Duration((1 - tokens) * float64(limit.Interval))
+		return tokens, false, retryAfter
+	}
+	return tokens - 1, true, 0
+}
+
+// memoryBucket メモリ上のトークンバケット（掃除の判定用に、最後に使われた設定を保持する）
+type memoryBucket struct {
+	tokens float64
+	last   time.Time
+	limit  RateLimit
+}
+
+type memoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+}
+
+// memoryRateLimitStoreMaxKeys メモリ上に保持するバケット数の上限の目安
+const memoryRateLimitStoreMaxKeys = 10000
+
+// NewMemoryRateLimitStore プロセス内メモリで保持する RateLimitStore を作成
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{buckets: make(map[string]*memoryBucket)}
+}
+
+func (s *memoryRateLimitStore) Take(key string, limit RateLimit, now time.Time) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, exists := s.buckets[key]
+	if !exists {
+		if len(s.buckets) >= memoryRateLimitStoreMaxKeys {
+			s.sweep(now)
+		}
+		b = &memoryBucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+
+	tokens, allowed, retryAfter := refillBucket(b.tokens, b.last, limit, now)
+	b.tokens = tokens
+	b.last = now
+	b.limit = limit
+	return allowed, retryAfter, nil
+}
+
+// sweep 満タンまで回復したバケットを削除する（満タンのバケットは新規作成と同じ状態のため）。
+// キーごとに制限が異なるため、各バケットに保持した設定で判定する
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if tokens, _, _ := refillBucket(b.tokens, b.last, b.limit, now); tokens >= float64(b.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+type gormRateLimitStore struct {
+	db *gorm.DB
+}
+
+// NewRateLimitStore DBに保存する RateLimitStore を作成（複数台構成でも制限を共有できる）
+func NewRateLimitStore(db *gorm.DB) RateLimitStore {
+	return &gormRateLimitStore{db: db}
+}
+
+func (s *gormRateLimitStore) Take(key string, limit RateLimit, now time.Time) (bool, time.Duration, error) {
+	var allowed bool
+	var retryAfter time.Duration
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var bucket models.RateLimitBucket
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(clause.Eq{Column: clause.Column{Name: "key"}, Value: key}).
+			First(&bucket).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			bucket = models.RateLimitBucket{Key: key, Tokens: float64(limit.Burst), UpdatedAt: now}
+		} else if err != nil {
+			return err
+		}
+
+		bucket.Tokens, allowed, retryAfter = refillBucket(bucket.Tokens, bucket.UpdatedAt, limit, now)
+		bucket.UpdatedAt = now
+		return tx.Save(&bucket).Error
+	})
+	if err != nil {
+		return false, 0, err
+	}
+	return allowed, retryAfter, nil
+}
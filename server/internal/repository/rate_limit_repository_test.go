@@ -0,0 +1,42 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"receipt/server/internal/repository"
+)
+
+func TestRateLimitStoreTake(t *testing.T) {
+	store := repository.NewRateLimitStore(newTestDB(t))
+	limit := repository.RateLimit{Burst: 2, Interval: time.Minute}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		allowed, _, err := store.Take("login:alice", limit, now)
+		if err != nil {
+			t.Fatalf("Take failed: %v", err)
+		}
+		if !allowed {
+			t.Fatalf("expected request %d to be allowed", i+1)
+		}
+	}
+
+	allowed, retryAfter, err := store.Take("login:alice", limit, now)
+	if err != nil {
+		t.Fatalf("Take failed: %v", err)
+	}
+	if allowed || retryAfter <= 0 || retryAfter > time.Minute {
+		t.Errorf("expected the third request to be limited, got allowed=%v retryAfter=%v", allowed, retryAfter)
+	}
+
+	// キーごとに別のバケット
+	if allowed, _, _ := store.Take("login:bob", limit, now); !allowed {
+		t.Error("expected another key to be allowed")
+	}
+
+	// トークンは時間の経過で補充される
+	if allowed, _, _ := store.Take("login:alice", limit, now.Add(time.Minute)); !allowed {
+		t.Error("expected a request to be allowed after the interval")
+	}
+}
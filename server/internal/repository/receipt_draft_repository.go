@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"time"
+
+	"receipt/server/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReceiptDraftRepository レシートの下書き関連データ操作インターフェース
+type ReceiptDraftRepository interface {
+	Create(draft *models.ReceiptDraft) error
+	GetByID(id uuid.UUID) (*models.ReceiptDraft, error)
+	GetByUserID(userID uuid.UUID, now time.Time) ([]models.ReceiptDraft, error)
+	Delete(draft *models.ReceiptDraft) error
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+type gormReceiptDraftRepository struct {
+	db *gorm.DB
+}
+
+// NewReceiptDraftRepository ReceiptDraftRepositoryの実装を作成
+func NewReceiptDraftRepository(db *gorm.DB) ReceiptDraftRepository {
+	return &gormReceiptDraftRepository{db: db}
+}
+
+func (r *gormReceiptDraftRepository) Create(draft *models.ReceiptDraft) error {
+	return r.db.Create(draft).Error
+}
+
+// GetByID 画像を含めて下書きを取得する
+func (r *gormReceiptDraftRepository) GetByID(id uuid.UUID) (*models.ReceiptDraft, error) {
+	var draft models.ReceiptDraft
+	if err := r.db.First(&draft, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &draft, nil
+}
+
+// GetByUserID ユーザーの有効期限内の下書きを新しい順に取得する（画像は読み込まない）
+func (r *gormReceiptDraftRepository) GetByUserID(userID uuid.UUID, now time.Time) ([]models.ReceiptDraft, error) {
+	var drafts []models.ReceiptDraft
+	err := r.db.Omit("image_data").
+		Where("user_id = ? AND expires_at > ?", userID, now).
+		Order("created_at desc").
+		Find(&drafts).Error
+	return drafts, err
+}
+
+func (r *gormReceiptDraftRepository) Delete(draft *models.ReceiptDraft) error {
+	return r.db.Delete(draft).Error
+}
+
+// DeleteExpired 有効期限を過ぎた下書きを削除し、削除した件数を返す
+func (r *gormReceiptDraftRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now).Delete(&models.ReceiptDraft{})
+	return result.RowsAffected, result.Error
+}
@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"strings"
+	"time"
+
 	"receipt/server/internal/models"
 
 	"github.com/google/uuid"
@@ -10,11 +13,27 @@ import (
 // ReceiptRepository レシート関連データ操作インターフェース
 type ReceiptRepository interface {
 	Create(receipt *models.Receipt) error
+	CreateBatch(receipts []models.Receipt) error
 	GetByID(id uuid.UUID) (*models.Receipt, error)
 	GetByIDWithPayer(id uuid.UUID) (*models.Receipt, error)
 	Update(receipt *models.Receipt) error
 	Delete(receipt *models.Receipt) error
 	GetReceiptsByFilter(groupID uuid.UUID, year *int, month *int) ([]models.Receipt, error)
+	GetReceiptsByUser(userID uuid.UUID) ([]models.Receipt, error)
+	GetReceiptsByDateRange(groupID uuid.UUID, from time.Time, to time.Time) ([]models.Receipt, error)
+	SearchReceipts(query *ReceiptQuery) ([]models.Receipt, error)
+	GetCategories(groupID uuid.UUID) ([]string, error)
+}
+
+// ReceiptQuery グループのレシートの検索条件。空の条件では絞り込まない（複数指定した値はいずれかに一致すればよい）
+type ReceiptQuery struct {
+	GroupID    uuid.UUID
+	From       *time.Time // 購入日がこの日時以上
+	To         *time.Time // 購入日がこの日時未満
+	Shops      []string   // 店舗名の部分一致
+	Categories []string   // 分類の完全一致
+	Keyword    string     // 品名・店舗名の部分一致
+	PayerID    *uuid.UUID
 }
 
 type gormReceiptRepository struct {
@@ -30,6 +49,13 @@ func (r *gormReceiptRepository) Create(receipt *models.Receipt) error {
 	return r.db.Create(receipt).Error
 }
 
+// CreateBatch 複数のレシートをまとめて登録する（1件でも失敗した場合はすべて登録しない）
+func (r *gormReceiptRepository) CreateBatch(receipts []models.Receipt) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&receipts).Error
+	})
+}
+
 func (r *gormReceiptRepository) GetByID(id uuid.UUID) (*models.Receipt, error) {
 	var receipt models.Receipt
 	if err := r.db.First(&receipt, "id = ?", id).Error; err != nil {
@@ -40,7 +66,7 @@ func (r *gormReceiptRepository) GetByID(id uuid.UUID) (*models.Receipt, error) {
 
 func (r *gormReceiptRepository) GetByIDWithPayer(id uuid.UUID) (*models.Receipt, error) {
 	var receipt models.Receipt
-	if err := r.db.Preload("Payer").First(&receipt, "id = ?", id).Error; err != nil {
+	if err := r.db.Preload("Payer", unscopedUser).First(&receipt, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	return &receipt, nil
@@ -55,7 +81,7 @@ func (r *gormReceiptRepository) Delete(receipt *models.Receipt) error {
 }
 
 func (r *gormReceiptRepository) GetReceiptsByFilter(groupID uuid.UUID, year *int, month *int) ([]models.Receipt, error) {
-	db := r.db.Preload("Payer").Where("group_id = ?", groupID)
+	db := r.db.Preload("Payer", unscopedUser).Where("group_id = ?", groupID)
 	if year != nil && month != nil {
 		db = db.Where("settlement_year = ? AND settlement_month = ?", *year, *month)
 	}
@@ -64,3 +90,78 @@ func (r *gormReceiptRepository) GetReceiptsByFilter(groupID uuid.UUID, year *int
 	err := db.Order("date desc").Find(&receipts).Error
 	return receipts, err
 }
+
+// GetReceiptsByUser ユーザーが登録した、または支払ったレシートを取得する
+func (r *gormReceiptRepository) GetReceiptsByUser(userID uuid.UUID) ([]models.Receipt, error) {
+	var receipts []models.Receipt
+	err := r.db.Where("user_id = ? OR payer_id = ?", userID, userID).
+		Order("date desc").
+		Find(&receipts).Error
+	return receipts, err
+}
+
+// GetReceiptsByDateRange グループの購入日が from 以上 to 未満のレシートを取得する
+func (r *gormReceiptRepository) GetReceiptsByDateRange(groupID uuid.UUID, from time.Time, to time.Time) ([]models.Receipt, error) {
+	var receipts []models.Receipt
+	err := r.db.Where("group_id = ? AND date >= ? AND date < ?", groupID, from, to).
+		Order("date asc").
+		Find(&receipts).Error
+	return receipts, err
+}
+
+// SearchReceipts 検索条件に一致するグループのレシートを支払者付きで取得する（購入日の新しい順）
+func (r *gormReceiptRepository) SearchReceipts(query *ReceiptQuery) ([]models.Receipt, error) {
+	db := r.db.Preload("Payer", unscopedUser).Where("group_id = ?", query.GroupID)
+	if query.From != nil {
+		db = db.Where("date >= ?", *query.From)
+	}
+	if query.To != nil {
+		db = db.Where("date < ?", *query.To)
+	}
+	if len(query.Shops) > 0 {
+		conditions := make([]string, 0, len(query.Shops))
+		args := make([]interface{}, 0, len(query.Shops))
+		for _, shop := range query.Shops {
+			conditions = append(conditions, "LOWER(shop) LIKE ? ESCAPE '!'")
+			args = append(args, containsPattern(shop))
+		}
+		db = db.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+	if len(query.Categories) > 0 {
+		db = db.Where("category IN ?", query.Categories)
+	}
+	if query.Keyword != "" {
+		pattern := containsPattern(query.Keyword)
+		db = db.Where("(LOWER(item) LIKE ? ESCAPE '!' OR LOWER(shop) LIKE ? ESCAPE '!')", pattern, pattern)
+	}
+	if query.PayerID != nil {
+		db = db.Where("payer_id = ?", *query.PayerID)
+	}
+
+	var receipts []models.Receipt
+	err := db.Order("date desc").Find(&receipts).Error
+	return receipts, err
+}
+
+// GetCategories グループのレシートで使われている分類の一覧
+func (r *gormReceiptRepository) GetCategories(groupID uuid.UUID) ([]string, error) {
+	var categories []string
+	err := r.db.Model(&models.Receipt{}).
+		Where("group_id = ? AND category <> ''", groupID).
+		Distinct("category").
+		Order("category").
+		Pluck("category", &categories).Error
+	return categories, err
+}
+
+// containsPattern 大文字・小文字を区別しない部分一致の LIKE のパターン（比較する列は LOWER で小文字にする）。
+// 値に含まれるワイルドカードは、どのデータベースでも同じに扱える「!」でエスケープする
+func containsPattern(value string) string {
+	replacer := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+	return "%" + replacer.Replace(strings.ToLower(value)) + "%"
+}
+
+// unscopedUser 退会済み（論理削除済み）のユーザーも匿名化された名前で表示するための Preload 条件
+func unscopedUser(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
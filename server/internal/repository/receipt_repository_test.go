@@ -0,0 +1,102 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// jst 日付の比較がUTCに変換されずに行われることを確かめるため、UTCとずれたタイムゾーンで登録する
+var jst = time.FixedZone("JST", 9*60*60)
+
+func newTestReceipt(t *testing.T, db *gorm.DB, group *models.Group, payer *models.User, date string, shop string, category string, item string, amount int) *models.Receipt {
+	t.Helper()
+	d, err := time.ParseInLocation("2006-01-02", date, jst)
+	if err != nil {
+		t.Fatalf("invalid date %s: %v", date, err)
+	}
+	receipt := &models.Receipt{
+		GroupID:         group.ID,
+		UserID:          payer.ID,
+		Date:            d,
+		SettlementYear:  d.Year(),
+		SettlementMonth: int(d.Month()),
+		Shop:            shop,
+		Category:        category,
+		Item:            item,
+		Amount:          amount,
+		PayerID:         payer.ID,
+		PaymentMethod:   models.PaymentMethodHalf,
+	}
+	if err := repository.NewReceiptRepository(db).Create(receipt); err != nil {
+		t.Fatalf("failed to create receipt: %v", err)
+	}
+	return receipt
+}
+
+func TestReceiptRepositorySearchReceipts(t *testing.T) {
+	db := newTestDB(t)
+	repo := repository.NewReceiptRepository(db)
+
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	group := newTestGroup(t, db, alice)
+	other := newTestGroup(t, db, bob)
+
+	newTestReceipt(t, db, group, alice, "2026-07-01", "Saizeriya 渋谷店", "外食", "夕食", 3200)
+	newTestReceipt(t, db, group, bob, "2026-07-31", "イオン", "食費", "野菜", 1500)
+	newTestReceipt(t, db, group, alice, "2026-08-01", "サイゼリヤ", "外食", "昼食", 1200)
+	newTestReceipt(t, db, group, alice, "2026-07-10", "100%ショップ", "日用品", "洗剤_詰替", 300)
+	newTestReceipt(t, db, other, bob, "2026-07-15", "Saizeriya", "外食", "夕食", 5000)
+
+	from := time.Date(2026, 7, 1, 0, 0, 0, 0, jst)
+	to := time.Date(2026, 8, 1, 0, 0, 0, 0, jst)
+
+	tests := []struct {
+		name  string
+		query repository.ReceiptQuery
+		want  []string
+	}{
+		{"period", repository.ReceiptQuery{From: &from, To: &to}, []string{"野菜", "洗剤_詰替", "夕食"}},
+		{"shop ignores case", repository.ReceiptQuery{Shops: []string{"saizeriya", "サイゼリヤ"}}, []string{"昼食", "夕食"}},
+		{"category", repository.ReceiptQuery{Categories: []string{"食費", "日用品"}}, []string{"野菜", "洗剤_詰替"}},
+		{"keyword in item or shop", repository.ReceiptQuery{Keyword: "食"}, []string{"昼食", "夕食"}},
+		{"wildcards are literal", repository.ReceiptQuery{Keyword: "%"}, []string{"洗剤_詰替"}},
+		{"underscore is literal", repository.ReceiptQuery{Keyword: "_"}, []string{"洗剤_詰替"}},
+		{"payer", repository.ReceiptQuery{PayerID: &bob.ID}, []string{"野菜"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.query.GroupID = group.ID
+			receipts, err := repo.SearchReceipts(&tc.query)
+			if err != nil {
+				t.Fatalf("SearchReceipts failed: %v", err)
+			}
+			var items []string
+			for _, r := range receipts {
+				items = append(items, r.Item)
+			}
+			if len(items) != len(tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, items)
+			}
+			for i := range items {
+				if items[i] != tc.want[i] {
+					t.Errorf("expected %v, got %v", tc.want, items)
+					break
+				}
+			}
+		})
+	}
+
+	categories, err := repo.GetCategories(group.ID)
+	if err != nil {
+		t.Fatalf("GetCategories failed: %v", err)
+	}
+	if len(categories) != 3 || categories[0] != "外食" || categories[1] != "日用品" || categories[2] != "食費" {
+		t.Errorf("expected distinct sorted categories, got %v", categories)
+	}
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"time"
+
+	"receipt/server/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Report Granularities
+const (
+	ReportGranularityMonth = "month" // 月ごと（キー: 2026-01）
+	ReportGranularityWeek  = "week"  // 週ごと（キー: 週の月曜日 2026-01-05）
+)
+
+// ReportTotal 集計結果の1行（キーごとの合計金額と件数）
+type ReportTotal struct {
+	Key   string `gorm:"column:report_key"`
+	Total int    `gorm:"column:total"`
+	Count int    `gorm:"column:count"`
+}
+
+// ReportPayerTotal 支払者ごとの集計結果
+type ReportPayerTotal struct {
+	PayerID  uuid.UUID `gorm:"column:payer_id"`
+	Nickname string    `gorm:"column:nickname"`
+	Total    int       `gorm:"column:total"`
+	Count    int       `gorm:"column:count"`
+}
+
+// ReportMonthlyTotal 月・キー（店舗名・支払者ID）ごとの集計結果
+type ReportMonthlyTotal struct {
+	Key   string `gorm:"column:report_key"`
+	Month string `gorm:"column:report_month"` // 2026-01
+	Total int    `gorm:"column:total"`
+	Count int    `gorm:"column:count"`
+}
+
+// ReportShareTotal 支払者・支払方法ごとのレシートの合計（メンバーの負担額の計算用）
+type ReportShareTotal struct {
+	PayerID       uuid.UUID `gorm:"column:payer_id"`
+	PaymentMethod string    `gorm:"column:payment_method"`
+	Total         int       `gorm:"column:total"`
+	Remainder     int       `gorm:"column:remainder"` // レシートごとの金額を divisor で割った余りの合計
+}
+
+// ReportRepository レシートの期間集計（SQLで集計する）インターフェース
+// 期間は from 以上 to 未満
+type ReportRepository interface {
+	GetTotalsByPeriod(groupID uuid.UUID, from time.Time, to time.Time, granularity string) ([]ReportTotal, error)
+	GetTotalsByPayer(groupID uuid.UUID, from time.Time, to time.Time) ([]ReportPayerTotal, error)
+	GetTotalsByPaymentMethod(groupID uuid.UUID, from time.Time, to time.Time) ([]ReportTotal, error)
+	GetTotalsByShop(groupID uuid.UUID, from time.Time, to time.Time, limit int) ([]ReportTotal, error)
+	GetMonthlyTotalsByShop(groupID uuid.UUID, from time.Time, to time.Time) ([]ReportMonthlyTotal, error)
+	GetMonthlyTotalsByPayer(groupID uuid.UUID, from time.Time, to time.Time) ([]ReportMonthlyTotal, error)
+	GetShareTotals(groupID uuid.UUID, from *time.Time, to *time.Time, year int, month int, divisor int) ([]ReportShareTotal, error)
+}
+
+type gormReportRepository struct {
+	db *gorm.DB
+}
+
+// NewReportRepository ReportRepositoryの実装を作成
+func NewReportRepository(db *gorm.DB) ReportRepository {
+	return &gormReportRepository{db: db}
+}
+
+const reportAggregates = "COALESCE(SUM(receipts.amount), 0) AS total, COUNT(*) AS count"
+
+const (
+	// joinReceiptShop レシートに紐付く店舗マスタの結合
+	joinReceiptShop = "LEFT JOIN shops ON shops.id = receipts.shop_id"
+	// shopNameExpr 店舗マスタの店舗名（紐付いていない場合は登録されたままの表記）
+	shopNameExpr = "COALESCE(shops.name, receipts.shop, '')"
+)
+
+func (r *gormReportRepository) receiptsInRange(groupID uuid.UUID, from time.Time, to time.Time) *gorm.DB {
+	return r.db.Model(&models.Receipt{}).
+		Where("receipts.group_id = ? AND receipts.date >= ? AND receipts.date < ?", groupID, from, to)
+}
+
+func (r *gormReportRepository) GetTotalsByPeriod(groupID uuid.UUID, from time.Time, to time.Time, granularity string) ([]ReportTotal, error) {
+	bucket := periodBucketExpr(r.db.Dialector.Name(), granularity)
+
+	var totals []ReportTotal
+	err := r.receiptsInRange(groupID, from, to).
+		Select(bucket + " AS report_key, " + reportAggregates).
+		Group("report_key").
+		Order("report_key").
+		Scan(&totals).Error
+	return totals, err
+}
+
+func (r *gormReportRepository) GetTotalsByPayer(groupID uuid.UUID, from time.Time, to time.Time) ([]ReportPayerTotal, error) {
+	var totals []ReportPayerTotal
+	err := r.receiptsInRange(groupID, from, to).
+		Select("receipts.payer_id AS payer_id, MAX(users.nickname) AS nickname, " + reportAggregates).
+		Joins("LEFT JOIN users ON users.id = receipts.payer_id").
+		Group("receipts.payer_id").
+		Order("total DESC").
+		Scan(&totals).Error
+	return totals, err
+}
+
+func (r *gormReportRepository) GetTotalsByPaymentMethod(groupID uuid.UUID, from time.Time, to time.Time) ([]ReportTotal, error) {
+	var totals []ReportTotal
+	err := r.receiptsInRange(groupID, from, to).
+		Select("receipts.payment_method AS report_key, " + reportAggregates).
+		Group("receipts.payment_method").
+		Order("total DESC").
+		Scan(&totals).Error
+	return totals, err
+}
+
+func (r *gormReportRepository) GetTotalsByShop(groupID uuid.UUID, from time.Time, to time.Time, limit int) ([]ReportTotal, error) {
+	var totals []ReportTotal
+	err := r.receiptsInRange(groupID, from, to).
+		Joins(joinReceiptShop).
+		Select(shopNameExpr + " AS report_key, " + reportAggregates).
+		Group("report_key").
+		Order("total DESC").
+		Limit(limit).
+		Scan(&totals).Error
+	return totals, err
+}
+
+// GetMonthlyTotalsByShop 店舗名・月ごとに集計する（店舗マスタに紐付いていないレシートは登録されたままの表記）
+func (r *gormReportRepository) GetMonthlyTotalsByShop(groupID uuid.UUID, from time.Time, to time.Time) ([]ReportMonthlyTotal, error) {
+	return monthlyTotalsBy(r.receiptsInRange(groupID, from, to).Joins(joinReceiptShop), shopNameExpr)
+}
+
+// GetMonthlyTotalsByPayer 支払者・月ごとに集計する
+func (r *gormReportRepository) GetMonthlyTotalsByPayer(groupID uuid.UUID, from time.Time, to time.Time) ([]ReportMonthlyTotal, error) {
+	return monthlyTotalsBy(r.receiptsInRange(groupID, from, to), "receipts.payer_id")
+}
+
+func monthlyTotalsBy(query *gorm.DB, keyExpr string) ([]ReportMonthlyTotal, error) {
+	var totals []ReportMonthlyTotal
+	err := query.
+		Select(keyExpr + " AS report_key, " + periodBucketExpr(query.Dialector.Name(), ReportGranularityMonth) + " AS report_month, " + reportAggregates).
+		Group("report_key, report_month").
+		Order("report_month").
+		Scan(&totals).Error
+	return totals, err
+}
+
+// periodBucketExpr レシートの日付を集計単位のキーに変換するSQL式（データベースの種類ごと）
+func periodBucketExpr(dialect string, granularity string) string {
+	switch dialect {
+	case "postgres":
+		if granularity == ReportGranularityWeek {
+			return "TO_CHAR(DATE_TRUNC('week', receipts.date), 'YYYY-MM-DD')"
+		}
+		return "TO_CHAR(receipts.date, 'YYYY-MM')"
+	case "sqlite":
+		// 日付は「2026-01-05 00:00:00+09:00」の形式の文字列で保存される。
+		// SQLiteの日付関数はタイムゾーンをUTCに変換するため、先頭の日付部分だけを使う
+		if granularity == ReportGranularityWeek {
+			return "DATE(SUBSTR(receipts.date, 1, 10), '-' || ((CAST(STRFTIME('%w', SUBSTR(receipts.date, 1, 10)) AS INTEGER) + 6) % 7) || ' days')"
+		}
+		return "SUBSTR(receipts.date, 1, 7)"
+	}
+	if granularity == ReportGranularityWeek {
+		return "DATE_FORMAT(DATE_SUB(receipts.date, INTERVAL WEEKDAY(receipts.date) DAY), '%Y-%m-%d')"
+	}
+	return "DATE_FORMAT(receipts.date, '%Y-%m')"
+}
+
+// GetShareTotals 精算月が year/month 以前のレシートを支払者・支払方法ごとに集計する（購入日の from・to は nil の場合は絞り込まない）。
+// 割り勘の負担額をレシートごとに切り捨てた場合と同じ結果にするため、金額を divisor で割った余りも合計する
+func (r *gormReportRepository) GetShareTotals(groupID uuid.UUID, from *time.Time, to *time.Time, year int, month int, divisor int) ([]ReportShareTotal, error) {
+	query := r.db.Model(&models.Receipt{}).
+		Where("group_id = ? AND (settlement_year < ? OR (settlement_year = ? AND settlement_month <= ?))", groupID, year, year, month)
+	if from != nil {
+		query = query.Where("date >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("date < ?", *to)
+	}
+
+	var totals []ReportShareTotal
+	err := query.
+		Select("payer_id, payment_method, COALESCE(SUM(amount), 0) AS total, COALESCE(SUM(amount % ?), 0) AS remainder", divisor).
+		Group("payer_id, payment_method").
+		Scan(&totals).Error
+	return totals, err
+}
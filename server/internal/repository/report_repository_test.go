@@ -0,0 +1,149 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+func TestReportRepositoryTotals(t *testing.T) {
+	db := newTestDB(t)
+	repo := repository.NewReportRepository(db)
+
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	group := newTestGroup(t, db, alice)
+
+	shop := &models.Shop{GroupID: group.ID, Name: "イオン"}
+	if err := repository.NewShopRepository(db).Create(shop); err != nil {
+		t.Fatalf("failed to create shop: %v", err)
+	}
+
+	newTestReceipt(t, db, group, alice, "2026-01-04", "イオン", "食費", "日曜", 100) // 前の週（2025-12-29 の週）
+	newTestReceipt(t, db, group, alice, "2026-01-05", "イオン", "食費", "月曜", 200)
+	linked := newTestReceipt(t, db, group, bob, "2026-01-11", "AEON", "食費", "日曜", 400)
+	newTestReceipt(t, db, group, bob, "2026-02-01", "コンビニ", "食費", "2月", 800)
+	newTestReceipt(t, db, group, alice, "2026-03-01", "コンビニ", "食費", "期間外", 1600)
+
+	if _, err := repository.NewShopRepository(db).AssignReceipts(shop, []uuid.UUID{linked.ID}); err != nil {
+		t.Fatalf("failed to assign receipt: %v", err)
+	}
+
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, jst)
+	to := time.Date(2026, 3, 1, 0, 0, 0, 0, jst)
+
+	assertTotals := func(t *testing.T, got []repository.ReportTotal, want []repository.ReportTotal) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("expected %+v, got %+v", want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("expected %+v, got %+v", want, got)
+				return
+			}
+		}
+	}
+
+	t.Run("week", func(t *testing.T) {
+		totals, err := repo.GetTotalsByPeriod(group.ID, from, to, repository.ReportGranularityWeek)
+		if err != nil {
+			t.Fatalf("GetTotalsByPeriod failed: %v", err)
+		}
+		assertTotals(t, totals, []repository.ReportTotal{
+			{Key: "2025-12-29", Total: 100, Count: 1},
+			{Key: "2026-01-05", Total: 600, Count: 2},
+			{Key: "2026-01-26", Total: 800, Count: 1},
+		})
+	})
+
+	t.Run("month", func(t *testing.T) {
+		totals, err := repo.GetTotalsByPeriod(group.ID, from, to, repository.ReportGranularityMonth)
+		if err != nil {
+			t.Fatalf("GetTotalsByPeriod failed: %v", err)
+		}
+		assertTotals(t, totals, []repository.ReportTotal{
+			{Key: "2026-01", Total: 700, Count: 3},
+			{Key: "2026-02", Total: 800, Count: 1},
+		})
+	})
+
+	t.Run("shop", func(t *testing.T) {
+		totals, err := repo.GetTotalsByShop(group.ID, from, to, 10)
+		if err != nil {
+			t.Fatalf("GetTotalsByShop failed: %v", err)
+		}
+		assertTotals(t, totals, []repository.ReportTotal{
+			{Key: "コンビニ", Total: 800, Count: 1},
+			{Key: "イオン", Total: 700, Count: 3},
+		})
+	})
+
+	t.Run("monthly by payer", func(t *testing.T) {
+		totals, err := repo.GetMonthlyTotalsByPayer(group.ID, from, to)
+		if err != nil {
+			t.Fatalf("GetMonthlyTotalsByPayer failed: %v", err)
+		}
+		want := map[string]int{
+			alice.ID.String() + "/2026-01": 300,
+			bob.ID.String() + "/2026-01":   400,
+			bob.ID.String() + "/2026-02":   800,
+		}
+		if len(totals) != len(want) {
+			t.Fatalf("expected %d rows, got %+v", len(want), totals)
+		}
+		for _, total := range totals {
+			if want[total.Key+"/"+total.Month] != total.Total {
+				t.Errorf("unexpected total %+v", total)
+			}
+		}
+	})
+
+	t.Run("payer", func(t *testing.T) {
+		totals, err := repo.GetTotalsByPayer(group.ID, from, to)
+		if err != nil {
+			t.Fatalf("GetTotalsByPayer failed: %v", err)
+		}
+		if len(totals) != 2 || totals[0].PayerID != bob.ID || totals[0].Nickname != "bob" || totals[0].Total != 1200 || totals[1].Total != 300 {
+			t.Errorf("unexpected payer totals %+v", totals)
+		}
+	})
+
+	t.Run("share totals", func(t *testing.T) {
+		// 1/4 から 2/1 までの、精算月が1月以前のレシート（2/1 の 800円は範囲外、3/1 の 1600円は精算月が範囲外）
+		to := time.Date(2026, 2, 1, 0, 0, 0, 0, jst)
+		totals, err := repo.GetShareTotals(group.ID, nil, &to, 2026, 1, 3)
+		if err != nil {
+			t.Fatalf("GetShareTotals failed: %v", err)
+		}
+		want := map[uuid.UUID]repository.ReportShareTotal{
+			alice.ID: {PayerID: alice.ID, PaymentMethod: models.PaymentMethodHalf, Total: 300, Remainder: 100%3 + 200%3},
+			bob.ID:   {PayerID: bob.ID, PaymentMethod: models.PaymentMethodHalf, Total: 400, Remainder: 400 % 3},
+		}
+		if len(totals) != len(want) {
+			t.Fatalf("expected %+v, got %+v", want, totals)
+		}
+		for _, total := range totals {
+			if total != want[total.PayerID] {
+				t.Errorf("expected %+v, got %+v", want[total.PayerID], total)
+			}
+		}
+
+		from := time.Date(2026, 1, 5, 0, 0, 0, 0, jst)
+		totals, err = repo.GetShareTotals(group.ID, &from, nil, 2026, 12, 2)
+		if err != nil {
+			t.Fatalf("GetShareTotals failed: %v", err)
+		}
+		sum := 0
+		for _, total := range totals {
+			sum += total.Total
+		}
+		if sum != 200+400+800+1600 {
+			t.Errorf("expected receipts from 1/5 to be included, got %+v", totals)
+		}
+	})
+}
@@ -1,20 +1,34 @@
 package repository
 
 import (
-	"time"
 	"receipt/server/internal/models"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// SettlementTotal 精算者・受取人ごとの精算額の合計
+type SettlementTotal struct {
+	SettledBy   uuid.UUID `gorm:"column:settled_by"`
+	RecipientID uuid.UUID `gorm:"column:recipient_id"`
+	Total       int       `gorm:"column:total"`
+}
+
 // SettlementRepository 精算関連データ操作インターフェース
 type SettlementRepository interface {
 	Create(settlement *models.Settlement) error
 	GetSettlementsByFilter(groupID uuid.UUID, year int, month int) ([]models.Settlement, error)
 	CreateSettlementAndSettleReceipts(settlement *models.Settlement) error
+	GetSettlementsByUser(userID uuid.UUID) ([]models.Settlement, error)
+	CreateCumulativeSettlement(settlement *models.Settlement) error
+	GetTotalsByMember(groupID uuid.UUID, year int, month int) ([]SettlementTotal, error)
+	GetSettlementsWithoutRecipient(groupID uuid.UUID, year int, month int) ([]models.Settlement, error)
 }
 
+// settledUntilCondition 精算月が指定月以前の精算の条件
+const settledUntilCondition = "group_id = ? AND (year < ? OR (year = ? AND month <= ?))"
+
 type gormSettlementRepository struct {
 	db *gorm.DB
 }
@@ -31,7 +45,7 @@ func (r *gormSettlementRepository) Create(settlement *models.Settlement) error {
 func (r *gormSettlementRepository) GetSettlementsByFilter(groupID uuid.UUID, year int, month int) ([]models.Settlement, error) {
 	var settlements []models.Settlement
 	err := r.db.Where("group_id = ? AND year = ? AND month = ?", groupID, year, month).
-		Preload("SettledByUser").
+		Preload("SettledByUser", unscopedUser).
 		Order("created_at desc").
 		Find(&settlements).Error
 	return settlements, err
@@ -45,7 +59,7 @@ func (r *gormSettlementRepository) CreateSettlementAndSettleReceipts(settlement
 
 		now := time.Now()
 		if err := tx.Model(&models.Receipt{}).
-			Where("group_id = ? AND settlement_year = ? AND settlement_month = ? AND settled_at IS NULL", 
+			Where("group_id = ? AND settlement_year = ? AND settlement_month = ? AND settled_at IS NULL",
 				settlement.GroupID, settlement.Year, settlement.Month).
 			Update("settled_at", now).Error; err != nil {
 			return err
@@ -54,3 +68,50 @@ func (r *gormSettlementRepository) CreateSettlementAndSettleReceipts(settlement
 		return nil
 	})
 }
+
+// GetSettlementsByUser ユーザーが記録した精算履歴を取得する
+func (r *gormSettlementRepository) GetSettlementsByUser(userID uuid.UUID) ([]models.Settlement, error) {
+	var settlements []models.Settlement
+	err := r.db.Where("settled_by = ?", userID).
+		Order("created_at desc").
+		Find(&settlements).Error
+	return settlements, err
+}
+
+// CreateCumulativeSettlement 精算を登録し、精算月以前の未精算レシートをまとめて精算済みにする。
+// 精算により累積残高が全員0になる場合にのみ使う（一部だけの支払いは Create で登録する）
+func (r *gormSettlementRepository) CreateCumulativeSettlement(settlement *models.Settlement) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(settlement).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Model(&models.Receipt{}).
+			Where("group_id = ? AND settled_at IS NULL AND (settlement_year < ? OR (settlement_year = ? AND settlement_month <= ?))",
+				settlement.GroupID, settlement.Year, settlement.Year, settlement.Month).
+			Update("settled_at", now).Error
+	})
+}
+
+// GetTotalsByMember 精算月が year/month 以前の、受取人が記録された精算を精算者・受取人ごとに集計する
+func (r *gormSettlementRepository) GetTotalsByMember(groupID uuid.UUID, year int, month int) ([]SettlementTotal, error) {
+	var totals []SettlementTotal
+	err := r.db.Model(&models.Settlement{}).
+		Where(settledUntilCondition, groupID, year, year, month).
+		Where("recipient_id IS NOT NULL").
+		Select("settled_by, recipient_id, COALESCE(SUM(amount), 0) AS total").
+		Group("settled_by, recipient_id").
+		Scan(&totals).Error
+	return totals, err
+}
+
+// GetSettlementsWithoutRecipient 精算月が year/month 以前の、受取人が記録されていない（受取人の導入前に登録した）精算を取得する
+func (r *gormSettlementRepository) GetSettlementsWithoutRecipient(groupID uuid.UUID, year int, month int) ([]models.Settlement, error) {
+	var settlements []models.Settlement
+	err := r.db.Where(settledUntilCondition, groupID, year, year, month).
+		Where("recipient_id IS NULL").
+		Order("created_at asc").
+		Find(&settlements).Error
+	return settlements, err
+}
@@ -0,0 +1,53 @@
+package repository_test
+
+import (
+	"testing"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+)
+
+func TestSettlementRepositoryTotals(t *testing.T) {
+	db := newTestDB(t)
+	repo := repository.NewSettlementRepository(db)
+
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	group := newTestGroup(t, db, alice)
+
+	for _, settlement := range []*models.Settlement{
+		{GroupID: group.ID, Year: 2026, Month: 1, Amount: 100, SettledBy: bob.ID, RecipientID: &alice.ID},
+		{GroupID: group.ID, Year: 2026, Month: 2, Amount: 200, SettledBy: bob.ID, RecipientID: &alice.ID},
+		{GroupID: group.ID, Year: 2026, Month: 2, Amount: 50, SettledBy: alice.ID, RecipientID: &bob.ID},
+		{GroupID: group.ID, Year: 2026, Month: 2, Amount: 30, SettledBy: bob.ID},
+		{GroupID: group.ID, Year: 2026, Month: 3, Amount: 400, SettledBy: bob.ID, RecipientID: &alice.ID}, // 精算月が範囲外
+	} {
+		if err := repo.Create(settlement); err != nil {
+			t.Fatalf("failed to create settlement: %v", err)
+		}
+	}
+
+	totals, err := repo.GetTotalsByMember(group.ID, 2026, 2)
+	if err != nil {
+		t.Fatalf("GetTotalsByMember failed: %v", err)
+	}
+	if len(totals) != 2 {
+		t.Fatalf("expected 2 rows, got %+v", totals)
+	}
+	for _, total := range totals {
+		switch {
+		case total.SettledBy == bob.ID && total.RecipientID == alice.ID && total.Total == 300:
+		case total.SettledBy == alice.ID && total.RecipientID == bob.ID && total.Total == 50:
+		default:
+			t.Errorf("unexpected total %+v", total)
+		}
+	}
+
+	legacy, err := repo.GetSettlementsWithoutRecipient(group.ID, 2026, 2)
+	if err != nil {
+		t.Fatalf("GetSettlementsWithoutRecipient failed: %v", err)
+	}
+	if len(legacy) != 1 || legacy[0].Amount != 30 {
+		t.Errorf("expected the settlement without a recipient, got %+v", legacy)
+	}
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"receipt/server/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ShopAliasRepository 店舗名の別名関連データ操作インターフェース
+type ShopAliasRepository interface {
+	GetByGroupID(groupID uuid.UUID, includeShopAliases bool) ([]models.ShopAlias, error)
+	Create(alias *models.ShopAlias) error
+	Delete(groupID uuid.UUID, id uuid.UUID) (bool, error)
+}
+
+type gormShopAliasRepository struct {
+	db *gorm.DB
+}
+
+// NewShopAliasRepository ShopAliasRepositoryの実装を作成
+func NewShopAliasRepository(db *gorm.DB) ShopAliasRepository {
+	return &gormShopAliasRepository{db: db}
+}
+
+// GetByGroupID グループの別名と全グループ共通の別名を取得する。
+// includeShopAliases が false の場合は、店舗マスタの別名（店舗マスタの画面で管理する）を除く
+func (r *gormShopAliasRepository) GetByGroupID(groupID uuid.UUID, includeShopAliases bool) ([]models.ShopAlias, error) {
+	query := r.db.Where("group_id = ? OR group_id IS NULL", groupID)
+	if !includeShopAliases {
+		query = query.Where("shop_id IS NULL")
+	}
+
+	var aliases []models.ShopAlias
+	err := query.Order("created_at asc").Find(&aliases).Error
+	return aliases, err
+}
+
+func (r *gormShopAliasRepository) Create(alias *models.ShopAlias) error {
+	return r.db.Create(alias).Error
+}
+
+// Delete グループの別名を削除する。該当する別名があった場合は true を返す
+// （全グループ共通の別名と、店舗マスタの別名は削除できない）
+func (r *gormShopAliasRepository) Delete(groupID uuid.UUID, id uuid.UUID) (bool, error) {
+	result := r.db.Where("id = ? AND group_id = ? AND shop_id IS NULL", id, groupID).Delete(&models.ShopAlias{})
+	return result.RowsAffected > 0, result.Error
+}
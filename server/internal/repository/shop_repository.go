@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"receipt/server/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ShopRepository 店舗マスタ関連データ操作インターフェース
+type ShopRepository interface {
+	Create(shop *models.Shop) error
+	GetByID(id uuid.UUID) (*models.Shop, error)
+	GetByGroupID(groupID uuid.UUID) ([]models.Shop, error)
+	Update(shop *models.Shop) error
+	Delete(shop *models.Shop) error
+	AddAlias(shop *models.Shop, alias string) (*models.ShopAlias, error)
+	Merge(target *models.Shop, sources []models.Shop) (int64, error)
+	AssignReceipts(shop *models.Shop, receiptIDs []uuid.UUID) (int64, error)
+	GetUnassignedShopNames(groupID uuid.UUID) ([]UnassignedShopName, error)
+}
+
+// UnassignedShopName 店舗マスタに紐付いていないレシートの店舗名（表記ごと）
+type UnassignedShopName struct {
+	Shop       string
+	ReceiptIDs []uuid.UUID
+}
+
+type gormShopRepository struct {
+	db *gorm.DB
+}
+
+// NewShopRepository ShopRepositoryの実装を作成
+func NewShopRepository(db *gorm.DB) ShopRepository {
+	return &gormShopRepository{db: db}
+}
+
+func (r *gormShopRepository) Create(shop *models.Shop) error {
+	return r.db.Create(shop).Error
+}
+
+func (r *gormShopRepository) GetByID(id uuid.UUID) (*models.Shop, error) {
+	var shop models.Shop
+	if err := r.db.Preload("Aliases").First(&shop, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &shop, nil
+}
+
+func (r *gormShopRepository) GetByGroupID(groupID uuid.UUID) ([]models.Shop, error) {
+	var shops []models.Shop
+	err := r.db.Preload("Aliases").
+		Where("group_id = ?", groupID).
+		Order("name asc").
+		Find(&shops).Error
+	return shops, err
+}
+
+// Update 店舗名・既定値を更新する。店舗名の変更は別名の正式名称にも反映する
+func (r *gormShopRepository) Update(shop *models.Shop) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Aliases").Save(shop).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ShopAlias{}).
+			Where("shop_id = ?", shop.ID).
+			Update("canonical_name", shop.Name).Error
+	})
+}
+
+// Delete 店舗と別名を削除する。紐付いていたレシート（削除済みを含む）・下書きは入力された店舗名のまま残す
+func (r *gormShopRepository) Delete(shop *models.Shop) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Receipt{}).Where("shop_id = ?", shop.ID).Update("shop_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ReceiptDraft{}).Where("shop_id = ?", shop.ID).Update("shop_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("shop_id = ?", shop.ID).Delete(&models.ShopAlias{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(shop).Error
+	})
+}
+
+func (r *gormShopRepository) AddAlias(shop *models.Shop, alias string) (*models.ShopAlias, error) {
+	shopAlias := models.ShopAlias{
+		GroupID:       &shop.GroupID,
+		ShopID:        &shop.ID,
+		Alias:         alias,
+		CanonicalName: shop.Name,
+	}
+	if err := r.db.Create(&shopAlias).Error; err != nil {
+		return nil, err
+	}
+	return &shopAlias, nil
+}
+
+// Merge 統合元の店舗のレシート・下書き・別名を統合先に付け替え、統合元の店舗名を別名として残して削除する。
+// 戻り値：付け替えたレシートの件数
+func (r *gormShopRepository) Merge(target *models.Shop, sources []models.Shop) (int64, error) {
+	var moved int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range sources {
+			source := &sources[i]
+
+			result := tx.Unscoped().Model(&models.Receipt{}).Where("shop_id = ?", source.ID).Update("shop_id", target.ID)
+			if result.Error != nil {
+				return result.Error
+			}
+			moved += result.RowsAffected
+
+			if err := tx.Model(&models.ReceiptDraft{}).Where("shop_id = ?", source.ID).Update("shop_id", target.ID).Error; err != nil {
+				return err
+			}
+
+			err := tx.Model(&models.ShopAlias{}).
+				Where("shop_id = ?", source.ID).
+				Updates(map[string]interface{}{"shop_id": target.ID, "canonical_name": target.Name}).Error
+			if err != nil {
+				return err
+			}
+
+			sourceAlias := models.ShopAlias{
+				GroupID:       &target.GroupID,
+				ShopID:        &target.ID,
+				Alias:         source.Name,
+				CanonicalName: target.Name,
+			}
+			if err := tx.Create(&sourceAlias).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Delete(source).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return moved, err
+}
+
+// AssignReceipts 店舗マスタに紐付いていないレシートを店舗に紐付ける。戻り値：紐付けたレシートの件数
+func (r *gormShopRepository) AssignReceipts(shop *models.Shop, receiptIDs []uuid.UUID) (int64, error) {
+	if len(receiptIDs) == 0 {
+		return 0, nil
+	}
+	result := r.db.Model(&models.Receipt{}).
+		Where("group_id = ? AND shop_id IS NULL AND id IN ?", shop.GroupID, receiptIDs).
+		Update("shop_id", shop.ID)
+	return result.RowsAffected, result.Error
+}
+
+// GetUnassignedShopNames 店舗マスタに紐付いていないレシートを店舗名の表記ごとにまとめて取得する
+func (r *gormShopRepository) GetUnassignedShopNames(groupID uuid.UUID) ([]UnassignedShopName, error) {
+	var rows []struct {
+		ID   uuid.UUID
+		Shop string
+	}
+	err := r.db.Model(&models.Receipt{}).
+		Select("id, shop").
+		Where("group_id = ? AND shop_id IS NULL AND shop <> ''", groupID).
+		Order("shop").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var names []UnassignedShopName
+	for _, row := range rows {
+		if len(names) == 0 || names[len(names)-1].Shop != row.Shop {
+			names = append(names, UnassignedShopName{Shop: row.Shop})
+		}
+		last := &names[len(names)-1]
+		last.ReceiptIDs = append(last.ReceiptIDs, row.ID)
+	}
+	return names, nil
+}
@@ -0,0 +1,70 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+)
+
+func TestShopRepositoryMergeAndDelete(t *testing.T) {
+	db := newTestDB(t)
+	repo := repository.NewShopRepository(db)
+
+	owner := newTestUser(t, db, "owner")
+	group := newTestGroup(t, db, owner)
+
+	newShop := func(name string) *models.Shop {
+		t.Helper()
+		shop := &models.Shop{GroupID: group.ID, Name: name}
+		if err := repo.Create(shop); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		return shop
+	}
+	newDraft := func(shop *models.Shop) *models.ReceiptDraft {
+		t.Helper()
+		draft := &models.ReceiptDraft{UserID: owner.ID, GroupID: &group.ID, Shop: shop.Name, ShopID: &shop.ID, ExpiresAt: time.Now().Add(time.Hour)}
+		if err := db.Create(draft).Error; err != nil {
+			t.Fatalf("failed to create draft: %v", err)
+		}
+		return draft
+	}
+	loadDraft := func(draft *models.ReceiptDraft) *models.ReceiptDraft {
+		t.Helper()
+		var stored models.ReceiptDraft
+		if err := db.First(&stored, "id = ?", draft.ID).Error; err != nil {
+			t.Fatalf("failed to load draft: %v", err)
+		}
+		return &stored
+	}
+
+	target := newShop("イオン")
+	source := newShop("AEON")
+	receipt := newTestReceipt(t, db, group, owner, "2026-07-01", "AEON", "食費", "野菜", 1000)
+	if err := db.Model(receipt).Update("shop_id", source.ID).Error; err != nil {
+		t.Fatalf("failed to link receipt: %v", err)
+	}
+	draft := newDraft(source)
+
+	// 統合元の店舗に紐付いた下書きも統合先に付け替える
+	moved, err := repo.Merge(target, []models.Shop{*source})
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if moved != 1 {
+		t.Errorf("expected 1 receipt to be moved, got %d", moved)
+	}
+	if stored := loadDraft(draft); stored.ShopID == nil || *stored.ShopID != target.ID {
+		t.Errorf("expected draft to be moved to the target shop, got %v", stored.ShopID)
+	}
+
+	// 削除した店舗に紐付いた下書きは、店舗名のまま紐付けだけを外す
+	if err := repo.Delete(target); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if stored := loadDraft(draft); stored.ShopID != nil || stored.Shop != "AEON" {
+		t.Errorf("expected draft to keep the shop name without the shop, got %v %q", stored.ShopID, stored.Shop)
+	}
+}
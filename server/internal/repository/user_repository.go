@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"receipt/server/internal/models"
 
 	"github.com/google/uuid"
@@ -13,6 +15,16 @@ type UserRepository interface {
 	GetByID(id uuid.UUID) (*models.User, error)
 	GetByEmail(email string) (*models.User, error)
 	Update(user *models.User) error
+	ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error
+	UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error)
+	DeleteRecoveryCodes(userID uuid.UUID) error
+	DeleteAccount(user *models.User, groups AccountGroupChanges) error
+}
+
+// AccountGroupChanges 退会時に行うグループの変更
+type AccountGroupChanges struct {
+	NewOwners     map[uuid.UUID]uuid.UUID // オーナーを引き継ぐグループとメンバー
+	DeletedGroups []uuid.UUID             // 他にメンバーがいないため削除するグループ
 }
 
 type gormUserRepository struct {
@@ -47,3 +59,87 @@ func (r *gormUserRepository) GetByEmail(email string) (*models.User, error) {
 func (r *gormUserRepository) Update(user *models.User) error {
 	return r.db.Save(user).Error
 }
+
+func (r *gormUserRepository) ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		codes := make([]models.RecoveryCode, 0, len(codeHashes))
+		for _, h := range codeHashes {
+			codes = append(codes, models.RecoveryCode{UserID: userID, CodeHash: h})
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseRecoveryCode 未使用のリカバリーコードを使用済みにする。該当コードがあった場合は true を返す
+func (r *gormUserRepository) UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error) {
+	result := r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormUserRepository) DeleteRecoveryCodes(userID uuid.UUID) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
+
+// DeleteAccount グループのオーナーの引き継ぎ（またはグループの削除）と全グループからの退出を行い、
+// 認証情報を削除し、ユーザー情報を匿名化したうえで論理削除する。途中で失敗した場合はすべて元に戻す。
+// 共有グループに残るレシートからは匿名化後のニックネームで参照される。
+func (r *gormUserRepository) DeleteAccount(user *models.User, groups AccountGroupChanges) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for groupID, newOwnerID := range groups.NewOwners {
+			if err := tx.Model(&models.Group{}).Where("id = ?", groupID).Update("owner_id", newOwnerID).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.GroupMember{}).
+				Where("group_id = ? AND user_id = ?", groupID, newOwnerID).
+				Update("role", models.GroupRoleOwner).Error; err != nil {
+				return err
+			}
+		}
+		if len(groups.DeletedGroups) > 0 {
+			if err := tx.Where("id IN ?", groups.DeletedGroups).Delete(&models.Group{}).Error; err != nil {
+				return err
+			}
+		}
+
+		// 全グループから退出し、在籍期間を終了する（過去の期間は集計のために残す）
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.GroupMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.GroupMembershipPeriod{}).
+			Where("user_id = ? AND left_at IS NULL", user.ID).
+			Update("left_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.Credential{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.ExternalIdentity{}).Error; err != nil {
+			return err
+		}
+		// 解析待ちのジョブ・下書きにはレシートの画像が含まれるため、退会時に削除する
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.AnalysisJob{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.ReceiptDraft{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Save(user).Error; err != nil {
+			return err
+		}
+		return tx.Delete(user).Error
+	})
+}
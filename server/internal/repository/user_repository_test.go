@@ -0,0 +1,80 @@
+package repository_test
+
+import (
+	"testing"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+func TestUserRepositoryDeleteAccount(t *testing.T) {
+	db := newTestDB(t)
+	repo := repository.NewUserRepository(db)
+	groupRepo := repository.NewGroupRepository(db)
+
+	owner := newTestUser(t, db, "owner")
+	member := newTestUser(t, db, "member")
+	shared := newTestGroup(t, db, owner)
+	solo := newTestGroup(t, db, owner)
+	if err := groupRepo.AddMember(shared, member); err != nil {
+		t.Fatalf("AddMember failed: %v", err)
+	}
+
+	t.Run("rolls back on failure", func(t *testing.T) {
+		// グループの変更の後、ユーザーの更新をメールアドレスの一意制約で失敗させる
+		failing := *owner
+		failing.Email = member.Email
+		changes := repository.AccountGroupChanges{
+			NewOwners:     map[uuid.UUID]uuid.UUID{shared.ID: member.ID},
+			DeletedGroups: []uuid.UUID{solo.ID},
+		}
+		if err := repo.DeleteAccount(&failing, changes); err == nil {
+			t.Fatal("expected DeleteAccount to fail")
+		}
+		if ok, _ := groupRepo.IsMember(shared.ID, owner.ID); !ok {
+			t.Error("expected membership to be kept")
+		}
+		if g, err := groupRepo.GetByID(shared.ID); err != nil || g.OwnerID != owner.ID {
+			t.Errorf("expected ownership to be kept, got %+v (%v)", g, err)
+		}
+		if _, err := groupRepo.GetByID(solo.ID); err != nil {
+			t.Errorf("expected solo group to be kept: %v", err)
+		}
+		if _, err := repo.GetByID(owner.ID); err != nil {
+			t.Errorf("expected user to be kept: %v", err)
+		}
+	})
+
+	t.Run("success", func(t *testing.T) {
+		changes := repository.AccountGroupChanges{
+			NewOwners:     map[uuid.UUID]uuid.UUID{shared.ID: member.ID},
+			DeletedGroups: []uuid.UUID{solo.ID},
+		}
+		if err := repo.DeleteAccount(owner, changes); err != nil {
+			t.Fatalf("DeleteAccount failed: %v", err)
+		}
+
+		if g, err := groupRepo.GetByID(shared.ID); err != nil || g.OwnerID != member.ID {
+			t.Errorf("expected ownership to be transferred, got %+v (%v)", g, err)
+		}
+		if role, _ := groupRepo.GetMemberRole(shared.ID, member.ID); role != models.GroupRoleOwner {
+			t.Errorf("expected new owner role, got %q", role)
+		}
+		if ok, _ := groupRepo.IsMember(shared.ID, owner.ID); ok {
+			t.Error("expected deleted user to leave the shared group")
+		}
+		if _, err := groupRepo.GetByID(solo.ID); err == nil {
+			t.Error("expected solo group to be deleted")
+		}
+		var open int64
+		db.Model(&models.GroupMembershipPeriod{}).Where("user_id = ? AND left_at IS NULL", owner.ID).Count(&open)
+		if open != 0 {
+			t.Errorf("expected membership periods to be closed, got %d open", open)
+		}
+		if _, err := repo.GetByID(owner.ID); err == nil {
+			t.Error("expected user to be deleted")
+		}
+	})
+}
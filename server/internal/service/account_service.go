@@ -0,0 +1,202 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+	"receipt/server/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// DeletedUserNickname 退会したユーザーの匿名化後のニックネーム
+const DeletedUserNickname = "退会したユーザー"
+
+// AccountExportProfile エクスポートに含めるプロフィール情報
+type AccountExportProfile struct {
+	ID          uuid.UUID `json:"id"`
+	Email       string    `json:"email"`
+	Nickname    string    `json:"nickname"`
+	TOTPEnabled bool      `json:"totp_enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	ExportedAt  time.Time `json:"exported_at"`
+}
+
+// AccountService アカウント削除・個人データのエクスポートに関するビジネスロジックインターフェース
+type AccountService interface {
+	DeleteAccount(userID uuid.UUID, password, code, reauthenticationToken string) error
+	ExportAccount(userID uuid.UUID) ([]byte, error)
+}
+
+type accountServiceImpl struct {
+	userRepo       repository.UserRepository
+	groupRepo      repository.GroupRepository
+	receiptRepo    repository.ReceiptRepository
+	settlementRepo repository.SettlementRepository
+}
+
+// NewAccountService AccountServiceの実装を作成
+func NewAccountService(
+	userRepo repository.UserRepository,
+	groupRepo repository.GroupRepository,
+	receiptRepo repository.ReceiptRepository,
+	settlementRepo repository.SettlementRepository,
+) AccountService {
+	return &accountServiceImpl{
+		userRepo:       userRepo,
+		groupRepo:      groupRepo,
+		receiptRepo:    receiptRepo,
+		settlementRepo: settlementRepo,
+	}
+}
+
+// DeleteAccount 退会する。本人確認として、パスワードを持つユーザーにはパスワードの再入力を、
+// 2段階認証が有効なユーザーにはTOTPコード（またはリカバリーコード）を求める。
+// どちらもないユーザーには、パスキー・外部IDプロバイダーでの直前のログインで発行された再認証トークンを求める
+func (s *accountServiceImpl) DeleteAccount(userID uuid.UUID, password, code, reauthenticationToken string) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.reauthenticate(user, password, code, reauthenticationToken); err != nil {
+		return err
+	}
+
+	// アーカイブ済みのグループも含め、オーナーのグループは引き継ぐ（他にメンバーがいない場合は削除する）
+	groups, err := s.groupRepo.GetGroupsByUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	changes := repository.AccountGroupChanges{NewOwners: make(map[uuid.UUID]uuid.UUID)}
+	for i := range groups {
+		group := &groups[i]
+		if group.OwnerID != userID {
+			continue
+		}
+		if newOwner := nextGroupOwner(group, userID); newOwner != nil {
+			changes.NewOwners[group.ID] = newOwner.ID
+		} else {
+			changes.DeletedGroups = append(changes.DeletedGroups, group.ID)
+		}
+	}
+
+	// 共有グループに残るレシートでは匿名化された名前で表示される。
+	// 認証トークンはユーザーの存在確認（AuthMiddleware）で無効になる。
+	user.Email = fmt.Sprintf("deleted-%s@deleted.invalid", user.ID)
+	user.Nickname = DeletedUserNickname
+	user.PasswordHash = ""
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	return s.userRepo.DeleteAccount(user, changes)
+}
+
+// reauthenticate 退会前の本人確認を行う
+func (s *accountServiceImpl) reauthenticate(user *models.User, password, code, reauthenticationToken string) error {
+	if user.PasswordHash != "" && !utils.CheckPasswordHash(password, user.PasswordHash) {
+		return ErrInvalidCredentials
+	}
+
+	if user.TOTPEnabled {
+		if code == "" {
+			return ErrInvalidTwoFactorCode
+		}
+		ok, err := verifySecondFactor(s.userRepo, user, code, time.Now())
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	if user.PasswordHash == "" {
+		tokenUserID, err := utils.ParseChallengeToken(reauthenticationToken, reauthenticationPurpose)
+		if err != nil || tokenUserID != user.ID {
+			return ErrReauthenticationRequired
+		}
+	}
+	return nil
+}
+
+// nextGroupOwner オーナー退会時に引き継ぐメンバーを選ぶ（退会者以外の最初のメンバー）
+func nextGroupOwner(group *models.Group, leavingUserID uuid.UUID) *models.User {
+	for i := range group.Members {
+		if group.Members[i].ID != leavingUserID {
+			return &group.Members[i]
+		}
+	}
+	return nil
+}
+
+// ExportAccount 個人データをZIPにまとめる。グループはアーカイブ済みのものを含む参加中のすべて、
+// レシートは本人が登録または支払ったもの、精算は本人が記録した（settled_by が本人の）もののみを含める。
+// 本人が受け取った精算は相手の記録のため含めず、グループの精算履歴で確認できる
+func (s *accountServiceImpl) ExportAccount(userID uuid.UUID) ([]byte, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	groups, err := s.groupRepo.GetGroupsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	receipts, err := s.receiptRepo.GetReceiptsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	settlements, err := s.settlementRepo.GetSettlementsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := AccountExportProfile{
+		ID:          user.ID,
+		Email:       user.Email,
+		Nickname:    user.Nickname,
+		TOTPEnabled: user.TOTPEnabled,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		ExportedAt:  time.Now(),
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", profile},
+		{"groups.json", groups},
+		{"receipts.json", receipts},
+		{"settlements.json", settlements},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
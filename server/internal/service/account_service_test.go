@@ -0,0 +1,235 @@
+package service_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+	"receipt/server/internal/service"
+	"receipt/server/internal/utils"
+)
+
+// mockAccountUserRepository 退会時のグループの変更を mockGroupRepository に反映する UserRepository のモック
+type mockAccountUserRepository struct {
+	*mockUserRepository
+	groupRepo *mockGroupRepository
+	// failDeleteAccount 退会の処理を失敗させる
+	failDeleteAccount bool
+}
+
+func (m *mockAccountUserRepository) DeleteAccount(user *models.User, groups repository.AccountGroupChanges) error {
+	if m.failDeleteAccount {
+		return errors.New("database error")
+	}
+	for groupID, newOwnerID := range groups.NewOwners {
+		group, _ := m.groupRepo.GetByID(groupID)
+		if err := m.groupRepo.TransferOwnership(group, newOwnerID); err != nil {
+			return err
+		}
+	}
+	for _, groupID := range groups.DeletedGroups {
+		if err := m.groupRepo.Delete(&models.Group{ID: groupID}); err != nil {
+			return err
+		}
+	}
+	joined, _ := m.groupRepo.GetGroupsByUserID(user.ID)
+	for i := range joined {
+		if err := m.groupRepo.RemoveMember(&joined[i], user); err != nil {
+			return err
+		}
+	}
+	return m.mockUserRepository.DeleteAccount(user, groups)
+}
+
+func TestAccountService_DeleteAccount(t *testing.T) {
+	groupRepo := newMockGroupRepository()
+	userRepo := &mockAccountUserRepository{mockUserRepository: newMockUserRepository(), groupRepo: groupRepo}
+	receiptRepo := newMockReceiptRepository()
+	settlementRepo := newMockSettlementRepository()
+	svc := service.NewAccountService(userRepo, groupRepo, receiptRepo, settlementRepo)
+
+	password := "securepassword"
+	hash, _ := utils.HashPassword(password)
+	userA := models.User{Email: "usera@example.com", Nickname: "UserA", PasswordHash: hash}
+	_ = userRepo.Create(&userA)
+	userB := models.User{Email: "userb@example.com", Nickname: "UserB"}
+	_ = userRepo.Create(&userB)
+
+	shared := models.Group{Name: "Family", OwnerID: userA.ID}
+	_ = groupRepo.Create(&shared)
+	_ = groupRepo.AddMember(&shared, &userA)
+	_ = groupRepo.AddMember(&shared, &userB)
+
+	solo := models.Group{Name: "Solo", OwnerID: userA.ID}
+	_ = groupRepo.Create(&solo)
+	_ = groupRepo.AddMember(&solo, &userA)
+
+	t.Run("Wrong Password", func(t *testing.T) {
+		err := svc.DeleteAccount(userA.ID, "wrongpassword", "", "")
+		if !errors.Is(err, service.ErrInvalidCredentials) {
+			t.Errorf("Expected error %v, got %v", service.ErrInvalidCredentials, err)
+		}
+	})
+
+	t.Run("Second Factor Is Required", func(t *testing.T) {
+		secret, _ := utils.GenerateTOTPSecret()
+		userTOTP := models.User{Email: "totp@example.com", Nickname: "TOTP", PasswordHash: hash, TOTPEnabled: true, TOTPSecret: secret}
+		_ = userRepo.Create(&userTOTP)
+
+		if err := svc.DeleteAccount(userTOTP.ID, password, "", ""); !errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			t.Errorf("Expected error %v without a code, got %v", service.ErrInvalidTwoFactorCode, err)
+		}
+		if err := svc.DeleteAccount(userTOTP.ID, password, "000000", ""); !errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			t.Errorf("Expected error %v for a wrong code, got %v", service.ErrInvalidTwoFactorCode, err)
+		}
+		code, _ := utils.GenerateTOTPCode(secret, time.Now())
+		if err := svc.DeleteAccount(userTOTP.ID, password, code, ""); err != nil {
+			t.Errorf("DeleteAccount with a TOTP code failed: %v", err)
+		}
+	})
+
+	t.Run("Passwordless User Requires Recent Login", func(t *testing.T) {
+		userOIDC := models.User{Email: "oidc@example.com", Nickname: "OIDC"}
+		_ = userRepo.Create(&userOIDC)
+
+		// パスキー・外部IDプロバイダーでのログイン時に発行された、本人の再認証トークンのみを受け付ける
+		challenge, _ := utils.GenerateChallengeToken(userOIDC.ID, "totp", time.Minute)
+		othersToken, _ := utils.GenerateChallengeToken(userB.ID, "reauthentication", time.Minute)
+		expired, _ := utils.GenerateChallengeToken(userOIDC.ID, "reauthentication", -time.Minute)
+		for _, token := range []string{"", challenge, othersToken, expired} {
+			if err := svc.DeleteAccount(userOIDC.ID, "", "", token); !errors.Is(err, service.ErrReauthenticationRequired) {
+				t.Errorf("Expected error %v, got %v", service.ErrReauthenticationRequired, err)
+			}
+		}
+
+		token, _ := utils.GenerateChallengeToken(userOIDC.ID, "reauthentication", time.Minute)
+		if err := svc.DeleteAccount(userOIDC.ID, "", "", token); err != nil {
+			t.Errorf("DeleteAccount with a reauthentication token failed: %v", err)
+		}
+	})
+
+	t.Run("Failure Leaves Groups Unchanged", func(t *testing.T) {
+		userRepo.failDeleteAccount = true
+		defer func() { userRepo.failDeleteAccount = false }()
+
+		if err := svc.DeleteAccount(userA.ID, password, "", ""); err == nil {
+			t.Fatal("Expected DeleteAccount to fail")
+		}
+		if g, _ := groupRepo.GetByID(shared.ID); g == nil || g.OwnerID != userA.ID {
+			t.Errorf("Expected ownership not to be transferred")
+		}
+		if isMember, _ := groupRepo.IsMember(shared.ID, userA.ID); !isMember {
+			t.Errorf("Expected user to remain a member")
+		}
+		if _, err := groupRepo.GetByID(solo.ID); err != nil {
+			t.Errorf("Expected solo group to remain")
+		}
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		if err := svc.DeleteAccount(userA.ID, password, "", ""); err != nil {
+			t.Fatalf("DeleteAccount failed: %v", err)
+		}
+
+		if _, err := userRepo.GetByID(userA.ID); err == nil {
+			t.Errorf("Expected user to be deleted")
+		}
+
+		// 共有グループのオーナーは残ったメンバーに引き継がれる
+		g, err := groupRepo.GetByID(shared.ID)
+		if err != nil {
+			t.Fatalf("Shared group should remain: %v", err)
+		}
+		if g.OwnerID != userB.ID {
+			t.Errorf("Expected ownership to be transferred to %s, got %s", userB.ID, g.OwnerID)
+		}
+		if isMember, _ := groupRepo.IsMember(shared.ID, userA.ID); isMember {
+			t.Errorf("Deleted user should be removed from the shared group")
+		}
+
+		// 他にメンバーがいないグループは削除される
+		if _, err := groupRepo.GetByID(solo.ID); err == nil {
+			t.Errorf("Expected solo group to be deleted")
+		}
+	})
+
+	t.Run("User Not Found", func(t *testing.T) {
+		err := svc.DeleteAccount(userA.ID, password, "", "")
+		if !errors.Is(err, service.ErrUserNotFound) {
+			t.Errorf("Expected error %v, got %v", service.ErrUserNotFound, err)
+		}
+	})
+}
+
+func TestAccountService_ExportAccount(t *testing.T) {
+	userRepo := newMockUserRepository()
+	groupRepo := newMockGroupRepository()
+	receiptRepo := newMockReceiptRepository()
+	settlementRepo := newMockSettlementRepository()
+	svc := service.NewAccountService(userRepo, groupRepo, receiptRepo, settlementRepo)
+
+	userA := models.User{Email: "usera@example.com", Nickname: "UserA"}
+	_ = userRepo.Create(&userA)
+	userB := models.User{Email: "userb@example.com", Nickname: "UserB"}
+	_ = userRepo.Create(&userB)
+
+	group := models.Group{Name: "Family", OwnerID: userA.ID}
+	_ = groupRepo.Create(&group)
+	_ = groupRepo.AddMember(&group, &userA)
+	_ = groupRepo.AddMember(&group, &userB)
+
+	date := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	// Aが登録したレシート、Bが登録してAが支払ったレシート、Aに関係しないレシート
+	_ = receiptRepo.Create(&models.Receipt{GroupID: group.ID, UserID: userA.ID, PayerID: userB.ID, Date: date, Amount: 1000, PaymentMethod: models.PaymentMethodHalf})
+	_ = receiptRepo.Create(&models.Receipt{GroupID: group.ID, UserID: userB.ID, PayerID: userA.ID, Date: date, Amount: 2000, PaymentMethod: models.PaymentMethodHalf})
+	_ = receiptRepo.Create(&models.Receipt{GroupID: group.ID, UserID: userB.ID, PayerID: userB.ID, Date: date, Amount: 3000, PaymentMethod: models.PaymentMethodHalf})
+	_ = settlementRepo.Create(&models.Settlement{GroupID: group.ID, Year: 2026, Month: 6, Amount: 500, SettledBy: userA.ID})
+
+	archive, err := svc.ExportAccount(userA.ID)
+	if err != nil {
+		t.Fatalf("ExportAccount failed: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("Export is not a valid ZIP archive: %v", err)
+	}
+
+	contents := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(rc)
+		rc.Close()
+		contents[f.Name] = buf.Bytes()
+	}
+
+	for _, name := range []string{"profile.json", "groups.json", "receipts.json", "settlements.json"} {
+		if _, ok := contents[name]; !ok {
+			t.Errorf("Expected %s in export", name)
+		}
+	}
+
+	var profile service.AccountExportProfile
+	_ = json.Unmarshal(contents["profile.json"], &profile)
+	if profile.Email != userA.Email {
+		t.Errorf("Expected profile email %s, got %s", userA.Email, profile.Email)
+	}
+
+	var receipts []models.Receipt
+	_ = json.Unmarshal(contents["receipts.json"], &receipts)
+	if len(receipts) != 2 {
+		t.Errorf("Expected 2 receipts created or paid by the user, got %d", len(receipts))
+	}
+
+	var settlements []models.Settlement
+	_ = json.Unmarshal(contents["settlements.json"], &settlements)
+	if len(settlements) != 1 {
+		t.Errorf("Expected 1 settlement, got %d", len(settlements))
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AI解析のバックエンド
+const (
+	AIBackendGemini = "gemini" // Google Gemini（既定）
+	AIBackendOpenAI = "openai" // OpenAI互換のHTTPエンドポイント（ローカルLLMサーバーを含む）
+	AIBackendLocal  = "local"  // ローカルのOCRとルールベースの解析（ネットワーク不要）
+	AIBackendFake   = "fake"   // 常に同じ結果を返す（テスト・開発用）
+)
+
+const (
+	// defaultAITimeout AI解析1回あたりの既定のタイムアウト
+	defaultAITimeout = 60 * time.Second
+	// defaultAnalyzePrompt レシート画像の解析を依頼する既定のプロンプト
+	defaultAnalyzePrompt = "Analyze this receipt and return JSON only. Use YYYY-MM-DD for date, the store name for shop, a short summary of the purchased items for item, and the total paid as an integer number of yen for amount. " +
+		"For each field, set confidence to how sure you are that the value is read correctly, from 0 to 1. JSON:\n" +
+		"{\"date\": \"YYYY-MM-DD\", \"shop\": \"name\", \"item\": \"summary\", \"amount\": 1234, \"confidence\": {\"date\": 0.9, \"shop\": 0.9, \"item\": 0.9, \"amount\": 0.9}}"
+)
+
+// AIAnalyzer AIによるレシート解析インターフェース
+type AIAnalyzer interface {
+	// AnalyzeReceipt ReceiptImageProcessor で正規化したレシートの画像・PDFを解析する
+	AnalyzeReceipt(ctx context.Context, img *ReceiptImage) (*AnalyzeReceiptResult, error)
+	// ParseText 自然文の説明（例: 「昨日サイゼリヤで夕食3200円、折半」）からレシートの項目を読み取る
+	ParseText(ctx context.Context, input *ReceiptTextInput) (*ReceiptTextResult, error)
+	// TranslateQuestion 支出に関する質問（例: 「夏に外食にいくら使った？」）を検索条件と集計方法に変換する
+	TranslateQuestion(ctx context.Context, input *SpendingQuestionInput) (*SpendingQuery, error)
+}
+
+// ReceiptTextInput 自然文からレシートの項目を読み取るための入力
+type ReceiptTextInput struct {
+	Text    string
+	Today   time.Time // 「昨日」などの相対的な日付の基準日
+	Speaker string    // 文を書いたメンバーのニックネーム
+	Members []string  // 支払者の候補となる、書いた本人以外のメンバーのニックネーム
+}
+
+// ReceiptTextResult 自然文から読み取ったレシートの項目。読み取れなかった項目は空（金額は0）
+type ReceiptTextResult struct {
+	Date          string // YYYY-MM-DD
+	Shop          string
+	Item          string
+	Amount        int
+	Payer         string // 支払ったメンバーのニックネーム（書いた本人の場合は空）
+	PaymentMethod string // half / self / other
+	Usage         AITokenUsage
+}
+
+// 質問に答えるための集計方法
+const (
+	SpendingAggregateSum     = "sum"     // 金額の合計（既定）
+	SpendingAggregateCount   = "count"   // 件数
+	SpendingAggregateAverage = "average" // 金額の平均
+	SpendingAggregateMax     = "max"     // 最も高い金額
+	SpendingAggregateMin     = "min"     // 最も安い金額
+)
+
+// SpendingQuestionInput 支出に関する質問を検索条件に変換するための入力
+type SpendingQuestionInput struct {
+	Question   string
+	Today      time.Time // 「先月」「夏」などの期間の基準日
+	Speaker    string    // 質問したメンバーのニックネーム
+	Members    []string  // 質問した本人以外のメンバーのニックネーム
+	Categories []string  // グループのレシートで使われている分類
+	Shops      []string  // グループの店舗マスタの店舗名
+}
+
+// SpendingQuery 質問から変換した検索条件と集計方法。SQLではなく決まった項目だけで表し、空の条件では絞り込まない
+type SpendingQuery struct {
+	From       string // YYYY-MM-DD（この日を含む）
+	To         string // YYYY-MM-DD（この日を含む）
+	Shops      []string
+	Categories []string
+	Keyword    string // 品名・店舗名に含まれる語
+	Payer      string // 支払ったメンバーのニックネーム
+	Aggregate  string // sum / count / average / max / min
+	Usage      AITokenUsage
+}
+
+// AnalyzeReceiptResult 解析結果
+type AnalyzeReceiptResult struct {
+	Date   string `json:"date"`
+	Shop   string `json:"shop"`
+	Item   string `json:"item"`
+	Amount int    `json:"amount"`
+
+	Confidence  FieldConfidence `json:"confidence"`
+	NeedsReview []string        `json:"needs_review"` // 確からしさが低く、利用者の確認が必要な項目（date, shop, item, amount）
+
+	// 店舗マスタと照合できた場合の店舗と既定値（ShopService.SuggestShop で設定）
+	ShopID        *uuid.UUID `json:"shop_id,omitempty"`
+	Category      string     `json:"category,omitempty"`
+	PaymentMethod string     `json:"payment_method,omitempty"`
+
+	// 利用者の過去の修正から補正した項目と、補正前の店舗名（AnalysisFeedbackService.RefineAnalysis で設定）
+	Corrected    []string `json:"corrected,omitempty"`
+	AnalyzedShop string   `json:"analyzed_shop,omitempty"`
+
+	// 解析結果を保存した下書き（ReceiptDraftService.CreateDraft で設定）
+	DraftID *uuid.UUID `json:"draft_id,omitempty"`
+
+	// モデルが報告したトークン数（AIUsageService が記録する。応答には含めない）
+	Usage AITokenUsage `json:"-"`
+}
+
+// AITokenUsage モデルへの問い合わせで使われたトークン数
+type AITokenUsage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+func (u *AITokenUsage) add(other AITokenUsage) {
+	u.PromptTokens += other.PromptTokens
+	u.OutputTokens += other.OutputTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+// AIAnalyzerConfig AI解析の設定
+type AIAnalyzerConfig struct {
+	Backend string        // gemini / openai / local / fake（空の場合は gemini）
+	APIKey  string        // gemini では必須、openai では設定されている場合のみ送信
+	BaseURL string        // openai: エンドポイントのベースURL（例: http://localhost:11434/v1）
+	Model   string        // 空の場合はバックエンドごとの既定値
+	Timeout time.Duration // 0以下の場合は既定値
+	Prompt  string        // 空の場合は既定のプロンプト
+
+	OCRCommand   string // local: OCRコマンド（空の場合は tesseract）
+	OCRLanguages string // local: OCRの言語（空の場合は jpn+eng）
+}
+
+// NewAIAnalyzer 設定されたバックエンドのAIAnalyzerを作成
+func NewAIAnalyzer(cfg AIAnalyzerConfig) (AIAnalyzer, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultAITimeout
+	}
+	if cfg.Prompt == "" {
+		cfg.Prompt = defaultAnalyzePrompt
+	}
+
+	switch cfg.Backend {
+	case "", AIBackendGemini:
+		return newGeminiAIAnalyzer(cfg), nil
+	case AIBackendOpenAI:
+		return newOpenAIAnalyzer(cfg)
+	case AIBackendLocal:
+		return newLocalAIAnalyzer(cfg), nil
+	case AIBackendFake:
+		return NewFakeAIAnalyzer(nil), nil
+	}
+	return nil, fmt.Errorf("unknown AI backend: %s", cfg.Backend)
+}
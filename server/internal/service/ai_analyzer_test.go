@@ -0,0 +1,313 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"receipt/server/internal/service"
+)
+
+// stubOCR 固定の文字列を返すOCREngine
+type stubOCR struct {
+	text string
+	err  error
+}
+
+func (s *stubOCR) Recognize(ctx context.Context, imgData []byte) (string, error) {
+	return s.text, s.err
+}
+
+// jpegImage テスト用の正規化済み画像（中身は解析に使われない）
+func jpegImage(data string) *service.ReceiptImage {
+	return &service.ReceiptImage{MIMEType: service.MIMETypeJPEG, Data: []byte(data), PageCount: 1}
+}
+
+const sampleReceiptText = `
+  スーパーABC 駅前店
+TEL 03-1234-5678
+２０２６年１月５日(月) 18:32
+牛乳          ¥198
+食パン        ¥158 軽
+たまご 10個   ¥248
+バナナ        ¥120
+小計          ¥724
+(8%対象  ¥724)
+合計          ¥782
+お預り        ¥1,000
+お釣り        ¥218
+`
+
+func TestParseReceiptText(t *testing.T) {
+	result := service.ParseReceiptText(sampleReceiptText)
+
+	if result.Date != "2026-01-05" {
+		t.Errorf("expected date 2026-01-05, got %q", result.Date)
+	}
+	if result.Shop != "スーパーABC 駅前店" {
+		t.Errorf("expected shop name, got %q", result.Shop)
+	}
+	if result.Amount != 782 {
+		t.Errorf("expected total 782, got %d", result.Amount)
+	}
+	if result.Item != "牛乳、食パン、たまご 10個 ほか" {
+		t.Errorf("unexpected item summary %q", result.Item)
+	}
+	// 先頭行を店舗名とみなす推定・品目の要約は確認が必要
+	if strings.Join(result.NeedsReview, ",") != "shop,item" {
+		t.Errorf("expected shop and item to need review, got %v", result.NeedsReview)
+	}
+
+	t.Run("Without Total Line", func(t *testing.T) {
+		result := service.ParseReceiptText("Bakery\n2026/02/30\nクロワッサン 280\nバゲット 350円")
+		if result.Date != "" {
+			t.Errorf("expected invalid date to be ignored, got %q", result.Date)
+		}
+		if result.Amount != 350 || result.Item != "クロワッサン、バゲット" {
+			t.Errorf("unexpected result %+v", result)
+		}
+	})
+}
+
+func TestNewAIAnalyzer(t *testing.T) {
+	if _, err := service.NewAIAnalyzer(service.AIAnalyzerConfig{Backend: "unknown"}); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+	if _, err := service.NewAIAnalyzer(service.AIAnalyzerConfig{Backend: service.AIBackendOpenAI}); err == nil {
+		t.Error("expected error for openai backend without base URL")
+	}
+
+	t.Run("Fake Is Deterministic", func(t *testing.T) {
+		analyzer, err := service.NewAIAnalyzer(service.AIAnalyzerConfig{Backend: service.AIBackendFake})
+		if err != nil {
+			t.Fatalf("NewAIAnalyzer failed: %v", err)
+		}
+		first, _ := analyzer.AnalyzeReceipt(context.Background(), jpegImage("a"))
+		first.Shop = "changed by caller"
+		second, _ := analyzer.AnalyzeReceipt(context.Background(), jpegImage("b"))
+		if second.Shop != "テストストア" || second.Amount != 1000 || len(second.NeedsReview) != 0 {
+			t.Errorf("expected the same sample result, got %+v", second)
+		}
+	})
+}
+
+func TestOpenAIAnalyzer(t *testing.T) {
+	var gotAuth, gotModel, gotPrompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		gotAuth = r.Header.Get("Authorization")
+
+		var req struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Content []struct {
+					Type string `json:"type"`
+					Text string `json:"text"`
+				} `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		gotModel = req.Model
+		gotPrompt = req.Messages[0].Content[0].Text
+
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"` + "```json\\n" + `{\"date\":\"2026-03-01\",\"shop\":\"Local Shop\",\"item\":\"Coffee\",\"amount\":450}` + "\\n```" + `"}}]}`))
+	}))
+	defer server.Close()
+
+	analyzer, err := service.NewAIAnalyzer(service.AIAnalyzerConfig{
+		Backend: service.AIBackendOpenAI,
+		BaseURL: server.URL + "/v1/",
+		APIKey:  "secret",
+		Model:   "local-vision",
+		Prompt:  "custom prompt",
+	})
+	if err != nil {
+		t.Fatalf("NewAIAnalyzer failed: %v", err)
+	}
+
+	result, err := analyzer.AnalyzeReceipt(context.Background(), jpegImage("image"))
+	if err != nil {
+		t.Fatalf("AnalyzeReceipt failed: %v", err)
+	}
+	if result.Shop != "Local Shop" || result.Amount != 450 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if gotAuth != "Bearer secret" || gotModel != "local-vision" || gotPrompt != "custom prompt" {
+		t.Errorf("unexpected request: auth=%q model=%q prompt=%q", gotAuth, gotModel, gotPrompt)
+	}
+
+	t.Run("PDF Without Preview", func(t *testing.T) {
+		pdf := &service.ReceiptImage{MIMEType: service.MIMETypePDF, Data: []byte("%PDF-1.4"), PageCount: 2}
+		if _, err := analyzer.AnalyzeReceipt(context.Background(), pdf); !errors.Is(err, service.ErrUnsupportedImageType) {
+			t.Errorf("expected ErrUnsupportedImageType, got %v", err)
+		}
+	})
+
+	t.Run("Error Status", func(t *testing.T) {
+		errServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error":{"message":"rate limited"}}`))
+		}))
+		defer errServer.Close()
+
+		analyzer, _ := service.NewAIAnalyzer(service.AIAnalyzerConfig{Backend: service.AIBackendOpenAI, BaseURL: errServer.URL})
+		if _, err := analyzer.AnalyzeReceipt(context.Background(), jpegImage("image")); err == nil || !strings.Contains(err.Error(), "rate limited") {
+			t.Errorf("expected endpoint error to be reported, got %v", err)
+		}
+	})
+}
+
+func TestLocalAIAnalyzer(t *testing.T) {
+	analyzer := service.NewLocalAIAnalyzer(&stubOCR{text: sampleReceiptText}, service.AIAnalyzerConfig{})
+	result, err := analyzer.AnalyzeReceipt(context.Background(), jpegImage("image"))
+	if err != nil {
+		t.Fatalf("AnalyzeReceipt failed: %v", err)
+	}
+	if result.Amount != 782 || result.Date != "2026-01-05" {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	t.Run("Nothing Recognized", func(t *testing.T) {
+		analyzer := service.NewLocalAIAnalyzer(&stubOCR{text: "\n  \n"}, service.AIAnalyzerConfig{})
+		if _, err := analyzer.AnalyzeReceipt(context.Background(), jpegImage("image")); err == nil {
+			t.Error("expected error when nothing is recognized")
+		}
+	})
+
+	t.Run("OCR Failure", func(t *testing.T) {
+		analyzer := service.NewLocalAIAnalyzer(&stubOCR{err: errors.New("tesseract not found")}, service.AIAnalyzerConfig{})
+		if _, err := analyzer.AnalyzeReceipt(context.Background(), jpegImage("image")); err == nil {
+			t.Error("expected OCR error to be returned")
+		}
+	})
+}
+
+// newSequencedOpenAIServer 呼び出しごとに contents を順に返すOpenAI互換サーバー。受け取ったプロンプトを prompts に記録する
+func newSequencedOpenAIServer(t *testing.T, contents []string, prompts *[][]string) *httptest.Server {
+	t.Helper()
+	calls := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content []struct {
+					Type string `json:"type"`
+					Text string `json:"text"`
+				} `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		var texts []string
+		for _, part := range req.Messages[0].Content {
+			if part.Type == "text" {
+				texts = append(texts, part.Text)
+			}
+		}
+		*prompts = append(*prompts, texts)
+
+		content := contents[min(calls, len(contents)-1)]
+		calls++
+		body, _ := json.Marshal(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": content}}},
+			"usage":   map[string]int{"prompt_tokens": 100, "completion_tokens": 20, "total_tokens": 120},
+		})
+		_, _ = w.Write(body)
+	}))
+}
+
+func TestOpenAIAnalyzer_Validation(t *testing.T) {
+	newAnalyzer := func(t *testing.T, url string) service.AIAnalyzer {
+		analyzer, err := service.NewAIAnalyzer(service.AIAnalyzerConfig{Backend: service.AIBackendOpenAI, BaseURL: url})
+		if err != nil {
+			t.Fatalf("NewAIAnalyzer failed: %v", err)
+		}
+		return analyzer
+	}
+
+	t.Run("Retries With Corrective Prompt", func(t *testing.T) {
+		var prompts [][]string
+		server := newSequencedOpenAIServer(t, []string{
+			`{"date":"2026-13-45","shop":"","item":"Coffee","amount":450,"confidence":{"date":0.9,"shop":0.9,"item":0.9,"amount":0.9}}`,
+			`{"date":"2026-03-01","shop":"Cafe","item":"Coffee","amount":450,"confidence":{"date":0.95,"shop":0.9,"item":0.8,"amount":0.99}}`,
+		}, &prompts)
+		defer server.Close()
+
+		result, err := newAnalyzer(t, server.URL).AnalyzeReceipt(context.Background(), jpegImage("image"))
+		if err != nil {
+			t.Fatalf("AnalyzeReceipt failed: %v", err)
+		}
+		if len(prompts) != 2 {
+			t.Fatalf("expected 2 requests, got %d", len(prompts))
+		}
+		correction := prompts[1][len(prompts[1])-1]
+		if !strings.Contains(correction, "2026-13-45") || !strings.Contains(correction, "shop must not be empty") {
+			t.Errorf("expected corrective prompt to describe the problems, got %q", correction)
+		}
+		if result.Date != "2026-03-01" || result.Shop != "Cafe" || len(result.NeedsReview) != 0 {
+			t.Errorf("unexpected result %+v", result)
+		}
+		if result.Usage != (service.AITokenUsage{PromptTokens: 200, OutputTokens: 40, TotalTokens: 240}) {
+			t.Errorf("expected token usage of both requests, got %+v", result.Usage)
+		}
+	})
+
+	t.Run("Normalized Amount Needs Review", func(t *testing.T) {
+		var prompts [][]string
+		server := newSequencedOpenAIServer(t, []string{
+			"Here is the result:\n```json\n" + `{"date":"2026/03/01","shop":"Cafe","item":"Coffee","amount":"¥1,234","confidence":{"date":0.9,"shop":0.9,"item":0.9,"amount":0.9}}` + "\n```",
+		}, &prompts)
+		defer server.Close()
+
+		result, err := newAnalyzer(t, server.URL).AnalyzeReceipt(context.Background(), jpegImage("image"))
+		if err != nil {
+			t.Fatalf("AnalyzeReceipt failed: %v", err)
+		}
+		if len(prompts) != 1 {
+			t.Errorf("expected no retry, got %d requests", len(prompts))
+		}
+		if result.Amount != 1234 || result.Date != "2026-03-01" {
+			t.Errorf("unexpected result %+v", result)
+		}
+		if result.Confidence.Amount >= service.ReviewConfidenceThreshold || len(result.NeedsReview) != 1 || result.NeedsReview[0] != "amount" {
+			t.Errorf("expected only amount to need review, got confidence=%v needs_review=%v", result.Confidence.Amount, result.NeedsReview)
+		}
+	})
+
+	t.Run("Still Invalid After Retry", func(t *testing.T) {
+		var prompts [][]string
+		server := newSequencedOpenAIServer(t, []string{
+			`{"date":"unknown","shop":"Cafe","item":"Coffee","amount":0,"confidence":{"date":0.9,"shop":0.9,"item":0.9,"amount":0.9}}`,
+		}, &prompts)
+		defer server.Close()
+
+		result, err := newAnalyzer(t, server.URL).AnalyzeReceipt(context.Background(), jpegImage("image"))
+		if err != nil {
+			t.Fatalf("AnalyzeReceipt failed: %v", err)
+		}
+		if len(prompts) != 2 {
+			t.Errorf("expected one retry, got %d requests", len(prompts))
+		}
+		if result.Date != "" || result.Amount != 0 || result.Shop != "Cafe" {
+			t.Errorf("expected invalid fields to be cleared, got %+v", result)
+		}
+		if strings.Join(result.NeedsReview, ",") != "date,amount" {
+			t.Errorf("expected date and amount to need review, got %v", result.NeedsReview)
+		}
+	})
+
+	t.Run("Not JSON", func(t *testing.T) {
+		var prompts [][]string
+		server := newSequencedOpenAIServer(t, []string{"I cannot read this receipt."}, &prompts)
+		defer server.Close()
+
+		if _, err := newAnalyzer(t, server.URL).AnalyzeReceipt(context.Background(), jpegImage("image")); err == nil {
+			t.Error("expected error when the response is never valid JSON")
+		}
+	})
+}
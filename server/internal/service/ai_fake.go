@@ -0,0 +1,52 @@
+package service
+
+import "context"
+
+// fakeAnalyzeResult 結果を指定しない場合にフェイクのAIAnalyzerが返す解析結果
+var fakeAnalyzeResult = AnalyzeReceiptResult{
+	Date:   "2026-01-01",
+	Shop:   "テストストア",
+	Item:   "テスト商品",
+	Amount: 1000,
+	Confidence: FieldConfidence{
+		Date: 1, Shop: 1, Item: 1, Amount: 1,
+	},
+}
+
+// fakeAIAnalyzer 画像の内容に関わらず常に同じ解析結果を返す
+type fakeAIAnalyzer struct {
+	result AnalyzeReceiptResult
+}
+
+// NewFakeAIAnalyzer 常に同じ解析結果を返すAIAnalyzerを作成（テスト・開発用。nilの場合は固定のサンプル）
+func NewFakeAIAnalyzer(result *AnalyzeReceiptResult) AIAnalyzer {
+	if result == nil {
+		result = &fakeAnalyzeResult
+	}
+	return &fakeAIAnalyzer{result: *result}
+}
+
+func (a *fakeAIAnalyzer) AnalyzeReceipt(ctx context.Context, img *ReceiptImage) (*AnalyzeReceiptResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	result := a.result
+	result.markFieldsForReview()
+	return &result, nil
+}
+
+// ParseText 文の内容をルールに従って読み取る（モデルは呼び出さない）
+func (a *fakeAIAnalyzer) ParseText(ctx context.Context, input *ReceiptTextInput) (*ReceiptTextResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ParseReceiptSentence(input), nil
+}
+
+// TranslateQuestion 質問をルールに従って検索条件に変換する（モデルは呼び出さない）
+func (a *fakeAIAnalyzer) TranslateQuestion(ctx context.Context, input *SpendingQuestionInput) (*SpendingQuery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ParseSpendingQuestion(input), nil
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+// defaultGeminiModel Geminiの既定のモデル
+const defaultGeminiModel = "gemini-flash-latest"
+
+// geminiResponseSchema Geminiに返させる解析結果のスキーマ
+var geminiResponseSchema = &genai.Schema{
+	Type: genai.TypeObject,
+	Properties: map[string]*genai.Schema{
+		"date":   {Type: genai.TypeString, Description: "Purchase date in YYYY-MM-DD format"},
+		"shop":   {Type: genai.TypeString, Description: "Store name"},
+		"item":   {Type: genai.TypeString, Description: "Short summary of purchased items"},
+		"amount": {Type: genai.TypeInteger, Description: "Total amount paid in yen"},
+		"confidence": {
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"date":   {Type: genai.TypeNumber},
+				"shop":   {Type: genai.TypeNumber},
+				"item":   {Type: genai.TypeNumber},
+				"amount": {Type: genai.TypeNumber},
+			},
+			Required: []string{"date", "shop", "item", "amount"},
+		},
+	},
+	Required: []string{"date", "shop", "item", "amount", "confidence"},
+}
+
+// geminiReceiptTextSchema 自然文の解析でGeminiに返させる項目のスキーマ
+var geminiReceiptTextSchema = &genai.Schema{
+	Type: genai.TypeObject,
+	Properties: map[string]*genai.Schema{
+		"date":           {Type: genai.TypeString, Description: "Purchase date in YYYY-MM-DD format, or an empty string"},
+		"shop":           {Type: genai.TypeString, Description: "Store name"},
+		"item":           {Type: genai.TypeString, Description: "Short summary of purchased items"},
+		"amount":         {Type: genai.TypeInteger, Description: "Total amount paid in yen"},
+		"payer":          {Type: genai.TypeString, Description: "Nickname of the member who paid, or an empty string if the writer paid"},
+		"payment_method": {Type: genai.TypeString, Description: "half, self, other, or an empty string"},
+	},
+	Required: []string{"date", "shop", "item", "amount", "payer", "payment_method"},
+}
+
+// geminiSpendingQuerySchema 質問の変換でGeminiに返させる検索条件のスキーマ
+var geminiSpendingQuerySchema = &genai.Schema{
+	Type: genai.TypeObject,
+	Properties: map[string]*genai.Schema{
+		"from":       {Type: genai.TypeString, Description: "First purchase date to include in YYYY-MM-DD format, or an empty string"},
+		"to":         {Type: genai.TypeString, Description: "Last purchase date to include in YYYY-MM-DD format, or an empty string"},
+		"shops":      {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}},
+		"categories": {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}},
+		"keyword":    {Type: genai.TypeString, Description: "Word contained in the item or store name, or an empty string"},
+		"payer":      {Type: genai.TypeString, Description: "Nickname of the member who paid, or an empty string"},
+		"aggregate":  {Type: genai.TypeString, Enum: spendingAggregates},
+	},
+	Required: []string{"from", "to", "shops", "categories", "keyword", "payer", "aggregate"},
+}
+
+// geminiAIAnalyzer Gemini でレシートを解析する。クライアントは初回の解析時に作成し、以降の解析で使い回す
+type geminiAIAnalyzer struct {
+	cfg AIAnalyzerConfig
+
+	mu     sync.Mutex
+	client *genai.Client
+}
+
+func newGeminiAIAnalyzer(cfg AIAnalyzerConfig) AIAnalyzer {
+	if cfg.Model == "" {
+		cfg.Model = defaultGeminiModel
+	}
+	return &geminiAIAnalyzer{cfg: cfg}
+}
+
+// generativeModel 共有のクライアントから、指定のスキーマでJSONを返させるモデルを作成する（クライアントの作成に失敗した場合は次回の解析で作成し直す）
+func (a *geminiAIAnalyzer) generativeModel(schema *genai.Schema) (*genai.GenerativeModel, error) {
+	if a.cfg.APIKey == "" {
+		return nil, errors.New("GOOGLE_API_KEY is not set")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.client == nil {
+		// クライアントは解析ごとのタイムアウトより長く使うため、リクエストの ctx では作成しない
+		client, err := genai.NewClient(context.Background(), option.WithAPIKey(a.cfg.APIKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+		}
+		a.client = client
+	}
+
+	model := a.client.GenerativeModel(a.cfg.Model)
+	model.ResponseMIMEType = "application/json"
+	model.ResponseSchema = schema
+	return model, nil
+}
+
+func (a *geminiAIAnalyzer) AnalyzeReceipt(ctx context.Context, img *ReceiptImage) (*AnalyzeReceiptResult, error) {
+	model, err := a.generativeModel(geminiResponseSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, a.cfg.Timeout)
+	defer cancel()
+
+	return analyzeWithValidation(ctx, func(ctx context.Context, correction string) (string, AITokenUsage, error) {
+		prompt := []genai.Part{
+			// Gemini は画像・PDF（複数ページを含む）をそのまま扱える
+			genai.Blob{MIMEType: img.MIMEType, Data: img.Data},
+			genai.Text(a.cfg.Prompt),
+		}
+		if correction != "" {
+			prompt = append(prompt, genai.Text(correction))
+		}
+
+		return generateGeminiText(ctx, model, prompt...)
+	})
+}
+
+func (a *geminiAIAnalyzer) ParseText(ctx context.Context, input *ReceiptTextInput) (*ReceiptTextResult, error) {
+	model, err := a.generativeModel(geminiReceiptTextSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, a.cfg.Timeout)
+	defer cancel()
+
+	text, usage, err := generateGeminiText(ctx, model, genai.Text(receiptTextPrompt(input)))
+	if err != nil {
+		return nil, err
+	}
+	result, err := decodeReceiptTextResponse(text)
+	if err != nil {
+		return nil, err
+	}
+	result.Usage = usage
+	return result, nil
+}
+
+func (a *geminiAIAnalyzer) TranslateQuestion(ctx context.Context, input *SpendingQuestionInput) (*SpendingQuery, error) {
+	model, err := a.generativeModel(geminiSpendingQuerySchema)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, a.cfg.Timeout)
+	defer cancel()
+
+	text, usage, err := generateGeminiText(ctx, model, genai.Text(spendingQuestionPrompt(input)))
+	if err != nil {
+		return nil, err
+	}
+	query, err := decodeSpendingQueryResponse(text)
+	if err != nil {
+		return nil, err
+	}
+	query.Usage = usage
+	return query, nil
+}
+
+// generateGeminiText Gemini に生成させた本文と使われたトークン数を返す
+func generateGeminiText(ctx context.Context, model *genai.GenerativeModel, prompt ...genai.Part) (string, AITokenUsage, error) {
+	resp, err := model.GenerateContent(ctx, prompt...)
+	if err != nil {
+		return "", AITokenUsage{}, fmt.Errorf("failed to generate content: %w", err)
+	}
+
+	var usage AITokenUsage
+	if resp.UsageMetadata != nil {
+		usage = AITokenUsage{
+			PromptTokens: int(resp.UsageMetadata.PromptTokenCount),
+			OutputTokens: int(resp.UsageMetadata.CandidatesTokenCount),
+			TotalTokens:  int(resp.UsageMetadata.TotalTokenCount),
+		}
+	}
+
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return "", usage, errors.New("no results from Gemini")
+	}
+
+	var resultText string
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if text, ok := part.(genai.Text); ok {
+			resultText += string(text)
+		}
+	}
+	return resultText, usage, nil
+}
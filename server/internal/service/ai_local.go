@@ -0,0 +1,109 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultOCRCommand ローカル解析の既定のOCRコマンド
+	defaultOCRCommand = "tesseract"
+	// defaultOCRLanguages ローカル解析の既定のOCR言語
+	defaultOCRLanguages = "jpn+eng"
+)
+
+// OCREngine 画像から文字列を読み取るインターフェース
+type OCREngine interface {
+	Recognize(ctx context.Context, imgData []byte) (string, error)
+}
+
+// localAIAnalyzer ローカルのOCRで読み取った文字列をルールベースで解析する（ネットワーク不要）
+type localAIAnalyzer struct {
+	ocr     OCREngine
+	timeout time.Duration
+}
+
+func newLocalAIAnalyzer(cfg AIAnalyzerConfig) AIAnalyzer {
+	command := cfg.OCRCommand
+	if command == "" {
+		command = defaultOCRCommand
+	}
+	languages := cfg.OCRLanguages
+	if languages == "" {
+		languages = defaultOCRLanguages
+	}
+	return NewLocalAIAnalyzer(&tesseractOCR{command: command, languages: languages}, cfg)
+}
+
+// NewLocalAIAnalyzer 指定のOCRエンジンを使うローカル解析のAIAnalyzerを作成
+func NewLocalAIAnalyzer(ocr OCREngine, cfg AIAnalyzerConfig) AIAnalyzer {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultAITimeout
+	}
+	return &localAIAnalyzer{ocr: ocr, timeout: cfg.Timeout}
+}
+
+func (a *localAIAnalyzer) AnalyzeReceipt(ctx context.Context, img *ReceiptImage) (*AnalyzeReceiptResult, error) {
+	imgData, err := img.JPEG()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	text, err := a.ocr.Recognize(ctx, imgData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recognize receipt text: %w", err)
+	}
+
+	result := ParseReceiptText(text)
+	if result.Shop == "" && result.Amount == 0 {
+		return nil, errors.New("no receipt information found in the image")
+	}
+	return result, nil
+}
+
+// ParseText 文の内容をルールに従って読み取る
+func (a *localAIAnalyzer) ParseText(ctx context.Context, input *ReceiptTextInput) (*ReceiptTextResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ParseReceiptSentence(input), nil
+}
+
+// TranslateQuestion 質問をルールに従って検索条件に変換する（モデルは呼び出さない）
+func (a *localAIAnalyzer) TranslateQuestion(ctx context.Context, input *SpendingQuestionInput) (*SpendingQuery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ParseSpendingQuestion(input), nil
+}
+
+// tesseractOCR tesseract コマンドで画像を読み取る（画像は標準入力から渡す）
+type tesseractOCR struct {
+	command   string
+	languages string
+}
+
+func (t *tesseractOCR) Recognize(ctx context.Context, imgData []byte) (string, error) {
+	cmd := exec.CommandContext(ctx, t.command, "stdin", "stdout", "-l", t.languages)
+	cmd.Stdin = bytes.NewReader(imgData)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// defaultOpenAIModel OpenAI互換エンドポイントの既定のモデル
+const defaultOpenAIModel = "gpt-4o-mini"
+
+// openAIAnalyzer OpenAI互換の Chat Completions API でレシート画像を解析する
+type openAIAnalyzer struct {
+	cfg    AIAnalyzerConfig
+	client *http.Client
+}
+
+func newOpenAIAnalyzer(cfg AIAnalyzerConfig) (AIAnalyzer, error) {
+	if cfg.BaseURL == "" {
+		return nil, errors.New("base URL is required for the openai backend")
+	}
+	if cfg.Model == "" {
+		cfg.Model = defaultOpenAIModel
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	return &openAIAnalyzer{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}, nil
+}
+
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+type openAIMessage struct {
+	Role    string              `json:"role"`
+	Content []openAIContentPart `json:"content"`
+}
+
+type openAIResponseFormat struct {
+	Type       string `json:"type"`
+	JSONSchema struct {
+		Name   string                 `json:"name"`
+		Strict bool                   `json:"strict"`
+		Schema map[string]interface{} `json:"schema"`
+	} `json:"json_schema"`
+}
+
+type openAIChatRequest struct {
+	Model          string                `json:"model"`
+	Messages       []openAIMessage       `json:"messages"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (a *openAIAnalyzer) AnalyzeReceipt(ctx context.Context, img *ReceiptImage) (*AnalyzeReceiptResult, error) {
+	// OpenAI互換のエンドポイントは画像のみ受け付けるため、PDFは画像に変換したものを送る
+	imgData, err := img.JPEG()
+	if err != nil {
+		return nil, err
+	}
+
+	return analyzeWithValidation(ctx, func(ctx context.Context, correction string) (string, AITokenUsage, error) {
+		content := []openAIContentPart{
+			{Type: "text", Text: a.cfg.Prompt},
+			{Type: "image_url", ImageURL: &openAIImageURL{URL: "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(imgData)}},
+		}
+		if correction != "" {
+			content = append(content, openAIContentPart{Type: "text", Text: correction})
+		}
+		return a.complete(ctx, content, "receipt", analyzeResponseJSONSchema)
+	})
+}
+
+func (a *openAIAnalyzer) ParseText(ctx context.Context, input *ReceiptTextInput) (*ReceiptTextResult, error) {
+	content := []openAIContentPart{{Type: "text", Text: receiptTextPrompt(input)}}
+	text, usage, err := a.complete(ctx, content, "receipt_text", receiptTextResponseJSONSchema)
+	if err != nil {
+		return nil, err
+	}
+	result, err := decodeReceiptTextResponse(text)
+	if err != nil {
+		return nil, err
+	}
+	result.Usage = usage
+	return result, nil
+}
+
+func (a *openAIAnalyzer) TranslateQuestion(ctx context.Context, input *SpendingQuestionInput) (*SpendingQuery, error) {
+	content := []openAIContentPart{{Type: "text", Text: spendingQuestionPrompt(input)}}
+	text, usage, err := a.complete(ctx, content, "spending_query", spendingQueryJSONSchema)
+	if err != nil {
+		return nil, err
+	}
+	query, err := decodeSpendingQueryResponse(text)
+	if err != nil {
+		return nil, err
+	}
+	query.Usage = usage
+	return query, nil
+}
+
+// complete Chat Completions API を呼び出し、schema に沿った応答の本文と使われたトークン数を返す
+func (a *openAIAnalyzer) complete(ctx context.Context, content []openAIContentPart, schemaName string, schema map[string]interface{}) (string, AITokenUsage, error) {
+	reqBody := openAIChatRequest{
+		Model:          a.cfg.Model,
+		Messages:       []openAIMessage{{Role: "user", Content: content}},
+		ResponseFormat: &openAIResponseFormat{Type: "json_schema"},
+	}
+	reqBody.ResponseFormat.JSONSchema.Name = schemaName
+	reqBody.ResponseFormat.JSONSchema.Strict = true
+	reqBody.ResponseFormat.JSONSchema.Schema = schema
+
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", AITokenUsage{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.cfg.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", AITokenUsage{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+a.cfg.APIKey)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", AITokenUsage{}, fmt.Errorf("failed to call AI endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", AITokenUsage{}, fmt.Errorf("failed to read AI endpoint response: %w", err)
+	}
+
+	var chatResp openAIChatResponse
+	if err := json.Unmarshal(respBody, &chatResp); err != nil {
+		return "", AITokenUsage{}, fmt.Errorf("failed to decode AI endpoint response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if chatResp.Error != nil {
+			return "", AITokenUsage{}, fmt.Errorf("AI endpoint returned status %d: %s", resp.StatusCode, chatResp.Error.Message)
+		}
+		return "", AITokenUsage{}, fmt.Errorf("AI endpoint returned status %d", resp.StatusCode)
+	}
+	if len(chatResp.Choices) == 0 {
+		return "", AITokenUsage{}, errors.New("no results from AI endpoint")
+	}
+	var usage AITokenUsage
+	if chatResp.Usage != nil {
+		usage = AITokenUsage{
+			PromptTokens: chatResp.Usage.PromptTokens,
+			OutputTokens: chatResp.Usage.CompletionTokens,
+			TotalTokens:  chatResp.Usage.TotalTokens,
+		}
+	}
+	return chatResp.Choices[0].Message.Content, usage, nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// maxSpendingQueryValues 検索条件の店舗名・分類として受け付ける最大の数
+const maxSpendingQueryValues = 10
+
+var spendingAggregates = []string{SpendingAggregateSum, SpendingAggregateCount, SpendingAggregateAverage, SpendingAggregateMax, SpendingAggregateMin}
+
+// spendingQueryJSONSchema 質問から変換させる検索条件のJSON Schema（OpenAI互換の response_format 用）
+var spendingQueryJSONSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"from":       map[string]interface{}{"type": "string", "description": "First purchase date to include in YYYY-MM-DD format, or an empty string"},
+		"to":         map[string]interface{}{"type": "string", "description": "Last purchase date to include in YYYY-MM-DD format, or an empty string"},
+		"shops":      map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		"categories": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		"keyword":    map[string]interface{}{"type": "string", "description": "Word contained in the item or store name, or an empty string"},
+		"payer":      map[string]interface{}{"type": "string", "description": "Nickname of the member who paid, or an empty string"},
+		"aggregate":  map[string]interface{}{"type": "string", "enum": spendingAggregates},
+	},
+	"required":             []string{"from", "to", "shops", "categories", "keyword", "payer", "aggregate"},
+	"additionalProperties": false,
+}
+
+// rawSpendingQueryResponse 質問の変換でのモデルの応答
+type rawSpendingQueryResponse struct {
+	From       string   `json:"from"`
+	To         string   `json:"to"`
+	Shops      []string `json:"shops"`
+	Categories []string `json:"categories"`
+	Keyword    string   `json:"keyword"`
+	Payer      string   `json:"payer"`
+	Aggregate  string   `json:"aggregate"`
+}
+
+// spendingQuestionPrompt 支出に関する質問を検索条件に変換させるプロンプト
+func spendingQuestionPrompt(input *SpendingQuestionInput) string {
+	return "Translate the question below about a group's shared receipts into search conditions and return JSON only. Do not write SQL.\n" +
+		fmt.Sprintf("Today is %s (%s). Resolve periods such as \"last month\" or \"summer\" to from and to (both inclusive, YYYY-MM-DD); use the most recent such period that has started. Use empty strings if no period is mentioned.\n", input.Today.Format("2006-01-02"), input.Today.Weekday()) +
+		fmt.Sprintf("Categories used by the group: %s. Set categories only to values from this list that match the question.\n", quotedList(input.Categories)) +
+		fmt.Sprintf("Registered stores: %s. Set shops to the store names mentioned in the question.\n", quotedList(input.Shops)) +
+		"Set keyword to a word that should appear in the purchased items when the question is about specific goods not covered by categories or shops, otherwise an empty string.\n" +
+		fmt.Sprintf("The question was asked by %q. Other group members: %s. Set payer to the nickname of the member who paid if the question restricts it (use %q for \"I\"), otherwise an empty string.\n", input.Speaker, quotedList(input.Members), input.Speaker) +
+		"Set aggregate to \"sum\" for how much was spent, \"count\" for how many times, \"average\" for the average amount, \"max\" for the most expensive and \"min\" for the cheapest.\n" +
+		"JSON:\n{\"from\": \"YYYY-MM-DD\", \"to\": \"YYYY-MM-DD\", \"shops\": [], \"categories\": [], \"keyword\": \"\", \"payer\": \"\", \"aggregate\": \"sum\"}\n\n" +
+		"Question:\n" + input.Question
+}
+
+// decodeSpendingQueryResponse 質問の変換でのモデルの応答を検索条件に変換する。形式が正しくない日付・集計方法は空とする
+func decodeSpendingQueryResponse(text string) (*SpendingQuery, error) {
+	var raw rawSpendingQueryResponse
+	if err := json.Unmarshal([]byte(extractJSONObject(text)), &raw); err != nil {
+		return nil, fmt.Errorf("invalid question translation response: %w", err)
+	}
+
+	query := &SpendingQuery{
+		Shops:      trimmedValues(raw.Shops),
+		Categories: trimmedValues(raw.Categories),
+		Keyword:    truncateRunes(strings.TrimSpace(raw.Keyword), maxAnalyzedTextLength),
+		Payer:      strings.TrimSpace(raw.Payer),
+	}
+	if date, ok := parseReceiptDate(strings.TrimSpace(raw.From)); ok {
+		query.From = date
+	}
+	if date, ok := parseReceiptDate(strings.TrimSpace(raw.To)); ok {
+		query.To = date
+	}
+	if isValidSpendingAggregate(raw.Aggregate) {
+		query.Aggregate = raw.Aggregate
+	}
+	return query, nil
+}
+
+// isValidSpendingAggregate 集計方法の定数かどうか
+func isValidSpendingAggregate(aggregate string) bool {
+	for _, a := range spendingAggregates {
+		if aggregate == a {
+			return true
+		}
+	}
+	return false
+}
+
+// trimmedValues 前後の空白を取り除き、空の値を除いた最大 maxSpendingQueryValues 件の値
+func trimmedValues(values []string) []string {
+	var trimmed []string
+	for _, v := range values {
+		if v = truncateRunes(strings.TrimSpace(v), maxAnalyzedTextLength); v != "" && len(trimmed) < maxSpendingQueryValues {
+			trimmed = append(trimmed, v)
+		}
+	}
+	return trimmed
+}
+
+// quotedList プロンプトに埋め込む値の一覧（空の場合は none）
+func quotedList(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, fmt.Sprintf("%q", v))
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"receipt/server/internal/models"
+)
+
+// receiptTextResponseJSONSchema 自然文から読み取らせる項目のJSON Schema（OpenAI互換の response_format 用）
+var receiptTextResponseJSONSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"date":           map[string]interface{}{"type": "string", "description": "Purchase date in YYYY-MM-DD format, or an empty string"},
+		"shop":           map[string]interface{}{"type": "string", "description": "Store name"},
+		"item":           map[string]interface{}{"type": "string", "description": "Short summary of purchased items"},
+		"amount":         map[string]interface{}{"type": "integer", "description": "Total amount paid in yen"},
+		"payer":          map[string]interface{}{"type": "string", "description": "Nickname of the member who paid, or an empty string if the writer paid"},
+		"payment_method": map[string]interface{}{"type": "string", "enum": []string{"", models.PaymentMethodHalf, models.PaymentMethodSelf, models.PaymentMethodOther}},
+	},
+	"required":             []string{"date", "shop", "item", "amount", "payer", "payment_method"},
+	"additionalProperties": false,
+}
+
+// rawReceiptTextResponse 自然文の解析でのモデルの応答
+type rawReceiptTextResponse struct {
+	Date          string          `json:"date"`
+	Shop          string          `json:"shop"`
+	Item          string          `json:"item"`
+	Amount        json.RawMessage `json:"amount"`
+	Payer         string          `json:"payer"`
+	PaymentMethod string          `json:"payment_method"`
+}
+
+// receiptTextPrompt 自然文からレシートの項目を読み取らせるプロンプト
+func receiptTextPrompt(input *ReceiptTextInput) string {
+	return "Extract a shared expense from the message below and return JSON only.\n" +
+		fmt.Sprintf("Today is %s (%s). Resolve relative dates such as \"yesterday\" or \"last Friday\" to YYYY-MM-DD. Use an empty string if no date is mentioned.\n", input.Today.Format("2006-01-02"), input.Today.Weekday()) +
+		fmt.Sprintf("The message was written by %q. Other group members: %s. ", input.Speaker, quotedList(input.Members)) +
+		"Set payer to the nickname of the other member who paid, exactly as listed, or an empty string if the writer paid.\n" +
+		"Set payment_method to \"half\" if the amount is split equally, \"self\" if the payer bears the whole amount, \"other\" if the members other than the payer bear the whole amount, or an empty string if it is not mentioned.\n" +
+		"Set shop to the store name, item to a short summary of what was bought, and amount to the total in yen as an integer.\n" +
+		"JSON:\n{\"date\": \"YYYY-MM-DD\", \"shop\": \"name\", \"item\": \"summary\", \"amount\": 1234, \"payer\": \"\", \"payment_method\": \"half\"}\n\n" +
+		"Message:\n" + input.Text
+}
+
+// decodeReceiptTextResponse 自然文の解析でのモデルの応答を変換する。形式が正しくない項目は空として返す
+func decodeReceiptTextResponse(text string) (*ReceiptTextResult, error) {
+	var raw rawReceiptTextResponse
+	if err := json.Unmarshal([]byte(extractJSONObject(text)), &raw); err != nil {
+		return nil, fmt.Errorf("invalid text analysis response: %w", err)
+	}
+
+	result := &ReceiptTextResult{
+		Shop:  truncateRunes(strings.TrimSpace(raw.Shop), maxAnalyzedTextLength),
+		Item:  truncateRunes(strings.TrimSpace(raw.Item), maxAnalyzedTextLength),
+		Payer: strings.TrimSpace(raw.Payer),
+	}
+	if date, ok := parseReceiptDate(strings.TrimSpace(raw.Date)); ok {
+		result.Date = date
+	}
+	if amount, _, err := parseAnalyzedAmount(raw.Amount); err == nil && amount > 0 && amount <= maxAnalyzedAmount {
+		result.Amount = amount
+	}
+	if isValidPaymentMethod(raw.PaymentMethod) {
+		result.PaymentMethod = raw.PaymentMethod
+	}
+	return result, nil
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultAnalysisCacheTTL 画像ごとの解析結果を保存しておく既定の期間
+	DefaultAnalysisCacheTTL = 30 * 24 * time.Hour
+	// defaultAIUsageDays 利用状況の既定の集計日数
+	defaultAIUsageDays = 30
+	// maxAIUsageDays 利用状況の最大の集計日数
+	maxAIUsageDays = 365
+)
+
+// ErrAIQuotaExceeded 1日あたりのAIの利用上限に達した場合のエラー
+var ErrAIQuotaExceeded = errors.New("daily AI usage limit exceeded")
+
+// AIQuota 1日あたりにモデルを呼び出せる回数の上限（0以下の場合は無制限）。
+// 保存済みの解析結果を返した分は数えない
+type AIQuota struct {
+	UserDailyLimit  int
+	GroupDailyLimit int
+}
+
+// AIQuotaStatus 今日の利用回数と上限
+type AIQuotaStatus struct {
+	Used      int  `json:"used"`
+	Limit     *int `json:"limit"`     // 無制限の場合はnull
+	Remaining *int `json:"remaining"` // 無制限の場合はnull
+}
+
+// AIUsageTotals AIの呼び出し回数と、モデルが報告したトークン数の合計
+type AIUsageTotals struct {
+	Kind         string `json:"kind,omitempty"` // analyze_receipt など（全体の合計では省略）
+	Calls        int    `json:"calls"`          // 保存済みの結果を返した分を含む
+	CachedCalls  int    `json:"cached_calls"`   // 保存済みの結果を返した（モデルを呼び出していない）回数
+	PromptTokens int    `json:"prompt_tokens"`
+	OutputTokens int    `json:"output_tokens"`
+	TotalTokens  int    `json:"total_tokens"`
+}
+
+// AIUsageReport ユーザーまたはグループのAIの利用状況
+type AIUsageReport struct {
+	From  time.Time       `json:"from"`
+	Today AIQuotaStatus   `json:"today"`
+	Total AIUsageTotals   `json:"total"`
+	Kinds []AIUsageTotals `json:"kinds"`
+}
+
+// AIUsageService AIの呼び出しの利用上限・解析結果の再利用・利用状況の記録に関するビジネスロジックインターフェース
+type AIUsageService interface {
+	// AnalyzeReceipt 同じ画像の解析結果が保存されていればそれを返し、なければ利用上限を確認してから解析する
+	// （グループを指定した場合は、グループにレシートを登録できるユーザーのみ）
+	AnalyzeReceipt(ctx context.Context, userID uuid.UUID, groupID *uuid.UUID, img *ReceiptImage) (*AnalyzeReceiptResult, error)
+	// ReserveUsage ユーザーと（指定された場合は）グループが今日の利用上限に達していなければ、モデルの呼び出し1回分を記録して
+	// 記録のIDを返す（グループのメンバーのみ）。同時に呼び出されても上限を超えないよう、モデルを呼び出す前に確保する
+	ReserveUsage(userID uuid.UUID, groupID *uuid.UUID, kind string) (uuid.UUID, error)
+	// FinishUsage 確保した呼び出しに、モデルが報告したトークン数を記録する。
+	// 停止による中断（ctx のキャンセル）の場合は、呼び出しとして数えないよう確保を取り消す
+	FinishUsage(ctx context.Context, usageID uuid.UUID, usage AITokenUsage)
+	GetUserUsage(userID uuid.UUID, days int) (*AIUsageReport, error)
+	GetGroupUsage(groupID uuid.UUID, userID uuid.UUID, days int) (*AIUsageReport, error)
+}
+
+type aiUsageServiceImpl struct {
+	usageRepo repository.AIUsageRepository
+	cacheRepo repository.AnalysisCacheRepository
+	groupRepo repository.GroupRepository
+	analyzer  AIAnalyzer
+	quota     AIQuota
+	cacheTTL  time.Duration
+	now       func() time.Time
+}
+
+// NewAIUsageService AIUsageServiceの実装を作成（cacheTTL が0以下の場合は既定値）
+func NewAIUsageService(usageRepo repository.AIUsageRepository, cacheRepo repository.AnalysisCacheRepository, groupRepo repository.GroupRepository, analyzer AIAnalyzer, quota AIQuota, cacheTTL time.Duration) AIUsageService {
+	if cacheTTL <= 0 {
+		cacheTTL = DefaultAnalysisCacheTTL
+	}
+	return &aiUsageServiceImpl{
+		usageRepo: usageRepo,
+		cacheRepo: cacheRepo,
+		groupRepo: groupRepo,
+		analyzer:  analyzer,
+		quota:     quota,
+		cacheTTL:  cacheTTL,
+		now:       time.Now,
+	}
+}
+
+func (s *aiUsageServiceImpl) AnalyzeReceipt(ctx context.Context, userID uuid.UUID, groupID *uuid.UUID, img *ReceiptImage) (*AnalyzeReceiptResult, error) {
+	if err := s.authorizeUsage(userID, groupID, PermissionCreateReceipt); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(img.Data)
+	contentHash := hex.EncodeToString(sum[:])
+
+	// 同じ画像の解析結果があれば、モデルを呼び出さず利用上限にも数えない
+	if entry, err := s.cacheRepo.Get(contentHash, s.now()); err == nil {
+		var result AnalyzeReceiptResult
+		if err := json.Unmarshal([]byte(entry.Result), &result); err == nil {
+			if err := s.recordUsage(userID, groupID, models.AIUsageAnalyzeReceipt, AITokenUsage{}, true); err != nil {
+				log.Printf("failed to record AI usage: %v", err)
+			}
+			return &result, nil
+		}
+	}
+
+	usageID, err := s.reserveUsage(userID, groupID, models.AIUsageAnalyzeReceipt)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.analyzer.AnalyzeReceipt(ctx, img)
+	// 解析に失敗してもモデルは呼び出しているため記録を残す
+	var usage AITokenUsage
+	if result != nil {
+		usage = result.Usage
+	}
+	s.FinishUsage(ctx, usageID, usage)
+	if err != nil {
+		return nil, err
+	}
+
+	if encoded, err := json.Marshal(result); err == nil {
+		entry := &models.AnalysisCache{ContentHash: contentHash, Result: string(encoded), ExpiresAt: s.now().Add(s.cacheTTL)}
+		if err := s.cacheRepo.Save(entry); err != nil {
+			log.Printf("failed to cache analysis result: %v", err)
+		}
+	}
+	return result, nil
+}
+
+func (s *aiUsageServiceImpl) ReserveUsage(userID uuid.UUID, groupID *uuid.UUID, kind string) (uuid.UUID, error) {
+	if err := s.authorizeUsage(userID, groupID, PermissionViewGroup); err != nil {
+		return uuid.Nil, err
+	}
+	return s.reserveUsage(userID, groupID, kind)
+}
+
+func (s *aiUsageServiceImpl) FinishUsage(ctx context.Context, usageID uuid.UUID, usage AITokenUsage) {
+	if ctx.Err() != nil {
+		if err := s.usageRepo.Delete(usageID); err != nil {
+			log.Printf("failed to release AI usage: %v", err)
+		}
+		return
+	}
+	if usage == (AITokenUsage{}) {
+		return
+	}
+	err := s.usageRepo.UpdateTokens(&models.AIUsage{
+		ID:           usageID,
+		PromptTokens: usage.PromptTokens,
+		OutputTokens: usage.OutputTokens,
+		TotalTokens:  usage.TotalTokens,
+	})
+	if err != nil {
+		log.Printf("failed to record AI usage: %v", err)
+	}
+}
+
+// authorizeUsage グループを指定した場合は、ユーザーがグループで操作を行えるか確認する。
+// 他のグループの利用上限を使い切ったり、他のグループの利用として記録したりできないようにする
+func (s *aiUsageServiceImpl) authorizeUsage(userID uuid.UUID, groupID *uuid.UUID, permission GroupPermission) error {
+	if groupID == nil {
+		return nil
+	}
+	_, err := authorizeGroup(s.groupRepo, *groupID, userID, permission)
+	return err
+}
+
+// reserveUsage 利用上限に達していなければ、モデルの呼び出し1回分（トークン数は呼び出し後に記録する）を記録する
+func (s *aiUsageServiceImpl) reserveUsage(userID uuid.UUID, groupID *uuid.UUID, kind string) (uuid.UUID, error) {
+	usage := &models.AIUsage{UserID: userID, GroupID: groupID, Kind: kind, CreatedAt: s.now()}
+	reserved, err := s.usageRepo.Reserve(usage, s.startOfToday(), s.quota.UserDailyLimit, s.quota.GroupDailyLimit)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !reserved {
+		return uuid.Nil, ErrAIQuotaExceeded
+	}
+	return usage.ID, nil
+}
+
+func (s *aiUsageServiceImpl) recordUsage(userID uuid.UUID, groupID *uuid.UUID, kind string, usage AITokenUsage, cached bool) error {
+	return s.usageRepo.Create(&models.AIUsage{
+		UserID:       userID,
+		GroupID:      groupID,
+		Kind:         kind,
+		Cached:       cached,
+		PromptTokens: usage.PromptTokens,
+		OutputTokens: usage.OutputTokens,
+		TotalTokens:  usage.TotalTokens,
+		CreatedAt:    s.now(),
+	})
+}
+
+func (s *aiUsageServiceImpl) GetUserUsage(userID uuid.UUID, days int) (*AIUsageReport, error) {
+	from := s.usageFrom(days)
+	kinds, err := s.usageRepo.GetTotalsByUser(userID, from)
+	if err != nil {
+		return nil, err
+	}
+	used, err := s.usageRepo.CountByUserSince(userID, s.startOfToday())
+	if err != nil {
+		return nil, err
+	}
+	return newAIUsageReport(from, kinds, used, s.quota.UserDailyLimit), nil
+}
+
+func (s *aiUsageServiceImpl) GetGroupUsage(groupID uuid.UUID, userID uuid.UUID, days int) (*AIUsageReport, error) {
+	if _, err := authorizeGroup(s.groupRepo, groupID, userID, PermissionViewGroup); err != nil {
+		return nil, err
+	}
+
+	from := s.usageFrom(days)
+	kinds, err := s.usageRepo.GetTotalsByGroup(groupID, from)
+	if err != nil {
+		return nil, err
+	}
+	used, err := s.usageRepo.CountByGroupSince(groupID, s.startOfToday())
+	if err != nil {
+		return nil, err
+	}
+	return newAIUsageReport(from, kinds, used, s.quota.GroupDailyLimit), nil
+}
+
+// startOfToday 利用上限を数え始める今日の0時（サーバーのタイムゾーン）
+func (s *aiUsageServiceImpl) startOfToday() time.Time {
+	now := s.now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
+
+// usageFrom 直近 days 日分を集計する場合の集計開始日時（days が範囲外の場合は既定値・上限に丸める）
+func (s *aiUsageServiceImpl) usageFrom(days int) time.Time {
+	if days <= 0 {
+		days = defaultAIUsageDays
+	}
+	if days > maxAIUsageDays {
+		days = maxAIUsageDays
+	}
+	return s.startOfToday().AddDate(0, 0, -(days - 1))
+}
+
+func newAIUsageReport(from time.Time, kinds []repository.AIUsageTotals, used int, limit int) *AIUsageReport {
+	report := &AIUsageReport{
+		From:  from,
+		Today: AIQuotaStatus{Used: used},
+		Kinds: make([]AIUsageTotals, 0, len(kinds)),
+	}
+	for _, k := range kinds {
+		report.Kinds = append(report.Kinds, AIUsageTotals{
+			Kind:         k.Kind,
+			Calls:        k.Calls,
+			CachedCalls:  k.CachedCalls,
+			PromptTokens: k.PromptTokens,
+			OutputTokens: k.OutputTokens,
+			TotalTokens:  k.TotalTokens,
+		})
+		report.Total.Calls += k.Calls
+		report.Total.CachedCalls += k.CachedCalls
+		report.Total.PromptTokens += k.PromptTokens
+		report.Total.OutputTokens += k.OutputTokens
+		report.Total.TotalTokens += k.TotalTokens
+	}
+	if limit > 0 {
+		remaining := max(limit-used, 0)
+		report.Today.Limit = &limit
+		report.Today.Remaining = &remaining
+	}
+	return report
+}
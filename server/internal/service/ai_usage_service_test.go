@@ -0,0 +1,363 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+	"receipt/server/internal/service"
+
+	"github.com/google/uuid"
+)
+
+// mockAIUsageRepository AIUsageRepositoryのモック（解析ワーカーから並行して呼ばれるためロックする）
+type mockAIUsageRepository struct {
+	mu     sync.Mutex
+	usages []models.AIUsage
+}
+
+func newMockAIUsageRepository() *mockAIUsageRepository {
+	return &mockAIUsageRepository{}
+}
+
+func (m *mockAIUsageRepository) Create(usage *models.AIUsage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	usage.ID = uuid.New()
+	m.usages = append(m.usages, *usage)
+	return nil
+}
+
+func (m *mockAIUsageRepository) Reserve(usage *models.AIUsage, since time.Time, userLimit int, groupLimit int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if userLimit > 0 && m.countLocked(func(u models.AIUsage) bool { return u.UserID == usage.UserID }, since) >= userLimit {
+		return false, nil
+	}
+	if usage.GroupID != nil && groupLimit > 0 && m.countLocked(func(u models.AIUsage) bool { return u.GroupID != nil && *u.GroupID == *usage.GroupID }, since) >= groupLimit {
+		return false, nil
+	}
+	usage.ID = uuid.New()
+	m.usages = append(m.usages, *usage)
+	return true, nil
+}
+
+func (m *mockAIUsageRepository) UpdateTokens(usage *models.AIUsage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.usages {
+		if m.usages[i].ID == usage.ID {
+			m.usages[i].PromptTokens = usage.PromptTokens
+			m.usages[i].OutputTokens = usage.OutputTokens
+			m.usages[i].TotalTokens = usage.TotalTokens
+		}
+	}
+	return nil
+}
+
+func (m *mockAIUsageRepository) Delete(id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.usages {
+		if m.usages[i].ID == id {
+			m.usages = append(m.usages[:i], m.usages[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *mockAIUsageRepository) CountByUserSince(userID uuid.UUID, since time.Time) (int, error) {
+	return m.count(func(u models.AIUsage) bool { return u.UserID == userID }, since), nil
+}
+
+func (m *mockAIUsageRepository) CountByGroupSince(groupID uuid.UUID, since time.Time) (int, error) {
+	return m.count(func(u models.AIUsage) bool { return u.GroupID != nil && *u.GroupID == groupID }, since), nil
+}
+
+func (m *mockAIUsageRepository) count(match func(models.AIUsage) bool, since time.Time) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.countLocked(match, since)
+}
+
+func (m *mockAIUsageRepository) countLocked(match func(models.AIUsage) bool, since time.Time) int {
+	n := 0
+	for _, u := range m.usages {
+		if match(u) && !u.Cached && !u.CreatedAt.Before(since) {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *mockAIUsageRepository) GetTotalsByUser(userID uuid.UUID, since time.Time) ([]repository.AIUsageTotals, error) {
+	return m.totals(func(u models.AIUsage) bool { return u.UserID == userID }, since), nil
+}
+
+func (m *mockAIUsageRepository) GetTotalsByGroup(groupID uuid.UUID, since time.Time) ([]repository.AIUsageTotals, error) {
+	return m.totals(func(u models.AIUsage) bool { return u.GroupID != nil && *u.GroupID == groupID }, since), nil
+}
+
+func (m *mockAIUsageRepository) totals(match func(models.AIUsage) bool, since time.Time) []repository.AIUsageTotals {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	byKind := make(map[string]*repository.AIUsageTotals)
+	for _, u := range m.usages {
+		if !match(u) || u.CreatedAt.Before(since) {
+			continue
+		}
+		t, exists := byKind[u.Kind]
+		if !exists {
+			t = &repository.AIUsageTotals{Kind: u.Kind}
+			byKind[u.Kind] = t
+		}
+		t.Calls++
+		if u.Cached {
+			t.CachedCalls++
+		}
+		t.PromptTokens += u.PromptTokens
+		t.OutputTokens += u.OutputTokens
+		t.TotalTokens += u.TotalTokens
+	}
+	var totals []repository.AIUsageTotals
+	for _, t := range byKind {
+		totals = append(totals, *t)
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].Kind < totals[j].Kind })
+	return totals
+}
+
+// mockAnalysisCacheRepository AnalysisCacheRepositoryのモック
+type mockAnalysisCacheRepository struct {
+	mu      sync.Mutex
+	entries map[string]models.AnalysisCache
+}
+
+func newMockAnalysisCacheRepository() *mockAnalysisCacheRepository {
+	return &mockAnalysisCacheRepository{entries: make(map[string]models.AnalysisCache)}
+}
+
+func (m *mockAnalysisCacheRepository) Get(contentHash string, now time.Time) (*models.AnalysisCache, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, exists := m.entries[contentHash]
+	if !exists || !entry.ExpiresAt.After(now) {
+		return nil, errors.New("record not found")
+	}
+	return &entry, nil
+}
+
+func (m *mockAnalysisCacheRepository) Save(entry *models.AnalysisCache) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[entry.ContentHash] = *entry
+	return nil
+}
+
+func (m *mockAnalysisCacheRepository) DeleteExpired(now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for hash, entry := range m.entries {
+		if !entry.ExpiresAt.After(now) {
+			delete(m.entries, hash)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// newTestAIUsageService 利用上限なしで analyzer を呼び出す AIUsageService を作成
+func newTestAIUsageService(groupRepo *mockGroupRepository, analyzer service.AIAnalyzer) service.AIUsageService {
+	return service.NewAIUsageService(newMockAIUsageRepository(), newMockAnalysisCacheRepository(), groupRepo, analyzer, service.AIQuota{}, 0)
+}
+
+func TestAIUsageService(t *testing.T) {
+	groupRepo := newMockGroupRepository()
+	userID := uuid.New()
+	memberID := uuid.New()
+	otherID := uuid.New()
+	groupID := newReceiptTestGroup(groupRepo, userID, memberID)
+
+	calls := 0
+	analyzer := &mockAIAnalyzer{
+		analyzeFunc: func(ctx context.Context, img *service.ReceiptImage) (*service.AnalyzeReceiptResult, error) {
+			calls++
+			if string(img.Data) == "broken" {
+				return nil, errors.New("model error")
+			}
+			return &service.AnalyzeReceiptResult{
+				Date: "2026-10-01", Shop: "Lawson", Amount: 580,
+				Usage: service.AITokenUsage{PromptTokens: 100, OutputTokens: 20, TotalTokens: 120},
+			}, nil
+		},
+	}
+	usageRepo := newMockAIUsageRepository()
+	svc := service.NewAIUsageService(usageRepo, newMockAnalysisCacheRepository(), groupRepo, analyzer, service.AIQuota{UserDailyLimit: 2, GroupDailyLimit: 3}, time.Hour)
+	image := func(data string) *service.ReceiptImage {
+		return &service.ReceiptImage{MIMEType: service.MIMETypeJPEG, Data: []byte(data), PageCount: 1}
+	}
+
+	t.Run("Reuses Result For Same Image", func(t *testing.T) {
+		first, err := svc.AnalyzeReceipt(context.Background(), userID, &groupID, image("a"))
+		if err != nil {
+			t.Fatalf("AnalyzeReceipt failed: %v", err)
+		}
+		first.Shop = "changed by caller"
+
+		second, err := svc.AnalyzeReceipt(context.Background(), userID, &groupID, image("a"))
+		if err != nil {
+			t.Fatalf("AnalyzeReceipt failed: %v", err)
+		}
+		if calls != 1 {
+			t.Errorf("expected analyzer to be called once, got %d", calls)
+		}
+		if second.Shop != "Lawson" || second.Amount != 580 {
+			t.Errorf("expected cached result, got %+v", second)
+		}
+	})
+
+	t.Run("Failed Calls Count Toward Quota", func(t *testing.T) {
+		if _, err := svc.AnalyzeReceipt(context.Background(), userID, &groupID, image("broken")); err == nil {
+			t.Fatal("expected analysis error")
+		}
+		if _, err := svc.AnalyzeReceipt(context.Background(), userID, &groupID, image("b")); !errors.Is(err, service.ErrAIQuotaExceeded) {
+			t.Errorf("expected ErrAIQuotaExceeded for user, got %v", err)
+		}
+		if _, err := svc.AnalyzeReceipt(context.Background(), userID, &groupID, image("a")); err != nil {
+			t.Errorf("expected cached result even over the limit, got %v", err)
+		}
+	})
+
+	t.Run("Group Quota", func(t *testing.T) {
+		if _, err := svc.AnalyzeReceipt(context.Background(), memberID, &groupID, image("c")); err != nil {
+			t.Fatalf("AnalyzeReceipt failed: %v", err)
+		}
+		if _, err := svc.AnalyzeReceipt(context.Background(), memberID, &groupID, image("d")); !errors.Is(err, service.ErrAIQuotaExceeded) {
+			t.Errorf("expected ErrAIQuotaExceeded for group, got %v", err)
+		}
+		if _, err := svc.AnalyzeReceipt(context.Background(), memberID, nil, image("d")); err != nil {
+			t.Errorf("expected analysis without group to be allowed, got %v", err)
+		}
+	})
+
+	t.Run("Requires Group Membership", func(t *testing.T) {
+		before := calls
+		if _, err := svc.AnalyzeReceipt(context.Background(), otherID, &groupID, image("e")); !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("expected ErrPermissionDenied, got %v", err)
+		}
+		if _, err := svc.AnalyzeReceipt(context.Background(), otherID, &groupID, image("a")); !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("expected ErrPermissionDenied for a cached image, got %v", err)
+		}
+		if _, err := svc.ReserveUsage(otherID, &groupID, models.AIUsageParseText); !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("expected ErrPermissionDenied from ReserveUsage, got %v", err)
+		}
+		if calls != before {
+			t.Errorf("expected analyzer not to be called, got %d calls", calls-before)
+		}
+	})
+
+	t.Run("User Usage", func(t *testing.T) {
+		report, err := svc.GetUserUsage(userID, 0)
+		if err != nil {
+			t.Fatalf("GetUserUsage failed: %v", err)
+		}
+		if report.Total.Calls != 4 || report.Total.CachedCalls != 2 || report.Total.TotalTokens != 120 {
+			t.Errorf("unexpected totals: %+v", report.Total)
+		}
+		if len(report.Kinds) != 1 || report.Kinds[0].Kind != models.AIUsageAnalyzeReceipt {
+			t.Errorf("expected usage by kind, got %+v", report.Kinds)
+		}
+		if report.Today.Used != 2 || report.Today.Limit == nil || *report.Today.Limit != 2 || *report.Today.Remaining != 0 {
+			t.Errorf("unexpected quota status: %+v", report.Today)
+		}
+	})
+
+	t.Run("Group Usage", func(t *testing.T) {
+		report, err := svc.GetGroupUsage(groupID, memberID, 7)
+		if err != nil {
+			t.Fatalf("GetGroupUsage failed: %v", err)
+		}
+		if report.Total.Calls != 5 || report.Today.Used != 3 || *report.Today.Remaining != 0 {
+			t.Errorf("unexpected group usage: %+v", report)
+		}
+		if _, err := svc.GetGroupUsage(groupID, otherID, 7); !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("expected ErrPermissionDenied, got %v", err)
+		}
+	})
+}
+
+func TestAIUsageService_ConcurrentQuota(t *testing.T) {
+	groupRepo := newMockGroupRepository()
+	userID := uuid.New()
+	groupID := newReceiptTestGroup(groupRepo, userID)
+
+	var mu sync.Mutex
+	calls := 0
+	analyzer := &mockAIAnalyzer{
+		analyzeFunc: func(ctx context.Context, img *service.ReceiptImage) (*service.AnalyzeReceiptResult, error) {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+			// 他の呼び出しが利用上限を確認している間に解析中の状態を作る
+			time.Sleep(10 * time.Millisecond)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return &service.AnalyzeReceiptResult{Date: "2026-10-01", Shop: "Lawson", Amount: 580}, nil
+		},
+	}
+	usageRepo := newMockAIUsageRepository()
+	svc := service.NewAIUsageService(usageRepo, newMockAnalysisCacheRepository(), groupRepo, analyzer, service.AIQuota{UserDailyLimit: 3}, time.Hour)
+
+	t.Run("Concurrent Calls Do Not Exceed Quota", func(t *testing.T) {
+		var wg sync.WaitGroup
+		var succeeded, exceeded int
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				img := &service.ReceiptImage{MIMEType: service.MIMETypeJPEG, Data: []byte{byte(i)}, PageCount: 1}
+				_, err := svc.AnalyzeReceipt(context.Background(), userID, &groupID, img)
+				mu.Lock()
+				defer mu.Unlock()
+				if err == nil {
+					succeeded++
+				} else if errors.Is(err, service.ErrAIQuotaExceeded) {
+					exceeded++
+				}
+			}(i)
+		}
+		wg.Wait()
+		if succeeded != 3 || exceeded != 7 || calls != 3 {
+			t.Errorf("expected 3 analyses and 7 rejections, got %d, %d (%d calls)", succeeded, exceeded, calls)
+		}
+	})
+
+	t.Run("Interrupted Call Is Not Counted", func(t *testing.T) {
+		used, _ := usageRepo.CountByUserSince(userID, time.Time{})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		usageID, err := svc.ReserveUsage(userID, &groupID, models.AIUsageParseText)
+		if !errors.Is(err, service.ErrAIQuotaExceeded) {
+			t.Fatalf("expected ErrAIQuotaExceeded, got %v (%s)", err, usageID)
+		}
+
+		// 上限のない別のユーザーの確保を、停止による中断として取り消す
+		unlimited := service.NewAIUsageService(usageRepo, newMockAnalysisCacheRepository(), groupRepo, analyzer, service.AIQuota{}, time.Hour)
+		usageID, err = unlimited.ReserveUsage(userID, &groupID, models.AIUsageParseText)
+		if err != nil {
+			t.Fatalf("ReserveUsage failed: %v", err)
+		}
+		unlimited.FinishUsage(ctx, usageID, service.AITokenUsage{TotalTokens: 10})
+		if after, _ := usageRepo.CountByUserSince(userID, time.Time{}); after != used {
+			t.Errorf("expected the interrupted call to be released, got %d calls (was %d)", after, used)
+		}
+	})
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// ReviewConfidenceThreshold この値未満の確からしさの項目は利用者の確認が必要とみなす
+	ReviewConfidenceThreshold = 0.7
+	// maxAnalyzeAttempts 検証に失敗した場合に修正を依頼する分を含めた、モデルへの問い合わせの最大回数
+	maxAnalyzeAttempts = 2
+	// maxAnalyzedAmount 解析結果として受け付ける金額の上限
+	maxAnalyzedAmount = 10_000_000
+	// maxAnalyzedTextLength 店舗名・品目の最大文字数（レシートのカラムの長さ）
+	maxAnalyzedTextLength = 255
+	// defaultModelConfidence モデルが確からしさを返さなかった項目の確からしさ
+	defaultModelConfidence = 0.5
+	// normalizedValueConfidence 形式を補正して読み取った項目の確からしさの上限
+	normalizedValueConfidence = 0.5
+)
+
+// FieldConfidence 解析結果の項目ごとの確からしさ（0〜1）
+type FieldConfidence struct {
+	Date   float64 `json:"date"`
+	Shop   float64 `json:"shop"`
+	Item   float64 `json:"item"`
+	Amount float64 `json:"amount"`
+}
+
+// analyzeResponseJSONSchema モデルに返させる解析結果のJSON Schema（OpenAI互換の response_format 用）
+var analyzeResponseJSONSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"date":   map[string]interface{}{"type": "string", "description": "Purchase date in YYYY-MM-DD format"},
+		"shop":   map[string]interface{}{"type": "string", "description": "Store name"},
+		"item":   map[string]interface{}{"type": "string", "description": "Short summary of purchased items"},
+		"amount": map[string]interface{}{"type": "integer", "description": "Total amount paid in yen"},
+		"confidence": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"date":   map[string]interface{}{"type": "number"},
+				"shop":   map[string]interface{}{"type": "number"},
+				"item":   map[string]interface{}{"type": "number"},
+				"amount": map[string]interface{}{"type": "number"},
+			},
+			"required":             []string{"date", "shop", "item", "amount"},
+			"additionalProperties": false,
+		},
+	},
+	"required":             []string{"date", "shop", "item", "amount", "confidence"},
+	"additionalProperties": false,
+}
+
+// rawAnalyzeResponse モデルの応答。型の揺れ（"1,234" のような金額など）を許容して受け取る
+type rawAnalyzeResponse struct {
+	Date       string                 `json:"date"`
+	Shop       string                 `json:"shop"`
+	Item       string                 `json:"item"`
+	Amount     json.RawMessage        `json:"amount"`
+	Confidence map[string]interface{} `json:"confidence"`
+}
+
+// generateAnalyzeResponse モデルに解析を依頼して応答の文字列と使われたトークン数を返す。correction が空でない場合は前回の応答の修正も依頼する
+type generateAnalyzeResponse func(ctx context.Context, correction string) (string, AITokenUsage, error)
+
+// analyzeWithValidation モデルの応答を検証し、問題があれば内容を伝えて修正を依頼する。
+// 修正後も検証に通らない項目は空欄・確からしさ0として返し、利用者の確認に回す
+func analyzeWithValidation(ctx context.Context, generate generateAnalyzeResponse) (*AnalyzeReceiptResult, error) {
+	var result *AnalyzeReceiptResult
+	var problems []string
+	var usage AITokenUsage
+	correction := ""
+
+	for attempt := 0; attempt < maxAnalyzeAttempts; attempt++ {
+		text, attemptUsage, err := generate(ctx, correction)
+		if err != nil {
+			return nil, err
+		}
+		usage.add(attemptUsage)
+
+		decoded, decodeProblems, err := decodeAnalyzeResponse(text, time.Now())
+		if err != nil {
+			problems = []string{"the response was not valid JSON (" + err.Error() + ")"}
+		} else {
+			result, problems = decoded, decodeProblems
+			if len(problems) == 0 {
+				break
+			}
+		}
+		correction = analyzeCorrectionPrompt(text, problems)
+	}
+
+	if result == nil {
+		return nil, fmt.Errorf("invalid analysis response: %s", strings.Join(problems, "; "))
+	}
+	result.markFieldsForReview()
+	result.Usage = usage
+	return result, nil
+}
+
+// analyzeCorrectionPrompt 検証に失敗した応答の修正を依頼するプロンプト
+func analyzeCorrectionPrompt(previous string, problems []string) string {
+	return "Your previous response had the following problems:\n- " + strings.Join(problems, "\n- ") +
+		"\n\nPrevious response:\n" + previous +
+		"\n\nLook at the receipt again and return the corrected JSON only. If a field cannot be read, return your best guess with a low confidence."
+}
+
+// decodeAnalyzeResponse モデルの応答を解析結果に変換し、検証に通らなかった項目の問題を返す
+func decodeAnalyzeResponse(text string, now time.Time) (*AnalyzeReceiptResult, []string, error) {
+	var raw rawAnalyzeResponse
+	if err := json.Unmarshal([]byte(extractJSONObject(text)), &raw); err != nil {
+		return nil, nil, err
+	}
+
+	result := &AnalyzeReceiptResult{
+		Confidence: FieldConfidence{
+			Date:   modelConfidence(raw.Confidence, "date"),
+			Shop:   modelConfidence(raw.Confidence, "shop"),
+			Item:   modelConfidence(raw.Confidence, "item"),
+			Amount: modelConfidence(raw.Confidence, "amount"),
+		},
+	}
+	var problems []string
+
+	if date, ok := parseReceiptDate(strings.TrimSpace(raw.Date)); !ok {
+		problems = append(problems, fmt.Sprintf("date %q is not a valid date in YYYY-MM-DD format", raw.Date))
+		result.Confidence.Date = 0
+	} else if parsed, _ := time.ParseInLocation("2006-01-02", date, now.Location()); parsed.After(now.AddDate(0, 0, 1)) {
+		problems = append(problems, fmt.Sprintf("date %s is in the future", date))
+		result.Confidence.Date = 0
+	} else {
+		result.Date = date
+	}
+
+	shop := strings.TrimSpace(raw.Shop)
+	switch {
+	case shop == "":
+		problems = append(problems, "shop must not be empty")
+		result.Confidence.Shop = 0
+	case utf8.RuneCountInString(shop) > maxAnalyzedTextLength:
+		problems = append(problems, fmt.Sprintf("shop must be at most %d characters", maxAnalyzedTextLength))
+		result.Confidence.Shop = 0
+	default:
+		result.Shop = shop
+	}
+
+	result.Item = truncateRunes(strings.TrimSpace(raw.Item), maxAnalyzedTextLength)
+
+	amount, normalized, err := parseAnalyzedAmount(raw.Amount)
+	switch {
+	case err != nil:
+		problems = append(problems, fmt.Sprintf("amount %s must be an integer number of yen", strings.TrimSpace(string(raw.Amount))))
+		result.Confidence.Amount = 0
+	case amount <= 0 || amount > maxAnalyzedAmount:
+		problems = append(problems, fmt.Sprintf("amount %d must be between 1 and %d", amount, maxAnalyzedAmount))
+		result.Confidence.Amount = 0
+	default:
+		result.Amount = amount
+		if normalized {
+			result.Confidence.Amount = math.Min(result.Confidence.Amount, normalizedValueConfidence)
+		}
+	}
+
+	return result, problems, nil
+}
+
+// extractJSONObject モデルの応答からJSONのオブジェクト部分を取り出す（コードブロックや前後の説明文を取り除く）
+func extractJSONObject(text string) string {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+	if start, end := strings.Index(text, "{"), strings.LastIndex(text, "}"); start >= 0 && end > start {
+		text = text[start : end+1]
+	}
+	return text
+}
+
+// parseAnalyzedAmount 金額を整数で読み取る。文字列（"¥1,234" など）の場合は補正して読み取り、normalized を true で返す
+func parseAnalyzedAmount(raw json.RawMessage) (amount int, normalized bool, err error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, false, errors.New("amount is missing")
+	}
+
+	var number float64
+	if err := json.Unmarshal(raw, &number); err == nil {
+		if number != math.Trunc(number) {
+			return 0, false, errors.New("amount is not an integer")
+		}
+		return int(number), false, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return 0, false, err
+	}
+	text = strings.TrimSpace(norm.NFKC.String(text))
+	text = strings.NewReplacer("¥", "", "\\", "", "円", "", ",", "", " ", "").Replace(text)
+	amount, err = strconv.Atoi(text)
+	if err != nil {
+		return 0, false, err
+	}
+	return amount, true, nil
+}
+
+// modelConfidence モデルが返した確からしさを0〜1に収めて返す（返されなかった場合は既定値）
+func modelConfidence(confidence map[string]interface{}, field string) float64 {
+	value, ok := confidence[field].(float64)
+	if !ok || math.IsNaN(value) {
+		return defaultModelConfidence
+	}
+	return math.Max(0, math.Min(1, value))
+}
+
+// markFieldsForReview 確からしさが基準未満の項目を、利用者の確認が必要な項目として記録する
+func (r *AnalyzeReceiptResult) markFieldsForReview() {
+	r.NeedsReview = []string{}
+	fields := []struct {
+		name       string
+		confidence float64
+	}{
+		{"date", r.Confidence.Date},
+		{"shop", r.Confidence.Shop},
+		{"item", r.Confidence.Item},
+		{"amount", r.Confidence.Amount},
+	}
+	for _, f := range fields {
+		if f.confidence < ReviewConfidenceThreshold {
+			r.NeedsReview = append(r.NeedsReview, f.name)
+		}
+	}
+}
+
+func truncateRunes(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	return string([]rune(s)[:limit])
+}
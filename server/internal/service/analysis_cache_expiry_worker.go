@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"receipt/server/internal/repository"
+)
+
+// AnalysisCacheExpiryWorker 有効期限を過ぎた画像ごとの解析結果を定期的に削除するバックグラウンドジョブ
+type AnalysisCacheExpiryWorker struct {
+	cacheRepo repository.AnalysisCacheRepository
+	interval  time.Duration
+}
+
+// NewAnalysisCacheExpiryWorker AnalysisCacheExpiryWorkerを作成
+func NewAnalysisCacheExpiryWorker(cacheRepo repository.AnalysisCacheRepository, interval time.Duration) *AnalysisCacheExpiryWorker {
+	return &AnalysisCacheExpiryWorker{
+		cacheRepo: cacheRepo,
+		interval:  interval,
+	}
+}
+
+// Run ctx がキャンセルされるまで一定間隔で期限切れの解析結果を削除する
+func (w *AnalysisCacheExpiryWorker) Run(ctx context.Context) {
+	runEvery(ctx, w.interval, "analysis cache expiry", func() error {
+		_, err := w.cacheRepo.DeleteExpired(time.Now())
+		return err
+	})
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+	"receipt/server/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// analysisFeedbackLookback 解析結果の補正に使う修正履歴の件数（新しいものから）
+	analysisFeedbackLookback = 500
+	// itemCorrectionThreshold 品名を補正するのに必要な、同じ店舗で続けて同じ品名に修正された回数
+	itemCorrectionThreshold = 2
+	// defaultAccuracyMonths 精度レポートの既定の集計月数
+	defaultAccuracyMonths = 3
+	// maxAccuracyMonths 精度レポートの最大の集計月数
+	maxAccuracyMonths = 24
+	// maxReportedShopCorrections 精度レポートに含める店舗名の修正の件数
+	maxReportedShopCorrections = 10
+)
+
+// FieldAccuracy 項目ごとの、AI解析の提案が修正された件数と割合
+type FieldAccuracy struct {
+	Field    string   `json:"field"` // date / shop / item / amount
+	Edited   int      `json:"edited"`
+	EditRate *float64 `json:"edit_rate"` // 確定件数が0の場合はnull
+}
+
+// ShopCorrection よく修正される店舗名（提案 → 確定）
+type ShopCorrection struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Count int    `json:"count"`
+}
+
+// AnalysisAccuracyReport グループのAI解析の精度（提案のうち、利用者が修正した割合）
+type AnalysisAccuracyReport struct {
+	GroupID         uuid.UUID        `json:"group_id"`
+	From            time.Time        `json:"from"`
+	Confirmed       int              `json:"confirmed"` // 期間内に確定された下書きの件数
+	Fields          []FieldAccuracy  `json:"fields"`
+	ShopCorrections []ShopCorrection `json:"shop_corrections"`
+}
+
+// AnalysisFeedbackService 利用者の修正から学習してAI解析の結果を補正するビジネスロジックインターフェース
+type AnalysisFeedbackService interface {
+	// RefineAnalysis 解析結果の店舗名を店舗マスタと照合し、グループで過去に修正された店舗名・品名を補正する
+	RefineAnalysis(groupID uuid.UUID, userID uuid.UUID, result *AnalyzeReceiptResult) error
+	GetAccuracy(groupID uuid.UUID, userID uuid.UUID, months int) (*AnalysisAccuracyReport, error)
+}
+
+type analysisFeedbackServiceImpl struct {
+	feedbackRepo repository.AnalysisFeedbackRepository
+	groupRepo    repository.GroupRepository
+	shopService  ShopService
+	now          func() time.Time
+}
+
+// NewAnalysisFeedbackService AnalysisFeedbackServiceの実装を作成
+func NewAnalysisFeedbackService(feedbackRepo repository.AnalysisFeedbackRepository, groupRepo repository.GroupRepository, shopService ShopService) AnalysisFeedbackService {
+	return &analysisFeedbackServiceImpl{
+		feedbackRepo: feedbackRepo,
+		groupRepo:    groupRepo,
+		shopService:  shopService,
+		now:          time.Now,
+	}
+}
+
+func (s *analysisFeedbackServiceImpl) RefineAnalysis(groupID uuid.UUID, userID uuid.UUID, result *AnalyzeReceiptResult) error {
+	if result.AnalyzedShop == "" {
+		result.AnalyzedShop = result.Shop
+	}
+	if err := s.shopService.SuggestShop(groupID, userID, result); err != nil {
+		return err
+	}
+
+	feedback, err := s.feedbackRepo.GetRecentByGroupID(groupID, analysisFeedbackLookback)
+	if err != nil {
+		return err
+	}
+
+	// 読み取った店舗名が前回の確定で修正されていれば、修正後の店舗名に置き換えて店舗マスタと照合し直す
+	if shop, found := correctedShop(feedback, result.AnalyzedShop); found && shop != result.Shop {
+		result.Shop = shop
+		result.ShopID, result.Category, result.PaymentMethod = nil, "", ""
+		result.Corrected = append(result.Corrected, "shop")
+		if err := s.shopService.SuggestShop(groupID, userID, result); err != nil {
+			return err
+		}
+	}
+	if item, found := correctedItem(feedback, result.Shop); found && item != result.Item {
+		result.Item = item
+		result.Corrected = append(result.Corrected, "item")
+	}
+	return nil
+}
+
+// correctedShop 読み取った店舗名が最後に確定されたときの店舗名。
+// 読み取ったままの店舗名で確定された場合（補正を取り消した場合を含む）は found が false
+func correctedShop(feedback []models.AnalysisFeedback, analyzed string) (string, bool) {
+	key := utils.NormalizeShopName(analyzed)
+	if key == "" {
+		return "", false
+	}
+	for _, f := range feedback {
+		if utils.NormalizeShopName(f.AnalyzedShop) != key {
+			continue
+		}
+		if strings.TrimSpace(f.FinalShop) == "" || utils.NormalizeShopName(f.FinalShop) == key {
+			return "", false
+		}
+		return f.FinalShop, true
+	}
+	return "", false
+}
+
+// correctedItem 店舗の直近の確定で、続けて同じ品名に修正されている場合はその品名
+func correctedItem(feedback []models.AnalysisFeedback, shop string) (string, bool) {
+	key := utils.NormalizeShopName(shop)
+	if key == "" {
+		return "", false
+	}
+
+	item, streak := "", 0
+	for _, f := range feedback {
+		if utils.NormalizeShopName(f.FinalShop) != key {
+			continue
+		}
+		if !f.ItemEdited || (streak > 0 && f.FinalItem != item) {
+			break
+		}
+		item = f.FinalItem
+		streak++
+		if streak >= itemCorrectionThreshold {
+			return item, strings.TrimSpace(item) != ""
+		}
+	}
+	return "", false
+}
+
+func (s *analysisFeedbackServiceImpl) GetAccuracy(groupID uuid.UUID, userID uuid.UUID, months int) (*AnalysisAccuracyReport, error) {
+	if months <= 0 {
+		months = defaultAccuracyMonths
+	}
+	if months > maxAccuracyMonths {
+		months = maxAccuracyMonths
+	}
+
+	if _, err := authorizeGroup(s.groupRepo, groupID, userID, PermissionViewGroup); err != nil {
+		return nil, err
+	}
+
+	from := s.now().AddDate(0, -months, 0)
+	totals, err := s.feedbackRepo.GetAccuracyTotals(groupID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &AnalysisAccuracyReport{
+		GroupID:         groupID,
+		From:            from,
+		Confirmed:       totals.Total,
+		ShopCorrections: []ShopCorrection{},
+	}
+	for _, field := range []struct {
+		name   string
+		edited int
+	}{
+		{"date", totals.DateEdited},
+		{"shop", totals.ShopEdited},
+		{"item", totals.ItemEdited},
+		{"amount", totals.AmountEdited},
+	} {
+		accuracy := FieldAccuracy{Field: field.name, Edited: field.edited}
+		if totals.Total > 0 {
+			rate := float64(field.edited) / float64(totals.Total)
+			accuracy.EditRate = &rate
+		}
+		report.Fields = append(report.Fields, accuracy)
+	}
+
+	feedback, err := s.feedbackRepo.GetRecentByGroupID(groupID, analysisFeedbackLookback)
+	if err != nil {
+		return nil, err
+	}
+	report.ShopCorrections = topShopCorrections(feedback, from)
+	return report, nil
+}
+
+// topShopCorrections from 以降の店舗名の修正を、提案と確定の組み合わせごとに多い順に集計する
+func topShopCorrections(feedback []models.AnalysisFeedback, from time.Time) []ShopCorrection {
+	counts := make(map[[2]string]int)
+	for _, f := range feedback {
+		if f.ShopEdited && !f.CreatedAt.Before(from) {
+			counts[[2]string{f.ProposedShop, f.FinalShop}]++
+		}
+	}
+
+	corrections := make([]ShopCorrection, 0, len(counts))
+	for pair, count := range counts {
+		corrections = append(corrections, ShopCorrection{From: pair[0], To: pair[1], Count: count})
+	}
+	sort.Slice(corrections, func(i, j int) bool {
+		if corrections[i].Count != corrections[j].Count {
+			return corrections[i].Count > corrections[j].Count
+		}
+		return corrections[i].From < corrections[j].From
+	})
+	if len(corrections) > maxReportedShopCorrections {
+		corrections = corrections[:maxReportedShopCorrections]
+	}
+	return corrections
+}
+
+// newAnalysisFeedback 下書きの提案と確定したレシートを比較し、修正された項目を記録する
+func newAnalysisFeedback(draft *models.ReceiptDraft, receipt *models.Receipt) *models.AnalysisFeedback {
+	feedback := &models.AnalysisFeedback{
+		GroupID:        receipt.GroupID,
+		UserID:         receipt.UserID,
+		ReceiptID:      receipt.ID,
+		AnalyzedShop:   draft.AnalyzedShop,
+		ProposedDate:   draft.Date,
+		ProposedShop:   draft.Shop,
+		ProposedItem:   draft.Item,
+		ProposedAmount: draft.Amount,
+		FinalShop:      receipt.Shop,
+		FinalItem:      receipt.Item,
+		ShopEdited:     utils.NormalizeShopName(draft.Shop) != utils.NormalizeShopName(receipt.Shop),
+		ItemEdited:     strings.TrimSpace(draft.Item) != strings.TrimSpace(receipt.Item),
+		AmountEdited:   draft.Amount != receipt.Amount,
+	}
+	if feedback.AnalyzedShop == "" {
+		feedback.AnalyzedShop = draft.Shop
+	}
+	feedback.DateEdited = draft.Date == nil || draft.Date.Format("2006-01-02") != receipt.Date.Format("2006-01-02")
+	return feedback
+}
@@ -0,0 +1,173 @@
+package service_test
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+	"receipt/server/internal/service"
+
+	"github.com/google/uuid"
+)
+
+// mockAnalysisFeedbackRepository AnalysisFeedbackRepositoryのモック（解析ワーカーから並行して呼ばれるためロックする）
+type mockAnalysisFeedbackRepository struct {
+	mu       sync.Mutex
+	feedback []models.AnalysisFeedback
+}
+
+func newMockAnalysisFeedbackRepository() *mockAnalysisFeedbackRepository {
+	return &mockAnalysisFeedbackRepository{}
+}
+
+func (m *mockAnalysisFeedbackRepository) Create(feedback *models.AnalysisFeedback) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	feedback.ID = uuid.New()
+	feedback.CreatedAt = time.Now().Add(time.Duration(len(m.feedback)) * time.Millisecond)
+	m.feedback = append(m.feedback, *feedback)
+	return nil
+}
+
+func (m *mockAnalysisFeedbackRepository) GetRecentByGroupID(groupID uuid.UUID, limit int) ([]models.AnalysisFeedback, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var feedback []models.AnalysisFeedback
+	for _, f := range m.feedback {
+		if f.GroupID == groupID {
+			feedback = append(feedback, f)
+		}
+	}
+	sort.Slice(feedback, func(i, j int) bool { return feedback[i].CreatedAt.After(feedback[j].CreatedAt) })
+	if len(feedback) > limit {
+		feedback = feedback[:limit]
+	}
+	return feedback, nil
+}
+
+func (m *mockAnalysisFeedbackRepository) GetAccuracyTotals(groupID uuid.UUID, from time.Time) (*repository.AnalysisAccuracyTotals, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	totals := &repository.AnalysisAccuracyTotals{}
+	count := func(edited bool) int {
+		if edited {
+			return 1
+		}
+		return 0
+	}
+	for _, f := range m.feedback {
+		if f.GroupID != groupID || f.CreatedAt.Before(from) {
+			continue
+		}
+		totals.Total++
+		totals.DateEdited += count(f.DateEdited)
+		totals.ShopEdited += count(f.ShopEdited)
+		totals.ItemEdited += count(f.ItemEdited)
+		totals.AmountEdited += count(f.AmountEdited)
+	}
+	return totals, nil
+}
+
+func TestAnalysisFeedbackService(t *testing.T) {
+	groupRepo := newMockGroupRepository()
+	shopRepo := newMockShopRepository()
+	userID := uuid.New()
+	otherID := uuid.New()
+	groupID := newReceiptTestGroup(groupRepo, userID)
+	receiptService := service.NewReceiptService(newMockReceiptRepository(), groupRepo, shopRepo)
+
+	feedbackRepo := newMockAnalysisFeedbackRepository()
+	draftService := service.NewReceiptDraftService(newMockReceiptDraftRepository(), feedbackRepo, receiptService, time.Hour)
+	svc := service.NewAnalysisFeedbackService(feedbackRepo, groupRepo, service.NewShopService(shopRepo, groupRepo))
+
+	// analyze 解析結果を補正して下書きに保存し、params の内容で確定する
+	analyze := func(shop, item string, params service.CreateReceiptParams) *service.AnalyzeReceiptResult {
+		t.Helper()
+		result := &service.AnalyzeReceiptResult{Date: "2026-10-01", Shop: shop, Item: item, Amount: 580}
+		if err := svc.RefineAnalysis(groupID, userID, result); err != nil {
+			t.Fatalf("RefineAnalysis failed: %v", err)
+		}
+		refined := *result
+		params.PaymentMethod = "half"
+		if _, err := draftService.CreateDraft(userID, &groupID, nil, result); err != nil {
+			t.Fatalf("CreateDraft failed: %v", err)
+		}
+		if _, err := draftService.ConfirmDraft(*result.DraftID, userID, &params); err != nil {
+			t.Fatalf("ConfirmDraft failed: %v", err)
+		}
+		return &refined
+	}
+
+	t.Run("Learns Shop Correction", func(t *testing.T) {
+		first := analyze("LAWS0N", "お弁当", service.CreateReceiptParams{Shop: "ローソン"})
+		if len(first.Corrected) != 0 {
+			t.Errorf("expected no correction before feedback, got %v", first.Corrected)
+		}
+
+		second := analyze("LAWS0N", "お弁当", service.CreateReceiptParams{})
+		if second.Shop != "ローソン" || len(second.Corrected) != 1 || second.Corrected[0] != "shop" {
+			t.Errorf("expected shop to be corrected, got %q %v", second.Shop, second.Corrected)
+		}
+		if second.AnalyzedShop != "LAWS0N" {
+			t.Errorf("expected analyzed shop to be kept, got %q", second.AnalyzedShop)
+		}
+	})
+
+	t.Run("Reverted Correction Is Not Reapplied", func(t *testing.T) {
+		analyze("LAWS0N", "お弁当", service.CreateReceiptParams{Shop: "LAWS0N"})
+
+		result := &service.AnalyzeReceiptResult{Date: "2026-10-01", Shop: "LAWS0N", Amount: 580}
+		if err := svc.RefineAnalysis(groupID, userID, result); err != nil {
+			t.Fatalf("RefineAnalysis failed: %v", err)
+		}
+		if result.Shop != "LAWS0N" || len(result.Corrected) != 0 {
+			t.Errorf("expected reverted correction not to be applied, got %q %v", result.Shop, result.Corrected)
+		}
+	})
+
+	t.Run("Item Correction Needs Repeated Edits", func(t *testing.T) {
+		analyze("ファミリーマート", "弁当", service.CreateReceiptParams{Item: "昼食"})
+		once := &service.AnalyzeReceiptResult{Date: "2026-10-01", Shop: "ファミリーマート", Item: "弁当", Amount: 580}
+		_ = svc.RefineAnalysis(groupID, userID, once)
+		if once.Item != "弁当" {
+			t.Errorf("expected item not to be corrected after one edit, got %q", once.Item)
+		}
+
+		analyze("ファミリーマート", "弁当", service.CreateReceiptParams{Item: "昼食"})
+		twice := &service.AnalyzeReceiptResult{Date: "2026-10-01", Shop: "ファミリーマート", Item: "弁当", Amount: 580}
+		_ = svc.RefineAnalysis(groupID, userID, twice)
+		if twice.Item != "昼食" || len(twice.Corrected) != 1 || twice.Corrected[0] != "item" {
+			t.Errorf("expected item to be corrected, got %q %v", twice.Item, twice.Corrected)
+		}
+	})
+
+	t.Run("Accuracy Report", func(t *testing.T) {
+		report, err := svc.GetAccuracy(groupID, userID, 0)
+		if err != nil {
+			t.Fatalf("GetAccuracy failed: %v", err)
+		}
+		if report.Confirmed != 5 || len(report.Fields) != 4 {
+			t.Fatalf("expected 5 confirmed receipts and 4 fields, got %+v", report)
+		}
+		edited := make(map[string]int)
+		for _, f := range report.Fields {
+			edited[f.Field] = f.Edited
+		}
+		if edited["date"] != 0 || edited["shop"] != 2 || edited["item"] != 2 || edited["amount"] != 0 {
+			t.Errorf("unexpected edit counts: %v", edited)
+		}
+		if len(report.ShopCorrections) != 2 {
+			t.Errorf("expected 2 shop corrections, got %+v", report.ShopCorrections)
+		}
+	})
+
+	t.Run("Accuracy Requires Group Membership", func(t *testing.T) {
+		if _, err := svc.GetAccuracy(groupID, otherID, 0); !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("expected ErrPermissionDenied, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrAnalysisJobNotFound 解析ジョブが見つからない（他のユーザーのジョブを含む）
+	ErrAnalysisJobNotFound = errors.New("analysis job not found")
+)
+
+// AnalysisJobStatus 解析ジョブの状態と結果
+type AnalysisJobStatus struct {
+	ID          uuid.UUID             `json:"id"`
+	Status      string                `json:"status"` // queued / processing / succeeded / failed
+	Attempts    int                   `json:"attempts"`
+	Result      *AnalyzeReceiptResult `json:"result,omitempty"` // succeeded の場合のみ
+	Error       string                `json:"error,omitempty"`
+	CreatedAt   time.Time             `json:"created_at"`
+	StartedAt   *time.Time            `json:"started_at"`
+	CompletedAt *time.Time            `json:"completed_at"`
+}
+
+// Done 解析が終わった（成功・失敗）かどうか
+func (s *AnalysisJobStatus) Done() bool {
+	return s.Status == models.AnalysisJobSucceeded || s.Status == models.AnalysisJobFailed
+}
+
+// newAnalysisJobStatus 保存されているジョブを応答用の状態に変換する
+func newAnalysisJobStatus(job *models.AnalysisJob) *AnalysisJobStatus {
+	status := &AnalysisJobStatus{
+		ID:          job.ID,
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		Error:       job.Error,
+		CreatedAt:   job.CreatedAt,
+		StartedAt:   job.StartedAt,
+		CompletedAt: job.CompletedAt,
+	}
+	if job.Status == models.AnalysisJobSucceeded && job.Result != "" {
+		var result AnalyzeReceiptResult
+		if err := json.Unmarshal([]byte(job.Result), &result); err == nil {
+			status.Result = &result
+		}
+	}
+	return status
+}
+
+// AnalysisJobService レシート画像の非同期解析ジョブ関連ビジネスロジックインターフェース
+type AnalysisJobService interface {
+	// SubmitJob 正規化済みの画像を解析待ちとして保存し、ワーカーに通知する
+	SubmitJob(userID uuid.UUID, groupID *uuid.UUID, img *ReceiptImage) (*AnalysisJobStatus, error)
+	GetJob(id uuid.UUID, userID uuid.UUID) (*AnalysisJobStatus, error)
+	// WatchJob ジョブの状態が変わるたびに通知を受け取る。戻り値の関数で購読を解除する
+	WatchJob(id uuid.UUID, userID uuid.UUID) (<-chan AnalysisJobStatus, func(), error)
+	// SubmitBatch 複数のレシートの画像を1つのバッチとして解析待ちにする（同時に解析する数はワーカー数で制限される）
+	SubmitBatch(userID uuid.UUID, groupID *uuid.UUID, uploads []ReceiptUpload) (*AnalysisBatchStatus, error)
+	// GetBatch バッチの各ジョブの状態と、解析が終わったものから作成したレシートの下書きを取得する
+	GetBatch(batchID uuid.UUID, userID uuid.UUID) (*AnalysisBatchStatus, error)
+}
+
+// ReceiptUpload まとめて解析するレシートの画像（正規化済み）とファイル名
+type ReceiptUpload struct {
+	FileName string
+	Image    *ReceiptImage
+}
+
+// AnalysisBatchDraft バッチの1件の解析状態と、解析結果から作成したレシートの下書き
+type AnalysisBatchDraft struct {
+	AnalysisJobStatus
+	FileName    string `json:"file_name"`
+	DuplicateOf *int   `json:"duplicate_of"` // 購入日・金額・店舗名が同じ、バッチ内で先に現れた下書きの位置（0始まり）
+}
+
+// AnalysisBatchStatus まとめて解析したレシートの状態
+type AnalysisBatchStatus struct {
+	ID        uuid.UUID            `json:"id"`
+	Total     int                  `json:"total"`
+	Succeeded int                  `json:"succeeded"`
+	Failed    int                  `json:"failed"`
+	Done      bool                 `json:"done"` // すべてのジョブの解析が終わったかどうか
+	Drafts    []AnalysisBatchDraft `json:"drafts"`
+}
+
+type analysisJobServiceImpl struct {
+	jobRepo   repository.AnalysisJobRepository
+	groupRepo repository.GroupRepository
+	notifier  *AnalysisJobNotifier
+}
+
+// NewAnalysisJobService AnalysisJobServiceの実装を作成（notifier は AnalysisWorkerPool と共有する）
+func NewAnalysisJobService(jobRepo repository.AnalysisJobRepository, groupRepo repository.GroupRepository, notifier *AnalysisJobNotifier) AnalysisJobService {
+	return &analysisJobServiceImpl{
+		jobRepo:   jobRepo,
+		groupRepo: groupRepo,
+		notifier:  notifier,
+	}
+}
+
+func (s *analysisJobServiceImpl) SubmitJob(userID uuid.UUID, groupID *uuid.UUID, img *ReceiptImage) (*AnalysisJobStatus, error) {
+	if groupID != nil {
+		if _, err := authorizeGroup(s.groupRepo, *groupID, userID, PermissionCreateReceipt); err != nil {
+			return nil, err
+		}
+	}
+
+	job := &models.AnalysisJob{
+		UserID:      userID,
+		GroupID:     groupID,
+		Status:      models.AnalysisJobQueued,
+		MIMEType:    img.MIMEType,
+		ImageData:   img.Data,
+		PreviewData: img.Preview,
+		PageCount:   img.PageCount,
+	}
+	if err := s.jobRepo.Create(job); err != nil {
+		return nil, err
+	}
+
+	s.notifier.wake()
+	return newAnalysisJobStatus(job), nil
+}
+
+func (s *analysisJobServiceImpl) GetJob(id uuid.UUID, userID uuid.UUID) (*AnalysisJobStatus, error) {
+	job, err := s.jobRepo.GetByID(id)
+	if err != nil || job.UserID != userID {
+		return nil, ErrAnalysisJobNotFound
+	}
+	return newAnalysisJobStatus(job), nil
+}
+
+func (s *analysisJobServiceImpl) WatchJob(id uuid.UUID, userID uuid.UUID) (<-chan AnalysisJobStatus, func(), error) {
+	if _, err := s.GetJob(id, userID); err != nil {
+		return nil, nil, err
+	}
+	ch, cancel := s.notifier.subscribe(id)
+	return ch, cancel, nil
+}
+
+func (s *analysisJobServiceImpl) SubmitBatch(userID uuid.UUID, groupID *uuid.UUID, uploads []ReceiptUpload) (*AnalysisBatchStatus, error) {
+	if groupID != nil {
+		if _, err := authorizeGroup(s.groupRepo, *groupID, userID, PermissionCreateReceipt); err != nil {
+			return nil, err
+		}
+	}
+	if len(uploads) == 0 {
+		return nil, ErrEmptyReceiptBatch
+	}
+	if len(uploads) > MaxReceiptBatchSize {
+		return nil, ErrReceiptBatchTooLarge
+	}
+
+	batchID, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*models.AnalysisJob, len(uploads))
+	for i, upload := range uploads {
+		jobs[i] = &models.AnalysisJob{
+			UserID:      userID,
+			GroupID:     groupID,
+			BatchID:     &batchID,
+			FileName:    upload.FileName,
+			Status:      models.AnalysisJobQueued,
+			MIMEType:    upload.Image.MIMEType,
+			ImageData:   upload.Image.Data,
+			PreviewData: upload.Image.Preview,
+			PageCount:   upload.Image.PageCount,
+		}
+	}
+	if err := s.jobRepo.CreateBatch(jobs); err != nil {
+		return nil, err
+	}
+
+	s.notifier.wake()
+
+	stored := make([]models.AnalysisJob, len(jobs))
+	for i, job := range jobs {
+		stored[i] = *job
+	}
+	return newAnalysisBatchStatus(batchID, stored), nil
+}
+
+func (s *analysisJobServiceImpl) GetBatch(batchID uuid.UUID, userID uuid.UUID) (*AnalysisBatchStatus, error) {
+	jobs, err := s.jobRepo.GetByBatchID(batchID)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 || jobs[0].UserID != userID {
+		return nil, ErrAnalysisJobNotFound
+	}
+	return newAnalysisBatchStatus(batchID, jobs), nil
+}
+
+// newAnalysisBatchStatus バッチのジョブを集計し、解析結果が重複しているものに印を付ける
+func newAnalysisBatchStatus(batchID uuid.UUID, jobs []models.AnalysisJob) *AnalysisBatchStatus {
+	batch := &AnalysisBatchStatus{
+		ID:     batchID,
+		Total:  len(jobs),
+		Drafts: make([]AnalysisBatchDraft, len(jobs)),
+	}
+
+	firstIndex := make(map[string]int, len(jobs))
+	for i := range jobs {
+		draft := AnalysisBatchDraft{
+			AnalysisJobStatus: *newAnalysisJobStatus(&jobs[i]),
+			FileName:          jobs[i].FileName,
+		}
+		switch draft.Status {
+		case models.AnalysisJobSucceeded:
+			batch.Succeeded++
+		case models.AnalysisJobFailed:
+			batch.Failed++
+		}
+
+		if result := draft.Result; result != nil {
+			if date, err := time.Parse("2006-01-02", result.Date); err == nil {
+				key := receiptDuplicateKey(date, result.Amount, result.Shop)
+				if first, found := firstIndex[key]; found {
+					draft.DuplicateOf = &first
+				} else {
+					firstIndex[key] = i
+				}
+			}
+		}
+		batch.Drafts[i] = draft
+	}
+	batch.Done = batch.Succeeded+batch.Failed == batch.Total
+	return batch
+}
+
+// AnalysisJobNotifier 解析ジョブの投入をワーカーに、状態の変化を購読者に伝える（同一プロセス内のみ）
+type AnalysisJobNotifier struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan AnalysisJobStatus]struct{}
+	queued      chan struct{}
+}
+
+// NewAnalysisJobNotifier AnalysisJobNotifierを作成
+func NewAnalysisJobNotifier() *AnalysisJobNotifier {
+	return &AnalysisJobNotifier{
+		subscribers: make(map[uuid.UUID]map[chan AnalysisJobStatus]struct{}),
+		queued:      make(chan struct{}, 1),
+	}
+}
+
+// wake 待機中のワーカーに新しいジョブがあることを伝える（既に通知済みの場合は何もしない）
+func (n *AnalysisJobNotifier) wake() {
+	select {
+	case n.queued <- struct{}{}:
+	default:
+	}
+}
+
+func (n *AnalysisJobNotifier) subscribe(id uuid.UUID) (<-chan AnalysisJobStatus, func()) {
+	ch := make(chan AnalysisJobStatus, 4)
+
+	n.mu.Lock()
+	if n.subscribers[id] == nil {
+		n.subscribers[id] = make(map[chan AnalysisJobStatus]struct{})
+	}
+	n.subscribers[id][ch] = struct{}{}
+	n.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			n.mu.Lock()
+			delete(n.subscribers[id], ch)
+			if len(n.subscribers[id]) == 0 {
+				delete(n.subscribers, id)
+			}
+			n.mu.Unlock()
+		})
+	}
+	return ch, cancel
+}
+
+// publish ジョブの状態を購読者に送る。受け取りが追いついていない購読者には送らない（次の状態か再取得で追いつく）
+func (n *AnalysisJobNotifier) publish(status *AnalysisJobStatus) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for ch := range n.subscribers[status.ID] {
+		select {
+		case ch <- *status:
+		default:
+		}
+	}
+}
@@ -0,0 +1,416 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/service"
+
+	"github.com/google/uuid"
+)
+
+// mockAnalysisJobRepository AnalysisJobRepositoryのモック（ワーカーから並行して呼ばれるためロックする）
+type mockAnalysisJobRepository struct {
+	mu   sync.Mutex
+	jobs map[uuid.UUID]*models.AnalysisJob
+}
+
+func newMockAnalysisJobRepository() *mockAnalysisJobRepository {
+	return &mockAnalysisJobRepository{jobs: make(map[uuid.UUID]*models.AnalysisJob)}
+}
+
+func (m *mockAnalysisJobRepository) Create(job *models.AnalysisJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job.ID = uuid.New()
+	job.CreatedAt = time.Now().Add(time.Duration(len(m.jobs)) * time.Millisecond)
+	stored := *job
+	m.jobs[job.ID] = &stored
+	return nil
+}
+
+func (m *mockAnalysisJobRepository) CreateBatch(jobs []*models.AnalysisJob) error {
+	for _, job := range jobs {
+		if err := m.Create(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mockAnalysisJobRepository) GetByID(id uuid.UUID) (*models.AnalysisJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, exists := m.jobs[id]
+	if !exists {
+		return nil, errors.New("record not found")
+	}
+	copied := *job
+	copied.ImageData, copied.PreviewData = nil, nil
+	return &copied, nil
+}
+
+func (m *mockAnalysisJobRepository) GetByBatchID(batchID uuid.UUID) ([]models.AnalysisJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var jobs []models.AnalysisJob
+	for _, job := range m.jobs {
+		if job.BatchID != nil && *job.BatchID == batchID {
+			copied := *job
+			copied.ImageData, copied.PreviewData = nil, nil
+			jobs = append(jobs, copied)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+	return jobs, nil
+}
+
+func (m *mockAnalysisJobRepository) ClaimNext(now time.Time) (*models.AnalysisJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var queued []*models.AnalysisJob
+	for _, job := range m.jobs {
+		if job.Status == models.AnalysisJobQueued {
+			queued = append(queued, job)
+		}
+	}
+	if len(queued) == 0 {
+		return nil, nil
+	}
+	sort.Slice(queued, func(i, j int) bool { return queued[i].CreatedAt.Before(queued[j].CreatedAt) })
+
+	job := queued[0]
+	job.Status = models.AnalysisJobProcessing
+	job.Attempts++
+	job.StartedAt = &now
+	copied := *job
+	return &copied, nil
+}
+
+func (m *mockAnalysisJobRepository) Requeue(job *models.AnalysisJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := m.jobs[job.ID]
+	stored.Status = models.AnalysisJobQueued
+	stored.Error = job.Error
+	stored.Attempts = job.Attempts
+	return nil
+}
+
+func (m *mockAnalysisJobRepository) Finish(job *models.AnalysisJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := m.jobs[job.ID]
+	stored.Status = job.Status
+	stored.Result = job.Result
+	stored.Error = job.Error
+	stored.CompletedAt = job.CompletedAt
+	stored.ImageData, stored.PreviewData = nil, nil
+	return nil
+}
+
+func (m *mockAnalysisJobRepository) RequeueInterrupted(startedBefore time.Time, maxAttempts int) (int64, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var requeued, failed int64
+	for _, job := range m.jobs {
+		if job.Status != models.AnalysisJobProcessing || job.StartedAt == nil || !job.StartedAt.Before(startedBefore) {
+			continue
+		}
+		if job.Attempts >= maxAttempts {
+			job.Status = models.AnalysisJobFailed
+			failed++
+			continue
+		}
+		job.Status = models.AnalysisJobQueued
+		requeued++
+	}
+	return requeued, failed, nil
+}
+
+func TestAnalysisJobService(t *testing.T) {
+	groupRepo := newMockGroupRepository()
+	userID := uuid.New()
+	otherID := uuid.New()
+	groupID := newReceiptTestGroup(groupRepo, userID)
+	_ = groupRepo.SetMemberRole(groupID, userID, models.GroupRoleOwner)
+
+	shopRepo := newMockShopRepository()
+	shopService := service.NewShopService(shopRepo, groupRepo)
+	shop, _ := shopService.CreateShop(&service.ShopParams{GroupID: groupID, Name: "セブンイレブン", Aliases: []string{"7-Eleven"}}, userID)
+
+	jobRepo := newMockAnalysisJobRepository()
+	notifier := service.NewAnalysisJobNotifier()
+	svc := service.NewAnalysisJobService(jobRepo, groupRepo, notifier)
+
+	var analyzeErr error
+	analyzed := 0
+	analyzer := &mockAIAnalyzer{
+		analyzeFunc: func(ctx context.Context, img *service.ReceiptImage) (*service.AnalyzeReceiptResult, error) {
+			analyzed++
+			if !strings.HasPrefix(string(img.Data), "jpeg") {
+				t.Errorf("expected stored image to be analyzed, got %q", img.Data)
+			}
+			if analyzeErr != nil {
+				return nil, analyzeErr
+			}
+			return &service.AnalyzeReceiptResult{Date: "2026-10-01", Shop: "7-Eleven 渋谷店", Amount: 580}, nil
+		},
+	}
+	draftRepo := newMockReceiptDraftRepository()
+	feedbackRepo := newMockAnalysisFeedbackRepository()
+	draftService := service.NewReceiptDraftService(draftRepo, feedbackRepo, service.NewReceiptService(newMockReceiptRepository(), groupRepo, shopRepo), 0)
+	pool := service.NewAnalysisWorkerPool(jobRepo, newTestAIUsageService(groupRepo, analyzer), service.NewAnalysisFeedbackService(feedbackRepo, groupRepo, shopService), draftService, notifier, 1, time.Minute)
+	img := &service.ReceiptImage{MIMEType: service.MIMETypeJPEG, Data: []byte("jpeg"), PageCount: 1}
+
+	t.Run("Submit Requires Group Membership", func(t *testing.T) {
+		if _, err := svc.SubmitJob(otherID, &groupID, img); !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("expected ErrPermissionDenied, got %v", err)
+		}
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		job, err := svc.SubmitJob(userID, &groupID, img)
+		if err != nil {
+			t.Fatalf("SubmitJob failed: %v", err)
+		}
+		if job.Status != models.AnalysisJobQueued {
+			t.Errorf("expected queued job, got %s", job.Status)
+		}
+
+		updates, unsubscribe, err := svc.WatchJob(job.ID, userID)
+		if err != nil {
+			t.Fatalf("WatchJob failed: %v", err)
+		}
+		defer unsubscribe()
+
+		processed, err := pool.ProcessNext(context.Background())
+		if err != nil || !processed {
+			t.Fatalf("expected job to be processed, got %v, %v", processed, err)
+		}
+
+		if update := <-updates; update.Status != models.AnalysisJobProcessing {
+			t.Errorf("expected processing update, got %s", update.Status)
+		}
+		if update := <-updates; update.Status != models.AnalysisJobSucceeded || update.Result == nil {
+			t.Errorf("expected succeeded update with result, got %+v", update)
+		}
+
+		got, err := svc.GetJob(job.ID, userID)
+		if err != nil {
+			t.Fatalf("GetJob failed: %v", err)
+		}
+		if !got.Done() || got.Result == nil || got.Result.Amount != 580 {
+			t.Fatalf("expected completed job with result, got %+v", got)
+		}
+		if got.Result.ShopID == nil || *got.Result.ShopID != shop.ID {
+			t.Errorf("expected shop to be matched, got %+v", got.Result)
+		}
+		if jobRepo.jobs[job.ID].ImageData != nil {
+			t.Error("expected image to be removed after analysis")
+		}
+		if got.Result.DraftID == nil || string(draftRepo.drafts[*got.Result.DraftID].ImageData) != "jpeg" {
+			t.Errorf("expected result to be saved as a draft with the image, got %v", got.Result.DraftID)
+		}
+	})
+
+	t.Run("Other User Cannot See Job", func(t *testing.T) {
+		job, _ := svc.SubmitJob(userID, nil, img)
+		if _, err := svc.GetJob(job.ID, otherID); !errors.Is(err, service.ErrAnalysisJobNotFound) {
+			t.Errorf("expected ErrAnalysisJobNotFound, got %v", err)
+		}
+		if _, _, err := svc.WatchJob(job.ID, otherID); !errors.Is(err, service.ErrAnalysisJobNotFound) {
+			t.Errorf("expected ErrAnalysisJobNotFound, got %v", err)
+		}
+		_, _ = pool.ProcessNext(context.Background())
+	})
+
+	t.Run("Retry Then Fail", func(t *testing.T) {
+		analyzeErr = errors.New("model overloaded")
+		defer func() { analyzeErr = nil }()
+		analyzed = 0
+
+		// 解析済みの画像は保存済みの結果が返るため、別の画像で試す
+		retried := &service.ReceiptImage{MIMEType: service.MIMETypeJPEG, Data: []byte("jpeg-retry"), PageCount: 1}
+		job, _ := svc.SubmitJob(userID, nil, retried)
+		for {
+			processed, err := pool.ProcessNext(context.Background())
+			if err != nil {
+				t.Fatalf("ProcessNext failed: %v", err)
+			}
+			if !processed {
+				break
+			}
+		}
+
+		got, _ := svc.GetJob(job.ID, userID)
+		if got.Status != models.AnalysisJobFailed || got.Error != "model overloaded" {
+			t.Errorf("expected failed job with error, got %+v", got)
+		}
+		if analyzed != 3 || got.Attempts != 3 {
+			t.Errorf("expected 3 attempts, got %d analyses and %d attempts", analyzed, got.Attempts)
+		}
+	})
+
+	t.Run("Stopped Job Is Requeued", func(t *testing.T) {
+		analyzeErr = context.Canceled
+		stopped := &service.ReceiptImage{MIMEType: service.MIMETypeJPEG, Data: []byte("jpeg-stop"), PageCount: 1}
+		job, _ := svc.SubmitJob(userID, nil, stopped)
+
+		// 停止（ctx のキャンセル）で中断したジョブは、試行回数に数えずに解析待ちに戻す
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if processed, err := pool.ProcessNext(ctx); err != nil || !processed {
+			t.Fatalf("expected job to be processed, got %v, %v", processed, err)
+		}
+		if got, _ := svc.GetJob(job.ID, userID); got.Status != models.AnalysisJobQueued || got.Attempts != 0 {
+			t.Errorf("expected stopped job to be queued without an attempt, got %s (%d attempts)", got.Status, got.Attempts)
+		}
+
+		analyzeErr = nil
+		if processed, err := pool.ProcessNext(context.Background()); err != nil || !processed {
+			t.Fatalf("expected job to be processed, got %v, %v", processed, err)
+		}
+		if got, _ := svc.GetJob(job.ID, userID); got.Status != models.AnalysisJobSucceeded {
+			t.Errorf("expected requeued job to succeed, got %s", got.Status)
+		}
+	})
+
+	t.Run("Interrupted Job Is Resumed", func(t *testing.T) {
+		startedAt := time.Now().Add(-time.Hour)
+		job, _ := svc.SubmitJob(userID, nil, img)
+		jobRepo.jobs[job.ID].Status = models.AnalysisJobProcessing
+		jobRepo.jobs[job.ID].Attempts = 1
+		jobRepo.jobs[job.ID].StartedAt = &startedAt
+		// タイムアウト前のジョブは他のサーバーで解析中のものとして戻さない
+		running, _ := svc.SubmitJob(userID, nil, img)
+		now := time.Now()
+		jobRepo.jobs[running.ID].Status = models.AnalysisJobProcessing
+		jobRepo.jobs[running.ID].Attempts = 1
+		jobRepo.jobs[running.ID].StartedAt = &now
+		// 中断を繰り返したジョブは失敗にする
+		exhausted, _ := svc.SubmitJob(userID, nil, img)
+		jobRepo.jobs[exhausted.ID].Status = models.AnalysisJobProcessing
+		jobRepo.jobs[exhausted.ID].Attempts = 3
+		jobRepo.jobs[exhausted.ID].StartedAt = &startedAt
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			pool.Run(ctx)
+			close(done)
+		}()
+
+		deadline := time.After(2 * time.Second)
+		for {
+			got, _ := svc.GetJob(job.ID, userID)
+			if got.Status == models.AnalysisJobSucceeded {
+				break
+			}
+			select {
+			case <-deadline:
+				t.Fatalf("expected interrupted job to be resumed, got %s", got.Status)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+		cancel()
+		<-done
+
+		if got, _ := svc.GetJob(running.ID, userID); got.Status != models.AnalysisJobProcessing {
+			t.Errorf("expected running job to be left alone, got %s", got.Status)
+		}
+		if got, _ := svc.GetJob(exhausted.ID, userID); got.Status != models.AnalysisJobFailed {
+			t.Errorf("expected exhausted job to fail, got %s", got.Status)
+		}
+	})
+}
+
+func TestAnalysisJobService_SubmitBatch(t *testing.T) {
+	groupRepo := newMockGroupRepository()
+	userID := uuid.New()
+	jobRepo := newMockAnalysisJobRepository()
+	notifier := service.NewAnalysisJobNotifier()
+	svc := service.NewAnalysisJobService(jobRepo, groupRepo, notifier)
+
+	results := map[string]*service.AnalyzeReceiptResult{
+		"a": {Date: "2026-10-01", Shop: "Lawson", Amount: 300},
+		"b": {Date: "2026-10-02", Shop: "FamilyMart", Amount: 450},
+		"c": {Date: "2026-10-01", Shop: "LAWSON", Amount: 300},
+	}
+	analyzer := &mockAIAnalyzer{
+		analyzeFunc: func(ctx context.Context, img *service.ReceiptImage) (*service.AnalyzeReceiptResult, error) {
+			result, found := results[string(img.Data)]
+			if !found {
+				return nil, service.ErrUnsupportedImageType
+			}
+			copied := *result
+			return &copied, nil
+		},
+	}
+	shopRepo := newMockShopRepository()
+	feedbackRepo := newMockAnalysisFeedbackRepository()
+	draftService := service.NewReceiptDraftService(newMockReceiptDraftRepository(), feedbackRepo, service.NewReceiptService(newMockReceiptRepository(), groupRepo, shopRepo), 0)
+	feedbackService := service.NewAnalysisFeedbackService(feedbackRepo, groupRepo, service.NewShopService(shopRepo, groupRepo))
+	pool := service.NewAnalysisWorkerPool(jobRepo, newTestAIUsageService(groupRepo, analyzer), feedbackService, draftService, notifier, 2, time.Minute)
+
+	var uploads []service.ReceiptUpload
+	for _, name := range []string{"a", "b", "c", "broken"} {
+		uploads = append(uploads, service.ReceiptUpload{
+			FileName: name + ".jpg",
+			Image:    &service.ReceiptImage{MIMEType: service.MIMETypeJPEG, Data: []byte(name), PageCount: 1},
+		})
+	}
+
+	t.Run("Empty Batch", func(t *testing.T) {
+		if _, err := svc.SubmitBatch(userID, nil, nil); !errors.Is(err, service.ErrEmptyReceiptBatch) {
+			t.Errorf("expected ErrEmptyReceiptBatch, got %v", err)
+		}
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		batch, err := svc.SubmitBatch(userID, nil, uploads)
+		if err != nil {
+			t.Fatalf("SubmitBatch failed: %v", err)
+		}
+		if batch.Total != 4 || batch.Done {
+			t.Errorf("expected 4 pending drafts, got %+v", batch)
+		}
+
+		for {
+			processed, err := pool.ProcessNext(context.Background())
+			if err != nil {
+				t.Fatalf("ProcessNext failed: %v", err)
+			}
+			if !processed {
+				break
+			}
+		}
+
+		got, err := svc.GetBatch(batch.ID, userID)
+		if err != nil {
+			t.Fatalf("GetBatch failed: %v", err)
+		}
+		if !got.Done || got.Succeeded != 3 || got.Failed != 1 {
+			t.Fatalf("expected 3 succeeded and 1 failed, got %+v", got)
+		}
+		if got.Drafts[0].FileName != "a.jpg" || got.Drafts[3].Status != models.AnalysisJobFailed {
+			t.Errorf("expected drafts in upload order, got %+v", got.Drafts)
+		}
+		if got.Drafts[2].DuplicateOf == nil || *got.Drafts[2].DuplicateOf != 0 {
+			t.Errorf("expected third draft to be marked as duplicate of the first, got %v", got.Drafts[2].DuplicateOf)
+		}
+		if got.Drafts[1].DuplicateOf != nil {
+			t.Errorf("expected second draft not to be a duplicate, got %v", *got.Drafts[1].DuplicateOf)
+		}
+
+		if _, err := svc.GetBatch(batch.ID, uuid.New()); !errors.Is(err, service.ErrAnalysisJobNotFound) {
+			t.Errorf("expected ErrAnalysisJobNotFound for other user, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+)
+
+const (
+	// DefaultAnalysisWorkers 同時に実行する解析の既定の数
+	DefaultAnalysisWorkers = 2
+	// maxAnalysisJobAttempts 解析に失敗したジョブを再試行する回数の上限（初回を含む）
+	maxAnalysisJobAttempts = 3
+	// analysisJobPollInterval 他のプロセスが投入したジョブを拾うための確認間隔
+	analysisJobPollInterval = 5 * time.Second
+	// maxAnalysisJobErrorLength 保存するエラーメッセージの最大文字数
+	maxAnalysisJobErrorLength = 500
+	// analysisJobSweepInterval 中断されたジョブ（停止したサーバーで解析中のままのジョブ）を確認する間隔
+	analysisJobSweepInterval = time.Minute
+	// analysisJobStaleMargin AI解析のタイムアウトに加えて、解析結果の補正・下書きの保存を待つ時間
+	analysisJobStaleMargin = time.Minute
+)
+
+// AnalysisWorkerPool 解析待ちのジョブを取り出して AIUsageService 経由で解析するバックグラウンドのワーカー群
+type AnalysisWorkerPool struct {
+	jobRepo         repository.AnalysisJobRepository
+	usageService    AIUsageService
+	feedbackService AnalysisFeedbackService
+	draftService    ReceiptDraftService
+	notifier        *AnalysisJobNotifier
+	concurrency     int
+	timeout         time.Duration
+}
+
+// NewAnalysisWorkerPool AnalysisWorkerPoolを作成（concurrency・timeout が0以下の場合は既定値）。
+// timeout はAI解析1回あたりのタイムアウトで、中断されたジョブを見分けるのに使う
+func NewAnalysisWorkerPool(jobRepo repository.AnalysisJobRepository, usageService AIUsageService, feedbackService AnalysisFeedbackService, draftService ReceiptDraftService, notifier *AnalysisJobNotifier, concurrency int, timeout time.Duration) *AnalysisWorkerPool {
+	if concurrency <= 0 {
+		concurrency = DefaultAnalysisWorkers
+	}
+	if timeout <= 0 {
+		timeout = defaultAITimeout
+	}
+	return &AnalysisWorkerPool{
+		jobRepo:         jobRepo,
+		usageService:    usageService,
+		feedbackService: feedbackService,
+		draftService:    draftService,
+		notifier:        notifier,
+		concurrency:     concurrency,
+		timeout:         timeout,
+	}
+}
+
+// Run ctx がキャンセルされるまでワーカーを動かし、解析中のジョブが解析待ちに戻されるまで待つ。
+// 起動時と一定間隔で、停止した（他の）サーバーで中断されたジョブを解析待ちに戻す
+func (p *AnalysisWorkerPool) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < p.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(ctx)
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		runEvery(ctx, analysisJobSweepInterval, "analysis job sweep", p.requeueInterrupted)
+	}()
+	wg.Wait()
+}
+
+// requeueInterrupted タイムアウトを過ぎても解析中のままのジョブを中断されたものとして解析待ちに戻す。
+// 再試行の上限に達したジョブは失敗にする
+func (p *AnalysisWorkerPool) requeueInterrupted() error {
+	requeued, failed, err := p.jobRepo.RequeueInterrupted(time.Now().Add(-p.timeout-analysisJobStaleMargin), maxAnalysisJobAttempts)
+	if err != nil {
+		return err
+	}
+	if requeued > 0 {
+		log.Printf("requeued %d interrupted analysis jobs", requeued)
+		p.notifier.wake()
+	}
+	if failed > 0 {
+		log.Printf("failed %d analysis jobs interrupted too many times", failed)
+	}
+	return nil
+}
+
+func (p *AnalysisWorkerPool) work(ctx context.Context) {
+	ticker := time.NewTicker(analysisJobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// 解析待ちのジョブがなくなるまで続けて処理する
+		for ctx.Err() == nil {
+			processed, err := p.ProcessNext(ctx)
+			if err != nil {
+				log.Printf("analysis job failed: %v", err)
+				break
+			}
+			if !processed {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.notifier.queued:
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessNext 解析待ちのジョブを1件解析する。解析待ちのジョブがなかった場合は false を返す
+func (p *AnalysisWorkerPool) ProcessNext(ctx context.Context) (bool, error) {
+	job, err := p.jobRepo.ClaimNext(time.Now())
+	if err != nil {
+		return false, err
+	}
+	if job == nil {
+		return false, nil
+	}
+	// 他にも解析待ちのジョブがあれば、待機中の別のワーカーに処理させる
+	p.notifier.wake()
+	p.notifier.publish(newAnalysisJobStatus(job))
+
+	img := &ReceiptImage{
+		MIMEType:  job.MIMEType,
+		Data:      job.ImageData,
+		PageCount: job.PageCount,
+		Preview:   job.PreviewData,
+	}
+	result, err := p.usageService.AnalyzeReceipt(ctx, job.UserID, job.GroupID, img)
+	if err != nil {
+		if ctx.Err() != nil {
+			// 停止による中断は失敗として数えず、解析待ちに戻して次回の起動時（または他のサーバー）で解析し直す
+			job.Status = models.AnalysisJobQueued
+			job.Attempts--
+			if err := p.jobRepo.Requeue(job); err != nil {
+				return true, err
+			}
+			p.notifier.publish(newAnalysisJobStatus(job))
+			return true, nil
+		}
+		return true, p.fail(job, err)
+	}
+
+	if job.GroupID != nil {
+		// 店舗マスタとの照合・過去の修正による補正に失敗しても、解析結果はそのまま返す
+		if err := p.feedbackService.RefineAnalysis(*job.GroupID, job.UserID, result); err != nil {
+			log.Printf("failed to refine analysis job %s: %v", job.ID, err)
+		}
+	}
+
+	// 利用者が画面を離れても結果を失わないよう、画像とともに下書きとして保存する
+	if _, err := p.draftService.CreateDraft(job.UserID, job.GroupID, img, result); err != nil {
+		log.Printf("failed to save receipt draft for analysis job %s: %v", job.ID, err)
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return true, p.fail(job, err)
+	}
+
+	now := time.Now()
+	job.Status = models.AnalysisJobSucceeded
+	job.Result = string(encoded)
+	job.Error = ""
+	job.CompletedAt = &now
+	if err := p.jobRepo.Finish(job); err != nil {
+		return true, err
+	}
+	p.notifier.publish(newAnalysisJobStatus(job))
+	return true, nil
+}
+
+// fail 解析に失敗したジョブを、再試行できる場合は解析待ちに戻し、できない場合は失敗として保存する
+func (p *AnalysisWorkerPool) fail(job *models.AnalysisJob, cause error) error {
+	job.Error = truncateRunes(cause.Error(), maxAnalysisJobErrorLength)
+
+	retryable := !errors.Is(cause, ErrUnsupportedImageType) && !errors.Is(cause, ErrAIQuotaExceeded)
+	if job.Attempts < maxAnalysisJobAttempts && retryable {
+		job.Status = models.AnalysisJobQueued
+		if err := p.jobRepo.Requeue(job); err != nil {
+			return err
+		}
+		p.notifier.publish(newAnalysisJobStatus(job))
+		return nil
+	}
+
+	now := time.Now()
+	job.Status = models.AnalysisJobFailed
+	job.CompletedAt = &now
+	if err := p.jobRepo.Finish(job); err != nil {
+		return err
+	}
+	p.notifier.publish(newAnalysisJobStatus(job))
+	return nil
+}
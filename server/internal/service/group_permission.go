@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrPermissionDenied グループ内のロールで許可されていない操作に対するエラー
+	ErrPermissionDenied = errors.New("you do not have permission to perform this action in this group")
+	// ErrInvalidRole 存在しないロールが指定された場合のエラー
+	ErrInvalidRole = errors.New("invalid group role")
+	// ErrGroupArchived アーカイブ中のグループを変更しようとした場合のエラー
+	ErrGroupArchived = errors.New("group is archived and read-only")
+)
+
+// GroupPermission グループ内で行える操作の種類
+type GroupPermission int
+
+const (
+	PermissionViewGroup         GroupPermission = iota // グループ・レシート・サマリーの閲覧
+	PermissionCreateReceipt                            // レシートの登録・編集・削除
+	PermissionSettle                                   // 精算の記録
+	PermissionEditGroup                                // グループ名の変更
+	PermissionManageMembers                            // メンバーの招待・削除
+	PermissionManageRoles                              // メンバーのロール変更
+	PermissionDeleteGroup                              // グループの削除
+	PermissionTransferOwnership                        // オーナー権限の譲渡
+	PermissionArchiveGroup                             // グループのアーカイブ・アーカイブ解除
+)
+
+// archivedGroupPermissions アーカイブ中のグループでも行える操作
+var archivedGroupPermissions = map[GroupPermission]bool{
+	PermissionViewGroup:         true,
+	PermissionDeleteGroup:       true,
+	PermissionTransferOwnership: true,
+	PermissionArchiveGroup:      true,
+}
+
+// groupRolePermissions ロールごとの権限表
+var groupRolePermissions = map[string][]GroupPermission{
+	models.GroupRoleOwner: {
+		PermissionViewGroup, PermissionCreateReceipt, PermissionSettle,
+		PermissionEditGroup, PermissionManageMembers, PermissionManageRoles, PermissionDeleteGroup,
+		PermissionTransferOwnership, PermissionArchiveGroup,
+	},
+	models.GroupRoleAdmin: {
+		PermissionViewGroup, PermissionCreateReceipt, PermissionSettle,
+		PermissionEditGroup, PermissionManageMembers, PermissionManageRoles, PermissionArchiveGroup,
+	},
+	models.GroupRoleMember: {
+		PermissionViewGroup, PermissionCreateReceipt, PermissionSettle,
+	},
+	models.GroupRoleViewer: {
+		PermissionViewGroup,
+	},
+}
+
+// IsValidGroupRole ロール名が定義済みかどうか
+func IsValidGroupRole(role string) bool {
+	_, ok := groupRolePermissions[role]
+	return ok
+}
+
+// HasGroupPermission ロールが指定の権限を持つかどうか
+func HasGroupPermission(role string, permission GroupPermission) bool {
+	for _, p := range groupRolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeGroup ユーザーがグループ内で指定の操作を行えるか確認し、ユーザーのロールを返す。
+// メンバーでない場合も ErrPermissionDenied を返す。アーカイブ中のグループは閲覧などの一部の操作のみ許可する。
+func authorizeGroup(groupRepo repository.GroupRepository, groupID uuid.UUID, userID uuid.UUID, permission GroupPermission) (string, error) {
+	group, err := groupRepo.GetByID(groupID)
+	if err != nil {
+		return "", ErrGroupNotFound
+	}
+
+	role, err := groupRepo.GetMemberRole(groupID, userID)
+	if err != nil {
+		return "", err
+	}
+	if !HasGroupPermission(role, permission) {
+		return role, ErrPermissionDenied
+	}
+	if group.ArchivedAt != nil && !archivedGroupPermissions[permission] {
+		return role, ErrGroupArchived
+	}
+	return role, nil
+}
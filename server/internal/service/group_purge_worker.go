@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"receipt/server/internal/repository"
+)
+
+// GroupPurgeWorker 完全削除が要求されたグループと、復元期限を過ぎた削除済みグループを定期的に物理削除するバックグラウンドジョブ
+type GroupPurgeWorker struct {
+	groupRepo repository.GroupRepository
+	interval  time.Duration
+}
+
+// NewGroupPurgeWorker GroupPurgeWorkerを作成
+func NewGroupPurgeWorker(groupRepo repository.GroupRepository, interval time.Duration) *GroupPurgeWorker {
+	return &GroupPurgeWorker{
+		groupRepo: groupRepo,
+		interval:  interval,
+	}
+}
+
+// Run ctx がキャンセルされるまで一定間隔で完全削除を実行する
+func (w *GroupPurgeWorker) Run(ctx context.Context) {
+	runEvery(ctx, w.interval, "group purge", func() error {
+		_, err := w.PurgeDue(time.Now())
+		return err
+	})
+}
+
+// PurgeDue 完全削除の対象となっているグループを削除し、削除した件数を返す
+func (w *GroupPurgeWorker) PurgeDue(now time.Time) (int, error) {
+	ids, err := w.groupRepo.GetGroupIDsDueForPurge(now.Add(-GroupRestoreWindow))
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		if err := w.groupRepo.Purge(id); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
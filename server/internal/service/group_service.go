@@ -2,6 +2,9 @@ package service
 
 import (
 	"errors"
+	"math"
+	"time"
+
 	"receipt/server/internal/models"
 	"receipt/server/internal/repository"
 
@@ -10,39 +13,74 @@ import (
 
 var (
 	// ErrGroupNotFound グループが見つからない場合のエラー
-	ErrGroupNotFound       = errors.New("group not found")
-	// ErrNotOwner グループオーナー以外の操作に対するエラー
-	ErrNotOwner            = errors.New("only group owner can perform this action")
+	ErrGroupNotFound = errors.New("group not found")
 	// ErrInviteUserNotFound 招待相手のユーザーが見つからない場合のエラー
-	ErrInviteUserNotFound  = errors.New("招待相手のユーザーが見つかりません。先に相手の方がアカウント登録を完了しているか確認してください。")
+	ErrInviteUserNotFound = errors.New("招待相手のユーザーが見つかりません。先に相手の方がアカウント登録を完了しているか確認してください。")
 	// ErrAlreadyMember 既にメンバーの場合のエラー
-	ErrAlreadyMember       = errors.New("user is already a member of this group")
+	ErrAlreadyMember = errors.New("user is already a member of this group")
 	// ErrOwnerCannotBeRemoved オーナー自身を削除しようとした場合のエラー
 	ErrOwnerCannotBeRemoved = errors.New("owner cannot be removed from the group")
 	// ErrMemberNotFound 削除対象メンバーが見つからない場合のエラー
-	ErrMemberNotFound      = errors.New("user to remove not found")
+	ErrMemberNotFound = errors.New("user to remove not found")
+	// ErrCannotChangeOwnerRole オーナーのロールを変更しようとした場合のエラー
+	ErrCannotChangeOwnerRole = errors.New("owner's role cannot be changed")
+	// ErrOwnerCannotLeave オーナーが譲渡せずにグループを退出しようとした場合のエラー
+	ErrOwnerCannotLeave = errors.New("owner must transfer ownership before leaving the group")
+	// ErrOutstandingBalance 未精算の残高が残っている状態で退出しようとした場合のエラー
+	ErrOutstandingBalance = errors.New("member has an outstanding balance in this group")
+	// ErrAlreadyOwner 既にオーナーであるユーザーへ譲渡しようとした場合のエラー
+	ErrAlreadyOwner = errors.New("user is already the owner of this group")
+	// ErrRestoreWindowExpired 復元期限を過ぎた削除済みグループを復元しようとした場合のエラー
+	ErrRestoreWindowExpired = errors.New("restore window for the deleted group has expired")
+	// ErrPurgeConfirmationMismatch 完全削除の確認入力がグループ名と一致しない場合のエラー
+	ErrPurgeConfirmationMismatch = errors.New("confirmation does not match the group name")
 )
 
+// GroupRestoreWindow 削除したグループを復元できる期間。過ぎたグループはバックグラウンドで完全削除される
+const GroupRestoreWindow = 30 * 24 * time.Hour
+
+// DeletedGroup 復元可能な削除済みグループ
+type DeletedGroup struct {
+	ID              uuid.UUID `json:"id"`
+	Name            string    `json:"name"`
+	DeletedAt       time.Time `json:"deleted_at"`
+	RestorableUntil time.Time `json:"restorable_until"`
+}
+
 // GroupService グループの管理に関するビジネスロジックインターフェース
 type GroupService interface {
 	CreateGroup(name string, ownerID uuid.UUID) (*models.Group, error)
-	InviteMember(groupID uuid.UUID, ownerID uuid.UUID, email string) error
-	RemoveMember(groupID uuid.UUID, ownerID uuid.UUID, memberID uuid.UUID) error
-	GetMyGroups(userID uuid.UUID) ([]models.Group, error)
-	UpdateGroup(groupID uuid.UUID, ownerID uuid.UUID, name string) (*models.Group, error)
-	DeleteGroup(groupID uuid.UUID, ownerID uuid.UUID) error
+	InviteMember(groupID uuid.UUID, actorID uuid.UUID, email string) error
+	RemoveMember(groupID uuid.UUID, actorID uuid.UUID, memberID uuid.UUID) error
+	GetMyGroups(userID uuid.UUID, includeArchived bool) ([]models.Group, error)
+	UpdateGroup(groupID uuid.UUID, actorID uuid.UUID, name string) (*models.Group, error)
+	DeleteGroup(groupID uuid.UUID, actorID uuid.UUID) error
+	ChangeMemberRole(groupID uuid.UUID, actorID uuid.UUID, memberID uuid.UUID, role string) (*models.Group, error)
+	TransferOwnership(groupID uuid.UUID, actorID uuid.UUID, newOwnerID uuid.UUID) (*models.Group, error)
+	LeaveGroup(groupID uuid.UUID, userID uuid.UUID, force bool) error
+	ArchiveGroup(groupID uuid.UUID, actorID uuid.UUID) (*models.Group, error)
+	UnarchiveGroup(groupID uuid.UUID, actorID uuid.UUID) (*models.Group, error)
+	GetDeletedGroups(userID uuid.UUID) ([]DeletedGroup, error)
+	RestoreGroup(groupID uuid.UUID, actorID uuid.UUID) (*models.Group, error)
+	PurgeGroup(groupID uuid.UUID, actorID uuid.UUID, confirmation string) error
 }
 
 type groupServiceImpl struct {
-	groupRepo repository.GroupRepository
-	userRepo  repository.UserRepository
+	groupRepo      repository.GroupRepository
+	userRepo       repository.UserRepository
+	receiptRepo    repository.ReceiptRepository
+	settlementRepo repository.SettlementRepository
+	reportRepo     repository.ReportRepository
 }
 
 // NewGroupService GroupServiceの実装を作成
-func NewGroupService(groupRepo repository.GroupRepository, userRepo repository.UserRepository) GroupService {
+func NewGroupService(groupRepo repository.GroupRepository, userRepo repository.UserRepository, receiptRepo repository.ReceiptRepository, settlementRepo repository.SettlementRepository, reportRepo repository.ReportRepository) GroupService {
 	return &groupServiceImpl{
-		groupRepo: groupRepo,
-		userRepo:  userRepo,
+		groupRepo:      groupRepo,
+		userRepo:       userRepo,
+		receiptRepo:    receiptRepo,
+		settlementRepo: settlementRepo,
+		reportRepo:     reportRepo,
 	}
 }
 
@@ -64,18 +102,21 @@ func (s *groupServiceImpl) CreateGroup(name string, ownerID uuid.UUID) (*models.
 	if err := s.groupRepo.AddMember(&group, user); err != nil {
 		return nil, err
 	}
+	if err := s.groupRepo.SetMemberRole(group.ID, user.ID, models.GroupRoleOwner); err != nil {
+		return nil, err
+	}
 
 	return s.groupRepo.GetByIDWithMembers(group.ID)
 }
 
-func (s *groupServiceImpl) InviteMember(groupID uuid.UUID, ownerID uuid.UUID, email string) error {
+func (s *groupServiceImpl) InviteMember(groupID uuid.UUID, actorID uuid.UUID, email string) error {
 	group, err := s.groupRepo.GetByID(groupID)
 	if err != nil {
 		return ErrGroupNotFound
 	}
 
-	if group.OwnerID != ownerID {
-		return ErrNotOwner
+	if _, err := authorizeGroup(s.groupRepo, groupID, actorID, PermissionManageMembers); err != nil {
+		return err
 	}
 
 	userToInvite, err := s.userRepo.GetByEmail(email)
@@ -94,20 +135,30 @@ func (s *groupServiceImpl) InviteMember(groupID uuid.UUID, ownerID uuid.UUID, em
 	return s.groupRepo.AddMember(group, userToInvite)
 }
 
-func (s *groupServiceImpl) RemoveMember(groupID uuid.UUID, ownerID uuid.UUID, memberID uuid.UUID) error {
+func (s *groupServiceImpl) RemoveMember(groupID uuid.UUID, actorID uuid.UUID, memberID uuid.UUID) error {
 	group, err := s.groupRepo.GetByID(groupID)
 	if err != nil {
 		return ErrGroupNotFound
 	}
 
-	if group.OwnerID != ownerID {
-		return ErrNotOwner
+	actorRole, err := authorizeGroup(s.groupRepo, groupID, actorID, PermissionManageMembers)
+	if err != nil {
+		return err
 	}
 
 	if memberID == group.OwnerID {
 		return ErrOwnerCannotBeRemoved
 	}
 
+	// 管理者を外せるのはオーナーのみ
+	memberRole, err := s.groupRepo.GetMemberRole(groupID, memberID)
+	if err != nil {
+		return err
+	}
+	if memberRole == models.GroupRoleAdmin && actorRole != models.GroupRoleOwner {
+		return ErrPermissionDenied
+	}
+
 	userToRemove, err := s.userRepo.GetByID(memberID)
 	if err != nil {
 		return ErrMemberNotFound
@@ -116,18 +167,29 @@ func (s *groupServiceImpl) RemoveMember(groupID uuid.UUID, ownerID uuid.UUID, me
 	return s.groupRepo.RemoveMember(group, userToRemove)
 }
 
-func (s *groupServiceImpl) GetMyGroups(userID uuid.UUID) ([]models.Group, error) {
-	return s.groupRepo.GetGroupsByUserID(userID)
+func (s *groupServiceImpl) GetMyGroups(userID uuid.UUID, includeArchived bool) ([]models.Group, error) {
+	groups, err := s.groupRepo.GetGroupsByUserID(userID)
+	if err != nil || includeArchived {
+		return groups, err
+	}
+
+	active := make([]models.Group, 0, len(groups))
+	for _, g := range groups {
+		if g.ArchivedAt == nil {
+			active = append(active, g)
+		}
+	}
+	return active, nil
 }
 
-func (s *groupServiceImpl) UpdateGroup(groupID uuid.UUID, ownerID uuid.UUID, name string) (*models.Group, error) {
+func (s *groupServiceImpl) UpdateGroup(groupID uuid.UUID, actorID uuid.UUID, name string) (*models.Group, error) {
 	group, err := s.groupRepo.GetByID(groupID)
 	if err != nil {
 		return nil, ErrGroupNotFound
 	}
 
-	if group.OwnerID != ownerID {
-		return nil, ErrNotOwner
+	if _, err := authorizeGroup(s.groupRepo, groupID, actorID, PermissionEditGroup); err != nil {
+		return nil, err
 	}
 
 	group.Name = name
@@ -138,15 +200,226 @@ func (s *groupServiceImpl) UpdateGroup(groupID uuid.UUID, ownerID uuid.UUID, nam
 	return s.groupRepo.GetByIDWithMembers(group.ID)
 }
 
-func (s *groupServiceImpl) DeleteGroup(groupID uuid.UUID, ownerID uuid.UUID) error {
+func (s *groupServiceImpl) DeleteGroup(groupID uuid.UUID, actorID uuid.UUID) error {
 	group, err := s.groupRepo.GetByID(groupID)
 	if err != nil {
 		return ErrGroupNotFound
 	}
 
-	if group.OwnerID != ownerID {
-		return ErrNotOwner
+	if _, err := authorizeGroup(s.groupRepo, groupID, actorID, PermissionDeleteGroup); err != nil {
+		return err
 	}
 
 	return s.groupRepo.Delete(group)
 }
+
+func (s *groupServiceImpl) ChangeMemberRole(groupID uuid.UUID, actorID uuid.UUID, memberID uuid.UUID, role string) (*models.Group, error) {
+	// オーナーの変更はロール変更ではなくオーナー権限の譲渡として扱う
+	if !IsValidGroupRole(role) || role == models.GroupRoleOwner {
+		return nil, ErrInvalidRole
+	}
+
+	group, err := s.groupRepo.GetByID(groupID)
+	if err != nil {
+		return nil, ErrGroupNotFound
+	}
+
+	actorRole, err := authorizeGroup(s.groupRepo, groupID, actorID, PermissionManageRoles)
+	if err != nil {
+		return nil, err
+	}
+
+	if memberID == group.OwnerID {
+		return nil, ErrCannotChangeOwnerRole
+	}
+
+	memberRole, err := s.groupRepo.GetMemberRole(groupID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if memberRole == "" {
+		return nil, ErrMemberNotFound
+	}
+
+	// 管理者の任命・解任はオーナーのみ
+	if actorRole != models.GroupRoleOwner && (memberRole == models.GroupRoleAdmin || role == models.GroupRoleAdmin) {
+		return nil, ErrPermissionDenied
+	}
+
+	if err := s.groupRepo.SetMemberRole(groupID, memberID, role); err != nil {
+		return nil, err
+	}
+
+	return s.groupRepo.GetByIDWithMembers(groupID)
+}
+
+func (s *groupServiceImpl) TransferOwnership(groupID uuid.UUID, actorID uuid.UUID, newOwnerID uuid.UUID) (*models.Group, error) {
+	group, err := s.groupRepo.GetByID(groupID)
+	if err != nil {
+		return nil, ErrGroupNotFound
+	}
+
+	if _, err := authorizeGroup(s.groupRepo, groupID, actorID, PermissionTransferOwnership); err != nil {
+		return nil, err
+	}
+
+	if newOwnerID == group.OwnerID {
+		return nil, ErrAlreadyOwner
+	}
+
+	isMember, err := s.groupRepo.IsMember(groupID, newOwnerID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrMemberNotFound
+	}
+
+	if err := s.groupRepo.TransferOwnership(group, newOwnerID); err != nil {
+		return nil, err
+	}
+
+	return s.groupRepo.GetByIDWithMembers(groupID)
+}
+
+func (s *groupServiceImpl) LeaveGroup(groupID uuid.UUID, userID uuid.UUID, force bool) error {
+	group, err := s.groupRepo.GetByIDWithMembers(groupID)
+	if err != nil {
+		return ErrGroupNotFound
+	}
+
+	if _, err := authorizeGroup(s.groupRepo, groupID, userID, PermissionViewGroup); err != nil {
+		return err
+	}
+
+	// オーナー不在のグループを作らないよう、先に譲渡（またはグループ削除）を求める
+	if group.OwnerID == userID {
+		return ErrOwnerCannotLeave
+	}
+
+	if !force {
+		balance, err := s.outstandingBalance(group, userID)
+		if err != nil {
+			return err
+		}
+		if balance != 0 {
+			return ErrOutstandingBalance
+		}
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	return s.groupRepo.RemoveMember(group, user)
+}
+
+// outstandingBalance 全期間のレシートと精算から、メンバーの累積残高（支払うべき額ならプラス）を計算する。
+// 一部だけ支払った累積精算も残高に反映する
+func (s *groupServiceImpl) outstandingBalance(group *models.Group, userID uuid.UUID) (int, error) {
+	periods, err := s.groupRepo.GetMembershipPeriods(group.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	// 精算月が先のレシート・精算も含める
+	balances, err := calculateBalances(s.reportRepo, s.receiptRepo, s.settlementRepo, group.ID, periods, math.MaxInt32, 12)
+	if err != nil {
+		return 0, err
+	}
+	return balances[userID], nil
+}
+
+func (s *groupServiceImpl) ArchiveGroup(groupID uuid.UUID, actorID uuid.UUID) (*models.Group, error) {
+	return s.setArchived(groupID, actorID, true)
+}
+
+func (s *groupServiceImpl) UnarchiveGroup(groupID uuid.UUID, actorID uuid.UUID) (*models.Group, error) {
+	return s.setArchived(groupID, actorID, false)
+}
+
+func (s *groupServiceImpl) setArchived(groupID uuid.UUID, actorID uuid.UUID, archived bool) (*models.Group, error) {
+	group, err := s.groupRepo.GetByID(groupID)
+	if err != nil {
+		return nil, ErrGroupNotFound
+	}
+
+	if _, err := authorizeGroup(s.groupRepo, groupID, actorID, PermissionArchiveGroup); err != nil {
+		return nil, err
+	}
+
+	if archived && group.ArchivedAt == nil {
+		now := time.Now()
+		group.ArchivedAt = &now
+	} else if !archived {
+		group.ArchivedAt = nil
+	}
+
+	if err := s.groupRepo.Update(group); err != nil {
+		return nil, err
+	}
+
+	return s.groupRepo.GetByIDWithMembers(groupID)
+}
+
+func (s *groupServiceImpl) GetDeletedGroups(userID uuid.UUID) ([]DeletedGroup, error) {
+	groups, err := s.groupRepo.GetDeletedGroupsByOwner(userID, time.Now().Add(-GroupRestoreWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]DeletedGroup, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, DeletedGroup{
+			ID:              g.ID,
+			Name:            g.Name,
+			DeletedAt:       g.DeletedAt.Time,
+			RestorableUntil: g.DeletedAt.Time.Add(GroupRestoreWindow),
+		})
+	}
+	return result, nil
+}
+
+func (s *groupServiceImpl) RestoreGroup(groupID uuid.UUID, actorID uuid.UUID) (*models.Group, error) {
+	group, err := s.groupRepo.GetDeletedByID(groupID)
+	if err != nil {
+		return nil, ErrGroupNotFound
+	}
+
+	// 削除済みのグループはロールではなくオーナーかどうかで判定する
+	if group.OwnerID != actorID {
+		return nil, ErrPermissionDenied
+	}
+
+	if group.PurgeRequestedAt != nil || time.Since(group.DeletedAt.Time) > GroupRestoreWindow {
+		return nil, ErrRestoreWindowExpired
+	}
+
+	if err := s.groupRepo.Restore(group); err != nil {
+		return nil, err
+	}
+
+	return s.groupRepo.GetByIDWithMembers(groupID)
+}
+
+// PurgeGroup グループの完全削除を要求する。確認としてグループ名の入力を求め、削除自体はバックグラウンドジョブが行う
+func (s *groupServiceImpl) PurgeGroup(groupID uuid.UUID, actorID uuid.UUID, confirmation string) error {
+	group, err := s.groupRepo.GetByID(groupID)
+	if err != nil {
+		group, err = s.groupRepo.GetDeletedByID(groupID)
+		if err != nil {
+			return ErrGroupNotFound
+		}
+	}
+
+	if group.OwnerID != actorID {
+		return ErrPermissionDenied
+	}
+
+	if confirmation != group.Name {
+		return ErrPurgeConfirmationMismatch
+	}
+
+	return s.groupRepo.RequestPurge(group)
+}
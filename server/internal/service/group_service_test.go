@@ -9,17 +9,24 @@ import (
 	"receipt/server/internal/service"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type mockGroupRepository struct {
 	groups       map[uuid.UUID]*models.Group
-	groupMembers map[uuid.UUID][]uuid.UUID // groupID -> []userID
+	groupMembers map[uuid.UUID][]uuid.UUID          // groupID -> []userID
+	memberRoles  map[uuid.UUID]map[uuid.UUID]string // groupID -> userID -> role
+	periods      []models.GroupMembershipPeriod
+	deleted      map[uuid.UUID]*models.Group // 論理削除されたグループ
+	purged       []uuid.UUID
 }
 
 func newMockGroupRepository() *mockGroupRepository {
 	return &mockGroupRepository{
 		groups:       make(map[uuid.UUID]*models.Group),
+		deleted:      make(map[uuid.UUID]*models.Group),
 		groupMembers: make(map[uuid.UUID][]uuid.UUID),
+		memberRoles:  make(map[uuid.UUID]map[uuid.UUID]string),
 	}
 }
 
@@ -63,8 +70,72 @@ func (m *mockGroupRepository) Delete(group *models.Group) error {
 	if _, exists := m.groups[group.ID]; !exists {
 		return errors.New("record not found")
 	}
+	stored := m.groups[group.ID]
+	stored.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	m.deleted[group.ID] = stored
 	delete(m.groups, group.ID)
-	delete(m.groupMembers, group.ID)
+	return nil
+}
+
+func (m *mockGroupRepository) GetDeletedByID(id uuid.UUID) (*models.Group, error) {
+	group, exists := m.deleted[id]
+	if !exists {
+		return nil, errors.New("record not found")
+	}
+	copied := *group
+	return &copied, nil
+}
+
+func (m *mockGroupRepository) GetDeletedGroupsByOwner(ownerID uuid.UUID, deletedSince time.Time) ([]models.Group, error) {
+	var result []models.Group
+	for _, g := range m.deleted {
+		if g.OwnerID == ownerID && !g.DeletedAt.Time.Before(deletedSince) && g.PurgeRequestedAt == nil {
+			result = append(result, *g)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockGroupRepository) Restore(group *models.Group) error {
+	stored, exists := m.deleted[group.ID]
+	if !exists {
+		return errors.New("record not found")
+	}
+	stored.DeletedAt = gorm.DeletedAt{}
+	m.groups[group.ID] = stored
+	delete(m.deleted, group.ID)
+	return nil
+}
+
+func (m *mockGroupRepository) RequestPurge(group *models.Group) error {
+	if _, exists := m.groups[group.ID]; exists {
+		_ = m.Delete(group)
+	}
+	stored, exists := m.deleted[group.ID]
+	if !exists {
+		return errors.New("record not found")
+	}
+	now := time.Now()
+	stored.PurgeRequestedAt = &now
+	return nil
+}
+
+func (m *mockGroupRepository) GetGroupIDsDueForPurge(deletedBefore time.Time) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for id, g := range m.deleted {
+		if g.PurgeRequestedAt != nil || g.DeletedAt.Time.Before(deletedBefore) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (m *mockGroupRepository) Purge(groupID uuid.UUID) error {
+	delete(m.groups, groupID)
+	delete(m.deleted, groupID)
+	delete(m.groupMembers, groupID)
+	delete(m.memberRoles, groupID)
+	m.purged = append(m.purged, groupID)
 	return nil
 }
 
@@ -84,6 +155,11 @@ func (m *mockGroupRepository) GetGroupsByUserID(userID uuid.UUID) ([]models.Grou
 
 func (m *mockGroupRepository) AddMember(group *models.Group, user *models.User) error {
 	m.groupMembers[group.ID] = append(m.groupMembers[group.ID], user.ID)
+	if m.memberRoles[group.ID] == nil {
+		m.memberRoles[group.ID] = make(map[uuid.UUID]string)
+	}
+	m.memberRoles[group.ID][user.ID] = models.GroupRoleMember
+	m.periods = append(m.periods, models.GroupMembershipPeriod{GroupID: group.ID, UserID: user.ID, JoinedAt: time.Now(), User: *user})
 	if g, exists := m.groups[group.ID]; exists {
 		g.Members = append(g.Members, *user)
 	}
@@ -91,6 +167,14 @@ func (m *mockGroupRepository) AddMember(group *models.Group, user *models.User)
 }
 
 func (m *mockGroupRepository) RemoveMember(group *models.Group, user *models.User) error {
+	delete(m.memberRoles[group.ID], user.ID)
+	now := time.Now()
+	for i := range m.periods {
+		p := &m.periods[i]
+		if p.GroupID == group.ID && p.UserID == user.ID && p.LeftAt == nil {
+			p.LeftAt = &now
+		}
+	}
 	memberIDs := m.groupMembers[group.ID]
 	for i, mID := range memberIDs {
 		if mID == user.ID {
@@ -119,10 +203,49 @@ func (m *mockGroupRepository) IsMember(groupID uuid.UUID, userID uuid.UUID) (boo
 	return false, nil
 }
 
+func (m *mockGroupRepository) GetMemberRole(groupID uuid.UUID, userID uuid.UUID) (string, error) {
+	return m.memberRoles[groupID][userID], nil
+}
+
+func (m *mockGroupRepository) TransferOwnership(group *models.Group, newOwnerID uuid.UUID) error {
+	stored, exists := m.groups[group.ID]
+	if !exists {
+		return errors.New("record not found")
+	}
+	if m.memberRoles[group.ID] == nil {
+		m.memberRoles[group.ID] = make(map[uuid.UUID]string)
+	}
+	if _, exists := m.memberRoles[group.ID][group.OwnerID]; exists {
+		m.memberRoles[group.ID][group.OwnerID] = models.GroupRoleAdmin
+	}
+	m.memberRoles[group.ID][newOwnerID] = models.GroupRoleOwner
+	stored.OwnerID = newOwnerID
+	group.OwnerID = newOwnerID
+	return nil
+}
+
+func (m *mockGroupRepository) GetMembershipPeriods(groupID uuid.UUID) ([]models.GroupMembershipPeriod, error) {
+	var result []models.GroupMembershipPeriod
+	for _, p := range m.periods {
+		if p.GroupID == groupID {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockGroupRepository) SetMemberRole(groupID uuid.UUID, userID uuid.UUID, role string) error {
+	if _, exists := m.memberRoles[groupID][userID]; !exists {
+		return errors.New("record not found")
+	}
+	m.memberRoles[groupID][userID] = role
+	return nil
+}
+
 func TestGroupService_CreateGroup(t *testing.T) {
 	userRepo := newMockUserRepository()
 	groupRepo := newMockGroupRepository()
-	svc := service.NewGroupService(groupRepo, userRepo)
+	svc := service.NewGroupService(groupRepo, userRepo, newMockReceiptRepository(), newMockSettlementRepository(), &mockReportRepository{})
 
 	owner := models.User{Email: "owner@example.com", Nickname: "Owner"}
 	_ = userRepo.Create(&owner)
@@ -149,7 +272,7 @@ func TestGroupService_CreateGroup(t *testing.T) {
 func TestGroupService_InviteMember(t *testing.T) {
 	userRepo := newMockUserRepository()
 	groupRepo := newMockGroupRepository()
-	svc := service.NewGroupService(groupRepo, userRepo)
+	svc := service.NewGroupService(groupRepo, userRepo, newMockReceiptRepository(), newMockSettlementRepository(), &mockReportRepository{})
 
 	owner := models.User{Email: "owner@example.com", Nickname: "Owner"}
 	_ = userRepo.Create(&owner)
@@ -173,8 +296,8 @@ func TestGroupService_InviteMember(t *testing.T) {
 
 	t.Run("Not Owner", func(t *testing.T) {
 		err := svc.InviteMember(group.ID, guest.ID, "other@example.com")
-		if !errors.Is(err, service.ErrNotOwner) {
-			t.Errorf("Expected error %v, got %v", service.ErrNotOwner, err)
+		if !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("Expected error %v, got %v", service.ErrPermissionDenied, err)
 		}
 	})
 
@@ -196,7 +319,7 @@ func TestGroupService_InviteMember(t *testing.T) {
 func TestGroupService_RemoveMember(t *testing.T) {
 	userRepo := newMockUserRepository()
 	groupRepo := newMockGroupRepository()
-	svc := service.NewGroupService(groupRepo, userRepo)
+	svc := service.NewGroupService(groupRepo, userRepo, newMockReceiptRepository(), newMockSettlementRepository(), &mockReportRepository{})
 
 	owner := models.User{Email: "owner@example.com", Nickname: "Owner"}
 	_ = userRepo.Create(&owner)
@@ -209,8 +332,8 @@ func TestGroupService_RemoveMember(t *testing.T) {
 
 	t.Run("Not Owner", func(t *testing.T) {
 		err := svc.RemoveMember(group.ID, guest.ID, guest.ID)
-		if !errors.Is(err, service.ErrNotOwner) {
-			t.Errorf("Expected error %v, got %v", service.ErrNotOwner, err)
+		if !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("Expected error %v, got %v", service.ErrPermissionDenied, err)
 		}
 	})
 
@@ -237,7 +360,7 @@ func TestGroupService_RemoveMember(t *testing.T) {
 func TestGroupService_UpdateGroup(t *testing.T) {
 	userRepo := newMockUserRepository()
 	groupRepo := newMockGroupRepository()
-	svc := service.NewGroupService(groupRepo, userRepo)
+	svc := service.NewGroupService(groupRepo, userRepo, newMockReceiptRepository(), newMockSettlementRepository(), &mockReportRepository{})
 
 	owner := models.User{Email: "owner@example.com", Nickname: "Owner"}
 	_ = userRepo.Create(&owner)
@@ -257,8 +380,8 @@ func TestGroupService_UpdateGroup(t *testing.T) {
 	t.Run("Forbidden", func(t *testing.T) {
 		guest := uuid.New()
 		_, err := svc.UpdateGroup(group.ID, guest, "Hack")
-		if !errors.Is(err, service.ErrNotOwner) {
-			t.Errorf("Expected error %v, got %v", service.ErrNotOwner, err)
+		if !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("Expected error %v, got %v", service.ErrPermissionDenied, err)
 		}
 	})
 }
@@ -266,7 +389,7 @@ func TestGroupService_UpdateGroup(t *testing.T) {
 func TestGroupService_DeleteGroup(t *testing.T) {
 	userRepo := newMockUserRepository()
 	groupRepo := newMockGroupRepository()
-	svc := service.NewGroupService(groupRepo, userRepo)
+	svc := service.NewGroupService(groupRepo, userRepo, newMockReceiptRepository(), newMockSettlementRepository(), &mockReportRepository{})
 
 	owner := models.User{Email: "owner@example.com", Nickname: "Owner"}
 	_ = userRepo.Create(&owner)
@@ -276,8 +399,8 @@ func TestGroupService_DeleteGroup(t *testing.T) {
 	t.Run("Forbidden", func(t *testing.T) {
 		guest := uuid.New()
 		err := svc.DeleteGroup(group.ID, guest)
-		if !errors.Is(err, service.ErrNotOwner) {
-			t.Errorf("Expected error %v, got %v", service.ErrNotOwner, err)
+		if !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("Expected error %v, got %v", service.ErrPermissionDenied, err)
 		}
 	})
 
@@ -293,3 +416,440 @@ func TestGroupService_DeleteGroup(t *testing.T) {
 		}
 	})
 }
+
+func TestGroupService_ChangeMemberRole(t *testing.T) {
+	userRepo := newMockUserRepository()
+	groupRepo := newMockGroupRepository()
+	svc := service.NewGroupService(groupRepo, userRepo, newMockReceiptRepository(), newMockSettlementRepository(), &mockReportRepository{})
+
+	owner := models.User{Email: "owner@example.com", Nickname: "Owner"}
+	_ = userRepo.Create(&owner)
+	admin := models.User{Email: "admin@example.com", Nickname: "Admin"}
+	_ = userRepo.Create(&admin)
+	member := models.User{Email: "member@example.com", Nickname: "Member"}
+	_ = userRepo.Create(&member)
+
+	group, _ := svc.CreateGroup("Family", owner.ID)
+	_ = svc.InviteMember(group.ID, owner.ID, admin.Email)
+	_ = svc.InviteMember(group.ID, owner.ID, member.Email)
+
+	t.Run("Owner Grants Admin", func(t *testing.T) {
+		_, err := svc.ChangeMemberRole(group.ID, owner.ID, admin.ID, models.GroupRoleAdmin)
+		if err != nil {
+			t.Fatalf("ChangeMemberRole failed: %v", err)
+		}
+
+		role, _ := groupRepo.GetMemberRole(group.ID, admin.ID)
+		if role != models.GroupRoleAdmin {
+			t.Errorf("Expected role %s, got %s", models.GroupRoleAdmin, role)
+		}
+	})
+
+	t.Run("Admin Changes Member To Viewer", func(t *testing.T) {
+		_, err := svc.ChangeMemberRole(group.ID, admin.ID, member.ID, models.GroupRoleViewer)
+		if err != nil {
+			t.Fatalf("ChangeMemberRole failed: %v", err)
+		}
+
+		role, _ := groupRepo.GetMemberRole(group.ID, member.ID)
+		if role != models.GroupRoleViewer {
+			t.Errorf("Expected role %s, got %s", models.GroupRoleViewer, role)
+		}
+	})
+
+	t.Run("Admin Cannot Grant Admin", func(t *testing.T) {
+		_, err := svc.ChangeMemberRole(group.ID, admin.ID, member.ID, models.GroupRoleAdmin)
+		if !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("Expected error %v, got %v", service.ErrPermissionDenied, err)
+		}
+	})
+
+	t.Run("Viewer Cannot Change Roles", func(t *testing.T) {
+		_, err := svc.ChangeMemberRole(group.ID, member.ID, admin.ID, models.GroupRoleMember)
+		if !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("Expected error %v, got %v", service.ErrPermissionDenied, err)
+		}
+	})
+
+	t.Run("Owner Role Cannot Be Changed", func(t *testing.T) {
+		_, err := svc.ChangeMemberRole(group.ID, admin.ID, owner.ID, models.GroupRoleMember)
+		if !errors.Is(err, service.ErrCannotChangeOwnerRole) {
+			t.Errorf("Expected error %v, got %v", service.ErrCannotChangeOwnerRole, err)
+		}
+	})
+
+	t.Run("Invalid Role", func(t *testing.T) {
+		_, err := svc.ChangeMemberRole(group.ID, owner.ID, member.ID, models.GroupRoleOwner)
+		if !errors.Is(err, service.ErrInvalidRole) {
+			t.Errorf("Expected error %v, got %v", service.ErrInvalidRole, err)
+		}
+	})
+
+	t.Run("Admin Can Invite But Not Delete", func(t *testing.T) {
+		guest := models.User{Email: "guest@example.com", Nickname: "Guest"}
+		_ = userRepo.Create(&guest)
+
+		if err := svc.InviteMember(group.ID, admin.ID, guest.Email); err != nil {
+			t.Fatalf("InviteMember by admin failed: %v", err)
+		}
+
+		err := svc.DeleteGroup(group.ID, admin.ID)
+		if !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("Expected error %v, got %v", service.ErrPermissionDenied, err)
+		}
+	})
+
+	t.Run("Admin Cannot Remove Admin", func(t *testing.T) {
+		other := models.User{Email: "other-admin@example.com", Nickname: "OtherAdmin"}
+		_ = userRepo.Create(&other)
+		_ = svc.InviteMember(group.ID, owner.ID, other.Email)
+		_, _ = svc.ChangeMemberRole(group.ID, owner.ID, other.ID, models.GroupRoleAdmin)
+
+		err := svc.RemoveMember(group.ID, admin.ID, other.ID)
+		if !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("Expected error %v, got %v", service.ErrPermissionDenied, err)
+		}
+	})
+}
+
+func TestGroupService_TransferOwnership(t *testing.T) {
+	userRepo := newMockUserRepository()
+	groupRepo := newMockGroupRepository()
+	svc := service.NewGroupService(groupRepo, userRepo, newMockReceiptRepository(), newMockSettlementRepository(), &mockReportRepository{})
+
+	owner := models.User{Email: "owner@example.com", Nickname: "Owner"}
+	_ = userRepo.Create(&owner)
+	guest := models.User{Email: "guest@example.com", Nickname: "Guest"}
+	_ = userRepo.Create(&guest)
+
+	group, _ := svc.CreateGroup("Family", owner.ID)
+	_ = svc.InviteMember(group.ID, owner.ID, guest.Email)
+
+	t.Run("Not Owner", func(t *testing.T) {
+		_, err := svc.TransferOwnership(group.ID, guest.ID, guest.ID)
+		if !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("Expected error %v, got %v", service.ErrPermissionDenied, err)
+		}
+	})
+
+	t.Run("Not Member", func(t *testing.T) {
+		_, err := svc.TransferOwnership(group.ID, owner.ID, uuid.New())
+		if !errors.Is(err, service.ErrMemberNotFound) {
+			t.Errorf("Expected error %v, got %v", service.ErrMemberNotFound, err)
+		}
+	})
+
+	t.Run("Already Owner", func(t *testing.T) {
+		_, err := svc.TransferOwnership(group.ID, owner.ID, owner.ID)
+		if !errors.Is(err, service.ErrAlreadyOwner) {
+			t.Errorf("Expected error %v, got %v", service.ErrAlreadyOwner, err)
+		}
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		updated, err := svc.TransferOwnership(group.ID, owner.ID, guest.ID)
+		if err != nil {
+			t.Fatalf("TransferOwnership failed: %v", err)
+		}
+		if updated.OwnerID != guest.ID {
+			t.Errorf("Expected OwnerID %s, got %s", guest.ID, updated.OwnerID)
+		}
+
+		newRole, _ := groupRepo.GetMemberRole(group.ID, guest.ID)
+		if newRole != models.GroupRoleOwner {
+			t.Errorf("Expected new owner role %s, got %s", models.GroupRoleOwner, newRole)
+		}
+		prevRole, _ := groupRepo.GetMemberRole(group.ID, owner.ID)
+		if prevRole != models.GroupRoleAdmin {
+			t.Errorf("Expected previous owner role %s, got %s", models.GroupRoleAdmin, prevRole)
+		}
+	})
+}
+
+func TestGroupService_LeaveGroup(t *testing.T) {
+	userRepo := newMockUserRepository()
+	groupRepo := newMockGroupRepository()
+	receiptRepo := newMockReceiptRepository()
+	settlementRepo := newMockSettlementRepository()
+	svc := service.NewGroupService(groupRepo, userRepo, receiptRepo, settlementRepo, &mockReportRepository{receiptRepo: receiptRepo})
+
+	owner := models.User{Email: "owner@example.com", Nickname: "Owner"}
+	_ = userRepo.Create(&owner)
+	guest := models.User{Email: "guest@example.com", Nickname: "Guest"}
+	_ = userRepo.Create(&guest)
+
+	group, _ := svc.CreateGroup("Family", owner.ID)
+	_ = svc.InviteMember(group.ID, owner.ID, guest.Email)
+
+	// オーナーが立て替えた折半の未精算レシート -> ゲストは500円の負担が残る
+	receipt := models.Receipt{
+		GroupID:         group.ID,
+		UserID:          owner.ID,
+		Date:            time.Now(),
+		SettlementYear:  2026,
+		SettlementMonth: 6,
+		Amount:          1000,
+		PayerID:         owner.ID,
+		PaymentMethod:   models.PaymentMethodHalf,
+	}
+	_ = receiptRepo.Create(&receipt)
+
+	t.Run("Owner Cannot Leave", func(t *testing.T) {
+		err := svc.LeaveGroup(group.ID, owner.ID, true)
+		if !errors.Is(err, service.ErrOwnerCannotLeave) {
+			t.Errorf("Expected error %v, got %v", service.ErrOwnerCannotLeave, err)
+		}
+	})
+
+	t.Run("Not Member", func(t *testing.T) {
+		err := svc.LeaveGroup(group.ID, uuid.New(), false)
+		if !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("Expected error %v, got %v", service.ErrPermissionDenied, err)
+		}
+	})
+
+	t.Run("Outstanding Balance", func(t *testing.T) {
+		err := svc.LeaveGroup(group.ID, guest.ID, false)
+		if !errors.Is(err, service.ErrOutstandingBalance) {
+			t.Errorf("Expected error %v, got %v", service.ErrOutstandingBalance, err)
+		}
+	})
+
+	t.Run("Partially Settled", func(t *testing.T) {
+		// 累積精算で一部（300円）だけ支払った場合は、残りの200円が残高として残る
+		_ = settlementRepo.Create(&models.Settlement{GroupID: group.ID, Year: 2026, Month: 6, Amount: 300, SettledBy: guest.ID, RecipientID: &owner.ID, Cumulative: true})
+
+		err := svc.LeaveGroup(group.ID, guest.ID, false)
+		if !errors.Is(err, service.ErrOutstandingBalance) {
+			t.Errorf("Expected error %v, got %v", service.ErrOutstandingBalance, err)
+		}
+	})
+
+	t.Run("Settled Balance", func(t *testing.T) {
+		_ = settlementRepo.Create(&models.Settlement{GroupID: group.ID, Year: 2026, Month: 6, Amount: 200, SettledBy: guest.ID, RecipientID: &owner.ID, Cumulative: true})
+
+		if err := svc.LeaveGroup(group.ID, guest.ID, false); err != nil {
+			t.Fatalf("LeaveGroup failed: %v", err)
+		}
+		if isMember, _ := groupRepo.IsMember(group.ID, guest.ID); isMember {
+			t.Errorf("Expected guest to have left the group")
+		}
+	})
+
+	t.Run("Force", func(t *testing.T) {
+		_ = svc.InviteMember(group.ID, owner.ID, guest.Email)
+		unsettled := models.Receipt{
+			GroupID:         group.ID,
+			UserID:          owner.ID,
+			Date:            time.Now(),
+			SettlementYear:  2026,
+			SettlementMonth: 7,
+			Amount:          2000,
+			PayerID:         owner.ID,
+			PaymentMethod:   models.PaymentMethodOther,
+		}
+		_ = receiptRepo.Create(&unsettled)
+
+		if err := svc.LeaveGroup(group.ID, guest.ID, true); err != nil {
+			t.Fatalf("LeaveGroup with force failed: %v", err)
+		}
+		if isMember, _ := groupRepo.IsMember(group.ID, guest.ID); isMember {
+			t.Errorf("Expected guest to have left the group")
+		}
+	})
+}
+
+func TestGroupService_ArchiveGroup(t *testing.T) {
+	userRepo := newMockUserRepository()
+	groupRepo := newMockGroupRepository()
+	svc := service.NewGroupService(groupRepo, userRepo, newMockReceiptRepository(), newMockSettlementRepository(), &mockReportRepository{})
+
+	owner := models.User{Email: "owner@example.com", Nickname: "Owner"}
+	_ = userRepo.Create(&owner)
+	guest := models.User{Email: "guest@example.com", Nickname: "Guest"}
+	_ = userRepo.Create(&guest)
+
+	group, _ := svc.CreateGroup("Family", owner.ID)
+	_ = svc.InviteMember(group.ID, owner.ID, guest.Email)
+
+	t.Run("Member Cannot Archive", func(t *testing.T) {
+		_, err := svc.ArchiveGroup(group.ID, guest.ID)
+		if !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("Expected error %v, got %v", service.ErrPermissionDenied, err)
+		}
+	})
+
+	t.Run("Archive", func(t *testing.T) {
+		archived, err := svc.ArchiveGroup(group.ID, owner.ID)
+		if err != nil {
+			t.Fatalf("ArchiveGroup failed: %v", err)
+		}
+		if archived.ArchivedAt == nil {
+			t.Errorf("Expected ArchivedAt to be set")
+		}
+	})
+
+	t.Run("Hidden From My Groups", func(t *testing.T) {
+		groups, _ := svc.GetMyGroups(owner.ID, false)
+		if len(groups) != 0 {
+			t.Errorf("Expected archived group to be hidden, got %d groups", len(groups))
+		}
+
+		groups, _ = svc.GetMyGroups(owner.ID, true)
+		if len(groups) != 1 {
+			t.Errorf("Expected archived group to be included, got %d groups", len(groups))
+		}
+	})
+
+	t.Run("Read Only", func(t *testing.T) {
+		_, err := svc.UpdateGroup(group.ID, owner.ID, "New Family")
+		if !errors.Is(err, service.ErrGroupArchived) {
+			t.Errorf("Expected error %v, got %v", service.ErrGroupArchived, err)
+		}
+
+		receiptSvc := service.NewReceiptService(newMockReceiptRepository(), groupRepo, newMockShopRepository())
+		_, err = receiptSvc.CreateReceipt(&service.CreateReceiptParams{
+			GroupID:       group.ID,
+			Date:          time.Now(),
+			Amount:        1000,
+			PayerID:       guest.ID,
+			PaymentMethod: models.PaymentMethodHalf,
+		}, guest.ID)
+		if !errors.Is(err, service.ErrGroupArchived) {
+			t.Errorf("Expected error %v, got %v", service.ErrGroupArchived, err)
+		}
+	})
+
+	t.Run("Unarchive", func(t *testing.T) {
+		unarchived, err := svc.UnarchiveGroup(group.ID, owner.ID)
+		if err != nil {
+			t.Fatalf("UnarchiveGroup failed: %v", err)
+		}
+		if unarchived.ArchivedAt != nil {
+			t.Errorf("Expected ArchivedAt to be cleared")
+		}
+
+		if _, err := svc.UpdateGroup(group.ID, owner.ID, "New Family"); err != nil {
+			t.Errorf("UpdateGroup after unarchive failed: %v", err)
+		}
+	})
+}
+
+func TestGroupService_RestoreGroup(t *testing.T) {
+	userRepo := newMockUserRepository()
+	groupRepo := newMockGroupRepository()
+	svc := service.NewGroupService(groupRepo, userRepo, newMockReceiptRepository(), newMockSettlementRepository(), &mockReportRepository{})
+
+	owner := models.User{Email: "owner@example.com", Nickname: "Owner"}
+	_ = userRepo.Create(&owner)
+	guest := models.User{Email: "guest@example.com", Nickname: "Guest"}
+	_ = userRepo.Create(&guest)
+
+	group, _ := svc.CreateGroup("Family", owner.ID)
+	_ = svc.InviteMember(group.ID, owner.ID, guest.Email)
+	_ = svc.DeleteGroup(group.ID, owner.ID)
+
+	t.Run("Listed As Deleted", func(t *testing.T) {
+		deleted, err := svc.GetDeletedGroups(owner.ID)
+		if err != nil {
+			t.Fatalf("GetDeletedGroups failed: %v", err)
+		}
+		if len(deleted) != 1 || deleted[0].ID != group.ID {
+			t.Fatalf("Expected deleted group to be listed, got %+v", deleted)
+		}
+		if !deleted[0].RestorableUntil.Equal(deleted[0].DeletedAt.Add(service.GroupRestoreWindow)) {
+			t.Errorf("Expected RestorableUntil to be DeletedAt + restore window")
+		}
+	})
+
+	t.Run("Not Owner", func(t *testing.T) {
+		_, err := svc.RestoreGroup(group.ID, guest.ID)
+		if !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("Expected error %v, got %v", service.ErrPermissionDenied, err)
+		}
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		restored, err := svc.RestoreGroup(group.ID, owner.ID)
+		if err != nil {
+			t.Fatalf("RestoreGroup failed: %v", err)
+		}
+		if restored.ID != group.ID {
+			t.Errorf("Expected restored group %s, got %s", group.ID, restored.ID)
+		}
+
+		// メンバーもそのまま残っている
+		if isMember, _ := groupRepo.IsMember(group.ID, guest.ID); !isMember {
+			t.Errorf("Expected guest to remain a member after restore")
+		}
+	})
+
+	t.Run("Window Expired", func(t *testing.T) {
+		_ = svc.DeleteGroup(group.ID, owner.ID)
+		groupRepo.deleted[group.ID].DeletedAt.Time = time.Now().Add(-service.GroupRestoreWindow - time.Hour)
+
+		_, err := svc.RestoreGroup(group.ID, owner.ID)
+		if !errors.Is(err, service.ErrRestoreWindowExpired) {
+			t.Errorf("Expected error %v, got %v", service.ErrRestoreWindowExpired, err)
+		}
+	})
+}
+
+func TestGroupService_PurgeGroup(t *testing.T) {
+	userRepo := newMockUserRepository()
+	groupRepo := newMockGroupRepository()
+	svc := service.NewGroupService(groupRepo, userRepo, newMockReceiptRepository(), newMockSettlementRepository(), &mockReportRepository{})
+	worker := service.NewGroupPurgeWorker(groupRepo, time.Hour)
+
+	owner := models.User{Email: "owner@example.com", Nickname: "Owner"}
+	_ = userRepo.Create(&owner)
+
+	group, _ := svc.CreateGroup("Family", owner.ID)
+	kept, _ := svc.CreateGroup("Kept", owner.ID)
+	_ = svc.DeleteGroup(kept.ID, owner.ID)
+
+	t.Run("Confirmation Mismatch", func(t *testing.T) {
+		err := svc.PurgeGroup(group.ID, owner.ID, "family")
+		if !errors.Is(err, service.ErrPurgeConfirmationMismatch) {
+			t.Errorf("Expected error %v, got %v", service.ErrPurgeConfirmationMismatch, err)
+		}
+		if _, err := groupRepo.GetByID(group.ID); err != nil {
+			t.Errorf("Expected group to remain after a mismatched confirmation")
+		}
+	})
+
+	t.Run("Scheduled And Purged In Background", func(t *testing.T) {
+		if err := svc.PurgeGroup(group.ID, owner.ID, "Family"); err != nil {
+			t.Fatalf("PurgeGroup failed: %v", err)
+		}
+
+		// 要求時点では論理削除のみで、復元もできない
+		if _, err := groupRepo.GetByID(group.ID); err == nil {
+			t.Errorf("Expected group to be hidden once purge is requested")
+		}
+		if _, err := svc.RestoreGroup(group.ID, owner.ID); !errors.Is(err, service.ErrRestoreWindowExpired) {
+			t.Errorf("Expected error %v, got %v", service.ErrRestoreWindowExpired, err)
+		}
+
+		purged, err := worker.PurgeDue(time.Now())
+		if err != nil {
+			t.Fatalf("PurgeDue failed: %v", err)
+		}
+		if purged != 1 || len(groupRepo.purged) != 1 || groupRepo.purged[0] != group.ID {
+			t.Errorf("Expected only the requested group to be purged, got %v", groupRepo.purged)
+		}
+	})
+
+	t.Run("Expired Deletions Are Purged", func(t *testing.T) {
+		purged, err := worker.PurgeDue(time.Now().Add(service.GroupRestoreWindow + time.Hour))
+		if err != nil {
+			t.Fatalf("PurgeDue failed: %v", err)
+		}
+		if purged != 1 {
+			t.Errorf("Expected the expired group to be purged, got %d", purged)
+		}
+		if _, err := groupRepo.GetDeletedByID(kept.ID); err == nil {
+			t.Errorf("Expected expired group to be purged")
+		}
+	})
+}
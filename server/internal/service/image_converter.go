@@ -0,0 +1,59 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const (
+	// defaultImageConvertCommand 既定の画像変換コマンド（ImageMagick）
+	defaultImageConvertCommand = "magick"
+	// pdfRasterizeDensity PDFを画像に変換する際の解像度（dpi）
+	pdfRasterizeDensity = "150"
+)
+
+// imageMagickConverter ImageMagick でHEICの変換・PDFの画像化を行う（PDFには Ghostscript が必要）
+type imageMagickConverter struct {
+	command string
+}
+
+// NewImageMagickConverter ImageMagick を使うImageConverterを作成（command が空の場合は magick）
+func NewImageMagickConverter(command string) ImageConverter {
+	if command == "" {
+		command = defaultImageConvertCommand
+	}
+	return &imageMagickConverter{command: command}
+}
+
+func (c *imageMagickConverter) ConvertHEIC(ctx context.Context, data []byte) ([]byte, error) {
+	return c.run(ctx, data, "heic:-", "-auto-orient", "jpeg:-")
+}
+
+func (c *imageMagickConverter) RasterizePDF(ctx context.Context, data []byte, maxPages int) ([]byte, error) {
+	return c.run(ctx, data,
+		"-density", pdfRasterizeDensity,
+		fmt.Sprintf("pdf:-[0-%d]", maxPages-1),
+		"-background", "white", "-alpha", "remove",
+		"-append", "jpeg:-",
+	)
+}
+
+func (c *imageMagickConverter) run(ctx context.Context, data []byte, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, c.command, args...)
+	cmd.Stdin = bytes.NewReader(data)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
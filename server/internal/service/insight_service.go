@@ -0,0 +1,368 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+	"receipt/server/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidShopAlias 店舗名の別名が空の場合のエラー
+	ErrInvalidShopAlias = errors.New("alias and canonical name must not be empty")
+	// ErrShopAliasNotFound 店舗名の別名が見つからない場合のエラー
+	ErrShopAliasNotFound = errors.New("shop alias not found")
+)
+
+const (
+	// defaultInsightMonths インサイトの既定の集計月数（当月を含む）
+	defaultInsightMonths = 6
+	// maxInsightMonths インサイトの最大集計月数
+	maxInsightMonths = 24
+	// defaultInsightShopLimit 上位店舗の既定の件数
+	defaultInsightShopLimit = 10
+	// unknownShopName 店舗名が未入力のレシートの表示名
+	unknownShopName = "未入力"
+)
+
+// MonthlyAmount 月ごとの合計
+type MonthlyAmount struct {
+	Month string `json:"month"` // 2026-01
+	Total int    `json:"total"`
+	Count int    `json:"count"`
+}
+
+// MonthOverMonth 当月と前月の比較
+type MonthOverMonth struct {
+	Current    int      `json:"current"`
+	Previous   int      `json:"previous"`
+	Change     int      `json:"change"`
+	ChangeRate *float64 `json:"change_rate"` // 前月が0円の場合はnull
+}
+
+// ShopInsight 店舗ごとのインサイト
+type ShopInsight struct {
+	Name           string          `json:"name"`
+	Variants       []string        `json:"variants"` // 集計に含めた店舗名の表記
+	Total          int             `json:"total"`
+	Count          int             `json:"count"`
+	AverageAmount  int             `json:"average_amount"`
+	VisitsPerMonth float64         `json:"visits_per_month"`
+	Monthly        []MonthlyAmount `json:"monthly"`
+	MonthOverMonth MonthOverMonth  `json:"month_over_month"`
+}
+
+// PayerInsight 支払者ごとのインサイト
+type PayerInsight struct {
+	UserID         uuid.UUID       `json:"user_id"`
+	Nickname       string          `json:"nickname"`
+	Total          int             `json:"total"`
+	Count          int             `json:"count"`
+	AverageAmount  int             `json:"average_amount"`
+	Monthly        []MonthlyAmount `json:"monthly"`
+	MonthOverMonth MonthOverMonth  `json:"month_over_month"`
+}
+
+// GroupInsights グループのインサイト
+type GroupInsights struct {
+	From          string         `json:"from"`
+	To            string         `json:"to"`
+	Months        []string       `json:"months"`
+	TotalSpent    int            `json:"total_spent"`
+	ReceiptCount  int            `json:"receipt_count"`
+	AverageAmount int            `json:"average_amount"`
+	Shops         []ShopInsight  `json:"shops"`
+	Payers        []PayerInsight `json:"payers"`
+}
+
+// InsightService 店舗別・支払者別のインサイトに関するビジネスロジックインターフェース
+type InsightService interface {
+	// GetInsights 当月を含む直近 months か月のインサイトを作成する（0以下の場合は既定値）
+	GetInsights(groupID uuid.UUID, userID uuid.UUID, months int, shopLimit int) (*GroupInsights, error)
+	GetShopAliases(groupID uuid.UUID, userID uuid.UUID) ([]models.ShopAlias, error)
+	CreateShopAlias(groupID uuid.UUID, userID uuid.UUID, alias string, canonicalName string) (*models.ShopAlias, error)
+	DeleteShopAlias(groupID uuid.UUID, userID uuid.UUID, aliasID uuid.UUID) error
+}
+
+type insightServiceImpl struct {
+	groupRepo     repository.GroupRepository
+	reportRepo    repository.ReportRepository
+	shopAliasRepo repository.ShopAliasRepository
+	now           func() time.Time
+}
+
+// NewInsightService InsightServiceの実装を作成
+func NewInsightService(
+	groupRepo repository.GroupRepository,
+	reportRepo repository.ReportRepository,
+	shopAliasRepo repository.ShopAliasRepository,
+) InsightService {
+	return &insightServiceImpl{
+		groupRepo:     groupRepo,
+		reportRepo:    reportRepo,
+		shopAliasRepo: shopAliasRepo,
+		now:           time.Now,
+	}
+}
+
+func (s *insightServiceImpl) GetInsights(groupID uuid.UUID, userID uuid.UUID, months int, shopLimit int) (*GroupInsights, error) {
+	if months <= 0 {
+		months = defaultInsightMonths
+	}
+	if months > maxInsightMonths {
+		months = maxInsightMonths
+	}
+	if shopLimit <= 0 {
+		shopLimit = defaultInsightShopLimit
+	}
+
+	if _, err := authorizeGroup(s.groupRepo, groupID, userID, PermissionViewGroup); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	end := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, 1, 0)
+	from := end.AddDate(0, -months, 0)
+
+	monthKeys := make([]string, 0, months)
+	for m := from; m.Before(end); m = m.AddDate(0, 1, 0) {
+		monthKeys = append(monthKeys, m.Format("2006-01"))
+	}
+
+	shopTotals, err := s.reportRepo.GetMonthlyTotalsByShop(groupID, from, end)
+	if err != nil {
+		return nil, err
+	}
+	payerMonthly, err := s.reportRepo.GetMonthlyTotalsByPayer(groupID, from, end)
+	if err != nil {
+		return nil, err
+	}
+	payerTotals, err := s.reportRepo.GetTotalsByPayer(groupID, from, end)
+	if err != nil {
+		return nil, err
+	}
+	// 店舗マスタの別名も、店舗マスタに紐付いていないレシートの表記ゆれをまとめるのに使う
+	aliases, err := s.shopAliasRepo.GetByGroupID(groupID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	insights := &GroupInsights{
+		From:   from.Format("2006-01-02"),
+		To:     end.AddDate(0, 0, -1).Format("2006-01-02"),
+		Months: monthKeys,
+		Shops:  buildShopInsights(shopTotals, newShopNameResolver(aliases), monthKeys),
+		Payers: make([]PayerInsight, 0, len(payerTotals)),
+	}
+	if len(insights.Shops) > shopLimit {
+		insights.Shops = insights.Shops[:shopLimit]
+	}
+
+	payerMonths := make(map[string][]repository.ReportMonthlyTotal)
+	for _, t := range payerMonthly {
+		payerMonths[t.Key] = append(payerMonths[t.Key], t)
+	}
+	for _, p := range payerTotals {
+		insights.TotalSpent += p.Total
+		insights.ReceiptCount += p.Count
+
+		monthly := fillMonthlyAmounts(payerMonths[p.PayerID.String()], monthKeys)
+		insights.Payers = append(insights.Payers, PayerInsight{
+			UserID:         p.PayerID,
+			Nickname:       p.Nickname,
+			Total:          p.Total,
+			Count:          p.Count,
+			AverageAmount:  averageAmount(p.Total, p.Count),
+			Monthly:        monthly,
+			MonthOverMonth: monthOverMonth(monthly),
+		})
+	}
+	insights.AverageAmount = averageAmount(insights.TotalSpent, insights.ReceiptCount)
+
+	return insights, nil
+}
+
+func (s *insightServiceImpl) GetShopAliases(groupID uuid.UUID, userID uuid.UUID) ([]models.ShopAlias, error) {
+	if _, err := authorizeGroup(s.groupRepo, groupID, userID, PermissionViewGroup); err != nil {
+		return nil, err
+	}
+	return s.shopAliasRepo.GetByGroupID(groupID, false)
+}
+
+func (s *insightServiceImpl) CreateShopAlias(groupID uuid.UUID, userID uuid.UUID, alias string, canonicalName string) (*models.ShopAlias, error) {
+	if _, err := authorizeGroup(s.groupRepo, groupID, userID, PermissionEditGroup); err != nil {
+		return nil, err
+	}
+
+	if utils.NormalizeShopName(alias) == "" || utils.TrimShopBranch(canonicalName) == "" {
+		return nil, ErrInvalidShopAlias
+	}
+
+	shopAlias := models.ShopAlias{
+		GroupID:       &groupID,
+		Alias:         alias,
+		CanonicalName: utils.TrimShopBranch(canonicalName),
+	}
+	if err := s.shopAliasRepo.Create(&shopAlias); err != nil {
+		return nil, err
+	}
+	return &shopAlias, nil
+}
+
+func (s *insightServiceImpl) DeleteShopAlias(groupID uuid.UUID, userID uuid.UUID, aliasID uuid.UUID) error {
+	if _, err := authorizeGroup(s.groupRepo, groupID, userID, PermissionEditGroup); err != nil {
+		return err
+	}
+
+	deleted, err := s.shopAliasRepo.Delete(groupID, aliasID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrShopAliasNotFound
+	}
+	return nil
+}
+
+// shopNameResolver 登録された店舗名を、別名表に従って集計用のキーと表示名に変換する
+type shopNameResolver struct {
+	aliases []models.ShopAlias
+	names   []string // aliases と同じ順の別名
+}
+
+func newShopNameResolver(aliases []models.ShopAlias) *shopNameResolver {
+	r := &shopNameResolver{aliases: aliases}
+	for _, a := range aliases {
+		r.names = append(r.names, a.Alias)
+	}
+	return r
+}
+
+// resolve 正規化した店舗名が最も長く一致する別名の正式名称を返す。一致しない場合は canonical が空
+func (r *shopNameResolver) resolve(shop string) (key string, canonical string) {
+	best := utils.MatchShopName(shop, r.names)
+	if best < 0 {
+		return utils.NormalizeShopName(shop), ""
+	}
+
+	canonical = r.aliases[best].CanonicalName
+	return utils.NormalizeShopName(canonical), canonical
+}
+
+// buildShopInsights 店舗名の表記ごとの月次集計を、正規化した店舗ごとにまとめる（合計金額の多い順）
+func buildShopInsights(totals []repository.ReportMonthlyTotal, resolver *shopNameResolver, monthKeys []string) []ShopInsight {
+	type shopGroup struct {
+		canonical     string
+		variantTotals map[string]int
+		months        []repository.ReportMonthlyTotal
+	}
+
+	groups := make(map[string]*shopGroup)
+	var order []string
+	for _, t := range totals {
+		key, canonical := resolver.resolve(t.Key)
+		g, exists := groups[key]
+		if !exists {
+			g = &shopGroup{canonical: canonical, variantTotals: make(map[string]int)}
+			groups[key] = g
+			order = append(order, key)
+		}
+		g.variantTotals[t.Key] += t.Total
+		g.months = append(g.months, repository.ReportMonthlyTotal{Key: key, Month: t.Month, Total: t.Total, Count: t.Count})
+	}
+
+	insights := make([]ShopInsight, 0, len(groups))
+	for _, key := range order {
+		g := groups[key]
+
+		variants := make([]string, 0, len(g.variantTotals))
+		for v := range g.variantTotals {
+			variants = append(variants, v)
+		}
+		// 表記は金額の多い順（同額は名前順）
+		sort.Slice(variants, func(i, j int) bool {
+			if g.variantTotals[variants[i]] != g.variantTotals[variants[j]] {
+				return g.variantTotals[variants[i]] > g.variantTotals[variants[j]]
+			}
+			return variants[i] < variants[j]
+		})
+
+		name := g.canonical
+		if name == "" {
+			name = utils.TrimShopBranch(variants[0])
+		}
+		if name == "" {
+			name = unknownShopName
+		}
+
+		monthly := fillMonthlyAmounts(g.months, monthKeys)
+		insight := ShopInsight{
+			Name:           name,
+			Variants:       variants,
+			Monthly:        monthly,
+			MonthOverMonth: monthOverMonth(monthly),
+		}
+		for _, m := range monthly {
+			insight.Total += m.Total
+			insight.Count += m.Count
+		}
+		insight.AverageAmount = averageAmount(insight.Total, insight.Count)
+		insight.VisitsPerMonth = float64(insight.Count) / float64(len(monthKeys))
+
+		insights = append(insights, insight)
+	}
+
+	sort.SliceStable(insights, func(i, j int) bool {
+		return insights[i].Total > insights[j].Total
+	})
+	return insights
+}
+
+// fillMonthlyAmounts 月次集計を集計期間の全ての月に割り当てる（同じ月の行は合算する）
+func fillMonthlyAmounts(totals []repository.ReportMonthlyTotal, monthKeys []string) []MonthlyAmount {
+	byMonth := make(map[string]*MonthlyAmount, len(monthKeys))
+	monthly := make([]MonthlyAmount, len(monthKeys))
+	for i, key := range monthKeys {
+		monthly[i].Month = key
+		byMonth[key] = &monthly[i]
+	}
+
+	for _, t := range totals {
+		if m, ok := byMonth[t.Month]; ok {
+			m.Total += t.Total
+			m.Count += t.Count
+		}
+	}
+	return monthly
+}
+
+// monthOverMonth 集計期間の最後の月（当月）と前月を比較する
+func monthOverMonth(monthly []MonthlyAmount) MonthOverMonth {
+	var mom MonthOverMonth
+	if len(monthly) == 0 {
+		return mom
+	}
+
+	mom.Current = monthly[len(monthly)-1].Total
+	if len(monthly) > 1 {
+		mom.Previous = monthly[len(monthly)-2].Total
+	}
+	mom.Change = mom.Current - mom.Previous
+	if mom.Previous != 0 {
+		rate := float64(mom.Change) / float64(mom.Previous)
+		mom.ChangeRate = &rate
+	}
+	return mom
+}
+
+func averageAmount(total int, count int) int {
+	if count == 0 {
+		return 0
+	}
+	return total / count
+}
@@ -0,0 +1,172 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/service"
+
+	"github.com/google/uuid"
+)
+
+// mockShopAliasRepository ShopAliasRepositoryのモック
+type mockShopAliasRepository struct {
+	aliases []models.ShopAlias
+}
+
+func (m *mockShopAliasRepository) GetByGroupID(groupID uuid.UUID, includeShopAliases bool) ([]models.ShopAlias, error) {
+	var result []models.ShopAlias
+	for _, a := range m.aliases {
+		if (a.GroupID == nil || *a.GroupID == groupID) && (includeShopAliases || a.ShopID == nil) {
+			result = append(result, a)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockShopAliasRepository) Create(alias *models.ShopAlias) error {
+	alias.ID = uuid.New()
+	m.aliases = append(m.aliases, *alias)
+	return nil
+}
+
+func (m *mockShopAliasRepository) Delete(groupID uuid.UUID, id uuid.UUID) (bool, error) {
+	for i, a := range m.aliases {
+		if a.ID == id && a.GroupID != nil && *a.GroupID == groupID && a.ShopID == nil {
+			m.aliases = append(m.aliases[:i], m.aliases[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestInsightService_GetInsights(t *testing.T) {
+	groupRepo := newMockGroupRepository()
+	userA := models.User{ID: uuid.New(), Nickname: "UserA"}
+	userB := models.User{ID: uuid.New(), Nickname: "UserB"}
+	outsider := uuid.New()
+	group := models.Group{Name: "Family", OwnerID: userA.ID}
+	_ = groupRepo.Create(&group)
+	_ = groupRepo.AddMember(&group, &userA)
+	_ = groupRepo.AddMember(&group, &userB)
+	_ = groupRepo.SetMemberRole(group.ID, userA.ID, models.GroupRoleOwner)
+
+	now := time.Now()
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 12, 0, 0, 0, time.Local)
+	lastMonth := thisMonth.AddDate(0, -1, 0)
+
+	reportRepo := &mockReportRepository{
+		nicknames: map[uuid.UUID]string{userA.ID: userA.Nickname, userB.ID: userB.Nickname},
+		receipts: []models.Receipt{
+			{GroupID: group.ID, Date: lastMonth, Shop: "セブンイレブン", Amount: 1000, PayerID: userA.ID},
+			{GroupID: group.ID, Date: thisMonth, Shop: "7-Eleven 新宿店", Amount: 1500, PayerID: userA.ID},
+			{GroupID: group.ID, Date: thisMonth, Shop: "ｽｰﾊﾟｰ ABC 駅前店", Amount: 3000, PayerID: userB.ID},
+			{GroupID: group.ID, Date: lastMonth, Shop: "スーパーABC", Amount: 2000, PayerID: userB.ID},
+			{GroupID: group.ID, Date: thisMonth, Shop: "Bakery", Amount: 300, PayerID: userB.ID},
+			// 集計期間外
+			{GroupID: group.ID, Date: thisMonth.AddDate(-1, 0, 0), Shop: "Bakery", Amount: 9999, PayerID: userA.ID},
+		},
+	}
+	aliasRepo := &mockShopAliasRepository{}
+	svc := service.NewInsightService(groupRepo, reportRepo, aliasRepo)
+
+	t.Run("別名で表記ゆれの店舗をまとめる", func(t *testing.T) {
+		if _, err := svc.CreateShopAlias(group.ID, userA.ID, "7-Eleven", "セブンイレブン"); err != nil {
+			t.Fatalf("failed to create alias: %v", err)
+		}
+
+		insights, err := svc.GetInsights(group.ID, userA.ID, 3, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(insights.Months) != 3 || insights.TotalSpent != 7800 || insights.ReceiptCount != 5 {
+			t.Fatalf("unexpected totals: months=%v total=%d count=%d", insights.Months, insights.TotalSpent, insights.ReceiptCount)
+		}
+		if len(insights.Shops) != 3 {
+			t.Fatalf("expected 3 shops, got %+v", insights.Shops)
+		}
+
+		// 全角・半角や支店名の違いは別名がなくてもまとめる
+		super := insights.Shops[0]
+		if super.Name != "スーパー ABC" || super.Total != 5000 || super.Count != 2 || super.AverageAmount != 2500 {
+			t.Errorf("unexpected supermarket insight: %+v", super)
+		}
+		if super.MonthOverMonth.Change != 1000 || super.MonthOverMonth.ChangeRate == nil || *super.MonthOverMonth.ChangeRate != 0.5 {
+			t.Errorf("unexpected month over month: %+v", super.MonthOverMonth)
+		}
+
+		seven := insights.Shops[1]
+		if seven.Name != "セブンイレブン" || seven.Total != 2500 || len(seven.Variants) != 2 {
+			t.Errorf("expected aliased shops to be merged, got %+v", seven)
+		}
+		if seven.VisitsPerMonth != 2.0/3.0 {
+			t.Errorf("expected 2/3 visits per month, got %v", seven.VisitsPerMonth)
+		}
+
+		// 前月が0円の店舗は増減率なし
+		if bakery := insights.Shops[2]; bakery.MonthOverMonth.ChangeRate != nil || bakery.MonthOverMonth.Change != 300 {
+			t.Errorf("unexpected bakery month over month: %+v", bakery.MonthOverMonth)
+		}
+
+		if len(insights.Payers) != 2 {
+			t.Fatalf("expected 2 payers, got %d", len(insights.Payers))
+		}
+		for _, p := range insights.Payers {
+			if p.UserID == userB.ID && (p.Total != 5300 || p.Nickname != "UserB" || p.MonthOverMonth.Current != 3300) {
+				t.Errorf("unexpected payer insight: %+v", p)
+			}
+		}
+	})
+
+	t.Run("上位店舗の件数を制限する", func(t *testing.T) {
+		insights, err := svc.GetInsights(group.ID, userA.ID, 0, 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(insights.Shops) != 1 || len(insights.Months) != 6 {
+			t.Errorf("expected 1 shop over 6 months, got %d shops over %d months", len(insights.Shops), len(insights.Months))
+		}
+	})
+
+	t.Run("メンバー以外は取得できない", func(t *testing.T) {
+		if _, err := svc.GetInsights(group.ID, outsider, 0, 0); !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("expected ErrPermissionDenied, got %v", err)
+		}
+	})
+
+	t.Run("一般メンバーは別名を登録できない", func(t *testing.T) {
+		if _, err := svc.CreateShopAlias(group.ID, userB.ID, "ABC", "スーパーABC"); !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("expected ErrPermissionDenied, got %v", err)
+		}
+	})
+
+	t.Run("空の別名は登録できない", func(t *testing.T) {
+		if _, err := svc.CreateShopAlias(group.ID, userA.ID, " - ", "Shop"); !errors.Is(err, service.ErrInvalidShopAlias) {
+			t.Errorf("expected ErrInvalidShopAlias, got %v", err)
+		}
+	})
+
+	t.Run("別名の削除", func(t *testing.T) {
+		// 店舗マスタの別名は店舗マスタの画面で管理するため、一覧・削除の対象にしない
+		shopID := uuid.New()
+		shopAlias := models.ShopAlias{ID: uuid.New(), GroupID: &group.ID, ShopID: &shopID, Alias: "ABC", CanonicalName: "スーパーABC"}
+		aliasRepo.aliases = append(aliasRepo.aliases, shopAlias)
+
+		aliases, _ := svc.GetShopAliases(group.ID, userA.ID)
+		if len(aliases) != 1 {
+			t.Fatalf("expected 1 alias, got %d", len(aliases))
+		}
+		if err := svc.DeleteShopAlias(group.ID, userA.ID, shopAlias.ID); !errors.Is(err, service.ErrShopAliasNotFound) {
+			t.Errorf("expected ErrShopAliasNotFound for a shop alias, got %v", err)
+		}
+		if err := svc.DeleteShopAlias(group.ID, userA.ID, aliases[0].ID); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := svc.DeleteShopAlias(group.ID, userA.ID, aliases[0].ID); !errors.Is(err, service.ErrShopAliasNotFound) {
+			t.Errorf("expected ErrShopAliasNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+	"receipt/server/internal/utils"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrOIDCNotConfigured OIDCログインが設定されていない場合のエラー
+	ErrOIDCNotConfigured = errors.New("OpenID Connect login is not configured")
+	// ErrInvalidOIDCState OIDCログインの state が無効・期限切れの場合のエラー
+	ErrInvalidOIDCState = errors.New("invalid or expired OpenID Connect login state")
+	// ErrOIDCAuthenticationFailed IDプロバイダーでの認証結果を検証できなかった場合のエラー
+	ErrOIDCAuthenticationFailed = errors.New("OpenID Connect authentication failed")
+	// ErrOIDCEmailNotVerified IDプロバイダー側でメールアドレスが確認されていない場合のエラー
+	ErrOIDCEmailNotVerified = errors.New("email address is not verified by the identity provider")
+)
+
+const (
+	oidcLoginPurpose = "oidc_login"
+	// oidcLoginTTL 認可リクエストからコールバックまでの有効期間
+	oidcLoginTTL = 10 * time.Minute
+)
+
+// OIDCConfig OpenID Connect（Google 等）のクライアント設定
+type OIDCConfig struct {
+	Issuer       string // 例: "https://accounts.google.com"
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // IDプロバイダーからのリダイレクト先（クライアントのコールバック画面）
+}
+
+// OIDCAuthorization OIDCログイン開始時にクライアントへ返す情報
+// クライアントは AuthorizationURL へ遷移し、コールバックで受け取った code / state と
+// SessionToken を FinishLogin に送る。SessionToken には PKCE の code_verifier が含まれるため URL に載せない。
+type OIDCAuthorization struct {
+	AuthorizationURL string `json:"authorization_url"`
+	SessionToken     string `json:"session_token"`
+}
+
+// oidcLoginSession 認可リクエストとコールバックを結び付けるセッション情報
+type oidcLoginSession struct {
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// oidcClaims IDトークンから取得するクレーム
+type oidcClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+// OIDCService OpenID Connect（認可コードフロー + PKCE）によるログインに関するビジネスロジックインターフェース
+type OIDCService interface {
+	BeginLogin(ctx context.Context) (*OIDCAuthorization, error)
+	FinishLogin(ctx context.Context, sessionToken, code, state string) (*LoginResult, error)
+}
+
+type oidcServiceImpl struct {
+	cfg          OIDCConfig
+	userRepo     repository.UserRepository
+	identityRepo repository.ExternalIdentityRepository
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+// NewOIDCService OIDCServiceの実装を作成
+// IDプロバイダーのディスカバリーは初回利用時に行うため、起動時にIDプロバイダーへ接続できなくてもよい。
+func NewOIDCService(cfg OIDCConfig, userRepo repository.UserRepository, identityRepo repository.ExternalIdentityRepository) OIDCService {
+	return &oidcServiceImpl{
+		cfg:          cfg,
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+	}
+}
+
+// getProvider ディスカバリー済みのプロバイダー情報を返す
+func (s *oidcServiceImpl) getProvider(ctx context.Context) (*oidc.Provider, error) {
+	if s.cfg.Issuer == "" || s.cfg.ClientID == "" {
+		return nil, ErrOIDCNotConfigured
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.provider == nil {
+		provider, err := oidc.NewProvider(ctx, s.cfg.Issuer)
+		if err != nil {
+			return nil, err
+		}
+		s.provider = provider
+	}
+	return s.provider, nil
+}
+
+func (s *oidcServiceImpl) oauth2Config(provider *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     s.cfg.ClientID,
+		ClientSecret: s.cfg.ClientSecret,
+		RedirectURL:  s.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+	}
+}
+
+func (s *oidcServiceImpl) BeginLogin(ctx context.Context) (*OIDCAuthorization, error) {
+	provider, err := s.getProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := randomURLSafeString()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomURLSafeString()
+	if err != nil {
+		return nil, err
+	}
+	session := oidcLoginSession{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: oauth2.GenerateVerifier(),
+	}
+
+	sessionToken, err := utils.GenerateStateToken(oidcLoginPurpose, session, oidcLoginTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	authURL := s.oauth2Config(provider).AuthCodeURL(state,
+		oidc.Nonce(nonce),
+		oauth2.S256ChallengeOption(session.CodeVerifier),
+	)
+
+	return &OIDCAuthorization{AuthorizationURL: authURL, SessionToken: sessionToken}, nil
+}
+
+func (s *oidcServiceImpl) FinishLogin(ctx context.Context, sessionToken, code, state string) (*LoginResult, error) {
+	provider, err := s.getProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var session oidcLoginSession
+	if err := utils.ParseStateToken(sessionToken, oidcLoginPurpose, &session); err != nil {
+		return nil, ErrInvalidOIDCState
+	}
+	// 別のブラウザで開始されたログイン（ログインCSRF）を防ぐ
+	if session.State == "" || session.State != state {
+		return nil, ErrInvalidOIDCState
+	}
+
+	oauthToken, err := s.oauth2Config(provider).Exchange(ctx, code, oauth2.VerifierOption(session.CodeVerifier))
+	if err != nil {
+		return nil, ErrOIDCAuthenticationFailed
+	}
+
+	rawIDToken, ok := oauthToken.Extra("id_token").(string)
+	if !ok {
+		return nil, ErrOIDCAuthenticationFailed
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: s.cfg.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil || idToken.Nonce != session.Nonce {
+		return nil, ErrOIDCAuthenticationFailed
+	}
+
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, ErrOIDCAuthenticationFailed
+	}
+
+	user, err := s.findOrCreateUser(idToken.Issuer, idToken.Subject, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkAccountLock(user, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// 2段階認証が有効なユーザーは、退会などの本人確認にTOTPコードを使う
+	return withReauthenticationToken(beginSecondFactorOrIssueToken(s.userRepo, user))
+}
+
+// findOrCreateUser IDプロバイダーのアカウントに紐付くユーザーを返す。
+// 紐付けがない場合は、確認済みメールアドレスで既存ユーザーに紐付けるか、新規ユーザーを作成する。
+func (s *oidcServiceImpl) findOrCreateUser(issuer, subject string, claims oidcClaims) (*models.User, error) {
+	if identity, err := s.identityRepo.GetByIssuerAndSubject(issuer, subject); err == nil {
+		user, err := s.userRepo.GetByID(identity.UserID)
+		if err != nil {
+			return nil, ErrUserNotFound
+		}
+		return user, nil
+	}
+
+	// 未確認のメールアドレスで紐付けると、他人のアカウントを乗っ取れてしまう
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	identity := models.ExternalIdentity{
+		Issuer:  issuer,
+		Subject: subject,
+		Email:   claims.Email,
+	}
+
+	if user, err := s.userRepo.GetByEmail(claims.Email); err == nil {
+		identity.UserID = user.ID
+		if err := s.identityRepo.Create(&identity); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	nickname := claims.Name
+	if nickname == "" {
+		nickname, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	// パスワードを持たない、外部IDプロバイダー専用のユーザーとして作成する
+	user := models.User{
+		Email:    claims.Email,
+		Nickname: nickname,
+	}
+	if err := s.identityRepo.CreateUserWithIdentity(&user, &identity); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// randomURLSafeString state・nonce 用のランダム文字列を生成する
+func randomURLSafeString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
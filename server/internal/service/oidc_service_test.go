@@ -0,0 +1,286 @@
+package service_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/service"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+type mockExternalIdentityRepository struct {
+	identities map[uuid.UUID]*models.ExternalIdentity
+	userRepo   *mockUserRepository
+}
+
+func newMockExternalIdentityRepository(userRepo *mockUserRepository) *mockExternalIdentityRepository {
+	return &mockExternalIdentityRepository{
+		identities: make(map[uuid.UUID]*models.ExternalIdentity),
+		userRepo:   userRepo,
+	}
+}
+
+func (m *mockExternalIdentityRepository) GetByIssuerAndSubject(issuer, subject string) (*models.ExternalIdentity, error) {
+	for _, identity := range m.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			copied := *identity
+			return &copied, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (m *mockExternalIdentityRepository) Create(identity *models.ExternalIdentity) error {
+	if identity.ID == uuid.Nil {
+		identity.ID = uuid.New()
+	}
+	identity.CreatedAt = time.Now()
+	m.identities[identity.ID] = identity
+	return nil
+}
+
+func (m *mockExternalIdentityRepository) CreateUserWithIdentity(user *models.User, identity *models.ExternalIdentity) error {
+	if err := m.userRepo.Create(user); err != nil {
+		return err
+	}
+	identity.UserID = user.ID
+	return m.Create(identity)
+}
+
+// stubIssuer テスト用のOpenID Connect IDプロバイダー
+type stubIssuer struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+
+	mu    sync.Mutex
+	codes map[string]stubAuthorization
+}
+
+type stubAuthorization struct {
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newStubIssuer(t *testing.T, clientID string) *stubIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	s := &stubIssuer{key: key, clientID: clientID, codes: make(map[string]stubAuthorization)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                s.server.URL,
+			"authorization_endpoint":                s.server.URL + "/authorize",
+			"token_endpoint":                        s.server.URL + "/token",
+			"jwks_uri":                              s.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-key",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		s.mu.Lock()
+		auth, ok := s.codes[r.PostForm.Get("code")]
+		delete(s.codes, r.PostForm.Get("code"))
+		s.mu.Unlock()
+
+		// PKCE の検証
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != auth.challenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		claims := jwt.MapClaims{
+			"iss":   s.server.URL,
+			"aud":   s.clientID,
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": auth.nonce,
+		}
+		for k, v := range auth.claims {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test-key"
+		idToken, _ := token.SignedString(key)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+
+	s.server = httptest.NewServer(mux)
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// authorize 認可エンドポイントでのユーザー認証をシミュレートし、コールバックに渡される code と state を返す
+func (s *stubIssuer) authorize(t *testing.T, authorizationURL string, claims jwt.MapClaims) (string, string) {
+	t.Helper()
+	u, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatalf("invalid authorization URL: %v", err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("Expected PKCE S256 challenge, got %q", q.Get("code_challenge_method"))
+	}
+
+	code := uuid.NewString()
+	s.mu.Lock()
+	s.codes[code] = stubAuthorization{challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), claims: claims}
+	s.mu.Unlock()
+	return code, q.Get("state")
+}
+
+func TestOIDCService_Login(t *testing.T) {
+	ctx := context.Background()
+	issuer := newStubIssuer(t, "receipt-client")
+	userRepo := newMockUserRepository()
+	identityRepo := newMockExternalIdentityRepository(userRepo)
+	svc := service.NewOIDCService(service.OIDCConfig{
+		Issuer:      issuer.server.URL,
+		ClientID:    "receipt-client",
+		RedirectURL: "http://localhost:3000/login/callback",
+	}, userRepo, identityRepo)
+
+	login := func(t *testing.T, claims jwt.MapClaims) (*service.LoginResult, error) {
+		t.Helper()
+		authorization, err := svc.BeginLogin(ctx)
+		if err != nil {
+			t.Fatalf("BeginLogin failed: %v", err)
+		}
+		code, state := issuer.authorize(t, authorization.AuthorizationURL, claims)
+		return svc.FinishLogin(ctx, authorization.SessionToken, code, state)
+	}
+
+	t.Run("Create federated user", func(t *testing.T) {
+		result, err := login(t, jwt.MapClaims{"sub": "sub-1", "email": "new@example.com", "email_verified": true, "name": "NewUser"})
+		if err != nil {
+			t.Fatalf("FinishLogin failed: %v", err)
+		}
+		if result.Token == "" || result.ReauthenticationToken == "" {
+			t.Errorf("Expected token and reauthentication token to be issued")
+		}
+
+		user, err := userRepo.GetByEmail("new@example.com")
+		if err != nil {
+			t.Fatalf("Expected user to be created: %v", err)
+		}
+		if user.PasswordHash != "" {
+			t.Errorf("Federated-only user should not have a password")
+		}
+		if user.Nickname != "NewUser" {
+			t.Errorf("Expected nickname NewUser, got %s", user.Nickname)
+		}
+
+		// パスワードではログインできない
+		_, err = service.NewUserService(userRepo).Login("new@example.com", "")
+		if !errors.Is(err, service.ErrInvalidCredentials) {
+			t.Errorf("Expected error %v, got %v", service.ErrInvalidCredentials, err)
+		}
+	})
+
+	t.Run("Same subject logs into same user", func(t *testing.T) {
+		result, err := login(t, jwt.MapClaims{"sub": "sub-1", "email": "changed@example.com", "email_verified": true})
+		if err != nil {
+			t.Fatalf("FinishLogin failed: %v", err)
+		}
+		if result.User.Email != "new@example.com" {
+			t.Errorf("Expected existing user, got %s", result.User.Email)
+		}
+	})
+
+	t.Run("Link existing user by verified email", func(t *testing.T) {
+		existing := models.User{Email: "existing@example.com", Nickname: "Existing", PasswordHash: "hash"}
+		_ = userRepo.Create(&existing)
+
+		result, err := login(t, jwt.MapClaims{"sub": "sub-2", "email": "existing@example.com", "email_verified": true})
+		if err != nil {
+			t.Fatalf("FinishLogin failed: %v", err)
+		}
+		if result.User.ID != existing.ID {
+			t.Errorf("Expected user %s to be linked, got %s", existing.ID, result.User.ID)
+		}
+		if _, err := identityRepo.GetByIssuerAndSubject(issuer.server.URL, "sub-2"); err != nil {
+			t.Errorf("Expected identity to be linked: %v", err)
+		}
+	})
+
+	t.Run("Unverified email is rejected", func(t *testing.T) {
+		_, err := login(t, jwt.MapClaims{"sub": "sub-3", "email": "existing@example.com", "email_verified": false})
+		if !errors.Is(err, service.ErrOIDCEmailNotVerified) {
+			t.Errorf("Expected error %v, got %v", service.ErrOIDCEmailNotVerified, err)
+		}
+	})
+
+	t.Run("State mismatch", func(t *testing.T) {
+		authorization, _ := svc.BeginLogin(ctx)
+		code, _ := issuer.authorize(t, authorization.AuthorizationURL, jwt.MapClaims{"sub": "sub-1"})
+		_, err := svc.FinishLogin(ctx, authorization.SessionToken, code, "other-state")
+		if !errors.Is(err, service.ErrInvalidOIDCState) {
+			t.Errorf("Expected error %v, got %v", service.ErrInvalidOIDCState, err)
+		}
+	})
+
+	t.Run("Session from another login cannot exchange the code", func(t *testing.T) {
+		first, _ := svc.BeginLogin(ctx)
+		second, _ := svc.BeginLogin(ctx)
+		code, state := issuer.authorize(t, first.AuthorizationURL, jwt.MapClaims{"sub": "sub-1"})
+
+		// state が一致しない別セッションでは交換できない
+		_, err := svc.FinishLogin(ctx, second.SessionToken, code, state)
+		if !errors.Is(err, service.ErrInvalidOIDCState) {
+			t.Errorf("Expected error %v, got %v", service.ErrInvalidOIDCState, err)
+		}
+	})
+}
+
+func TestOIDCService_NotConfigured(t *testing.T) {
+	userRepo := newMockUserRepository()
+	svc := service.NewOIDCService(service.OIDCConfig{}, userRepo, newMockExternalIdentityRepository(userRepo))
+
+	_, err := svc.BeginLogin(context.Background())
+	if !errors.Is(err, service.ErrOIDCNotConfigured) {
+		t.Errorf("Expected error %v, got %v", service.ErrOIDCNotConfigured, err)
+	}
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+	"receipt/server/internal/utils"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrPasskeyNotFound パスキーが見つからない場合のエラー
+	ErrPasskeyNotFound = errors.New("passkey not found")
+	// ErrInvalidPasskeySession パスキー登録・認証のセッションが無効・期限切れの場合のエラー
+	ErrInvalidPasskeySession = errors.New("invalid or expired passkey session")
+	// ErrPasskeyVerificationFailed パスキーの検証に失敗した場合のエラー
+	ErrPasskeyVerificationFailed = errors.New("passkey verification failed")
+)
+
+const (
+	passkeyRegistrationPurpose = "webauthn_registration"
+	passkeyLoginPurpose        = "webauthn_login"
+	// passkeySessionTTL 登録・認証セレモニーの有効期間
+	passkeySessionTTL = 5 * time.Minute
+)
+
+// PasskeyConfig パスキー（WebAuthn）のRelying Party設定
+type PasskeyConfig struct {
+	RPID          string   // 例: "receipt.example.com"
+	RPDisplayName string   // 認証ダイアログに表示されるサービス名
+	RPOrigins     []string // 例: "https://receipt.example.com"
+}
+
+// PasskeyCeremony ブラウザの navigator.credentials に渡すオプションと、完了時に送り返すセッショントークン
+type PasskeyCeremony struct {
+	Options      interface{} `json:"options"`
+	SessionToken string      `json:"session_token"`
+}
+
+// PasskeyService パスキー（WebAuthn）による登録・ログインに関するビジネスロジックインターフェース
+type PasskeyService interface {
+	BeginRegistration(userID uuid.UUID) (*PasskeyCeremony, error)
+	FinishRegistration(userID uuid.UUID, sessionToken string, name string, response []byte) (*models.Credential, error)
+	BeginLogin() (*PasskeyCeremony, error)
+	FinishLogin(sessionToken string, response []byte) (*LoginResult, error)
+	GetPasskeys(userID uuid.UUID) ([]models.Credential, error)
+	DeletePasskey(userID uuid.UUID, passkeyID uuid.UUID) error
+}
+
+type passkeyServiceImpl struct {
+	webAuthn       *webauthn.WebAuthn
+	userRepo       repository.UserRepository
+	credentialRepo repository.CredentialRepository
+}
+
+// NewPasskeyService PasskeyServiceの実装を作成
+func NewPasskeyService(cfg PasskeyConfig, userRepo repository.UserRepository, credentialRepo repository.CredentialRepository) (PasskeyService, error) {
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPDisplayName,
+		RPOrigins:     cfg.RPOrigins,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &passkeyServiceImpl{
+		webAuthn:       w,
+		userRepo:       userRepo,
+		credentialRepo: credentialRepo,
+	}, nil
+}
+
+// passkeyUser models.User を webauthn.User として扱うためのアダプター
+type passkeyUser struct {
+	user        *models.User
+	credentials []webauthn.Credential
+}
+
+func (u *passkeyUser) WebAuthnID() []byte {
+	id := u.user.ID
+	return id[:]
+}
+
+func (u *passkeyUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *passkeyUser) WebAuthnDisplayName() string {
+	return u.user.Nickname
+}
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+// loadPasskeyUser ユーザーと登録済みパスキーを読み込む
+func (s *passkeyServiceImpl) loadPasskeyUser(userID uuid.UUID) (*passkeyUser, []models.Credential, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, nil, ErrUserNotFound
+	}
+
+	stored, err := s.credentialRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	credentials := make([]webauthn.Credential, 0, len(stored))
+	for _, c := range stored {
+		var credential webauthn.Credential
+		if err := json.Unmarshal([]byte(c.Data), &credential); err != nil {
+			return nil, nil, err
+		}
+		credentials = append(credentials, credential)
+	}
+
+	return &passkeyUser{user: user, credentials: credentials}, stored, nil
+}
+
+func (s *passkeyServiceImpl) BeginRegistration(userID uuid.UUID) (*PasskeyCeremony, error) {
+	pu, _, err := s.loadPasskeyUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 同じ認証器への二重登録を防ぐため、登録済みのクレデンシャルを除外リストに入れる
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(pu.credentials))
+	for _, c := range pu.credentials {
+		exclusions = append(exclusions, c.Descriptor())
+	}
+
+	creation, session, err := s.webAuthn.BeginRegistration(pu,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithExclusions(exclusions),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionToken, err := utils.GenerateStateToken(passkeyRegistrationPurpose, session, passkeySessionTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PasskeyCeremony{Options: creation, SessionToken: sessionToken}, nil
+}
+
+func (s *passkeyServiceImpl) FinishRegistration(userID uuid.UUID, sessionToken string, name string, response []byte) (*models.Credential, error) {
+	var session webauthn.SessionData
+	if err := utils.ParseStateToken(sessionToken, passkeyRegistrationPurpose, &session); err != nil {
+		return nil, ErrInvalidPasskeySession
+	}
+
+	pu, _, err := s.loadPasskeyUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 他のユーザー向けに発行されたセッションは受け付けない
+	if string(session.UserID) != string(pu.WebAuthnID()) {
+		return nil, ErrInvalidPasskeySession
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return nil, ErrPasskeyVerificationFailed
+	}
+
+	credential, err := s.webAuthn.CreateCredential(pu, session, parsed)
+	if err != nil {
+		return nil, ErrPasskeyVerificationFailed
+	}
+
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := models.Credential{
+		UserID:       userID,
+		CredentialID: base64.RawURLEncoding.EncodeToString(credential.ID),
+		Name:         name,
+		Data:         string(data),
+	}
+	if err := s.credentialRepo.Create(&stored); err != nil {
+		return nil, err
+	}
+
+	return &stored, nil
+}
+
+func (s *passkeyServiceImpl) BeginLogin() (*PasskeyCeremony, error) {
+	// ユーザー名を入力せずにログインできるよう、Discoverable Credential で認証する
+	assertion, session, err := s.webAuthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionToken, err := utils.GenerateStateToken(passkeyLoginPurpose, session, passkeySessionTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PasskeyCeremony{Options: assertion, SessionToken: sessionToken}, nil
+}
+
+func (s *passkeyServiceImpl) FinishLogin(sessionToken string, response []byte) (*LoginResult, error) {
+	var session webauthn.SessionData
+	if err := utils.ParseStateToken(sessionToken, passkeyLoginPurpose, &session); err != nil {
+		return nil, ErrInvalidPasskeySession
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return nil, ErrPasskeyVerificationFailed
+	}
+
+	var pu *passkeyUser
+	var stored []models.Credential
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		userID, err := uuid.FromBytes(userHandle)
+		if err != nil {
+			return nil, err
+		}
+		pu, stored, err = s.loadPasskeyUser(userID)
+		if err != nil {
+			return nil, err
+		}
+		return pu, nil
+	}
+
+	credential, err := s.webAuthn.ValidateDiscoverableLogin(handler, session, parsed)
+	if err != nil || pu == nil {
+		return nil, ErrPasskeyVerificationFailed
+	}
+
+	// 署名カウンタが巻き戻っている場合は認証器の複製が疑われるため拒否する
+	if credential.Authenticator.CloneWarning {
+		return nil, ErrPasskeyVerificationFailed
+	}
+
+	if err := checkAccountLock(pu.user, time.Now()); err != nil {
+		return nil, err
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(credential.ID)
+	for i := range stored {
+		if stored[i].CredentialID != credentialID {
+			continue
+		}
+		data, err := json.Marshal(credential)
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		stored[i].Data = string(data)
+		stored[i].LastUsedAt = &now
+		if err := s.credentialRepo.Update(&stored[i]); err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	return withReauthenticationToken(issueLoginToken(s.userRepo, pu.user))
+}
+
+func (s *passkeyServiceImpl) GetPasskeys(userID uuid.UUID) ([]models.Credential, error) {
+	return s.credentialRepo.GetByUserID(userID)
+}
+
+func (s *passkeyServiceImpl) DeletePasskey(userID uuid.UUID, passkeyID uuid.UUID) error {
+	credential, err := s.credentialRepo.GetByID(passkeyID)
+	if err != nil || credential.UserID != userID {
+		return ErrPasskeyNotFound
+	}
+
+	return s.credentialRepo.Delete(credential)
+}
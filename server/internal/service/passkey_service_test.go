@@ -0,0 +1,200 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/service"
+
+	"github.com/google/uuid"
+)
+
+type mockCredentialRepository struct {
+	credentials map[uuid.UUID]*models.Credential
+}
+
+func newMockCredentialRepository() *mockCredentialRepository {
+	return &mockCredentialRepository{
+		credentials: make(map[uuid.UUID]*models.Credential),
+	}
+}
+
+func (m *mockCredentialRepository) Create(credential *models.Credential) error {
+	if credential.ID == uuid.Nil {
+		credential.ID = uuid.New()
+	}
+	credential.CreatedAt = time.Now()
+	credential.UpdatedAt = time.Now()
+	m.credentials[credential.ID] = credential
+	return nil
+}
+
+func (m *mockCredentialRepository) GetByID(id uuid.UUID) (*models.Credential, error) {
+	credential, exists := m.credentials[id]
+	if !exists {
+		return nil, errors.New("record not found")
+	}
+	copied := *credential
+	return &copied, nil
+}
+
+func (m *mockCredentialRepository) GetByCredentialID(credentialID string) (*models.Credential, error) {
+	for _, credential := range m.credentials {
+		if credential.CredentialID == credentialID {
+			copied := *credential
+			return &copied, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (m *mockCredentialRepository) GetByUserID(userID uuid.UUID) ([]models.Credential, error) {
+	var result []models.Credential
+	for _, credential := range m.credentials {
+		if credential.UserID == userID {
+			result = append(result, *credential)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockCredentialRepository) Update(credential *models.Credential) error {
+	if _, exists := m.credentials[credential.ID]; !exists {
+		return errors.New("record not found")
+	}
+	credential.UpdatedAt = time.Now()
+	m.credentials[credential.ID] = credential
+	return nil
+}
+
+func (m *mockCredentialRepository) Delete(credential *models.Credential) error {
+	if _, exists := m.credentials[credential.ID]; !exists {
+		return errors.New("record not found")
+	}
+	delete(m.credentials, credential.ID)
+	return nil
+}
+
+func newTestPasskeyService(t *testing.T, userRepo *mockUserRepository, credentialRepo *mockCredentialRepository) service.PasskeyService {
+	t.Helper()
+	svc, err := service.NewPasskeyService(service.PasskeyConfig{
+		RPID:          "localhost",
+		RPDisplayName: "Receipt",
+		RPOrigins:     []string{"http://localhost:3000"},
+	}, userRepo, credentialRepo)
+	if err != nil {
+		t.Fatalf("NewPasskeyService failed: %v", err)
+	}
+	return svc
+}
+
+func TestPasskeyService_Registration(t *testing.T) {
+	userRepo := newMockUserRepository()
+	credentialRepo := newMockCredentialRepository()
+	svc := newTestPasskeyService(t, userRepo, credentialRepo)
+
+	userA := models.User{Email: "usera@example.com", Nickname: "UserA"}
+	_ = userRepo.Create(&userA)
+	userB := models.User{Email: "userb@example.com", Nickname: "UserB"}
+	_ = userRepo.Create(&userB)
+
+	ceremony, err := svc.BeginRegistration(userA.ID)
+	if err != nil {
+		t.Fatalf("BeginRegistration failed: %v", err)
+	}
+	if ceremony.Options == nil || ceremony.SessionToken == "" {
+		t.Fatalf("Expected options and session token, got %+v", ceremony)
+	}
+
+	t.Run("User Not Found", func(t *testing.T) {
+		_, err := svc.BeginRegistration(uuid.New())
+		if !errors.Is(err, service.ErrUserNotFound) {
+			t.Errorf("Expected error %v, got %v", service.ErrUserNotFound, err)
+		}
+	})
+
+	t.Run("Invalid Session Token", func(t *testing.T) {
+		_, err := svc.FinishRegistration(userA.ID, "invalid-token", "iPhone", []byte(`{}`))
+		if !errors.Is(err, service.ErrInvalidPasskeySession) {
+			t.Errorf("Expected error %v, got %v", service.ErrInvalidPasskeySession, err)
+		}
+	})
+
+	t.Run("Session Issued For Another User", func(t *testing.T) {
+		_, err := svc.FinishRegistration(userB.ID, ceremony.SessionToken, "iPhone", []byte(`{}`))
+		if !errors.Is(err, service.ErrInvalidPasskeySession) {
+			t.Errorf("Expected error %v, got %v", service.ErrInvalidPasskeySession, err)
+		}
+	})
+
+	t.Run("Malformed Response", func(t *testing.T) {
+		_, err := svc.FinishRegistration(userA.ID, ceremony.SessionToken, "iPhone", []byte(`{}`))
+		if !errors.Is(err, service.ErrPasskeyVerificationFailed) {
+			t.Errorf("Expected error %v, got %v", service.ErrPasskeyVerificationFailed, err)
+		}
+		if len(credentialRepo.credentials) != 0 {
+			t.Errorf("Expected no credential to be stored")
+		}
+	})
+}
+
+func TestPasskeyService_Login(t *testing.T) {
+	userRepo := newMockUserRepository()
+	credentialRepo := newMockCredentialRepository()
+	svc := newTestPasskeyService(t, userRepo, credentialRepo)
+
+	ceremony, err := svc.BeginLogin()
+	if err != nil {
+		t.Fatalf("BeginLogin failed: %v", err)
+	}
+	if ceremony.Options == nil || ceremony.SessionToken == "" {
+		t.Fatalf("Expected options and session token, got %+v", ceremony)
+	}
+
+	t.Run("Registration Session Is Rejected", func(t *testing.T) {
+		user := models.User{Email: "user@example.com", Nickname: "User"}
+		_ = userRepo.Create(&user)
+		registration, _ := svc.BeginRegistration(user.ID)
+
+		_, err := svc.FinishLogin(registration.SessionToken, []byte(`{}`))
+		if !errors.Is(err, service.ErrInvalidPasskeySession) {
+			t.Errorf("Expected error %v, got %v", service.ErrInvalidPasskeySession, err)
+		}
+	})
+
+	t.Run("Malformed Response", func(t *testing.T) {
+		_, err := svc.FinishLogin(ceremony.SessionToken, []byte(`{}`))
+		if !errors.Is(err, service.ErrPasskeyVerificationFailed) {
+			t.Errorf("Expected error %v, got %v", service.ErrPasskeyVerificationFailed, err)
+		}
+	})
+}
+
+func TestPasskeyService_DeletePasskey(t *testing.T) {
+	userRepo := newMockUserRepository()
+	credentialRepo := newMockCredentialRepository()
+	svc := newTestPasskeyService(t, userRepo, credentialRepo)
+
+	ownerID := uuid.New()
+	credential := models.Credential{UserID: ownerID, CredentialID: "cred-1", Name: "iPhone", Data: "{}"}
+	_ = credentialRepo.Create(&credential)
+
+	t.Run("Not Owner", func(t *testing.T) {
+		err := svc.DeletePasskey(uuid.New(), credential.ID)
+		if !errors.Is(err, service.ErrPasskeyNotFound) {
+			t.Errorf("Expected error %v, got %v", service.ErrPasskeyNotFound, err)
+		}
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		if err := svc.DeletePasskey(ownerID, credential.ID); err != nil {
+			t.Fatalf("DeletePasskey failed: %v", err)
+		}
+		passkeys, _ := svc.GetPasskeys(ownerID)
+		if len(passkeys) != 0 {
+			t.Errorf("Expected no passkeys, got %d", len(passkeys))
+		}
+	})
+}
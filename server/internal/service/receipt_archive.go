@@ -0,0 +1,76 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+// MaxReceiptArchiveSize まとめて解析するZIPファイルの最大サイズ
+const MaxReceiptArchiveSize = 200 << 20
+
+var (
+	// ErrInvalidReceiptArchive ZIPファイルとして読み込めない場合のエラー
+	ErrInvalidReceiptArchive = errors.New("invalid receipt archive")
+)
+
+// ReceiptFile アップロードされたレシートのファイル（正規化前）
+type ReceiptFile struct {
+	Name string
+	Data []byte
+}
+
+// ExtractReceiptArchive ZIPファイルに含まれるレシートのファイルを格納順に取り出す。
+// フォルダー、隠しファイル（macOS の __MACOSX など）は読み飛ばす
+func ExtractReceiptArchive(data []byte) ([]ReceiptFile, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, ErrInvalidReceiptArchive
+	}
+
+	var files []ReceiptFile
+	for _, entry := range reader.File {
+		if entry.FileInfo().IsDir() || isHiddenArchivePath(entry.Name) {
+			continue
+		}
+		if len(files) >= MaxReceiptBatchSize {
+			return nil, ErrReceiptBatchTooLarge
+		}
+		if entry.UncompressedSize64 > MaxReceiptUploadSize {
+			return nil, ErrImageTooLarge
+		}
+
+		src, err := entry.Open()
+		if err != nil {
+			return nil, ErrInvalidReceiptArchive
+		}
+		// 展開後のサイズを偽ったファイルに備え、読み込む量も制限する
+		fileData, err := io.ReadAll(io.LimitReader(src, MaxReceiptUploadSize+1))
+		src.Close()
+		if err != nil {
+			return nil, ErrInvalidReceiptArchive
+		}
+		if len(fileData) > MaxReceiptUploadSize {
+			return nil, ErrImageTooLarge
+		}
+
+		files = append(files, ReceiptFile{Name: path.Base(entry.Name), Data: fileData})
+	}
+
+	if len(files) == 0 {
+		return nil, ErrEmptyReceiptBatch
+	}
+	return files, nil
+}
+
+func isHiddenArchivePath(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
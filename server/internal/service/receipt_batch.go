@@ -0,0 +1,180 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// MaxReceiptBatchSize 一度にまとめて登録・解析できるレシートの最大件数
+const MaxReceiptBatchSize = 50
+
+var (
+	// ErrEmptyReceiptBatch まとめて登録・解析するレシートが1件もない場合のエラー
+	ErrEmptyReceiptBatch = errors.New("receipt batch is empty")
+	// ErrReceiptBatchTooLarge まとめて登録・解析するレシートが多すぎる場合のエラー
+	ErrReceiptBatchTooLarge = errors.New("receipt batch is too large")
+)
+
+// ReceiptBatchError まとめて登録するレシートのうち、Index 番目（0始まり）の入力が不正な場合のエラー
+type ReceiptBatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *ReceiptBatchError) Error() string {
+	return fmt.Sprintf("receipt %d: %v", e.Index, e.Err)
+}
+
+func (e *ReceiptBatchError) Unwrap() error {
+	return e.Err
+}
+
+// ReceiptDuplicate 重複の疑いで登録しなかったレシート
+type ReceiptDuplicate struct {
+	Index             int        `json:"index"`               // 入力の何番目（0始まり）か
+	DuplicateOfIndex  *int       `json:"duplicate_of_index"`  // 同じ入力内で先に現れた重複相手
+	ExistingReceiptID *uuid.UUID `json:"existing_receipt_id"` // 登録済みのレシートの重複相手
+}
+
+// ReceiptBatchResult レシートのまとめて登録の結果
+type ReceiptBatchResult struct {
+	Receipts   []models.Receipt   `json:"receipts"`
+	Duplicates []ReceiptDuplicate `json:"duplicates"`
+}
+
+// receiptDuplicateKey 購入日・金額・店舗名（正規化）が同じレシートを重複とみなすためのキー
+func receiptDuplicateKey(date time.Time, amount int, shop string) string {
+	return date.UTC().Format("2006-01-02") + "|" + strconv.Itoa(amount) + "|" + utils.NormalizeShopName(shop)
+}
+
+// CreateReceipts 複数のレシートをまとめて登録する。
+// 入力内で、または登録済みのレシートと購入日・金額・店舗名が同じものは、allowDuplicates が false の場合は登録せずに Duplicates で返す。
+// 入力に不正なものが1件でもある場合は何も登録しない
+func (s *receiptServiceImpl) CreateReceipts(groupID uuid.UUID, params []*CreateReceiptParams, userID uuid.UUID, allowDuplicates bool) (*ReceiptBatchResult, error) {
+	if _, err := authorizeGroup(s.groupRepo, groupID, userID, PermissionCreateReceipt); err != nil {
+		return nil, err
+	}
+	if len(params) == 0 {
+		return nil, ErrEmptyReceiptBatch
+	}
+	if len(params) > MaxReceiptBatchSize {
+		return nil, ErrReceiptBatchTooLarge
+	}
+
+	receipts := make([]models.Receipt, len(params))
+	for i, p := range params {
+		if p.Amount <= 0 {
+			return nil, &ReceiptBatchError{Index: i, Err: ErrInvalidAmount}
+		}
+
+		settlementYear, settlementMonth := p.SettlementYear, p.SettlementMonth
+		if settlementYear == 0 || settlementMonth == 0 {
+			settlementYear = p.Date.Year()
+			settlementMonth = int(p.Date.Month())
+		}
+
+		receipts[i] = models.Receipt{
+			GroupID:         groupID,
+			UserID:          userID,
+			Date:            p.Date,
+			SettlementYear:  settlementYear,
+			SettlementMonth: settlementMonth,
+			Shop:            p.Shop,
+			Category:        p.Category,
+			Item:            p.Item,
+			Amount:          p.Amount,
+			PayerID:         p.PayerID,
+			PaymentMethod:   p.PaymentMethod,
+		}
+		if err := s.applyShop(&receipts[i], p.ShopID); err != nil {
+			return nil, &ReceiptBatchError{Index: i, Err: err}
+		}
+	}
+
+	result := &ReceiptBatchResult{Receipts: []models.Receipt{}, Duplicates: []ReceiptDuplicate{}}
+	toCreate := receipts
+	if !allowDuplicates {
+		duplicates, err := s.findDuplicates(groupID, receipts)
+		if err != nil {
+			return nil, err
+		}
+		result.Duplicates = append(result.Duplicates, duplicates...)
+
+		skipped := make(map[int]bool, len(duplicates))
+		for _, d := range duplicates {
+			skipped[d.Index] = true
+		}
+		toCreate = make([]models.Receipt, 0, len(receipts))
+		for i, r := range receipts {
+			if !skipped[i] {
+				toCreate = append(toCreate, r)
+			}
+		}
+	}
+
+	if len(toCreate) == 0 {
+		return result, nil
+	}
+	if err := s.receiptRepo.CreateBatch(toCreate); err != nil {
+		return nil, err
+	}
+
+	for _, r := range toCreate {
+		created, err := s.receiptRepo.GetByIDWithPayer(r.ID)
+		if err != nil {
+			return nil, err
+		}
+		result.Receipts = append(result.Receipts, *created)
+	}
+	return result, nil
+}
+
+// findDuplicates 入力内で先に現れたもの、または登録済みのレシートと重複するものを探す
+func (s *receiptServiceImpl) findDuplicates(groupID uuid.UUID, receipts []models.Receipt) ([]ReceiptDuplicate, error) {
+	from, to := receipts[0].Date, receipts[0].Date
+	for _, r := range receipts[1:] {
+		if r.Date.Before(from) {
+			from = r.Date
+		}
+		if r.Date.After(to) {
+			to = r.Date
+		}
+	}
+	from = from.UTC().Truncate(24 * time.Hour)
+	to = to.UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+
+	existing, err := s.receiptRepo.GetReceiptsByDateRange(groupID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	existingByKey := make(map[string]uuid.UUID, len(existing))
+	for _, r := range existing {
+		key := receiptDuplicateKey(r.Date, r.Amount, r.Shop)
+		if _, found := existingByKey[key]; !found {
+			existingByKey[key] = r.ID
+		}
+	}
+
+	var duplicates []ReceiptDuplicate
+	firstIndex := make(map[string]int, len(receipts))
+	for i, r := range receipts {
+		key := receiptDuplicateKey(r.Date, r.Amount, r.Shop)
+		if id, found := existingByKey[key]; found {
+			duplicates = append(duplicates, ReceiptDuplicate{Index: i, ExistingReceiptID: &id})
+			continue
+		}
+		if first, found := firstIndex[key]; found {
+			duplicates = append(duplicates, ReceiptDuplicate{Index: i, DuplicateOfIndex: &first})
+			continue
+		}
+		firstIndex[key] = i
+	}
+	return duplicates, nil
+}
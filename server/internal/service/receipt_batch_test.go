@@ -0,0 +1,120 @@
+package service_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/service"
+
+	"github.com/google/uuid"
+)
+
+func TestReceiptService_CreateReceipts(t *testing.T) {
+	receiptRepo := newMockReceiptRepository()
+	groupRepo := newMockGroupRepository()
+	userID := uuid.New()
+	groupID := newReceiptTestGroup(groupRepo, userID)
+	svc := service.NewReceiptService(receiptRepo, groupRepo, newMockShopRepository())
+
+	date := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	existing := &models.Receipt{GroupID: groupID, UserID: userID, PayerID: userID, Date: date, Shop: "FamilyMart", Amount: 450, PaymentMethod: "half"}
+	_ = receiptRepo.Create(existing)
+
+	newParams := func() []*service.CreateReceiptParams {
+		return []*service.CreateReceiptParams{
+			{Date: date, Shop: "Lawson", Amount: 300, PayerID: userID, PaymentMethod: "half"},
+			{Date: date, Shop: "ファミリーマート", Amount: 450, PayerID: userID, PaymentMethod: "half"},
+			{Date: date, Shop: "ＬＡＷＳＯＮ", Amount: 300, PayerID: userID, PaymentMethod: "half"},
+			{Date: date, Shop: "familymart", Amount: 450, PayerID: userID, PaymentMethod: "half"},
+		}
+	}
+
+	t.Run("Invalid Receipt Rejects Batch", func(t *testing.T) {
+		params := newParams()
+		params[1].Amount = 0
+		_, err := svc.CreateReceipts(groupID, params, userID, false)
+		var batchErr *service.ReceiptBatchError
+		if !errors.As(err, &batchErr) || batchErr.Index != 1 || !errors.Is(err, service.ErrInvalidAmount) {
+			t.Fatalf("expected ErrInvalidAmount for receipt 1, got %v", err)
+		}
+		if len(receiptRepo.receipts) != 1 {
+			t.Errorf("expected no receipts to be created, got %d receipts", len(receiptRepo.receipts))
+		}
+	})
+
+	t.Run("Skip Duplicates", func(t *testing.T) {
+		result, err := svc.CreateReceipts(groupID, newParams(), userID, false)
+		if err != nil {
+			t.Fatalf("CreateReceipts failed: %v", err)
+		}
+		if len(result.Receipts) != 2 {
+			t.Fatalf("expected 2 receipts to be created, got %d", len(result.Receipts))
+		}
+		if len(result.Duplicates) != 2 {
+			t.Fatalf("expected 2 duplicates, got %+v", result.Duplicates)
+		}
+		if d := result.Duplicates[0]; d.Index != 2 || d.DuplicateOfIndex == nil || *d.DuplicateOfIndex != 0 {
+			t.Errorf("expected receipt 2 to duplicate receipt 0, got %+v", d)
+		}
+		if d := result.Duplicates[1]; d.Index != 3 || d.ExistingReceiptID == nil || *d.ExistingReceiptID != existing.ID {
+			t.Errorf("expected receipt 3 to duplicate the existing receipt, got %+v", d)
+		}
+	})
+
+	t.Run("Allow Duplicates", func(t *testing.T) {
+		result, err := svc.CreateReceipts(groupID, newParams(), userID, true)
+		if err != nil {
+			t.Fatalf("CreateReceipts failed: %v", err)
+		}
+		if len(result.Receipts) != 4 || len(result.Duplicates) != 0 {
+			t.Errorf("expected all 4 receipts to be created, got %d created and %d duplicates", len(result.Receipts), len(result.Duplicates))
+		}
+	})
+
+	t.Run("Too Many Receipts", func(t *testing.T) {
+		params := make([]*service.CreateReceiptParams, service.MaxReceiptBatchSize+1)
+		for i := range params {
+			params[i] = &service.CreateReceiptParams{Date: date, Amount: 100, PayerID: userID, PaymentMethod: "half"}
+		}
+		if _, err := svc.CreateReceipts(groupID, params, userID, true); !errors.Is(err, service.ErrReceiptBatchTooLarge) {
+			t.Errorf("expected ErrReceiptBatchTooLarge, got %v", err)
+		}
+	})
+}
+
+func TestExtractReceiptArchive(t *testing.T) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"trip/1.jpg":            "first",
+		"trip/2.pdf":            "second",
+		"__MACOSX/trip/._1.jpg": "resource fork",
+		"trip/.DS_Store":        "finder",
+	} {
+		f, _ := w.Create(name)
+		_, _ = f.Write([]byte(content))
+	}
+	_, _ = w.Create("trip/")
+	_ = w.Close()
+
+	files, err := service.ExtractReceiptArchive(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ExtractReceiptArchive failed: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 receipt files, got %+v", files)
+	}
+	for _, f := range files {
+		if (f.Name != "1.jpg" || string(f.Data) != "first") && (f.Name != "2.pdf" || string(f.Data) != "second") {
+			t.Errorf("unexpected file %s: %q", f.Name, f.Data)
+		}
+	}
+
+	if _, err := service.ExtractReceiptArchive([]byte("not a zip")); !errors.Is(err, service.ErrInvalidReceiptArchive) {
+		t.Errorf("expected ErrInvalidReceiptArchive, got %v", err)
+	}
+}
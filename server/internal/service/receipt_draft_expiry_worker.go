@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"receipt/server/internal/repository"
+)
+
+// ReceiptDraftExpiryWorker 有効期限を過ぎたレシートの下書きを定期的に削除するバックグラウンドジョブ
+type ReceiptDraftExpiryWorker struct {
+	draftRepo repository.ReceiptDraftRepository
+	interval  time.Duration
+}
+
+// NewReceiptDraftExpiryWorker ReceiptDraftExpiryWorkerを作成
+func NewReceiptDraftExpiryWorker(draftRepo repository.ReceiptDraftRepository, interval time.Duration) *ReceiptDraftExpiryWorker {
+	return &ReceiptDraftExpiryWorker{
+		draftRepo: draftRepo,
+		interval:  interval,
+	}
+}
+
+// Run ctx がキャンセルされるまで一定間隔で期限切れの下書きを削除する
+func (w *ReceiptDraftExpiryWorker) Run(ctx context.Context) {
+	runEvery(ctx, w.interval, "receipt draft expiry", func() error {
+		_, err := w.draftRepo.DeleteExpired(time.Now())
+		return err
+	})
+}
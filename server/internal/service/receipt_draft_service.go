@@ -0,0 +1,169 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// DefaultReceiptDraftTTL 下書きを保存しておく既定の期間
+const DefaultReceiptDraftTTL = 7 * 24 * time.Hour
+
+var (
+	// ErrReceiptDraftNotFound 下書きが見つからない（他のユーザーの下書き・期限切れを含む）
+	ErrReceiptDraftNotFound = errors.New("receipt draft not found")
+	// ErrDraftGroupRequired 下書きにも確定時の入力にもグループがない場合のエラー
+	ErrDraftGroupRequired = errors.New("group is required to confirm the draft")
+	// ErrDraftDateRequired 下書きの購入日を読み取れず、確定時にも指定されない場合のエラー
+	ErrDraftDateRequired = errors.New("date is required to confirm the draft")
+)
+
+// ReceiptDraftService レシートの下書き関連ビジネスロジックインターフェース
+type ReceiptDraftService interface {
+	// CreateDraft AI解析の結果と画像を下書きとして保存し、result.DraftID に下書きのIDを設定する
+	CreateDraft(userID uuid.UUID, groupID *uuid.UUID, img *ReceiptImage, result *AnalyzeReceiptResult) (*models.ReceiptDraft, error)
+	GetDrafts(userID uuid.UUID) ([]models.ReceiptDraft, error)
+	GetDraft(id uuid.UUID, userID uuid.UUID) (*models.ReceiptDraft, error)
+	// ConfirmDraft 下書きをレシートとして登録し、提案から修正された項目を記録して下書きを削除する。
+	// params の未指定（ゼロ値）の項目は下書きの値を使う（支払者の省略時は確定したユーザー）
+	ConfirmDraft(id uuid.UUID, userID uuid.UUID, params *CreateReceiptParams) (*models.Receipt, error)
+	DeleteDraft(id uuid.UUID, userID uuid.UUID) error
+}
+
+type receiptDraftServiceImpl struct {
+	draftRepo      repository.ReceiptDraftRepository
+	feedbackRepo   repository.AnalysisFeedbackRepository
+	receiptService ReceiptService
+	ttl            time.Duration
+	now            func() time.Time
+}
+
+// NewReceiptDraftService ReceiptDraftServiceの実装を作成（ttl が0以下の場合は既定値）
+func NewReceiptDraftService(draftRepo repository.ReceiptDraftRepository, feedbackRepo repository.AnalysisFeedbackRepository, receiptService ReceiptService, ttl time.Duration) ReceiptDraftService {
+	if ttl <= 0 {
+		ttl = DefaultReceiptDraftTTL
+	}
+	return &receiptDraftServiceImpl{
+		draftRepo:      draftRepo,
+		feedbackRepo:   feedbackRepo,
+		receiptService: receiptService,
+		ttl:            ttl,
+		now:            time.Now,
+	}
+}
+
+func (s *receiptDraftServiceImpl) CreateDraft(userID uuid.UUID, groupID *uuid.UUID, img *ReceiptImage, result *AnalyzeReceiptResult) (*models.ReceiptDraft, error) {
+	draft := models.ReceiptDraft{
+		UserID:        userID,
+		GroupID:       groupID,
+		Shop:          result.Shop,
+		AnalyzedShop:  result.AnalyzedShop,
+		ShopID:        result.ShopID,
+		Category:      result.Category,
+		Item:          result.Item,
+		Amount:        result.Amount,
+		PaymentMethod: result.PaymentMethod,
+		NeedsReview:   result.NeedsReview,
+		ExpiresAt:     s.now().Add(s.ttl),
+	}
+	if draft.AnalyzedShop == "" {
+		draft.AnalyzedShop = result.Shop
+	}
+	if date, err := time.Parse("2006-01-02", result.Date); err == nil {
+		draft.Date = &date
+	}
+	if img != nil {
+		draft.MIMEType = img.MIMEType
+		draft.ImageData = img.Data
+	}
+
+	if err := s.draftRepo.Create(&draft); err != nil {
+		return nil, err
+	}
+	result.DraftID = &draft.ID
+	return &draft, nil
+}
+
+func (s *receiptDraftServiceImpl) GetDrafts(userID uuid.UUID) ([]models.ReceiptDraft, error) {
+	return s.draftRepo.GetByUserID(userID, s.now())
+}
+
+func (s *receiptDraftServiceImpl) GetDraft(id uuid.UUID, userID uuid.UUID) (*models.ReceiptDraft, error) {
+	draft, err := s.draftRepo.GetByID(id)
+	if err != nil || draft.UserID != userID || !draft.ExpiresAt.After(s.now()) {
+		return nil, ErrReceiptDraftNotFound
+	}
+	return draft, nil
+}
+
+func (s *receiptDraftServiceImpl) ConfirmDraft(id uuid.UUID, userID uuid.UUID, params *CreateReceiptParams) (*models.Receipt, error) {
+	draft, err := s.GetDraft(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := *params
+	if merged.GroupID == uuid.Nil {
+		if draft.GroupID == nil {
+			return nil, ErrDraftGroupRequired
+		}
+		merged.GroupID = *draft.GroupID
+	}
+	if merged.Date.IsZero() {
+		if draft.Date == nil {
+			return nil, ErrDraftDateRequired
+		}
+		merged.Date = *draft.Date
+	}
+	if merged.Shop == "" {
+		merged.Shop = draft.Shop
+	}
+	// 店舗マスタの店舗は、下書きと同じグループに登録する場合のみ引き継ぐ
+	if merged.ShopID == nil && draft.GroupID != nil && *draft.GroupID == merged.GroupID {
+		merged.ShopID = draft.ShopID
+	}
+	if merged.Category == "" {
+		merged.Category = draft.Category
+	}
+	if merged.Item == "" {
+		merged.Item = draft.Item
+	}
+	if merged.Amount == 0 {
+		merged.Amount = draft.Amount
+	}
+	if merged.PaymentMethod == "" {
+		merged.PaymentMethod = draft.PaymentMethod
+	}
+	if merged.PayerID == uuid.Nil {
+		merged.PayerID = userID
+	}
+
+	receipt, err := s.receiptService.CreateReceipt(&merged, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 提案から修正された項目を記録し、次回以降の解析の補正に使う（レシートは登録済みのため、記録の失敗は確定を妨げない）
+	if err := s.feedbackRepo.Create(newAnalysisFeedback(draft, receipt)); err != nil {
+		log.Printf("failed to record analysis feedback for receipt %s: %v", receipt.ID, err)
+	}
+
+	// レシートは登録済みのため、下書きの削除に失敗してもエラーにしない（再送で重複して登録させない。残った下書きは期限切れで削除される）
+	if err := s.draftRepo.Delete(draft); err != nil {
+		log.Printf("failed to delete receipt draft %s after confirmation: %v", draft.ID, err)
+	}
+	return receipt, nil
+}
+
+func (s *receiptDraftServiceImpl) DeleteDraft(id uuid.UUID, userID uuid.UUID) error {
+	draft, err := s.GetDraft(id, userID)
+	if err != nil {
+		return err
+	}
+	return s.draftRepo.Delete(draft)
+}
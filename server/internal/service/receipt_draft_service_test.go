@@ -0,0 +1,179 @@
+package service_test
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/service"
+
+	"github.com/google/uuid"
+)
+
+// mockReceiptDraftRepository ReceiptDraftRepositoryのモック（解析ワーカーから並行して呼ばれるためロックする）
+type mockReceiptDraftRepository struct {
+	mu        sync.Mutex
+	drafts    map[uuid.UUID]*models.ReceiptDraft
+	deleteErr error
+}
+
+func newMockReceiptDraftRepository() *mockReceiptDraftRepository {
+	return &mockReceiptDraftRepository{drafts: make(map[uuid.UUID]*models.ReceiptDraft)}
+}
+
+func (m *mockReceiptDraftRepository) Create(draft *models.ReceiptDraft) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	draft.ID = uuid.New()
+	draft.CreatedAt = time.Now().Add(time.Duration(len(m.drafts)) * time.Millisecond)
+	stored := *draft
+	m.drafts[draft.ID] = &stored
+	return nil
+}
+
+func (m *mockReceiptDraftRepository) GetByID(id uuid.UUID) (*models.ReceiptDraft, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	draft, exists := m.drafts[id]
+	if !exists {
+		return nil, errors.New("record not found")
+	}
+	copied := *draft
+	return &copied, nil
+}
+
+func (m *mockReceiptDraftRepository) GetByUserID(userID uuid.UUID, now time.Time) ([]models.ReceiptDraft, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var drafts []models.ReceiptDraft
+	for _, draft := range m.drafts {
+		if draft.UserID == userID && draft.ExpiresAt.After(now) {
+			copied := *draft
+			copied.ImageData = nil
+			drafts = append(drafts, copied)
+		}
+	}
+	sort.Slice(drafts, func(i, j int) bool { return drafts[i].CreatedAt.After(drafts[j].CreatedAt) })
+	return drafts, nil
+}
+
+func (m *mockReceiptDraftRepository) Delete(draft *models.ReceiptDraft) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.deleteErr != nil {
+		return m.deleteErr
+	}
+	delete(m.drafts, draft.ID)
+	return nil
+}
+
+func (m *mockReceiptDraftRepository) DeleteExpired(now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for id, draft := range m.drafts {
+		if !draft.ExpiresAt.After(now) {
+			delete(m.drafts, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestReceiptDraftService(t *testing.T) {
+	receiptRepo := newMockReceiptRepository()
+	groupRepo := newMockGroupRepository()
+	userID := uuid.New()
+	otherID := uuid.New()
+	groupID := newReceiptTestGroup(groupRepo, userID)
+	receiptService := service.NewReceiptService(receiptRepo, groupRepo, newMockShopRepository())
+
+	draftRepo := newMockReceiptDraftRepository()
+	svc := service.NewReceiptDraftService(draftRepo, newMockAnalysisFeedbackRepository(), receiptService, time.Hour)
+	img := &service.ReceiptImage{MIMEType: service.MIMETypeJPEG, Data: []byte("jpeg"), PageCount: 1}
+
+	newResult := func() *service.AnalyzeReceiptResult {
+		return &service.AnalyzeReceiptResult{Date: "2026-10-01", Shop: "Lawson", Item: "お弁当", Amount: 580, NeedsReview: []string{"item"}}
+	}
+
+	t.Run("Create And List", func(t *testing.T) {
+		result := newResult()
+		draft, err := svc.CreateDraft(userID, &groupID, img, result)
+		if err != nil {
+			t.Fatalf("CreateDraft failed: %v", err)
+		}
+		if result.DraftID == nil || *result.DraftID != draft.ID {
+			t.Errorf("expected draft id to be set on result, got %v", result.DraftID)
+		}
+		if draft.Date == nil || draft.Date.Format("2006-01-02") != "2026-10-01" || string(draft.ImageData) != "jpeg" {
+			t.Errorf("expected analysis and image to be stored, got %+v", draft)
+		}
+
+		drafts, _ := svc.GetDrafts(userID)
+		if len(drafts) != 1 || drafts[0].ID != draft.ID {
+			t.Errorf("expected 1 draft for user, got %+v", drafts)
+		}
+		if others, _ := svc.GetDrafts(otherID); len(others) != 0 {
+			t.Errorf("expected no drafts for other user, got %d", len(others))
+		}
+		if _, err := svc.GetDraft(draft.ID, otherID); !errors.Is(err, service.ErrReceiptDraftNotFound) {
+			t.Errorf("expected ErrReceiptDraftNotFound for other user, got %v", err)
+		}
+	})
+
+	t.Run("Confirm", func(t *testing.T) {
+		draft, _ := svc.CreateDraft(userID, &groupID, img, newResult())
+
+		receipt, err := svc.ConfirmDraft(draft.ID, userID, &service.CreateReceiptParams{Item: "夕食", PaymentMethod: "half"})
+		if err != nil {
+			t.Fatalf("ConfirmDraft failed: %v", err)
+		}
+		if receipt.GroupID != groupID || receipt.Shop != "Lawson" || receipt.Amount != 580 || receipt.Item != "夕食" || receipt.PayerID != userID {
+			t.Errorf("expected draft values with overrides, got %+v", receipt)
+		}
+		if _, err := svc.GetDraft(draft.ID, userID); !errors.Is(err, service.ErrReceiptDraftNotFound) {
+			t.Errorf("expected draft to be removed after confirmation, got %v", err)
+		}
+	})
+
+	t.Run("Confirm When Draft Deletion Fails", func(t *testing.T) {
+		draft, _ := svc.CreateDraft(userID, &groupID, img, newResult())
+		before, _ := receiptRepo.GetReceiptsByFilter(groupID, nil, nil)
+
+		// レシートは登録済みのため、下書きを削除できなくても登録したレシートを返す
+		draftRepo.deleteErr = errors.New("connection reset")
+		defer func() { draftRepo.deleteErr = nil }()
+		receipt, err := svc.ConfirmDraft(draft.ID, userID, &service.CreateReceiptParams{PaymentMethod: "half"})
+		if err != nil {
+			t.Fatalf("expected the created receipt to be returned, got %v", err)
+		}
+		if after, _ := receiptRepo.GetReceiptsByFilter(groupID, nil, nil); receipt == nil || len(after) != len(before)+1 {
+			t.Errorf("expected exactly one receipt to be created, got %d (was %d)", len(after), len(before))
+		}
+	})
+
+	t.Run("Confirm Without Group", func(t *testing.T) {
+		draft, _ := svc.CreateDraft(userID, nil, img, newResult())
+		if _, err := svc.ConfirmDraft(draft.ID, userID, &service.CreateReceiptParams{PaymentMethod: "half"}); !errors.Is(err, service.ErrDraftGroupRequired) {
+			t.Errorf("expected ErrDraftGroupRequired, got %v", err)
+		}
+		if _, err := svc.GetDraft(draft.ID, userID); err != nil {
+			t.Errorf("expected draft to be kept when confirmation fails, got %v", err)
+		}
+	})
+
+	t.Run("Expired Draft", func(t *testing.T) {
+		draft, _ := svc.CreateDraft(userID, &groupID, img, newResult())
+		draftRepo.drafts[draft.ID].ExpiresAt = time.Now().Add(-time.Minute)
+
+		if _, err := svc.GetDraft(draft.ID, userID); !errors.Is(err, service.ErrReceiptDraftNotFound) {
+			t.Errorf("expected expired draft to be hidden, got %v", err)
+		}
+		if n, _ := draftRepo.DeleteExpired(time.Now()); n != 1 {
+			t.Errorf("expected 1 expired draft to be deleted, got %d", n)
+		}
+	})
+}
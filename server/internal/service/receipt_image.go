@@ -0,0 +1,303 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"regexp"
+
+	_ "image/gif" // image.Decode で GIF を扱う
+	_ "image/png" // image.Decode で PNG を扱う
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // image.Decode で WebP を扱う
+)
+
+var (
+	// ErrImageTooLarge アップロードされたファイルが上限を超える場合のエラー
+	ErrImageTooLarge = errors.New("receipt image is too large")
+	// ErrUnsupportedImageType 解析できない形式のファイルの場合のエラー
+	ErrUnsupportedImageType = errors.New("unsupported receipt image type")
+	// ErrImageProcessingFailed 画像の読み込み・変換に失敗した場合のエラー
+	ErrImageProcessingFailed = errors.New("failed to process receipt image")
+)
+
+const (
+	// MaxReceiptUploadSize 解析できるファイルの最大サイズ
+	MaxReceiptUploadSize = 20 << 20
+	// maxReceiptImageDimension 正規化後の画像の長辺の最大ピクセル数
+	maxReceiptImageDimension = 2048
+	// maxReceiptImagePixels 読み込む画像の最大画素数（48メガピクセルのカメラの画像まで）。
+	// 小さなファイルでも巨大な画素数を宣言した画像を展開するとメモリを使い果たすため、展開する前に確認する
+	maxReceiptImagePixels = 50_000_000
+	// receiptJPEGQuality 正規化後の画像のJPEG品質
+	receiptJPEGQuality = 85
+	// maxReceiptPDFPages 画像に変換するPDFの最大ページ数（画像のみ受け付けるバックエンド向け）
+	maxReceiptPDFPages = 5
+)
+
+// 正規化後のファイル形式
+const (
+	MIMETypeJPEG = "image/jpeg"
+	MIMETypePDF  = "application/pdf"
+)
+
+// pdfPagePattern PDFのページオブジェクト（/Type /Pages は含まない）
+var pdfPagePattern = regexp.MustCompile(`/Type\s*/Page[^s]`)
+
+// ReceiptImage 解析用に正規化したレシートのファイル
+type ReceiptImage struct {
+	MIMEType  string // image/jpeg または application/pdf
+	Data      []byte
+	PageCount int    // PDFのページ数（画像は1）
+	Preview   []byte // PDFの場合、先頭のページを縦に連結したJPEG（変換できない場合はnil）
+}
+
+// JPEG 画像のみを受け付けるバックエンド向けのJPEGデータを返す
+func (img *ReceiptImage) JPEG() ([]byte, error) {
+	if img.MIMEType == MIMETypeJPEG {
+		return img.Data, nil
+	}
+	if img.Preview == nil {
+		return nil, fmt.Errorf("%w: PDF cannot be converted to an image", ErrUnsupportedImageType)
+	}
+	return img.Preview, nil
+}
+
+// ImageConverter 標準ライブラリで扱えない形式を変換するインターフェース
+type ImageConverter interface {
+	// ConvertHEIC HEIC/HEIF画像をJPEGに変換する
+	ConvertHEIC(ctx context.Context, data []byte) ([]byte, error)
+	// RasterizePDF PDFの先頭 maxPages ページを縦に連結したJPEGに変換する
+	RasterizePDF(ctx context.Context, data []byte, maxPages int) ([]byte, error)
+}
+
+// ReceiptImageProcessor アップロードされたファイルを解析用に正規化するインターフェース
+type ReceiptImageProcessor interface {
+	// Prepare ファイル形式を判定し、画像はEXIFの向きに回転・縮小してJPEGに、PDFはそのまま返す
+	Prepare(ctx context.Context, data []byte) (*ReceiptImage, error)
+}
+
+type receiptImageProcessorImpl struct {
+	converter ImageConverter
+}
+
+// NewReceiptImageProcessor ReceiptImageProcessorの実装を作成（converter がnilの場合はHEICを受け付けず、PDFは画像に変換しない）
+func NewReceiptImageProcessor(converter ImageConverter) ReceiptImageProcessor {
+	return &receiptImageProcessorImpl{converter: converter}
+}
+
+func (p *receiptImageProcessorImpl) Prepare(ctx context.Context, data []byte) (*ReceiptImage, error) {
+	if len(data) > MaxReceiptUploadSize {
+		return nil, ErrImageTooLarge
+	}
+
+	switch mimeType := DetectReceiptMIMEType(data); mimeType {
+	case MIMETypePDF:
+		img := &ReceiptImage{MIMEType: MIMETypePDF, Data: data, PageCount: len(pdfPagePattern.FindAllIndex(data, -1))}
+		if img.PageCount == 0 {
+			img.PageCount = 1
+		}
+		// Gemini はPDFをそのまま扱えるため、画像への変換に失敗しても解析は続ける
+		if p.converter != nil {
+			if preview, err := p.converter.RasterizePDF(ctx, data, maxReceiptPDFPages); err == nil {
+				img.Preview = preview
+			}
+		}
+		return img, nil
+
+	case "image/heic":
+		if p.converter == nil {
+			return nil, fmt.Errorf("%w: HEIC conversion is not configured", ErrUnsupportedImageType)
+		}
+		converted, err := p.converter.ConvertHEIC(ctx, data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrImageProcessingFailed, err)
+		}
+		return normalizeReceiptImage(converted)
+
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return normalizeReceiptImage(data)
+
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedImageType, mimeType)
+	}
+}
+
+// DetectReceiptMIMEType ファイルの先頭のバイト列から形式を判定する（HEIC/HEIFにも対応）
+func DetectReceiptMIMEType(data []byte) string {
+	if isHEIC(data) {
+		return "image/heic"
+	}
+	return http.DetectContentType(data)
+}
+
+// heicBrands HEIC/HEIF画像の ftyp ボックスのブランド
+var heicBrands = map[string]bool{
+	"heic": true, "heix": true, "heim": true, "heis": true,
+	"hevc": true, "hevx": true, "mif1": true, "msf1": true,
+}
+
+func isHEIC(data []byte) bool {
+	if len(data) < 12 || string(data[4:8]) != "ftyp" {
+		return false
+	}
+	size := int(binary.BigEndian.Uint32(data[0:4]))
+	if size < 12 || size > len(data) {
+		size = len(data)
+	}
+	// メジャーブランドと互換ブランドのいずれかがHEIFであればHEICとみなす
+	if heicBrands[string(data[8:12])] {
+		return true
+	}
+	for i := 16; i+4 <= size; i += 4 {
+		if heicBrands[string(data[i:i+4])] {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeReceiptImage 画像の長辺が上限を超える場合は縮小し、EXIFの向きに回転してJPEGに変換する
+func normalizeReceiptImage(data []byte) (*ReceiptImage, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImageProcessingFailed, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width > maxReceiptImagePixels/config.Height {
+		return nil, fmt.Errorf("%w: %dx%d pixels", ErrImageTooLarge, config.Width, config.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImageProcessingFailed, err)
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if longest := max(width, height); longest > maxReceiptImageDimension {
+		width = max(1, width*maxReceiptImageDimension/longest)
+		height = max(1, height*maxReceiptImageDimension/longest)
+	}
+
+	// 透過部分は白で塗りつぶす（JPEGはアルファを持たない）
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(scaled, scaled.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), src, bounds, draw.Over, nil)
+
+	// 縮小した後の画像を回転する（元の解像度の画像を複製しない）
+	dst := applyEXIFOrientation(scaled, jpegEXIFOrientation(data))
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: receiptJPEGQuality}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImageProcessingFailed, err)
+	}
+	return &ReceiptImage{MIMEType: MIMETypeJPEG, Data: buf.Bytes(), PageCount: 1}, nil
+}
+
+// jpegEXIFOrientation JPEGのEXIFに記録された向き（1〜8）を返す。記録がない場合は1
+func jpegEXIFOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xD9 || marker == 0xDA { // EOI / SOS 以降にEXIFはない
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation EXIF（TIFF形式）の IFD0 から Orientation タグ（0x0112）を読み取る
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd : ifd+2]))
+	for n := 0; n < entries; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			if orientation := int(order.Uint16(tiff[entry+8 : entry+10])); orientation >= 1 && orientation <= 8 {
+				return orientation
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyEXIFOrientation EXIFの向きに従って画像を回転・反転する
+func applyEXIFOrientation(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	// 5〜8 は90度回転を含むため縦横が入れ替わる
+	dstW, dstH := w, h
+	if orientation >= 5 {
+		dstW, dstH = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // 左右反転
+				dx, dy = w-1-x, y
+			case 3: // 180度回転
+				dx, dy = w-1-x, h-1-y
+			case 4: // 上下反転
+				dx, dy = x, h-1-y
+			case 5: // 左右反転して反時計回りに90度
+				dx, dy = y, x
+			case 6: // 時計回りに90度
+				dx, dy = h-1-y, x
+			case 7: // 左右反転して時計回りに90度
+				dx, dy = h-1-y, w-1-x
+			case 8: // 反時計回りに90度
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(b.Min.X+x, b.Min.Y+y):])
+		}
+	}
+	return dst
+}
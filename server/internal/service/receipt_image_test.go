@@ -0,0 +1,213 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"receipt/server/internal/service"
+)
+
+// stubImageConverter 変換結果を固定で返すImageConverter
+type stubImageConverter struct {
+	heic     []byte
+	pdf      []byte
+	err      error
+	maxPages int
+}
+
+func (s *stubImageConverter) ConvertHEIC(ctx context.Context, data []byte) ([]byte, error) {
+	return s.heic, s.err
+}
+
+func (s *stubImageConverter) RasterizePDF(ctx context.Context, data []byte, maxPages int) ([]byte, error) {
+	s.maxPages = maxPages
+	return s.pdf, s.err
+}
+
+// encodeTestImage 左上の8x8ピクセルだけ赤い白色の画像をエンコードする
+func encodeTestImage(t *testing.T, width int, height int, encode func(*bytes.Buffer, image.Image) error) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	for y := 0; y < min(8, height); y++ {
+		for x := 0; x < min(8, width); x++ {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(buf *bytes.Buffer, img image.Image) error { return png.Encode(buf, img) }
+
+func encodeJPEG(buf *bytes.Buffer, img image.Image) error { return jpeg.Encode(buf, img, nil) }
+
+// withEXIFOrientation JPEGの先頭に Orientation タグだけを持つEXIF（APP1）を挿入する
+func withEXIFOrientation(jpegData []byte, orientation uint16) []byte {
+	var tiff bytes.Buffer
+	tiff.WriteString("MM")
+	_ = binary.Write(&tiff, binary.BigEndian, uint16(42))
+	_ = binary.Write(&tiff, binary.BigEndian, uint32(8))                // IFD0 のオフセット
+	_ = binary.Write(&tiff, binary.BigEndian, uint16(1))                // エントリ数
+	_ = binary.Write(&tiff, binary.BigEndian, []uint16{0x0112, 3})      // Orientation, SHORT
+	_ = binary.Write(&tiff, binary.BigEndian, uint32(1))                // 個数
+	_ = binary.Write(&tiff, binary.BigEndian, []uint16{orientation, 0}) // 値
+	_ = binary.Write(&tiff, binary.BigEndian, uint32(0))                // 次の IFD なし
+
+	segment := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	var out bytes.Buffer
+	out.Write(jpegData[:2]) // SOI
+	out.Write([]byte{0xFF, 0xE1})
+	_ = binary.Write(&out, binary.BigEndian, uint16(len(segment)+2))
+	out.Write(segment)
+	out.Write(jpegData[2:])
+	return out.Bytes()
+}
+
+// pngHeader 画素データを持たず、幅と高さだけを宣言したPNG（シグネチャとIHDRチャンク）
+func pngHeader(width, height uint32) []byte {
+	var ihdr bytes.Buffer
+	ihdr.WriteString("IHDR")
+	_ = binary.Write(&ihdr, binary.BigEndian, width)
+	_ = binary.Write(&ihdr, binary.BigEndian, height)
+	ihdr.Write([]byte{8, 6, 0, 0, 0}) // 8bit RGBA
+
+	var out bytes.Buffer
+	out.WriteString("\x89PNG\r\n\x1a\n")
+	_ = binary.Write(&out, binary.BigEndian, uint32(ihdr.Len()-4))
+	out.Write(ihdr.Bytes())
+	_ = binary.Write(&out, binary.BigEndian, crc32.ChecksumIEEE(ihdr.Bytes()))
+	return out.Bytes()
+}
+
+func decodeJPEG(t *testing.T, data []byte) image.Image {
+	t.Helper()
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("expected JPEG output: %v", err)
+	}
+	return img
+}
+
+func TestReceiptImageProcessor_Prepare(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("PNG Is Resized To JPEG", func(t *testing.T) {
+		processor := service.NewReceiptImageProcessor(nil)
+		img, err := processor.Prepare(ctx, encodeTestImage(t, 4096, 1024, encodePNG))
+		if err != nil {
+			t.Fatalf("Prepare failed: %v", err)
+		}
+		if img.MIMEType != service.MIMETypeJPEG {
+			t.Errorf("expected image/jpeg, got %s", img.MIMEType)
+		}
+		if b := decodeJPEG(t, img.Data).Bounds(); b.Dx() != 2048 || b.Dy() != 512 {
+			t.Errorf("expected 2048x512, got %dx%d", b.Dx(), b.Dy())
+		}
+	})
+
+	t.Run("JPEG Is Rotated By EXIF", func(t *testing.T) {
+		processor := service.NewReceiptImageProcessor(nil)
+		// 時計回りに90度回転して表示する画像（Orientation 6）
+		data := withEXIFOrientation(encodeTestImage(t, 40, 20, encodeJPEG), 6)
+		img, err := processor.Prepare(ctx, data)
+		if err != nil {
+			t.Fatalf("Prepare failed: %v", err)
+		}
+		decoded := decodeJPEG(t, img.Data)
+		if b := decoded.Bounds(); b.Dx() != 20 || b.Dy() != 40 {
+			t.Fatalf("expected 20x40 after rotation, got %dx%d", b.Dx(), b.Dy())
+		}
+		// 左上の赤い部分は右上に移動する
+		if r, g, _, _ := decoded.At(16, 3).RGBA(); r < 0x8000 || g > 0x8000 {
+			t.Errorf("expected top-right corner to be red after rotation")
+		}
+		if r, g, _, _ := decoded.At(3, 3).RGBA(); r < 0x8000 || g < 0x8000 {
+			t.Errorf("expected top-left corner to be white after rotation")
+		}
+
+		// 縮小する画像も、縮小後に回転する
+		img, err = processor.Prepare(ctx, withEXIFOrientation(encodeTestImage(t, 4096, 1024, encodeJPEG), 6))
+		if err != nil {
+			t.Fatalf("Prepare failed: %v", err)
+		}
+		if b := decodeJPEG(t, img.Data).Bounds(); b.Dx() != 512 || b.Dy() != 2048 {
+			t.Errorf("expected 512x2048 after resizing and rotation, got %dx%d", b.Dx(), b.Dy())
+		}
+	})
+
+	t.Run("HEIC Is Converted", func(t *testing.T) {
+		heic := append([]byte{0, 0, 0, 24}, []byte("ftypmif1\x00\x00\x00\x00mif1heic")...)
+		if got := service.DetectReceiptMIMEType(heic); got != "image/heic" {
+			t.Fatalf("expected image/heic, got %s", got)
+		}
+
+		if _, err := service.NewReceiptImageProcessor(nil).Prepare(ctx, heic); !errors.Is(err, service.ErrUnsupportedImageType) {
+			t.Errorf("expected ErrUnsupportedImageType without converter, got %v", err)
+		}
+
+		converter := &stubImageConverter{heic: encodeTestImage(t, 10, 10, encodeJPEG)}
+		img, err := service.NewReceiptImageProcessor(converter).Prepare(ctx, heic)
+		if err != nil {
+			t.Fatalf("Prepare failed: %v", err)
+		}
+		if img.MIMEType != service.MIMETypeJPEG {
+			t.Errorf("expected image/jpeg, got %s", img.MIMEType)
+		}
+
+		converter.err = errors.New("no delegate for heic")
+		if _, err := service.NewReceiptImageProcessor(converter).Prepare(ctx, heic); !errors.Is(err, service.ErrImageProcessingFailed) {
+			t.Errorf("expected ErrImageProcessingFailed, got %v", err)
+		}
+	})
+
+	t.Run("Multi-page PDF Is Kept", func(t *testing.T) {
+		pdf := []byte("%PDF-1.7\n1 0 obj << /Type /Pages /Count 2 >>\n2 0 obj << /Type /Page >>\n3 0 obj << /Type/Page >>\n%%EOF")
+		preview := encodeTestImage(t, 10, 20, encodeJPEG)
+		converter := &stubImageConverter{pdf: preview}
+
+		img, err := service.NewReceiptImageProcessor(converter).Prepare(ctx, pdf)
+		if err != nil {
+			t.Fatalf("Prepare failed: %v", err)
+		}
+		if img.MIMEType != service.MIMETypePDF || img.PageCount != 2 || !bytes.Equal(img.Data, pdf) {
+			t.Errorf("unexpected PDF image: type=%s pages=%d", img.MIMEType, img.PageCount)
+		}
+		if data, err := img.JPEG(); err != nil || !bytes.Equal(data, preview) {
+			t.Errorf("expected rasterized preview, got err=%v", err)
+		}
+		if converter.maxPages <= 1 {
+			t.Errorf("expected multiple pages to be rasterized, got %d", converter.maxPages)
+		}
+	})
+
+	t.Run("Unsupported And Too Large", func(t *testing.T) {
+		processor := service.NewReceiptImageProcessor(nil)
+		if _, err := processor.Prepare(ctx, []byte("just some text")); !errors.Is(err, service.ErrUnsupportedImageType) {
+			t.Errorf("expected ErrUnsupportedImageType, got %v", err)
+		}
+		if _, err := processor.Prepare(ctx, make([]byte, service.MaxReceiptUploadSize+1)); !errors.Is(err, service.ErrImageTooLarge) {
+			t.Errorf("expected ErrImageTooLarge, got %v", err)
+		}
+		// ファイルは小さくても、展開すると巨大になる画像は読み込まない
+		if _, err := processor.Prepare(ctx, pngHeader(60000, 60000)); !errors.Is(err, service.ErrImageTooLarge) {
+			t.Errorf("expected ErrImageTooLarge for too many pixels, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"receipt/server/internal/models"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	// sentenceMonthDayPattern 年を省略した日付（例: 10/18、10月18日）
+	sentenceMonthDayPattern = regexp.MustCompile(`(\d{1,2})\s*[/月]\s*(\d{1,2})日?`)
+	// sentenceDaysAgoPattern 何日前か（例: 3日前、3 days ago）
+	sentenceDaysAgoPattern = regexp.MustCompile(`(\d+)\s*(?:日前|days?\s+ago)`)
+	// sentenceAmountPattern 金額（例: 3200円、¥3,200、3200 yen）
+	sentenceAmountPattern = regexp.MustCompile(`([¥\\])?\s*(\d{1,3}(?:,\d{3})+|\d+)\s*(円|yen)?`)
+	// sentenceEnglishShopPattern 英語の店舗名（例: "at Saizeriya yesterday"）
+	sentenceEnglishShopPattern = regexp.MustCompile(`(?i)\bat\s+(.+?)(?:\s+(?:yesterday|today|on|for|last|and)\b|\s+[¥\\]?\d|[,.;!]|$)`)
+	// sentenceEnglishItemPattern 英語の品目（例: "for dinner at ..."）
+	sentenceEnglishItemPattern = regexp.MustCompile(`(?i)\bfor\s+(.+?)(?:\s+(?:at|yesterday|today|on|last|and)\b|\s+[¥\\]?\d|[,.;!]|$)`)
+	// sentenceJapaneseShopPattern 日本語の店舗名（例: 「サイゼリヤで」）
+	sentenceJapaneseShopPattern = regexp.MustCompile(`([^\s、。,.!！?？\d]+?)で`)
+)
+
+var (
+	// sentenceRelativeDays 相対的な日付を表す語と基準日からの日数（長い語から照合する）
+	sentenceRelativeDays = []struct {
+		words []string
+		days  int
+	}{
+		{[]string{"一昨日", "おととい", "day before yesterday"}, -2},
+		{[]string{"昨日", "きのう", "yesterday"}, -1},
+		{[]string{"今日", "本日", "きょう", "today"}, 0},
+	}
+	// sentencePaymentMethods 負担の仕方を表す語（相手負担・自分負担・折半の順に照合する）
+	sentencePaymentMethods = []struct {
+		method   string
+		keywords []string
+	}{
+		{models.PaymentMethodOther, []string{"全額相手", "相手持ち", "相手負担", "相手が全額", "立て替え", "立替", "they pay all", "on them"}},
+		{models.PaymentMethodSelf, []string{"全額自分", "自分持ち", "自分負担", "自分が全額", "おごり", "奢り", "pay all", "paid all", "my treat", "on me"}},
+		{models.PaymentMethodHalf, []string{"折半", "割り勘", "割勘", "半分", "split", "half", "50/50"}},
+	}
+	// sentenceNonShopWords 「〜で」の前にあっても店舗名ではない語
+	sentenceNonShopWords = []string{"現金", "カード", "クレジット", "電子マネー", "paypay", "折半", "割り勘", "割勘", "半分", "全額", "自分", "相手", "みんな", "二人", "2人"}
+	// sentencePayerVerbs 支払者を表す語（ニックネームの後に続く）
+	sentencePayerVerbs = []string{"が払", "が支払", "が立て替", "が出し", " paid"}
+	// sentenceHonorifics ニックネームに付く敬称
+	sentenceHonorifics = []string{"", "さん", "くん", "君", "ちゃん"}
+)
+
+// ParseReceiptSentence 自然文のレシートの説明から、日付・店舗名・品目・金額・支払者・負担の仕方をルールに従って推定する。
+// AIを使わない解析（local / fake）と、AIが読み取れなかった項目の補完に使う
+func ParseReceiptSentence(input *ReceiptTextInput) *ReceiptTextResult {
+	text := norm.NFKC.String(strings.TrimSpace(input.Text))
+	lower := strings.ToLower(text)
+	result := &ReceiptTextResult{}
+
+	if date, ok := resolveSentenceDate(lower, input.Today); ok {
+		result.Date = date
+	}
+	result.Amount = sentenceAmount(lower)
+	result.PaymentMethod = sentencePaymentMethod(lower)
+	result.Payer = sentencePayer(lower, input.Members)
+
+	if m := sentenceEnglishShopPattern.FindStringSubmatch(text); m != nil {
+		result.Shop = strings.TrimSpace(m[1])
+	} else {
+		for _, m := range sentenceJapaneseShopPattern.FindAllStringSubmatch(text, -1) {
+			shop := trimRelativeDayWords(m[1])
+			if shop != "" && !containsAny(strings.ToLower(shop), sentenceNonShopWords) {
+				result.Shop = shop
+				break
+			}
+		}
+	}
+	if m := sentenceEnglishItemPattern.FindStringSubmatch(text); m != nil {
+		result.Item = strings.TrimSpace(m[1])
+	}
+	return result
+}
+
+// resolveSentenceDate 文中の日付（年月日・月日・「昨日」・「3日前」など）を、today を基準に YYYY-MM-DD に変換する
+func resolveSentenceDate(lower string, today time.Time) (string, bool) {
+	if date, ok := parseReceiptDate(lower); ok {
+		return date, true
+	}
+
+	if m := sentenceDaysAgoPattern.FindStringSubmatch(lower); m != nil {
+		days, _ := strconv.Atoi(m[1])
+		return today.AddDate(0, 0, -days).Format("2006-01-02"), true
+	}
+
+	if m := sentenceMonthDayPattern.FindStringSubmatch(lower); m != nil {
+		month, _ := strconv.Atoi(m[1])
+		day, _ := strconv.Atoi(m[2])
+		date := time.Date(today.Year(), time.Month(month), day, 0, 0, 0, 0, today.Location())
+		if int(date.Month()) == month && date.Day() == day {
+			// 年を省略した日付が未来になる場合は前年とみなす
+			if date.After(today) {
+				date = date.AddDate(-1, 0, 0)
+			}
+			return date.Format("2006-01-02"), true
+		}
+	}
+
+	for _, relative := range sentenceRelativeDays {
+		if containsAny(lower, relative.words) {
+			return today.AddDate(0, 0, relative.days).Format("2006-01-02"), true
+		}
+	}
+	return "", false
+}
+
+// sentenceAmount 文中の金額。通貨の記号・単位が付いた数値を優先し、なければ日付以外の最大の数値
+func sentenceAmount(lower string) int {
+	lower = receiptDatePattern.ReplaceAllString(lower, " ")
+	lower = sentenceMonthDayPattern.ReplaceAllString(lower, " ")
+	lower = sentenceDaysAgoPattern.ReplaceAllString(lower, " ")
+
+	largest := 0
+	for _, m := range sentenceAmountPattern.FindAllStringSubmatch(lower, -1) {
+		amount, err := strconv.Atoi(strings.ReplaceAll(m[2], ",", ""))
+		if err != nil {
+			continue
+		}
+		if m[1] != "" || m[3] != "" {
+			return amount
+		}
+		largest = max(largest, amount)
+	}
+	return largest
+}
+
+// sentencePaymentMethod 文中の負担の仕方を表す語から支払い方法の定数を返す（見つからない場合は空）
+func sentencePaymentMethod(lower string) string {
+	for _, pm := range sentencePaymentMethods {
+		if containsAny(lower, pm.keywords) {
+			return pm.method
+		}
+	}
+	return ""
+}
+
+// sentencePayer 「太郎が払った」「paid by Taro」のように支払者として書かれたメンバーのニックネーム
+func sentencePayer(lower string, members []string) string {
+	for _, member := range members {
+		name := strings.ToLower(norm.NFKC.String(member))
+		if name == "" {
+			continue
+		}
+		if strings.Contains(lower, "paid by "+name) {
+			return member
+		}
+		for _, honorific := range sentenceHonorifics {
+			for _, verb := range sentencePayerVerbs {
+				if strings.Contains(lower, name+honorific+verb) {
+					return member
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// trimRelativeDayWords 店舗名の前に付いた「昨日」などの語を取り除く
+func trimRelativeDayWords(s string) string {
+	for _, relative := range sentenceRelativeDays {
+		for _, word := range relative.words {
+			s = strings.TrimPrefix(s, word)
+		}
+	}
+	return strings.TrimSpace(s)
+}
@@ -1,19 +1,14 @@
 package service
 
 import (
-	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
 	"strings"
 	"time"
 
 	"receipt/server/internal/models"
 	"receipt/server/internal/repository"
 
-	"github.com/google/generative-ai-go/genai"
 	"github.com/google/uuid"
-	"google.golang.org/api/option"
 )
 
 var (
@@ -25,6 +20,8 @@ var (
 	ErrAlreadySettled  = errors.New("cannot modify settled receipt")
 	// ErrInvalidAmount 金額が不正な場合のエラー
 	ErrInvalidAmount   = errors.New("amount must be at least 1")
+	// ErrPaymentMethodRequired 支払い方法が未指定で、店舗の既定値もない場合のエラー
+	ErrPaymentMethodRequired = errors.New("payment method is required")
 )
 
 // CreateReceiptParams レシート作成・更新用パラメータ
@@ -34,35 +31,54 @@ type CreateReceiptParams struct {
 	SettlementYear  int
 	SettlementMonth int
 	Shop            string
+	ShopID          *uuid.UUID // 店舗マスタの店舗（nilの場合は店舗名から照合する）
+	Category        string     // 空の場合は店舗の既定の分類
 	Item            string
 	Amount          int
 	PayerID         uuid.UUID
-	PaymentMethod   string
+	PaymentMethod   string // 空の場合は店舗の既定の支払い方法
 }
 
 // ReceiptService レシートのCRUD管理に関するビジネスロジックインターフェース
 type ReceiptService interface {
-	GetReceipts(groupID uuid.UUID, year *int, month *int) ([]models.Receipt, error)
+	GetReceipts(groupID uuid.UUID, userID uuid.UUID, year *int, month *int) ([]models.Receipt, error)
 	CreateReceipt(params *CreateReceiptParams, userID uuid.UUID) (*models.Receipt, error)
-	GetReceipt(id uuid.UUID) (*models.Receipt, error)
+	// CreateReceipts 複数のレシートをまとめて登録する（重複の疑いがあるものは allowDuplicates が false の場合は登録しない）
+	CreateReceipts(groupID uuid.UUID, params []*CreateReceiptParams, userID uuid.UUID, allowDuplicates bool) (*ReceiptBatchResult, error)
+	GetReceipt(id uuid.UUID, userID uuid.UUID) (*models.Receipt, error)
 	UpdateReceipt(id uuid.UUID, params *CreateReceiptParams, userID uuid.UUID) (*models.Receipt, error)
 	DeleteReceipt(id uuid.UUID, userID uuid.UUID) error
 }
 
 type receiptServiceImpl struct {
 	receiptRepo repository.ReceiptRepository
+	groupRepo   repository.GroupRepository
+	shopRepo    repository.ShopRepository
 }
 
 // NewReceiptService ReceiptServiceの実装を作成
-func NewReceiptService(receiptRepo repository.ReceiptRepository) ReceiptService {
-	return &receiptServiceImpl{receiptRepo: receiptRepo}
+func NewReceiptService(receiptRepo repository.ReceiptRepository, groupRepo repository.GroupRepository, shopRepo repository.ShopRepository) ReceiptService {
+	return &receiptServiceImpl{
+		receiptRepo: receiptRepo,
+		groupRepo:   groupRepo,
+		shopRepo:    shopRepo,
+	}
 }
 
-func (s *receiptServiceImpl) GetReceipts(groupID uuid.UUID, year *int, month *int) ([]models.Receipt, error) {
+func (s *receiptServiceImpl) GetReceipts(groupID uuid.UUID, userID uuid.UUID, year *int, month *int) ([]models.Receipt, error) {
+	if _, err := authorizeGroup(s.groupRepo, groupID, userID, PermissionViewGroup); err != nil {
+		return nil, err
+	}
+
 	return s.receiptRepo.GetReceiptsByFilter(groupID, year, month)
 }
 
 func (s *receiptServiceImpl) CreateReceipt(params *CreateReceiptParams, userID uuid.UUID) (*models.Receipt, error) {
+	// 閲覧のみのメンバーはレシートを登録できない
+	if _, err := authorizeGroup(s.groupRepo, params.GroupID, userID, PermissionCreateReceipt); err != nil {
+		return nil, err
+	}
+
 	if params.Amount <= 0 {
 		return nil, ErrInvalidAmount
 	}
@@ -81,12 +97,17 @@ func (s *receiptServiceImpl) CreateReceipt(params *CreateReceiptParams, userID u
 		SettlementYear:  settlementYear,
 		SettlementMonth: settlementMonth,
 		Shop:            params.Shop,
+		Category:        params.Category,
 		Item:            params.Item,
 		Amount:          params.Amount,
 		PayerID:         params.PayerID,
 		PaymentMethod:   params.PaymentMethod,
 	}
 
+	if err := s.applyShop(&receipt, params.ShopID); err != nil {
+		return nil, err
+	}
+
 	if err := s.receiptRepo.Create(&receipt); err != nil {
 		return nil, err
 	}
@@ -94,11 +115,15 @@ func (s *receiptServiceImpl) CreateReceipt(params *CreateReceiptParams, userID u
 	return s.receiptRepo.GetByIDWithPayer(receipt.ID)
 }
 
-func (s *receiptServiceImpl) GetReceipt(id uuid.UUID) (*models.Receipt, error) {
+func (s *receiptServiceImpl) GetReceipt(id uuid.UUID, userID uuid.UUID) (*models.Receipt, error) {
 	receipt, err := s.receiptRepo.GetByIDWithPayer(id)
 	if err != nil {
 		return nil, ErrReceiptNotFound
 	}
+
+	if _, err := authorizeGroup(s.groupRepo, receipt.GroupID, userID, PermissionViewGroup); err != nil {
+		return nil, err
+	}
 	return receipt, nil
 }
 
@@ -112,6 +137,11 @@ func (s *receiptServiceImpl) UpdateReceipt(id uuid.UUID, params *CreateReceiptPa
 		return nil, ErrNotCreator
 	}
 
+	// 閲覧のみに変更されたメンバーは、自分が登録したレシートも編集できない
+	if _, err := authorizeGroup(s.groupRepo, receipt.GroupID, userID, PermissionCreateReceipt); err != nil {
+		return nil, err
+	}
+
 	if receipt.SettledAt != nil {
 		return nil, ErrAlreadySettled
 	}
@@ -131,11 +161,17 @@ func (s *receiptServiceImpl) UpdateReceipt(id uuid.UUID, params *CreateReceiptPa
 	receipt.SettlementYear = settlementYear
 	receipt.SettlementMonth = settlementMonth
 	receipt.Shop = params.Shop
+	receipt.ShopID = nil
+	receipt.Category = params.Category
 	receipt.Item = params.Item
 	receipt.Amount = params.Amount
 	receipt.PayerID = params.PayerID
 	receipt.PaymentMethod = params.PaymentMethod
 
+	if err := s.applyShop(receipt, params.ShopID); err != nil {
+		return nil, err
+	}
+
 	if err := s.receiptRepo.Update(receipt); err != nil {
 		return nil, err
 	}
@@ -153,6 +189,10 @@ func (s *receiptServiceImpl) DeleteReceipt(id uuid.UUID, userID uuid.UUID) error
 		return ErrNotCreator
 	}
 
+	if _, err := authorizeGroup(s.groupRepo, receipt.GroupID, userID, PermissionCreateReceipt); err != nil {
+		return err
+	}
+
 	if receipt.SettledAt != nil {
 		return ErrAlreadySettled
 	}
@@ -160,72 +200,39 @@ func (s *receiptServiceImpl) DeleteReceipt(id uuid.UUID, userID uuid.UUID) error
 	return s.receiptRepo.Delete(receipt)
 }
 
-// AIAnalyzer AIによるレシート解析インターフェース
-type AIAnalyzer interface {
-	AnalyzeReceipt(ctx context.Context, imgData []byte) (*AnalyzeReceiptResult, error)
-}
-
-// AnalyzeReceiptResult 解析結果
-type AnalyzeReceiptResult struct {
-	Date   string `json:"date"`
-	Shop   string `json:"shop"`
-	Item   string `json:"item"`
-	Amount int    `json:"amount"`
-}
-
-type geminiAIAnalyzer struct {
-	apiKey string
-}
-
-// NewAIAnalyzer AIAnalyzerの実装を作成
-func NewAIAnalyzer(apiKey string) AIAnalyzer {
-	return &geminiAIAnalyzer{apiKey: apiKey}
-}
-
-func (a *geminiAIAnalyzer) AnalyzeReceipt(ctx context.Context, imgData []byte) (*AnalyzeReceiptResult, error) {
-	if a.apiKey == "" {
-		return nil, errors.New("GOOGLE_API_KEY is not set")
-	}
-
-	client, err := genai.NewClient(ctx, option.WithAPIKey(a.apiKey))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
-	}
-	defer client.Close()
-
-	model := client.GenerativeModel("gemini-flash-latest")
-
-	prompt := []genai.Part{
-		genai.ImageData("jpeg", imgData),
-		genai.Text("Analyze this receipt and return JSON only. Use YYYY-MM-DD for date, name for shop, summary for item, and integer for amount. JSON:\n{\"date\": \"YYYY-MM-DD\", \"shop\": \"name\", \"item\": \"summary\", \"amount\": 1234}"),
-	}
-
-	resp, err := model.GenerateContent(ctx, prompt...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate content: %w", err)
-	}
-
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		return nil, errors.New("no results from Gemini")
+// applyShop レシートを店舗マスタの店舗に紐付け、未指定の分類・支払い方法に店舗の既定値を設定する。
+// shopID が指定されない場合は入力された店舗名を店舗名・別名と照合する
+func (s *receiptServiceImpl) applyShop(receipt *models.Receipt, shopID *uuid.UUID) error {
+	var shop *models.Shop
+	if shopID != nil {
+		found, err := s.shopRepo.GetByID(*shopID)
+		if err != nil || found.GroupID != receipt.GroupID {
+			return ErrShopNotFound
+		}
+		shop = found
+	} else if strings.TrimSpace(receipt.Shop) != "" {
+		shops, err := s.shopRepo.GetByGroupID(receipt.GroupID)
+		if err != nil {
+			return err
+		}
+		shop = matchShop(shops, receipt.Shop)
 	}
 
-	var resultText string
-	for _, part := range resp.Candidates[0].Content.Parts {
-		if text, ok := part.(genai.Text); ok {
-			resultText += string(text)
+	if shop != nil {
+		receipt.ShopID = &shop.ID
+		if strings.TrimSpace(receipt.Shop) == "" {
+			receipt.Shop = shop.Name
+		}
+		if receipt.Category == "" {
+			receipt.Category = shop.DefaultCategory
+		}
+		if receipt.PaymentMethod == "" {
+			receipt.PaymentMethod = shop.DefaultPaymentMethod
 		}
 	}
 
-	resultText = strings.TrimSpace(resultText)
-	resultText = strings.TrimPrefix(resultText, "```json")
-	resultText = strings.TrimPrefix(resultText, "```")
-	resultText = strings.TrimSuffix(resultText, "```")
-	resultText = strings.TrimSpace(resultText)
-
-	var analyzeResult AnalyzeReceiptResult
-	if err := json.Unmarshal([]byte(resultText), &analyzeResult); err != nil {
-		return nil, fmt.Errorf("failed to parse Gemini response: %w", err)
+	if receipt.PaymentMethod == "" {
+		return ErrPaymentMethodRequired
 	}
-
-	return &analyzeResult, nil
+	return nil
 }
@@ -3,10 +3,14 @@ package service_test
 import (
 	"context"
 	"errors"
+	"slices"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
 	"receipt/server/internal/service"
 
 	"github.com/google/uuid"
@@ -32,6 +36,17 @@ func (m *mockReceiptRepository) Create(receipt *models.Receipt) error {
 	return nil
 }
 
+func (m *mockReceiptRepository) CreateBatch(receipts []models.Receipt) error {
+	for i := range receipts {
+		created := receipts[i]
+		if err := m.Create(&created); err != nil {
+			return err
+		}
+		receipts[i].ID = created.ID
+	}
+	return nil
+}
+
 func (m *mockReceiptRepository) GetByID(id uuid.UUID) (*models.Receipt, error) {
 	receipt, exists := m.receipts[id]
 	if !exists {
@@ -78,20 +93,100 @@ func (m *mockReceiptRepository) GetReceiptsByFilter(groupID uuid.UUID, year *int
 	return result, nil
 }
 
+func (m *mockReceiptRepository) GetReceiptsByUser(userID uuid.UUID) ([]models.Receipt, error) {
+	var result []models.Receipt
+	for _, receipt := range m.receipts {
+		if receipt.UserID == userID || receipt.PayerID == userID {
+			result = append(result, *receipt)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockReceiptRepository) GetReceiptsByDateRange(groupID uuid.UUID, from time.Time, to time.Time) ([]models.Receipt, error) {
+	var result []models.Receipt
+	for _, receipt := range m.receipts {
+		if receipt.GroupID == groupID && !receipt.Date.Before(from) && receipt.Date.Before(to) {
+			result = append(result, *receipt)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockReceiptRepository) SearchReceipts(query *repository.ReceiptQuery) ([]models.Receipt, error) {
+	var result []models.Receipt
+	for _, receipt := range m.receipts {
+		if receipt.GroupID != query.GroupID ||
+			(query.From != nil && receipt.Date.Before(*query.From)) ||
+			(query.To != nil && !receipt.Date.Before(*query.To)) ||
+			(query.PayerID != nil && receipt.PayerID != *query.PayerID) ||
+			(len(query.Categories) > 0 && !slices.Contains(query.Categories, receipt.Category)) ||
+			(query.Keyword != "" && !strings.Contains(receipt.Item, query.Keyword) && !strings.Contains(receipt.Shop, query.Keyword)) {
+			continue
+		}
+		if len(query.Shops) > 0 && !slices.ContainsFunc(query.Shops, func(shop string) bool { return strings.Contains(receipt.Shop, shop) }) {
+			continue
+		}
+		result = append(result, *receipt)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Date.After(result[j].Date) })
+	return result, nil
+}
+
+func (m *mockReceiptRepository) GetCategories(groupID uuid.UUID) ([]string, error) {
+	var categories []string
+	for _, receipt := range m.receipts {
+		if receipt.GroupID == groupID && receipt.Category != "" && !slices.Contains(categories, receipt.Category) {
+			categories = append(categories, receipt.Category)
+		}
+	}
+	sort.Strings(categories)
+	return categories, nil
+}
+
 type mockAIAnalyzer struct {
-	analyzeFunc func(ctx context.Context, imgData []byte) (*service.AnalyzeReceiptResult, error)
+	analyzeFunc   func(ctx context.Context, img *service.ReceiptImage) (*service.AnalyzeReceiptResult, error)
+	parseTextFunc func(ctx context.Context, input *service.ReceiptTextInput) (*service.ReceiptTextResult, error)
+	questionFunc  func(ctx context.Context, input *service.SpendingQuestionInput) (*service.SpendingQuery, error)
+}
+
+func (m *mockAIAnalyzer) AnalyzeReceipt(ctx context.Context, img *service.ReceiptImage) (*service.AnalyzeReceiptResult, error) {
+	return m.analyzeFunc(ctx, img)
+}
+
+// ParseText parseTextFunc が指定されていなければルールベースの解析結果を返す
+func (m *mockAIAnalyzer) ParseText(ctx context.Context, input *service.ReceiptTextInput) (*service.ReceiptTextResult, error) {
+	if m.parseTextFunc == nil {
+		return service.ParseReceiptSentence(input), nil
+	}
+	return m.parseTextFunc(ctx, input)
 }
 
-func (m *mockAIAnalyzer) AnalyzeReceipt(ctx context.Context, imgData []byte) (*service.AnalyzeReceiptResult, error) {
-	return m.analyzeFunc(ctx, imgData)
+// TranslateQuestion questionFunc が指定されていなければルールベースの変換結果を返す
+func (m *mockAIAnalyzer) TranslateQuestion(ctx context.Context, input *service.SpendingQuestionInput) (*service.SpendingQuery, error) {
+	if m.questionFunc == nil {
+		return service.ParseSpendingQuestion(input), nil
+	}
+	return m.questionFunc(ctx, input)
+}
+
+// newReceiptTestGroup テスト用にグループを作成し、指定ユーザーをメンバーとして追加する
+func newReceiptTestGroup(groupRepo *mockGroupRepository, memberIDs ...uuid.UUID) uuid.UUID {
+	group := models.Group{Name: "Receipt Test"}
+	_ = groupRepo.Create(&group)
+	for _, id := range memberIDs {
+		_ = groupRepo.AddMember(&group, &models.User{ID: id})
+	}
+	return group.ID
 }
 
 func TestReceiptService_CreateReceipt(t *testing.T) {
 	repo := newMockReceiptRepository()
-	svc := service.NewReceiptService(repo)
+	groupRepo := newMockGroupRepository()
+	svc := service.NewReceiptService(repo, groupRepo, newMockShopRepository())
 
 	userID := uuid.New()
-	groupID := uuid.New()
+	groupID := newReceiptTestGroup(groupRepo, userID)
 	payerID := userID
 	date := time.Date(2026, 6, 5, 12, 0, 0, 0, time.UTC)
 
@@ -133,14 +228,49 @@ func TestReceiptService_CreateReceipt(t *testing.T) {
 			t.Errorf("Expected error %v, got %v", service.ErrInvalidAmount, err)
 		}
 	})
+
+	t.Run("Forbidden - Viewer", func(t *testing.T) {
+		viewerID := uuid.New()
+		_ = groupRepo.AddMember(&models.Group{ID: groupID}, &models.User{ID: viewerID})
+		_ = groupRepo.SetMemberRole(groupID, viewerID, models.GroupRoleViewer)
+
+		params := &service.CreateReceiptParams{
+			GroupID:       groupID,
+			Date:          date,
+			Amount:        1000,
+			PayerID:       viewerID,
+			PaymentMethod: "half",
+		}
+
+		_, err := svc.CreateReceipt(params, viewerID)
+		if !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("Expected error %v, got %v", service.ErrPermissionDenied, err)
+		}
+	})
+
+	t.Run("Forbidden - Not Member", func(t *testing.T) {
+		params := &service.CreateReceiptParams{
+			GroupID:       groupID,
+			Date:          date,
+			Amount:        1000,
+			PayerID:       userID,
+			PaymentMethod: "half",
+		}
+
+		_, err := svc.CreateReceipt(params, uuid.New())
+		if !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("Expected error %v, got %v", service.ErrPermissionDenied, err)
+		}
+	})
 }
 
 func TestReceiptService_GetReceipt(t *testing.T) {
 	repo := newMockReceiptRepository()
-	svc := service.NewReceiptService(repo)
+	groupRepo := newMockGroupRepository()
+	svc := service.NewReceiptService(repo, groupRepo, newMockShopRepository())
 
 	userID := uuid.New()
-	groupID := uuid.New()
+	groupID := newReceiptTestGroup(groupRepo, userID)
 	date := time.Now()
 
 	params := &service.CreateReceiptParams{
@@ -155,7 +285,7 @@ func TestReceiptService_GetReceipt(t *testing.T) {
 	created, _ := svc.CreateReceipt(params, userID)
 
 	t.Run("Success", func(t *testing.T) {
-		receipt, err := svc.GetReceipt(created.ID)
+		receipt, err := svc.GetReceipt(created.ID, userID)
 		if err != nil {
 			t.Fatalf("GetReceipt failed: %v", err)
 		}
@@ -165,7 +295,7 @@ func TestReceiptService_GetReceipt(t *testing.T) {
 	})
 
 	t.Run("Not Found", func(t *testing.T) {
-		_, err := svc.GetReceipt(uuid.New())
+		_, err := svc.GetReceipt(uuid.New(), userID)
 		if !errors.Is(err, service.ErrReceiptNotFound) {
 			t.Errorf("Expected error %v, got %v", service.ErrReceiptNotFound, err)
 		}
@@ -174,11 +304,12 @@ func TestReceiptService_GetReceipt(t *testing.T) {
 
 func TestReceiptService_UpdateReceipt(t *testing.T) {
 	repo := newMockReceiptRepository()
-	svc := service.NewReceiptService(repo)
+	groupRepo := newMockGroupRepository()
+	svc := service.NewReceiptService(repo, groupRepo, newMockShopRepository())
 
 	userID := uuid.New()
 	otherUserID := uuid.New()
-	groupID := uuid.New()
+	groupID := newReceiptTestGroup(groupRepo, userID, otherUserID)
 	date := time.Now()
 
 	params := &service.CreateReceiptParams{
@@ -249,11 +380,12 @@ func TestReceiptService_UpdateReceipt(t *testing.T) {
 
 func TestReceiptService_DeleteReceipt(t *testing.T) {
 	repo := newMockReceiptRepository()
-	svc := service.NewReceiptService(repo)
+	groupRepo := newMockGroupRepository()
+	svc := service.NewReceiptService(repo, groupRepo, newMockShopRepository())
 
 	userID := uuid.New()
 	otherUserID := uuid.New()
-	groupID := uuid.New()
+	groupID := newReceiptTestGroup(groupRepo, userID, otherUserID)
 	date := time.Now()
 
 	params := &service.CreateReceiptParams{
@@ -296,12 +428,12 @@ func TestAIAnalyzer_Mock(t *testing.T) {
 	}
 
 	analyzer := &mockAIAnalyzer{
-		analyzeFunc: func(ctx context.Context, imgData []byte) (*service.AnalyzeReceiptResult, error) {
+		analyzeFunc: func(ctx context.Context, img *service.ReceiptImage) (*service.AnalyzeReceiptResult, error) {
 			return mockResult, nil
 		},
 	}
 
-	result, err := analyzer.AnalyzeReceipt(context.Background(), []byte("fake image data"))
+	result, err := analyzer.AnalyzeReceipt(context.Background(), &service.ReceiptImage{MIMEType: service.MIMETypeJPEG, Data: []byte("fake image data")})
 	if err != nil {
 		t.Fatalf("AnalyzeReceipt failed: %v", err)
 	}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	// receiptDatePattern レシートの日付（例: 2026/01/05、2026-1-5、2026年1月5日）
+	receiptDatePattern = regexp.MustCompile(`(\d{4})\s*[/\-.年]\s*(\d{1,2})\s*[/\-.月]\s*(\d{1,2})`)
+	// receiptPriceLinePattern 末尾に金額がある行（例: "牛乳 ¥198"、"パン 2,480円 軽"）
+	receiptPriceLinePattern = regexp.MustCompile(`^(.*?)[\s:：]*[¥\\]?\s*(\d{1,3}(?:,\d{3})+|\d+)\s*円?\s*[*※軽外内税]?$`)
+	// receiptPhonePattern 電話番号の行
+	receiptPhonePattern = regexp.MustCompile(`(?i)(tel|電話|\d{2,4}-\d{2,4}-\d{3,4})`)
+)
+
+var (
+	// receiptTotalKeywords 合計金額の行に含まれる語（小計は除く）
+	receiptTotalKeywords = []string{"合計", "お買上", "お買い上げ", "お会計", "ご請求", "total"}
+	// receiptNonItemKeywords 品目ではない金額の行に含まれる語
+	receiptNonItemKeywords = []string{"合計", "小計", "税", "お釣", "釣銭", "おつり", "預", "現金", "クレジット", "カード", "点数", "対象", "値引", "割引", "ポイント", "total", "change", "cash", "tax"}
+	// receiptNonShopKeywords 店舗名ではない行に含まれる語
+	receiptNonShopKeywords = []string{"領収", "レシート", "ありがとう", "いらっしゃいませ", "http", "www"}
+)
+
+// maxReceiptItemNames 品目の要約に含める品目名の数
+const maxReceiptItemNames = 3
+
+// ルールベースの解析で推定した項目の確からしさ
+const (
+	parsedDateConfidence     = 0.8 // 日付の形式に一致した
+	parsedShopConfidence     = 0.6 // 先頭付近の文字列の行を店舗名とみなした
+	parsedItemConfidence     = 0.5 // 金額の付いた行を品目とみなした
+	parsedTotalConfidence    = 0.8 // 合計の行から読み取った
+	parsedMaxPriceConfidence = 0.3 // 合計の行がなく、品目の最大金額で代用した
+)
+
+// ParseReceiptText OCRで読み取ったレシートの文字列から、日付・店舗名・品目・合計金額をルールに従って推定する。
+// 推定できなかった項目は空（金額は0）・確からしさ0のまま返す
+func ParseReceiptText(text string) *AnalyzeReceiptResult {
+	result := &AnalyzeReceiptResult{}
+
+	var lines []string
+	for _, line := range strings.Split(norm.NFKC.String(text), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	var itemNames []string
+	maxPrice := 0
+	for _, line := range lines {
+		lower := strings.ToLower(line)
+
+		if result.Date == "" {
+			if date, ok := parseReceiptDate(line); ok {
+				result.Date = date
+				continue
+			}
+		}
+
+		if result.Shop == "" && isReceiptShopLine(line, lower) {
+			result.Shop = line
+			continue
+		}
+
+		// 日付・電話番号の数字は金額として扱わない
+		if receiptDatePattern.MatchString(line) || receiptPhonePattern.MatchString(line) {
+			continue
+		}
+
+		m := receiptPriceLinePattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		price, err := strconv.Atoi(strings.ReplaceAll(m[2], ",", ""))
+		if err != nil {
+			continue
+		}
+
+		switch {
+		case containsAny(lower, receiptTotalKeywords) && !strings.Contains(lower, "小計"):
+			// 「合計」が複数ある場合（税率ごとの内訳など）は最も大きいものを合計とみなす
+			if price > result.Amount {
+				result.Amount = price
+			}
+		case containsAny(lower, receiptNonItemKeywords):
+		default:
+			name := strings.TrimSpace(m[1])
+			if name != "" {
+				itemNames = append(itemNames, name)
+				if price > maxPrice {
+					maxPrice = price
+				}
+			}
+		}
+	}
+
+	// 合計の行が読み取れない場合は、品目の最大金額で代用する
+	if result.Amount > 0 {
+		result.Confidence.Amount = parsedTotalConfidence
+	} else if maxPrice > 0 {
+		result.Amount = maxPrice
+		result.Confidence.Amount = parsedMaxPriceConfidence
+	}
+	if result.Date != "" {
+		result.Confidence.Date = parsedDateConfidence
+	}
+	if result.Shop != "" {
+		result.Shop = truncateRunes(result.Shop, maxAnalyzedTextLength)
+		result.Confidence.Shop = parsedShopConfidence
+	}
+	if len(itemNames) > 0 {
+		result.Confidence.Item = parsedItemConfidence
+	}
+
+	if len(itemNames) > maxReceiptItemNames {
+		result.Item = strings.Join(itemNames[:maxReceiptItemNames], "、") + " ほか"
+	} else {
+		result.Item = strings.Join(itemNames, "、")
+	}
+	result.Item = truncateRunes(result.Item, maxAnalyzedTextLength)
+
+	result.markFieldsForReview()
+	return result
+}
+
+// parseReceiptDate 行に含まれる日付を YYYY-MM-DD 形式で返す
+func parseReceiptDate(line string) (string, bool) {
+	m := receiptDatePattern.FindStringSubmatch(line)
+	if m == nil {
+		return "", false
+	}
+	year, _ := strconv.Atoi(m[1])
+	month, _ := strconv.Atoi(m[2])
+	day, _ := strconv.Atoi(m[3])
+
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local)
+	if date.Year() != year || int(date.Month()) != month || date.Day() != day {
+		return "", false
+	}
+	return date.Format("2006-01-02"), true
+}
+
+// isReceiptShopLine 店舗名とみなせる行かどうか（文字を含み、日付・電話番号・金額・定型文ではない）
+func isReceiptShopLine(line string, lower string) bool {
+	if receiptDatePattern.MatchString(line) || receiptPhonePattern.MatchString(line) || receiptPriceLinePattern.MatchString(line) {
+		return false
+	}
+	if containsAny(lower, receiptNonShopKeywords) {
+		return false
+	}
+	return strings.IndexFunc(line, unicode.IsLetter) >= 0
+}
+
+func containsAny(s string, keywords []string) bool {
+	for _, k := range keywords {
+		if strings.Contains(s, k) {
+			return true
+		}
+	}
+	return false
+}
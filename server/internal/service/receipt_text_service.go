@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+
+	"github.com/google/uuid"
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxReceiptTextLength 自然文の入力の最大文字数
+const maxReceiptTextLength = 500
+
+// ErrInvalidReceiptText 自然文の入力が空、または長すぎる場合のエラー
+var ErrInvalidReceiptText = errors.New("text must be between 1 and 500 characters")
+
+// receiptTextSelfWords 書いた本人を表す支払者の語
+var receiptTextSelfWords = []string{"me", "i", "myself", "自分", "私", "わたし", "僕", "俺"}
+
+// ParsedReceiptText 自然文から作成したレシートの登録内容
+type ParsedReceiptText struct {
+	Params      *CreateReceiptParams
+	NeedsReview []string // 文から読み取れず既定値で補った、または空のままの項目（date, shop, amount, payer, payment_method）
+}
+
+// ReceiptTextService 自然文からのレシートの入力に関するビジネスロジックインターフェース
+type ReceiptTextService interface {
+	// ParseText 「昨日サイゼリヤで3200円、折半」のような文を、登録前のレシートの内容に変換する（登録はしない）
+	ParseText(ctx context.Context, groupID uuid.UUID, userID uuid.UUID, text string) (*ParsedReceiptText, error)
+}
+
+type receiptTextServiceImpl struct {
+	groupRepo    repository.GroupRepository
+	shopService  ShopService
+	usageService AIUsageService
+	analyzer     AIAnalyzer
+	now          func() time.Time
+}
+
+// NewReceiptTextService ReceiptTextServiceの実装を作成
+func NewReceiptTextService(groupRepo repository.GroupRepository, shopService ShopService, usageService AIUsageService, analyzer AIAnalyzer) ReceiptTextService {
+	return &receiptTextServiceImpl{
+		groupRepo:    groupRepo,
+		shopService:  shopService,
+		usageService: usageService,
+		analyzer:     analyzer,
+		now:          time.Now,
+	}
+}
+
+func (s *receiptTextServiceImpl) ParseText(ctx context.Context, groupID uuid.UUID, userID uuid.UUID, text string) (*ParsedReceiptText, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || utf8.RuneCountInString(text) > maxReceiptTextLength {
+		return nil, ErrInvalidReceiptText
+	}
+
+	if _, err := authorizeGroup(s.groupRepo, groupID, userID, PermissionCreateReceipt); err != nil {
+		return nil, err
+	}
+	group, err := s.groupRepo.GetByIDWithMembers(groupID)
+	if err != nil {
+		return nil, ErrGroupNotFound
+	}
+
+	now := s.now()
+	input := &ReceiptTextInput{
+		Text:  text,
+		Today: time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()),
+	}
+	for _, member := range group.Members {
+		if member.ID == userID {
+			input.Speaker = member.Nickname
+		} else {
+			input.Members = append(input.Members, member.Nickname)
+		}
+	}
+
+	usageID, err := s.usageService.ReserveUsage(userID, &groupID, models.AIUsageParseText)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.analyzer.ParseText(ctx, input)
+	// 読み取りに失敗してもモデルは呼び出しているため記録を残す
+	var usage AITokenUsage
+	if result != nil {
+		usage = result.Usage
+	}
+	s.usageService.FinishUsage(ctx, usageID, usage)
+	if err != nil {
+		return nil, err
+	}
+
+	// モデルが読み取れなかった項目はルールに従って補う
+	fallback := ParseReceiptSentence(input)
+	if result.Date == "" {
+		result.Date = fallback.Date
+	}
+	if result.Shop == "" {
+		result.Shop = fallback.Shop
+	}
+	if result.Item == "" {
+		result.Item = fallback.Item
+	}
+	if result.Amount <= 0 {
+		result.Amount = fallback.Amount
+	}
+	if result.Payer == "" {
+		result.Payer = fallback.Payer
+	}
+	if !isValidPaymentMethod(result.PaymentMethod) {
+		result.PaymentMethod = fallback.PaymentMethod
+	}
+
+	parsed := &ParsedReceiptText{Params: &CreateReceiptParams{
+		GroupID: groupID,
+		Shop:    result.Shop,
+		Item:    result.Item,
+		Amount:  result.Amount,
+	}}
+
+	date, err := time.ParseInLocation("2006-01-02", result.Date, now.Location())
+	if err != nil {
+		date = input.Today
+		parsed.NeedsReview = append(parsed.NeedsReview, "date")
+	}
+	parsed.Params.Date = date
+
+	// 店舗マスタと照合できた場合は、店舗と既定の分類・支払い方法を使う（文中の支払い方法を優先する）
+	suggestion := &AnalyzeReceiptResult{Shop: result.Shop}
+	if err := s.shopService.SuggestShop(groupID, userID, suggestion); err != nil {
+		return nil, err
+	}
+	parsed.Params.Shop = suggestion.Shop
+	parsed.Params.ShopID = suggestion.ShopID
+	parsed.Params.Category = suggestion.Category
+	parsed.Params.PaymentMethod = result.PaymentMethod
+	if parsed.Params.PaymentMethod == "" {
+		parsed.Params.PaymentMethod = suggestion.PaymentMethod
+	}
+
+	payerID, ok := resolveReceiptTextPayer(result.Payer, userID, group.Members)
+	parsed.Params.PayerID = payerID
+
+	if parsed.Params.Shop == "" {
+		parsed.NeedsReview = append(parsed.NeedsReview, "shop")
+	}
+	if parsed.Params.Amount <= 0 {
+		parsed.NeedsReview = append(parsed.NeedsReview, "amount")
+	}
+	if !ok {
+		parsed.NeedsReview = append(parsed.NeedsReview, "payer")
+	}
+	if parsed.Params.PaymentMethod == "" {
+		parsed.NeedsReview = append(parsed.NeedsReview, "payment_method")
+	}
+	return parsed, nil
+}
+
+// resolveReceiptTextPayer 支払者のニックネームをメンバーのIDに変換する（大文字・小文字、全角・半角、敬称の違いは無視する）。
+// 空または本人を表す語の場合は書いた本人、どのメンバーとも一致しない場合は書いた本人と false を返す
+func resolveReceiptTextPayer(payer string, userID uuid.UUID, members []models.User) (uuid.UUID, bool) {
+	name := normalizeNickname(payer)
+	if name == "" {
+		return userID, true
+	}
+	for _, word := range receiptTextSelfWords {
+		if name == word {
+			return userID, true
+		}
+	}
+	for _, member := range members {
+		if normalizeNickname(member.Nickname) == name {
+			return member.ID, true
+		}
+	}
+	return userID, false
+}
+
+// normalizeNickname ニックネームを照合用に正規化する
+func normalizeNickname(nickname string) string {
+	name := strings.ToLower(strings.TrimSpace(norm.NFKC.String(nickname)))
+	for _, honorific := range sentenceHonorifics {
+		if honorific != "" {
+			name = strings.TrimSuffix(name, honorific)
+		}
+	}
+	return strings.TrimSpace(name)
+}
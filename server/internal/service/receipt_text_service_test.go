@@ -0,0 +1,188 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/service"
+
+	"github.com/google/uuid"
+)
+
+func TestParseReceiptSentence(t *testing.T) {
+	today := time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local)
+	members := []string{"Hanako", "太郎"}
+
+	tests := []struct {
+		name string
+		text string
+		want service.ReceiptTextResult
+	}{
+		{
+			name: "Japanese Relative Date",
+			text: "昨日サイゼリヤで夕食3,200円、折半",
+			want: service.ReceiptTextResult{Date: "2026-10-18", Shop: "サイゼリヤ", Amount: 3200, PaymentMethod: models.PaymentMethodHalf},
+		},
+		{
+			name: "English Pay All",
+			text: "Dinner at Saizeriya yesterday 3200 yen, I pay all",
+			want: service.ReceiptTextResult{Date: "2026-10-18", Shop: "Saizeriya", Amount: 3200, PaymentMethod: models.PaymentMethodSelf},
+		},
+		{
+			name: "Member Paid",
+			text: "3日前 スーパーで¥1,280 太郎さんが払った",
+			want: service.ReceiptTextResult{Date: "2026-10-16", Shop: "スーパー", Amount: 1280, Payer: "太郎"},
+		},
+		{
+			name: "Paid By Member For Other",
+			text: "Paid by hanako for groceries at Aeon 10/20 4500, they pay all",
+			want: service.ReceiptTextResult{Date: "2025-10-20", Shop: "Aeon", Item: "groceries", Amount: 4500, Payer: "Hanako", PaymentMethod: models.PaymentMethodOther},
+		},
+		{
+			name: "No Date",
+			text: "コンビニで500円",
+			want: service.ReceiptTextResult{Shop: "コンビニ", Amount: 500},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := service.ParseReceiptSentence(&service.ReceiptTextInput{Text: tt.text, Today: today, Members: members})
+			if *got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, *got)
+			}
+		})
+	}
+}
+
+func TestReceiptTextService(t *testing.T) {
+	groupRepo := newMockGroupRepository()
+	shopRepo := newMockShopRepository()
+	userID := uuid.New()
+	memberID := uuid.New()
+	otherID := uuid.New()
+
+	group := models.Group{Name: "Receipt Text Test"}
+	_ = groupRepo.Create(&group)
+	_ = groupRepo.AddMember(&group, &models.User{ID: userID, Nickname: "Hanako"})
+	_ = groupRepo.AddMember(&group, &models.User{ID: memberID, Nickname: "太郎"})
+	groupID := group.ID
+
+	shopService := service.NewShopService(shopRepo, groupRepo)
+	_ = shopRepo.Create(&models.Shop{GroupID: groupID, Name: "サイゼリヤ", DefaultCategory: "外食", DefaultPaymentMethod: models.PaymentMethodHalf})
+
+	var modelResult *service.ReceiptTextResult
+	var received *service.ReceiptTextInput
+	analyzer := &mockAIAnalyzer{
+		parseTextFunc: func(ctx context.Context, input *service.ReceiptTextInput) (*service.ReceiptTextResult, error) {
+			received = input
+			result := *modelResult
+			return &result, nil
+		},
+	}
+	svc := service.NewReceiptTextService(groupRepo, shopService, newTestAIUsageService(groupRepo, analyzer), analyzer)
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	t.Run("Resolves Member Nickname And Shop", func(t *testing.T) {
+		modelResult = &service.ReceiptTextResult{Date: "2026-10-18", Shop: "サイゼリヤ", Item: "夕食", Amount: 3200, Payer: "太郎さん", PaymentMethod: models.PaymentMethodSelf}
+
+		parsed, err := svc.ParseText(context.Background(), groupID, userID, "昨日サイゼリヤで夕食3200円、太郎が全額")
+		if err != nil {
+			t.Fatalf("ParseText failed: %v", err)
+		}
+		if received.Speaker != "Hanako" || len(received.Members) != 1 || received.Members[0] != "太郎" {
+			t.Errorf("expected speaker and members to be passed, got %q %v", received.Speaker, received.Members)
+		}
+		p := parsed.Params
+		if p.PayerID != memberID {
+			t.Errorf("expected payer to be resolved to the member, got %v", p.PayerID)
+		}
+		if p.Date.Format("2006-01-02") != "2026-10-18" || p.Amount != 3200 || p.Item != "夕食" {
+			t.Errorf("unexpected params: %+v", p)
+		}
+		if p.ShopID == nil || p.Category != "外食" {
+			t.Errorf("expected shop to be matched, got %+v", p)
+		}
+		if p.PaymentMethod != models.PaymentMethodSelf {
+			t.Errorf("expected payment method from the text to take precedence, got %q", p.PaymentMethod)
+		}
+		if len(parsed.NeedsReview) != 0 {
+			t.Errorf("expected nothing to review, got %v", parsed.NeedsReview)
+		}
+	})
+
+	t.Run("Falls Back To Rules", func(t *testing.T) {
+		modelResult = &service.ReceiptTextResult{Payer: "me"}
+
+		parsed, err := svc.ParseText(context.Background(), groupID, userID, "昨日サイゼリヤで3200円")
+		if err != nil {
+			t.Fatalf("ParseText failed: %v", err)
+		}
+		p := parsed.Params
+		if p.Date != today.AddDate(0, 0, -1) || p.Shop != "サイゼリヤ" || p.Amount != 3200 {
+			t.Errorf("expected missing fields to be filled by rules, got %+v", p)
+		}
+		if p.PayerID != userID || p.PaymentMethod != models.PaymentMethodHalf {
+			t.Errorf("expected the writer and the shop default, got %v %q", p.PayerID, p.PaymentMethod)
+		}
+	})
+
+	t.Run("Marks Unresolved Fields For Review", func(t *testing.T) {
+		modelResult = &service.ReceiptTextResult{Shop: "八百屋", Amount: 800, Payer: "次郎", PaymentMethod: "card"}
+
+		parsed, err := svc.ParseText(context.Background(), groupID, userID, "八百屋で800円 次郎")
+		if err != nil {
+			t.Fatalf("ParseText failed: %v", err)
+		}
+		if !parsed.Params.Date.Equal(today) || parsed.Params.PayerID != userID || parsed.Params.PaymentMethod != "" {
+			t.Errorf("expected defaults for unresolved fields, got %+v", parsed.Params)
+		}
+		want := []string{"date", "payer", "payment_method"}
+		if len(parsed.NeedsReview) != len(want) {
+			t.Fatalf("expected %v to be reviewed, got %v", want, parsed.NeedsReview)
+		}
+		for i := range want {
+			if parsed.NeedsReview[i] != want[i] {
+				t.Errorf("expected %v to be reviewed, got %v", want, parsed.NeedsReview)
+			}
+		}
+	})
+
+	t.Run("Invalid Text", func(t *testing.T) {
+		if _, err := svc.ParseText(context.Background(), groupID, userID, "  "); !errors.Is(err, service.ErrInvalidReceiptText) {
+			t.Errorf("expected ErrInvalidReceiptText, got %v", err)
+		}
+	})
+
+	t.Run("Requires Group Membership", func(t *testing.T) {
+		if _, err := svc.ParseText(context.Background(), groupID, otherID, "コンビニで500円"); !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("expected ErrPermissionDenied, got %v", err)
+		}
+	})
+
+	t.Run("Quota Exceeded", func(t *testing.T) {
+		modelResult = &service.ReceiptTextResult{Shop: "コンビニ", Amount: 500}
+		usageService := service.NewAIUsageService(newMockAIUsageRepository(), newMockAnalysisCacheRepository(), groupRepo, analyzer, service.AIQuota{UserDailyLimit: 1}, 0)
+		limited := service.NewReceiptTextService(groupRepo, shopService, usageService, analyzer)
+
+		if _, err := limited.ParseText(context.Background(), groupID, userID, "コンビニで500円"); err != nil {
+			t.Fatalf("ParseText failed: %v", err)
+		}
+		if _, err := limited.ParseText(context.Background(), groupID, userID, "コンビニで500円"); !errors.Is(err, service.ErrAIQuotaExceeded) {
+			t.Errorf("expected ErrAIQuotaExceeded, got %v", err)
+		}
+
+		report, err := usageService.GetUserUsage(userID, 1)
+		if err != nil {
+			t.Fatalf("GetUserUsage failed: %v", err)
+		}
+		if len(report.Kinds) != 1 || report.Kinds[0].Kind != models.AIUsageParseText {
+			t.Errorf("expected parse_text usage to be recorded, got %+v", report.Kinds)
+		}
+	})
+}
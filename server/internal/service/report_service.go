@@ -0,0 +1,193 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"receipt/server/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidReportRange 集計期間が不正な場合のエラー
+	ErrInvalidReportRange = errors.New("invalid report range")
+	// ErrInvalidReportGranularity 集計単位が不正な場合のエラー
+	ErrInvalidReportGranularity = errors.New("granularity must be month or week")
+)
+
+const (
+	// MaxReportRange 一度に集計できる最大期間
+	MaxReportRange = 5 * 366 * 24 * time.Hour
+	// reportShopLimit 店舗別集計で返す最大件数
+	reportShopLimit = 20
+)
+
+// ReportBucket 集計単位（月・週）ごとの合計
+type ReportBucket struct {
+	Period string `json:"period"` // 月: 2026-01 / 週: 週の月曜日 2026-01-05
+	Total  int    `json:"total"`
+	Count  int    `json:"count"`
+}
+
+// ReportBreakdown 支払方法・店舗ごとの合計
+type ReportBreakdown struct {
+	Key   string `json:"key"`
+	Total int    `json:"total"`
+	Count int    `json:"count"`
+}
+
+// ReportMemberTotal 支払者ごとの合計
+type ReportMemberTotal struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Nickname string    `json:"nickname"`
+	Paid     int       `json:"paid"`
+	Count    int       `json:"count"`
+}
+
+// ReportResult 期間集計結果
+type ReportResult struct {
+	From           string              `json:"from"`
+	To             string              `json:"to"`
+	Granularity    string              `json:"granularity"`
+	TotalSpent     int                 `json:"total_spent"`
+	ReceiptCount   int                 `json:"receipt_count"`
+	Buckets        []ReportBucket      `json:"buckets"`
+	Members        []ReportMemberTotal `json:"members"`
+	PaymentMethods []ReportBreakdown   `json:"payment_methods"`
+	Shops          []ReportBreakdown   `json:"shops"`
+}
+
+// ReportService 年次・任意期間の支出レポートに関するビジネスロジックインターフェース
+type ReportService interface {
+	// GetReport from から to（両端の日付を含む）までのレポートを作成する
+	GetReport(groupID uuid.UUID, userID uuid.UUID, from time.Time, to time.Time, granularity string) (*ReportResult, error)
+}
+
+type reportServiceImpl struct {
+	groupRepo  repository.GroupRepository
+	reportRepo repository.ReportRepository
+}
+
+// NewReportService ReportServiceの実装を作成
+func NewReportService(groupRepo repository.GroupRepository, reportRepo repository.ReportRepository) ReportService {
+	return &reportServiceImpl{
+		groupRepo:  groupRepo,
+		reportRepo: reportRepo,
+	}
+}
+
+func (s *reportServiceImpl) GetReport(groupID uuid.UUID, userID uuid.UUID, from time.Time, to time.Time, granularity string) (*ReportResult, error) {
+	if granularity == "" {
+		granularity = repository.ReportGranularityMonth
+	}
+	if granularity != repository.ReportGranularityMonth && granularity != repository.ReportGranularityWeek {
+		return nil, ErrInvalidReportGranularity
+	}
+
+	from = startOfDay(from)
+	end := startOfDay(to).AddDate(0, 0, 1) // to の日付を含めるため翌日0時未満で集計
+	if !from.Before(end) || end.Sub(from) > MaxReportRange {
+		return nil, ErrInvalidReportRange
+	}
+
+	if _, err := authorizeGroup(s.groupRepo, groupID, userID, PermissionViewGroup); err != nil {
+		return nil, err
+	}
+
+	periodTotals, err := s.reportRepo.GetTotalsByPeriod(groupID, from, end, granularity)
+	if err != nil {
+		return nil, err
+	}
+	payerTotals, err := s.reportRepo.GetTotalsByPayer(groupID, from, end)
+	if err != nil {
+		return nil, err
+	}
+	methodTotals, err := s.reportRepo.GetTotalsByPaymentMethod(groupID, from, end)
+	if err != nil {
+		return nil, err
+	}
+	shopTotals, err := s.reportRepo.GetTotalsByShop(groupID, from, end, reportShopLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReportResult{
+		From:           from.Format("2006-01-02"),
+		To:             startOfDay(to).Format("2006-01-02"),
+		Granularity:    granularity,
+		Buckets:        fillReportBuckets(periodTotals, from, end, granularity),
+		Members:        make([]ReportMemberTotal, 0, len(payerTotals)),
+		PaymentMethods: toReportBreakdowns(methodTotals),
+		Shops:          toReportBreakdowns(shopTotals),
+	}
+
+	for _, b := range result.Buckets {
+		result.TotalSpent += b.Total
+		result.ReceiptCount += b.Count
+	}
+	for _, p := range payerTotals {
+		result.Members = append(result.Members, ReportMemberTotal{
+			UserID:   p.PayerID,
+			Nickname: p.Nickname,
+			Paid:     p.Total,
+			Count:    p.Count,
+		})
+	}
+
+	return result, nil
+}
+
+// fillReportBuckets SQLの集計結果を期間内の全ての集計単位に割り当てる（レシートのない月・週は0円）
+func fillReportBuckets(totals []repository.ReportTotal, from time.Time, end time.Time, granularity string) []ReportBucket {
+	byPeriod := make(map[string]repository.ReportTotal, len(totals))
+	for _, t := range totals {
+		byPeriod[t.Key] = t
+	}
+
+	var buckets []ReportBucket
+	for cursor := reportBucketStart(from, granularity); cursor.Before(end); cursor = nextReportBucket(cursor, granularity) {
+		key := reportBucketKey(cursor, granularity)
+		buckets = append(buckets, ReportBucket{
+			Period: key,
+			Total:  byPeriod[key].Total,
+			Count:  byPeriod[key].Count,
+		})
+	}
+	return buckets
+}
+
+func reportBucketStart(t time.Time, granularity string) time.Time {
+	if granularity == repository.ReportGranularityWeek {
+		// 週は月曜日始まり
+		offset := (int(t.Weekday()) + 6) % 7
+		return startOfDay(t).AddDate(0, 0, -offset)
+	}
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+func nextReportBucket(t time.Time, granularity string) time.Time {
+	if granularity == repository.ReportGranularityWeek {
+		return t.AddDate(0, 0, 7)
+	}
+	return t.AddDate(0, 1, 0)
+}
+
+func reportBucketKey(t time.Time, granularity string) string {
+	if granularity == repository.ReportGranularityWeek {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01")
+}
+
+func toReportBreakdowns(totals []repository.ReportTotal) []ReportBreakdown {
+	breakdowns := make([]ReportBreakdown, 0, len(totals))
+	for _, t := range totals {
+		breakdowns = append(breakdowns, ReportBreakdown{Key: t.Key, Total: t.Total, Count: t.Count})
+	}
+	return breakdowns
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,240 @@
+package service_test
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+	"receipt/server/internal/service"
+
+	"github.com/google/uuid"
+)
+
+// mockReportRepository SQL集計の代わりにメモリ上のレシートを集計する
+type mockReportRepository struct {
+	receipts  []models.Receipt
+	nicknames map[uuid.UUID]string
+	// receiptRepo 指定した場合は、負担額の集計（GetShareTotals）にそのレシートを使う
+	receiptRepo *mockReceiptRepository
+}
+
+func (m *mockReportRepository) inRange(groupID uuid.UUID, from time.Time, to time.Time) []models.Receipt {
+	var result []models.Receipt
+	for _, r := range m.receipts {
+		if r.GroupID == groupID && !r.Date.Before(from) && r.Date.Before(to) {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+func aggregate(receipts []models.Receipt, key func(models.Receipt) string) []repository.ReportTotal {
+	byKey := make(map[string]*repository.ReportTotal)
+	var keys []string
+	for _, r := range receipts {
+		k := key(r)
+		if byKey[k] == nil {
+			byKey[k] = &repository.ReportTotal{Key: k}
+			keys = append(keys, k)
+		}
+		byKey[k].Total += r.Amount
+		byKey[k].Count++
+	}
+	sort.Strings(keys)
+
+	totals := make([]repository.ReportTotal, 0, len(keys))
+	for _, k := range keys {
+		totals = append(totals, *byKey[k])
+	}
+	return totals
+}
+
+func (m *mockReportRepository) GetTotalsByPeriod(groupID uuid.UUID, from time.Time, to time.Time, granularity string) ([]repository.ReportTotal, error) {
+	return aggregate(m.inRange(groupID, from, to), func(r models.Receipt) string {
+		if granularity == repository.ReportGranularityWeek {
+			offset := (int(r.Date.Weekday()) + 6) % 7
+			return r.Date.AddDate(0, 0, -offset).Format("2006-01-02")
+		}
+		return r.Date.Format("2006-01")
+	}), nil
+}
+
+func (m *mockReportRepository) GetTotalsByPayer(groupID uuid.UUID, from time.Time, to time.Time) ([]repository.ReportPayerTotal, error) {
+	var result []repository.ReportPayerTotal
+	for _, t := range aggregate(m.inRange(groupID, from, to), func(r models.Receipt) string { return r.PayerID.String() }) {
+		payerID := uuid.MustParse(t.Key)
+		result = append(result, repository.ReportPayerTotal{PayerID: payerID, Nickname: m.nicknames[payerID], Total: t.Total, Count: t.Count})
+	}
+	return result, nil
+}
+
+func (m *mockReportRepository) GetTotalsByPaymentMethod(groupID uuid.UUID, from time.Time, to time.Time) ([]repository.ReportTotal, error) {
+	return aggregate(m.inRange(groupID, from, to), func(r models.Receipt) string { return r.PaymentMethod }), nil
+}
+
+func (m *mockReportRepository) GetTotalsByShop(groupID uuid.UUID, from time.Time, to time.Time, limit int) ([]repository.ReportTotal, error) {
+	totals := aggregate(m.inRange(groupID, from, to), func(r models.Receipt) string { return r.Shop })
+	if len(totals) > limit {
+		totals = totals[:limit]
+	}
+	return totals, nil
+}
+
+func (m *mockReportRepository) monthlyTotals(groupID uuid.UUID, from time.Time, to time.Time, key func(models.Receipt) string) []repository.ReportMonthlyTotal {
+	var result []repository.ReportMonthlyTotal
+	for _, t := range aggregate(m.inRange(groupID, from, to), func(r models.Receipt) string {
+		return key(r) + "\x00" + r.Date.Format("2006-01")
+	}) {
+		parts := strings.SplitN(t.Key, "\x00", 2)
+		result = append(result, repository.ReportMonthlyTotal{Key: parts[0], Month: parts[1], Total: t.Total, Count: t.Count})
+	}
+	return result
+}
+
+func (m *mockReportRepository) GetMonthlyTotalsByShop(groupID uuid.UUID, from time.Time, to time.Time) ([]repository.ReportMonthlyTotal, error) {
+	return m.monthlyTotals(groupID, from, to, func(r models.Receipt) string { return r.Shop }), nil
+}
+
+func (m *mockReportRepository) GetMonthlyTotalsByPayer(groupID uuid.UUID, from time.Time, to time.Time) ([]repository.ReportMonthlyTotal, error) {
+	return m.monthlyTotals(groupID, from, to, func(r models.Receipt) string { return r.PayerID.String() }), nil
+}
+
+func (m *mockReportRepository) GetShareTotals(groupID uuid.UUID, from *time.Time, to *time.Time, year int, month int, divisor int) ([]repository.ReportShareTotal, error) {
+	receipts := m.receipts
+	if m.receiptRepo != nil {
+		receipts = nil
+		for _, r := range m.receiptRepo.receipts {
+			receipts = append(receipts, *r)
+		}
+	}
+
+	type key struct {
+		payerID       uuid.UUID
+		paymentMethod string
+	}
+	byKey := make(map[key]*repository.ReportShareTotal)
+	for _, r := range receipts {
+		if r.GroupID != groupID || (from != nil && r.Date.Before(*from)) || (to != nil && !r.Date.Before(*to)) ||
+			r.SettlementYear > year || (r.SettlementYear == year && r.SettlementMonth > month) {
+			continue
+		}
+		k := key{r.PayerID, r.PaymentMethod}
+		if byKey[k] == nil {
+			byKey[k] = &repository.ReportShareTotal{PayerID: r.PayerID, PaymentMethod: r.PaymentMethod}
+		}
+		byKey[k].Total += r.Amount
+		byKey[k].Remainder += r.Amount % divisor
+	}
+
+	var totals []repository.ReportShareTotal
+	for _, t := range byKey {
+		totals = append(totals, *t)
+	}
+	return totals, nil
+}
+
+func TestReportService_GetReport(t *testing.T) {
+	groupRepo := newMockGroupRepository()
+	userA := models.User{ID: uuid.New(), Nickname: "UserA"}
+	userB := models.User{ID: uuid.New(), Nickname: "UserB"}
+	group := models.Group{Name: "Family", OwnerID: userA.ID}
+	_ = groupRepo.Create(&group)
+	_ = groupRepo.AddMember(&group, &userA)
+	_ = groupRepo.AddMember(&group, &userB)
+
+	day := func(month time.Month, d int) time.Time { return time.Date(2026, month, d, 12, 0, 0, 0, time.Local) }
+	reportRepo := &mockReportRepository{
+		nicknames: map[uuid.UUID]string{userA.ID: userA.Nickname, userB.ID: userB.Nickname},
+		receipts: []models.Receipt{
+			{GroupID: group.ID, Date: day(time.January, 5), Shop: "Supermarket", Amount: 1000, PayerID: userA.ID, PaymentMethod: models.PaymentMethodHalf},
+			{GroupID: group.ID, Date: day(time.January, 20), Shop: "Pharmacy", Amount: 500, PayerID: userB.ID, PaymentMethod: models.PaymentMethodSelf},
+			{GroupID: group.ID, Date: day(time.March, 3), Shop: "Supermarket", Amount: 2000, PayerID: userA.ID, PaymentMethod: models.PaymentMethodHalf},
+			{GroupID: group.ID, Date: day(time.December, 31), Shop: "Bakery", Amount: 300, PayerID: userB.ID, PaymentMethod: models.PaymentMethodOther},
+			// 期間外・他グループ
+			{GroupID: group.ID, Date: time.Date(2027, time.January, 1, 0, 0, 0, 0, time.Local), Amount: 9999, PayerID: userA.ID, PaymentMethod: models.PaymentMethodHalf},
+			{GroupID: uuid.New(), Date: day(time.February, 1), Amount: 8888, PayerID: userA.ID, PaymentMethod: models.PaymentMethodHalf},
+		},
+	}
+	svc := service.NewReportService(groupRepo, reportRepo)
+
+	from := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(2026, time.December, 31, 0, 0, 0, 0, time.Local)
+
+	t.Run("Yearly Monthly Buckets", func(t *testing.T) {
+		result, err := svc.GetReport(group.ID, userA.ID, from, to, "")
+		if err != nil {
+			t.Fatalf("GetReport failed: %v", err)
+		}
+
+		if result.TotalSpent != 3800 || result.ReceiptCount != 4 {
+			t.Errorf("Expected total 3800 over 4 receipts, got %d over %d", result.TotalSpent, result.ReceiptCount)
+		}
+		if len(result.Buckets) != 12 {
+			t.Fatalf("Expected 12 monthly buckets, got %d", len(result.Buckets))
+		}
+		if b := result.Buckets[0]; b.Period != "2026-01" || b.Total != 1500 || b.Count != 2 {
+			t.Errorf("Unexpected January bucket: %+v", b)
+		}
+		if b := result.Buckets[1]; b.Period != "2026-02" || b.Total != 0 {
+			t.Errorf("Expected empty February bucket, got %+v", b)
+		}
+		if b := result.Buckets[11]; b.Period != "2026-12" || b.Total != 300 {
+			t.Errorf("Expected the last day of the range to be included, got %+v", b)
+		}
+
+		paid := make(map[uuid.UUID]int)
+		for _, m := range result.Members {
+			paid[m.UserID] = m.Paid
+		}
+		if paid[userA.ID] != 3000 || paid[userB.ID] != 800 {
+			t.Errorf("Unexpected member totals: %+v", result.Members)
+		}
+
+		shops := make(map[string]int)
+		for _, s := range result.Shops {
+			shops[s.Key] = s.Total
+		}
+		if shops["Supermarket"] != 3000 {
+			t.Errorf("Expected Supermarket total 3000, got %d", shops["Supermarket"])
+		}
+	})
+
+	t.Run("Weekly Buckets", func(t *testing.T) {
+		// 2026-01-05 は月曜日
+		result, err := svc.GetReport(group.ID, userA.ID, day(time.January, 1), day(time.January, 31), "week")
+		if err != nil {
+			t.Fatalf("GetReport failed: %v", err)
+		}
+		if result.Buckets[0].Period != "2025-12-29" {
+			t.Errorf("Expected the first week to start on Monday 2025-12-29, got %s", result.Buckets[0].Period)
+		}
+		if b := result.Buckets[1]; b.Period != "2026-01-05" || b.Total != 1000 {
+			t.Errorf("Unexpected week bucket: %+v", b)
+		}
+	})
+
+	t.Run("Invalid Range", func(t *testing.T) {
+		_, err := svc.GetReport(group.ID, userA.ID, to, from, "month")
+		if !errors.Is(err, service.ErrInvalidReportRange) {
+			t.Errorf("Expected error %v, got %v", service.ErrInvalidReportRange, err)
+		}
+	})
+
+	t.Run("Invalid Granularity", func(t *testing.T) {
+		_, err := svc.GetReport(group.ID, userA.ID, from, to, "day")
+		if !errors.Is(err, service.ErrInvalidReportGranularity) {
+			t.Errorf("Expected error %v, got %v", service.ErrInvalidReportGranularity, err)
+		}
+	})
+
+	t.Run("Not Member", func(t *testing.T) {
+		_, err := svc.GetReport(group.ID, uuid.New(), from, to, "month")
+		if !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("Expected error %v, got %v", service.ErrPermissionDenied, err)
+		}
+	})
+}
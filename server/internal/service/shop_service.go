@@ -0,0 +1,285 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+	"receipt/server/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrShopNotFound 店舗が見つからない場合のエラー
+	ErrShopNotFound = errors.New("shop not found")
+	// ErrInvalidShopName 店舗名が空の場合のエラー
+	ErrInvalidShopName = errors.New("shop name must not be empty")
+	// ErrShopAlreadyExists 同じ店舗名（正規化後）の店舗がグループに既にある場合のエラー
+	ErrShopAlreadyExists = errors.New("shop with the same name already exists in this group")
+	// ErrInvalidPaymentMethod 支払い方法が定義済みの値でない場合のエラー
+	ErrInvalidPaymentMethod = errors.New("invalid payment method")
+	// ErrInvalidShopMerge 統合元・統合先の指定が不正な場合のエラー
+	ErrInvalidShopMerge = errors.New("merge sources must be other shops in the same group")
+)
+
+// ShopParams 店舗の作成・更新用パラメータ
+type ShopParams struct {
+	GroupID              uuid.UUID
+	Name                 string
+	Aliases              []string // 作成時のみ使用
+	DefaultCategory      string
+	DefaultPaymentMethod string
+}
+
+// ShopMergeResult 店舗統合の結果
+type ShopMergeResult struct {
+	Shop           *models.Shop `json:"shop"`
+	ReceiptsMoved  int64        `json:"receipts_moved"`  // 統合元の店舗から付け替えたレシート数
+	ReceiptsLinked int64        `json:"receipts_linked"` // 店舗名・別名が一致し、新たに紐付けたレシート数
+}
+
+// ShopService 店舗マスタに関するビジネスロジックインターフェース
+type ShopService interface {
+	GetShops(groupID uuid.UUID, userID uuid.UUID) ([]models.Shop, error)
+	CreateShop(params *ShopParams, userID uuid.UUID) (*models.Shop, error)
+	UpdateShop(id uuid.UUID, params *ShopParams, userID uuid.UUID) (*models.Shop, error)
+	DeleteShop(id uuid.UUID, userID uuid.UUID) error
+	AddShopAlias(id uuid.UUID, userID uuid.UUID, alias string) (*models.ShopAlias, error)
+	// MergeShops 統合元の店舗を統合先にまとめ、店舗マスタに紐付いていないレシートのうち
+	// 統合先の店舗名・別名に一致するものを統合先に紐付ける（統合元が空の場合は紐付けのみ）
+	MergeShops(targetID uuid.UUID, userID uuid.UUID, sourceIDs []uuid.UUID) (*ShopMergeResult, error)
+	// SuggestShop AI解析結果の店舗名を店舗マスタに照合し、一致した店舗と既定値を設定する
+	SuggestShop(groupID uuid.UUID, userID uuid.UUID, result *AnalyzeReceiptResult) error
+}
+
+type shopServiceImpl struct {
+	shopRepo  repository.ShopRepository
+	groupRepo repository.GroupRepository
+}
+
+// NewShopService ShopServiceの実装を作成
+func NewShopService(shopRepo repository.ShopRepository, groupRepo repository.GroupRepository) ShopService {
+	return &shopServiceImpl{
+		shopRepo:  shopRepo,
+		groupRepo: groupRepo,
+	}
+}
+
+func (s *shopServiceImpl) GetShops(groupID uuid.UUID, userID uuid.UUID) ([]models.Shop, error) {
+	if _, err := authorizeGroup(s.groupRepo, groupID, userID, PermissionViewGroup); err != nil {
+		return nil, err
+	}
+	return s.shopRepo.GetByGroupID(groupID)
+}
+
+func (s *shopServiceImpl) CreateShop(params *ShopParams, userID uuid.UUID) (*models.Shop, error) {
+	if _, err := authorizeGroup(s.groupRepo, params.GroupID, userID, PermissionEditGroup); err != nil {
+		return nil, err
+	}
+
+	shop := models.Shop{GroupID: params.GroupID}
+	if err := s.applyShopParams(&shop, params); err != nil {
+		return nil, err
+	}
+
+	for _, alias := range params.Aliases {
+		if utils.NormalizeShopName(alias) == "" {
+			return nil, ErrInvalidShopAlias
+		}
+		shop.Aliases = append(shop.Aliases, models.ShopAlias{
+			GroupID:       &shop.GroupID,
+			Alias:         alias,
+			CanonicalName: shop.Name,
+		})
+	}
+
+	if err := s.shopRepo.Create(&shop); err != nil {
+		return nil, err
+	}
+	return s.shopRepo.GetByID(shop.ID)
+}
+
+func (s *shopServiceImpl) UpdateShop(id uuid.UUID, params *ShopParams, userID uuid.UUID) (*models.Shop, error) {
+	shop, err := s.getAuthorizedShop(id, userID, PermissionEditGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyShopParams(shop, params); err != nil {
+		return nil, err
+	}
+
+	if err := s.shopRepo.Update(shop); err != nil {
+		return nil, err
+	}
+	return s.shopRepo.GetByID(shop.ID)
+}
+
+func (s *shopServiceImpl) DeleteShop(id uuid.UUID, userID uuid.UUID) error {
+	shop, err := s.getAuthorizedShop(id, userID, PermissionEditGroup)
+	if err != nil {
+		return err
+	}
+	return s.shopRepo.Delete(shop)
+}
+
+func (s *shopServiceImpl) AddShopAlias(id uuid.UUID, userID uuid.UUID, alias string) (*models.ShopAlias, error) {
+	shop, err := s.getAuthorizedShop(id, userID, PermissionEditGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	if utils.NormalizeShopName(alias) == "" {
+		return nil, ErrInvalidShopAlias
+	}
+	return s.shopRepo.AddAlias(shop, alias)
+}
+
+func (s *shopServiceImpl) MergeShops(targetID uuid.UUID, userID uuid.UUID, sourceIDs []uuid.UUID) (*ShopMergeResult, error) {
+	target, err := s.getAuthorizedShop(targetID, userID, PermissionEditGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]models.Shop, 0, len(sourceIDs))
+	seen := make(map[uuid.UUID]bool)
+	for _, sourceID := range sourceIDs {
+		if sourceID == target.ID || seen[sourceID] {
+			return nil, ErrInvalidShopMerge
+		}
+		seen[sourceID] = true
+
+		source, err := s.shopRepo.GetByID(sourceID)
+		if err != nil {
+			return nil, ErrShopNotFound
+		}
+		if source.GroupID != target.GroupID {
+			return nil, ErrInvalidShopMerge
+		}
+		sources = append(sources, *source)
+	}
+
+	result := &ShopMergeResult{}
+	if len(sources) > 0 {
+		if result.ReceiptsMoved, err = s.shopRepo.Merge(target, sources); err != nil {
+			return nil, err
+		}
+	}
+
+	if target, err = s.shopRepo.GetByID(target.ID); err != nil {
+		return nil, err
+	}
+
+	// 自由入力のまま登録されたレシートのうち、統合先に一致するものをまとめて紐付ける
+	shops, err := s.shopRepo.GetByGroupID(target.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	unassigned, err := s.shopRepo.GetUnassignedShopNames(target.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	var receiptIDs []uuid.UUID
+	for _, u := range unassigned {
+		if matched := matchShop(shops, u.Shop); matched != nil && matched.ID == target.ID {
+			receiptIDs = append(receiptIDs, u.ReceiptIDs...)
+		}
+	}
+	if result.ReceiptsLinked, err = s.shopRepo.AssignReceipts(target, receiptIDs); err != nil {
+		return nil, err
+	}
+
+	result.Shop = target
+	return result, nil
+}
+
+func (s *shopServiceImpl) SuggestShop(groupID uuid.UUID, userID uuid.UUID, result *AnalyzeReceiptResult) error {
+	if _, err := authorizeGroup(s.groupRepo, groupID, userID, PermissionViewGroup); err != nil {
+		return err
+	}
+
+	shops, err := s.shopRepo.GetByGroupID(groupID)
+	if err != nil {
+		return err
+	}
+
+	shop := matchShop(shops, result.Shop)
+	if shop == nil {
+		return nil
+	}
+	result.ShopID = &shop.ID
+	result.Shop = shop.Name
+	result.Category = shop.DefaultCategory
+	result.PaymentMethod = shop.DefaultPaymentMethod
+	return nil
+}
+
+// getAuthorizedShop 店舗を取得し、店舗のグループで指定の操作を行えるか確認する
+func (s *shopServiceImpl) getAuthorizedShop(id uuid.UUID, userID uuid.UUID, permission GroupPermission) (*models.Shop, error) {
+	shop, err := s.shopRepo.GetByID(id)
+	if err != nil {
+		return nil, ErrShopNotFound
+	}
+
+	if _, err := authorizeGroup(s.groupRepo, shop.GroupID, userID, permission); err != nil {
+		return nil, err
+	}
+	return shop, nil
+}
+
+// applyShopParams 店舗名・既定値を検証して店舗に設定する
+func (s *shopServiceImpl) applyShopParams(shop *models.Shop, params *ShopParams) error {
+	name := utils.TrimShopBranch(params.Name)
+	if utils.NormalizeShopName(name) == "" {
+		return ErrInvalidShopName
+	}
+	if params.DefaultPaymentMethod != "" && !isValidPaymentMethod(params.DefaultPaymentMethod) {
+		return ErrInvalidPaymentMethod
+	}
+
+	shops, err := s.shopRepo.GetByGroupID(shop.GroupID)
+	if err != nil {
+		return err
+	}
+	for _, other := range shops {
+		if other.ID != shop.ID && utils.NormalizeShopName(other.Name) == utils.NormalizeShopName(name) {
+			return ErrShopAlreadyExists
+		}
+	}
+
+	shop.Name = name
+	shop.DefaultCategory = strings.TrimSpace(params.DefaultCategory)
+	shop.DefaultPaymentMethod = params.DefaultPaymentMethod
+	return nil
+}
+
+// matchShop 自由入力の店舗名に、店舗名または別名が最も長く前方一致する店舗を返す（一致しない場合はnil）
+func matchShop(shops []models.Shop, name string) *models.Shop {
+	var names []string
+	var owners []int
+	for i := range shops {
+		names = append(names, shops[i].Name)
+		owners = append(owners, i)
+		for _, alias := range shops[i].Aliases {
+			names = append(names, alias.Alias)
+			owners = append(owners, i)
+		}
+	}
+
+	best := utils.MatchShopName(name, names)
+	if best < 0 {
+		return nil
+	}
+	return &shops[owners[best]]
+}
+
+// isValidPaymentMethod 支払い方法が定義済みの値かどうか
+func isValidPaymentMethod(method string) bool {
+	switch method {
+	case models.PaymentMethodHalf, models.PaymentMethodSelf, models.PaymentMethodOther:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,309 @@
+package service_test
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+	"receipt/server/internal/service"
+
+	"github.com/google/uuid"
+)
+
+// mockShopRepository ShopRepositoryのモック（レシートは receipts に登録されたものを参照する）
+type mockShopRepository struct {
+	shops    map[uuid.UUID]*models.Shop
+	receipts []*models.Receipt
+}
+
+func newMockShopRepository() *mockShopRepository {
+	return &mockShopRepository{shops: make(map[uuid.UUID]*models.Shop)}
+}
+
+func (m *mockShopRepository) Create(shop *models.Shop) error {
+	shop.ID = uuid.New()
+	shop.CreatedAt = time.Now()
+	for i := range shop.Aliases {
+		shop.Aliases[i].ID = uuid.New()
+		shop.Aliases[i].ShopID = &shop.ID
+	}
+	stored := *shop
+	m.shops[shop.ID] = &stored
+	return nil
+}
+
+func (m *mockShopRepository) GetByID(id uuid.UUID) (*models.Shop, error) {
+	shop, exists := m.shops[id]
+	if !exists {
+		return nil, errors.New("record not found")
+	}
+	copied := *shop
+	copied.Aliases = append([]models.ShopAlias(nil), shop.Aliases...)
+	return &copied, nil
+}
+
+func (m *mockShopRepository) GetByGroupID(groupID uuid.UUID) ([]models.Shop, error) {
+	var shops []models.Shop
+	for _, shop := range m.shops {
+		if shop.GroupID == groupID {
+			shops = append(shops, *shop)
+		}
+	}
+	sort.Slice(shops, func(i, j int) bool { return shops[i].Name < shops[j].Name })
+	return shops, nil
+}
+
+func (m *mockShopRepository) Update(shop *models.Shop) error {
+	stored := m.shops[shop.ID]
+	stored.Name = shop.Name
+	stored.DefaultCategory = shop.DefaultCategory
+	stored.DefaultPaymentMethod = shop.DefaultPaymentMethod
+	for i := range stored.Aliases {
+		stored.Aliases[i].CanonicalName = shop.Name
+	}
+	return nil
+}
+
+func (m *mockShopRepository) Delete(shop *models.Shop) error {
+	for _, r := range m.receipts {
+		if r.ShopID != nil && *r.ShopID == shop.ID {
+			r.ShopID = nil
+		}
+	}
+	delete(m.shops, shop.ID)
+	return nil
+}
+
+func (m *mockShopRepository) AddAlias(shop *models.Shop, alias string) (*models.ShopAlias, error) {
+	shopAlias := models.ShopAlias{ID: uuid.New(), GroupID: &shop.GroupID, ShopID: &shop.ID, Alias: alias, CanonicalName: shop.Name}
+	stored := m.shops[shop.ID]
+	stored.Aliases = append(stored.Aliases, shopAlias)
+	return &shopAlias, nil
+}
+
+func (m *mockShopRepository) Merge(target *models.Shop, sources []models.Shop) (int64, error) {
+	var moved int64
+	stored := m.shops[target.ID]
+	for _, source := range sources {
+		for _, r := range m.receipts {
+			if r.ShopID != nil && *r.ShopID == source.ID {
+				r.ShopID = &stored.ID
+				moved++
+			}
+		}
+		for _, a := range m.shops[source.ID].Aliases {
+			a.ShopID, a.CanonicalName = &stored.ID, stored.Name
+			stored.Aliases = append(stored.Aliases, a)
+		}
+		stored.Aliases = append(stored.Aliases, models.ShopAlias{ID: uuid.New(), GroupID: &stored.GroupID, ShopID: &stored.ID, Alias: source.Name, CanonicalName: stored.Name})
+		delete(m.shops, source.ID)
+	}
+	return moved, nil
+}
+
+func (m *mockShopRepository) AssignReceipts(shop *models.Shop, receiptIDs []uuid.UUID) (int64, error) {
+	var linked int64
+	for _, id := range receiptIDs {
+		for _, r := range m.receipts {
+			if r.ID == id && r.GroupID == shop.GroupID && r.ShopID == nil {
+				r.ShopID = &shop.ID
+				linked++
+			}
+		}
+	}
+	return linked, nil
+}
+
+func (m *mockShopRepository) GetUnassignedShopNames(groupID uuid.UUID) ([]repository.UnassignedShopName, error) {
+	byShop := make(map[string]*repository.UnassignedShopName)
+	var names []string
+	for _, r := range m.receipts {
+		if r.GroupID != groupID || r.ShopID != nil || r.Shop == "" {
+			continue
+		}
+		if byShop[r.Shop] == nil {
+			byShop[r.Shop] = &repository.UnassignedShopName{Shop: r.Shop}
+			names = append(names, r.Shop)
+		}
+		byShop[r.Shop].ReceiptIDs = append(byShop[r.Shop].ReceiptIDs, r.ID)
+	}
+	sort.Strings(names)
+
+	var result []repository.UnassignedShopName
+	for _, name := range names {
+		result = append(result, *byShop[name])
+	}
+	return result, nil
+}
+
+func TestShopService_CreateShop(t *testing.T) {
+	groupRepo := newMockGroupRepository()
+	owner := uuid.New()
+	member := uuid.New()
+	groupID := newReceiptTestGroup(groupRepo, owner, member)
+	_ = groupRepo.SetMemberRole(groupID, owner, models.GroupRoleOwner)
+	svc := service.NewShopService(newMockShopRepository(), groupRepo)
+
+	t.Run("Success", func(t *testing.T) {
+		shop, err := svc.CreateShop(&service.ShopParams{
+			GroupID:              groupID,
+			Name:                 "  セブンイレブン 新宿店 ",
+			Aliases:              []string{"7-Eleven"},
+			DefaultCategory:      "日用品",
+			DefaultPaymentMethod: models.PaymentMethodHalf,
+		}, owner)
+		if err != nil {
+			t.Fatalf("CreateShop failed: %v", err)
+		}
+		if shop.Name != "セブンイレブン" || len(shop.Aliases) != 1 || shop.Aliases[0].CanonicalName != "セブンイレブン" {
+			t.Errorf("unexpected shop: %+v", shop)
+		}
+	})
+
+	t.Run("Duplicate Name", func(t *testing.T) {
+		_, err := svc.CreateShop(&service.ShopParams{GroupID: groupID, Name: "ｾﾌﾞﾝｲﾚﾌﾞﾝ"}, owner)
+		if !errors.Is(err, service.ErrShopAlreadyExists) {
+			t.Errorf("expected ErrShopAlreadyExists, got %v", err)
+		}
+	})
+
+	t.Run("Invalid Payment Method", func(t *testing.T) {
+		_, err := svc.CreateShop(&service.ShopParams{GroupID: groupID, Name: "Bakery", DefaultPaymentMethod: "card"}, owner)
+		if !errors.Is(err, service.ErrInvalidPaymentMethod) {
+			t.Errorf("expected ErrInvalidPaymentMethod, got %v", err)
+		}
+	})
+
+	t.Run("Member Cannot Create", func(t *testing.T) {
+		_, err := svc.CreateShop(&service.ShopParams{GroupID: groupID, Name: "Bakery"}, member)
+		if !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("expected ErrPermissionDenied, got %v", err)
+		}
+	})
+}
+
+func TestReceiptService_CreateReceipt_ShopDefaults(t *testing.T) {
+	groupRepo := newMockGroupRepository()
+	owner := uuid.New()
+	groupID := newReceiptTestGroup(groupRepo, owner)
+	_ = groupRepo.SetMemberRole(groupID, owner, models.GroupRoleOwner)
+
+	shopRepo := newMockShopRepository()
+	shopSvc := service.NewShopService(shopRepo, groupRepo)
+	shop, err := shopSvc.CreateShop(&service.ShopParams{
+		GroupID:              groupID,
+		Name:                 "セブンイレブン",
+		Aliases:              []string{"7-Eleven"},
+		DefaultCategory:      "食費",
+		DefaultPaymentMethod: models.PaymentMethodSelf,
+	}, owner)
+	if err != nil {
+		t.Fatalf("CreateShop failed: %v", err)
+	}
+
+	svc := service.NewReceiptService(newMockReceiptRepository(), groupRepo, shopRepo)
+	params := func(shopName string, paymentMethod string) *service.CreateReceiptParams {
+		return &service.CreateReceiptParams{GroupID: groupID, Date: time.Now(), Shop: shopName, Amount: 500, PayerID: owner, PaymentMethod: paymentMethod}
+	}
+
+	t.Run("Alias Match Applies Defaults", func(t *testing.T) {
+		receipt, err := svc.CreateReceipt(params("7-ELEVEN 新宿三丁目店", ""), owner)
+		if err != nil {
+			t.Fatalf("CreateReceipt failed: %v", err)
+		}
+		if receipt.ShopID == nil || *receipt.ShopID != shop.ID {
+			t.Errorf("expected receipt to be linked to shop, got %v", receipt.ShopID)
+		}
+		if receipt.Category != "食費" || receipt.PaymentMethod != models.PaymentMethodSelf {
+			t.Errorf("expected shop defaults, got category=%q payment_method=%q", receipt.Category, receipt.PaymentMethod)
+		}
+		if receipt.Shop != "7-ELEVEN 新宿三丁目店" {
+			t.Errorf("expected entered shop name to be kept, got %q", receipt.Shop)
+		}
+	})
+
+	t.Run("Explicit Values Win", func(t *testing.T) {
+		receipt, err := svc.CreateReceipt(params("セブンイレブン", models.PaymentMethodHalf), owner)
+		if err != nil {
+			t.Fatalf("CreateReceipt failed: %v", err)
+		}
+		if receipt.PaymentMethod != models.PaymentMethodHalf {
+			t.Errorf("expected explicit payment method, got %q", receipt.PaymentMethod)
+		}
+	})
+
+	t.Run("Unknown Shop Requires Payment Method", func(t *testing.T) {
+		receipt, err := svc.CreateReceipt(params("Bakery", ""), owner)
+		if !errors.Is(err, service.ErrPaymentMethodRequired) {
+			t.Errorf("expected ErrPaymentMethodRequired, got %v (%+v)", err, receipt)
+		}
+	})
+
+	t.Run("Shop Of Other Group", func(t *testing.T) {
+		otherShop := models.Shop{GroupID: uuid.New(), Name: "Other"}
+		_ = shopRepo.Create(&otherShop)
+		p := params("", models.PaymentMethodHalf)
+		p.ShopID = &otherShop.ID
+		if _, err := svc.CreateReceipt(p, owner); !errors.Is(err, service.ErrShopNotFound) {
+			t.Errorf("expected ErrShopNotFound, got %v", err)
+		}
+	})
+}
+
+func TestShopService_MergeShops(t *testing.T) {
+	groupRepo := newMockGroupRepository()
+	owner := uuid.New()
+	groupID := newReceiptTestGroup(groupRepo, owner)
+	_ = groupRepo.SetMemberRole(groupID, owner, models.GroupRoleOwner)
+
+	shopRepo := newMockShopRepository()
+	svc := service.NewShopService(shopRepo, groupRepo)
+	target, _ := svc.CreateShop(&service.ShopParams{GroupID: groupID, Name: "セブンイレブン"}, owner)
+	source, _ := svc.CreateShop(&service.ShopParams{GroupID: groupID, Name: "7-Eleven"}, owner)
+	_, _ = svc.CreateShop(&service.ShopParams{GroupID: groupID, Name: "Lawson"}, owner)
+
+	shopRepo.receipts = []*models.Receipt{
+		{ID: uuid.New(), GroupID: groupID, Shop: "7-Eleven", ShopID: &source.ID},
+		{ID: uuid.New(), GroupID: groupID, Shop: "7-Eleven 渋谷店"},
+		{ID: uuid.New(), GroupID: groupID, Shop: "セブンイレブン"},
+		{ID: uuid.New(), GroupID: groupID, Shop: "Lawson"},
+	}
+
+	t.Run("Invalid Source", func(t *testing.T) {
+		if _, err := svc.MergeShops(target.ID, owner, []uuid.UUID{target.ID}); !errors.Is(err, service.ErrInvalidShopMerge) {
+			t.Errorf("expected ErrInvalidShopMerge, got %v", err)
+		}
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		result, err := svc.MergeShops(target.ID, owner, []uuid.UUID{source.ID})
+		if err != nil {
+			t.Fatalf("MergeShops failed: %v", err)
+		}
+		if result.ReceiptsMoved != 1 || result.ReceiptsLinked != 2 {
+			t.Errorf("expected 1 moved and 2 linked receipts, got %+v", result)
+		}
+		if len(result.Shop.Aliases) != 1 || result.Shop.Aliases[0].Alias != "7-Eleven" {
+			t.Errorf("expected source name to be kept as alias, got %+v", result.Shop.Aliases)
+		}
+		if _, err := shopRepo.GetByID(source.ID); err == nil {
+			t.Error("expected source shop to be deleted")
+		}
+		if lawson := shopRepo.receipts[3]; lawson.ShopID != nil {
+			t.Errorf("expected receipts of other shops to stay unlinked, got %v", lawson.ShopID)
+		}
+	})
+
+	t.Run("Suggest Shop For Analysis", func(t *testing.T) {
+		result := &service.AnalyzeReceiptResult{Shop: "7-Eleven 池袋店"}
+		if err := svc.SuggestShop(groupID, owner, result); err != nil {
+			t.Fatalf("SuggestShop failed: %v", err)
+		}
+		if result.ShopID == nil || *result.ShopID != target.ID || result.Shop != "セブンイレブン" {
+			t.Errorf("expected analysis to be mapped to merged shop, got %+v", result)
+		}
+	})
+}
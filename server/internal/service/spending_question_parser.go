@@ -0,0 +1,171 @@
+package service
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	// questionRecentDaysPattern 直近の日数（例: 過去30日、last 30 days）
+	questionRecentDaysPattern = regexp.MustCompile(`(?:過去|直近|last|past)\s*(\d+)\s*(?:日|days?)`)
+	// questionMonthPattern 月（例: 10月）
+	questionMonthPattern = regexp.MustCompile(`(\d{1,2})\s*月`)
+	// questionMonthNamePattern 英語の月名
+	questionMonthNamePattern = regexp.MustCompile(`\b(january|february|march|april|may|june|july|august|september|october|november|december)\b`)
+)
+
+var (
+	// questionSeasons 季節を表す語と始まりの月（3か月間）
+	questionSeasons = []struct {
+		words []string
+		month time.Month
+	}{
+		{[]string{"spring", "春"}, time.March},
+		{[]string{"summer", "夏"}, time.June},
+		{[]string{"autumn", "fall", "秋"}, time.September},
+		{[]string{"winter", "冬"}, time.December},
+	}
+	// questionLastYearWords 昨年を表す語
+	questionLastYearWords = []string{"last year", "去年", "昨年"}
+	// questionThisYearWords 今年を表す語
+	questionThisYearWords = []string{"this year", "今年"}
+	// questionAggregates 集計方法を表す語（件数・平均・最高・最安の順に照合し、いずれもなければ合計）
+	questionAggregates = []struct {
+		aggregate string
+		keywords  []string
+	}{
+		{SpendingAggregateCount, []string{"how many", "how often", "number of", "何回", "何件", "何度", "回数", "件数"}},
+		{SpendingAggregateAverage, []string{"average", "平均"}},
+		{SpendingAggregateMax, []string{"most expensive", "biggest", "largest", "highest", "一番高", "最も高", "最高", "最大"}},
+		{SpendingAggregateMin, []string{"cheapest", "smallest", "lowest", "一番安", "最も安", "最安", "最小"}},
+	}
+	// questionCategoryWords 分類を言い換えた語（グループでその分類が使われている場合だけ照合する）
+	questionCategoryWords = map[string][]string{
+		"外食":  {"eating out", "dining out", "restaurant", "外食", "食事に行"},
+		"食費":  {"groceries", "grocery", "food", "食費", "食料品", "食材"},
+		"日用品": {"daily necessities", "household goods", "日用品", "生活用品"},
+		"交通費": {"transportation", "transport", "交通費", "電車", "タクシー"},
+	}
+	// questionSelfPayerWords 質問した本人が支払ったことを表す語
+	questionSelfPayerWords = []string{"i paid", "did i pay", "have i paid", "自分が払", "自分が支払", "私が払", "私が支払", "僕が払", "俺が払"}
+)
+
+// ParseSpendingQuestion 支出に関する質問から、期間・店舗・分類・支払者・集計方法をルールに従って推定する。
+// AIを使わない変換（local / fake）に使う
+func ParseSpendingQuestion(input *SpendingQuestionInput) *SpendingQuery {
+	lower := strings.ToLower(norm.NFKC.String(strings.TrimSpace(input.Question)))
+	query := &SpendingQuery{Aggregate: SpendingAggregateSum}
+
+	if from, to, ok := resolveQuestionPeriod(lower, input.Today); ok {
+		query.From, query.To = from.Format("2006-01-02"), to.Format("2006-01-02")
+	}
+
+	for _, qa := range questionAggregates {
+		if containsAny(lower, qa.keywords) {
+			query.Aggregate = qa.aggregate
+			break
+		}
+	}
+
+	for _, shop := range input.Shops {
+		if name := strings.ToLower(norm.NFKC.String(shop)); name != "" && strings.Contains(lower, name) {
+			query.Shops = append(query.Shops, shop)
+		}
+	}
+	for _, category := range input.Categories {
+		name := strings.ToLower(norm.NFKC.String(category))
+		if name != "" && (strings.Contains(lower, name) || containsAny(lower, questionCategoryWords[category])) {
+			query.Categories = append(query.Categories, category)
+		}
+	}
+
+	if containsAny(lower, questionSelfPayerWords) {
+		query.Payer = input.Speaker
+	} else if payer := sentencePayer(lower, input.Members); payer != "" {
+		query.Payer = payer
+	} else {
+		for _, member := range input.Members {
+			if name := strings.ToLower(norm.NFKC.String(member)); name != "" && strings.Contains(lower, "did "+name+" pay") {
+				query.Payer = member
+				break
+			}
+		}
+	}
+	return query
+}
+
+// resolveQuestionPeriod 質問中の期間（日付・直近の日数・季節・月・先月・今年など）を、today を基準に開始日と終了日（どちらも含む）に変換する。
+// 季節・月で年を省略した場合は、today までに始まっている直近のものとする
+func resolveQuestionPeriod(lower string, today time.Time) (time.Time, time.Time, bool) {
+	year, month, _ := today.Date()
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, today.Location()) }
+	// months 指定の月から n か月間
+	months := func(start time.Time, n int) (time.Time, time.Time, bool) {
+		return start, start.AddDate(0, n, -1), true
+	}
+	// monthsOf 指定の月から n か月間（「去年」「今年」がなく、まだ始まっていない場合は前年）
+	lastYear, thisYear := containsAny(lower, questionLastYearWords), containsAny(lower, questionThisYearWords)
+	monthsOf := func(m time.Month, n int) (time.Time, time.Time, bool) {
+		start := day(year, m, 1)
+		if lastYear || (!thisYear && start.After(today)) {
+			start = start.AddDate(-1, 0, 0)
+		}
+		return months(start, n)
+	}
+
+	if date, ok := parseReceiptDate(lower); ok {
+		d, _ := time.ParseInLocation("2006-01-02", date, today.Location())
+		return d, d, true
+	}
+	if m := questionRecentDaysPattern.FindStringSubmatch(lower); m != nil {
+		if n, err := strconv.Atoi(m[1]); err == nil && n > 0 {
+			return today.AddDate(0, 0, -(n - 1)), today, true
+		}
+	}
+
+	switch {
+	case containsAny(lower, []string{"last month", "先月"}):
+		return months(day(year, month, 1).AddDate(0, -1, 0), 1)
+	case containsAny(lower, []string{"this month", "今月"}):
+		return months(day(year, month, 1), 1)
+	case containsAny(lower, []string{"last week", "先週"}):
+		start := today.AddDate(0, 0, -int(today.Weekday())-7)
+		return start, start.AddDate(0, 0, 6), true
+	case containsAny(lower, []string{"this week", "今週"}):
+		return today.AddDate(0, 0, -int(today.Weekday())), today, true
+	}
+
+	for _, season := range questionSeasons {
+		if containsAny(lower, season.words) {
+			return monthsOf(season.month, 3)
+		}
+	}
+	if m := questionMonthPattern.FindStringSubmatch(lower); m != nil {
+		if n, err := strconv.Atoi(m[1]); err == nil && n >= 1 && n <= 12 {
+			return monthsOf(time.Month(n), 1)
+		}
+	}
+	if m := questionMonthNamePattern.FindStringSubmatch(lower); m != nil {
+		month, _ := time.Parse("January", strings.ToUpper(m[1][:1])+m[1][1:])
+		return monthsOf(month.Month(), 1)
+	}
+
+	switch {
+	case lastYear:
+		return months(day(year-1, time.January, 1), 12)
+	case thisYear:
+		return months(day(year, time.January, 1), 12)
+	}
+
+	for _, relative := range sentenceRelativeDays {
+		if containsAny(lower, relative.words) {
+			d := today.AddDate(0, 0, relative.days)
+			return d, d, true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
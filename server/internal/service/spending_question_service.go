@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
+
+	"github.com/google/uuid"
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxSpendingQuestionLength 質問の最大文字数
+const maxSpendingQuestionLength = 500
+
+// ErrInvalidSpendingQuestion 質問が空、または長すぎる場合のエラー
+var ErrInvalidSpendingQuestion = errors.New("question must be between 1 and 500 characters")
+
+// SpendingQueryConditions 質問から変換し、実際に適用した検索条件
+type SpendingQueryConditions struct {
+	From       *string    `json:"from"` // YYYY-MM-DD（この日を含む）
+	To         *string    `json:"to"`   // YYYY-MM-DD（この日を含む）
+	Shops      []string   `json:"shops"`
+	Categories []string   `json:"categories"`
+	Keyword    string     `json:"keyword"`
+	PayerID    *uuid.UUID `json:"payer_id"`
+	Aggregate  string     `json:"aggregate"`
+}
+
+// SpendingAnswer 支出に関する質問への回答と、集計に使ったレシート
+type SpendingAnswer struct {
+	Question string                  `json:"question"`
+	Query    SpendingQueryConditions `json:"query"`
+	Ignored  []string                `json:"ignored"` // 質問から読み取ったが適用できなかった条件（period, payer）
+	Value    int                     `json:"value"`   // 集計方法に応じた金額（count の場合は件数）
+	Count    int                     `json:"count"`
+	Receipts []models.Receipt        `json:"receipts"`
+}
+
+// SpendingQuestionService 支出に関する質問への回答に関するビジネスロジックインターフェース
+type SpendingQuestionService interface {
+	// Ask 質問をAIで検索条件に変換し、グループのレシートを検索・集計して答える（AIにはSQLを書かせない）
+	Ask(ctx context.Context, groupID uuid.UUID, userID uuid.UUID, question string) (*SpendingAnswer, error)
+}
+
+type spendingQuestionServiceImpl struct {
+	groupRepo    repository.GroupRepository
+	receiptRepo  repository.ReceiptRepository
+	shopRepo     repository.ShopRepository
+	usageService AIUsageService
+	analyzer     AIAnalyzer
+	now          func() time.Time
+}
+
+// NewSpendingQuestionService SpendingQuestionServiceの実装を作成
+func NewSpendingQuestionService(groupRepo repository.GroupRepository, receiptRepo repository.ReceiptRepository, shopRepo repository.ShopRepository, usageService AIUsageService, analyzer AIAnalyzer) SpendingQuestionService {
+	return &spendingQuestionServiceImpl{
+		groupRepo:    groupRepo,
+		receiptRepo:  receiptRepo,
+		shopRepo:     shopRepo,
+		usageService: usageService,
+		analyzer:     analyzer,
+		now:          time.Now,
+	}
+}
+
+func (s *spendingQuestionServiceImpl) Ask(ctx context.Context, groupID uuid.UUID, userID uuid.UUID, question string) (*SpendingAnswer, error) {
+	question = strings.TrimSpace(question)
+	if question == "" || utf8.RuneCountInString(question) > maxSpendingQuestionLength {
+		return nil, ErrInvalidSpendingQuestion
+	}
+
+	if _, err := authorizeGroup(s.groupRepo, groupID, userID, PermissionViewGroup); err != nil {
+		return nil, err
+	}
+	group, err := s.groupRepo.GetByIDWithMembers(groupID)
+	if err != nil {
+		return nil, ErrGroupNotFound
+	}
+	categories, err := s.receiptRepo.GetCategories(groupID)
+	if err != nil {
+		return nil, err
+	}
+	shops, err := s.shopRepo.GetByGroupID(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	input := &SpendingQuestionInput{
+		Question:   question,
+		Today:      time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()),
+		Categories: categories,
+	}
+	for _, member := range group.Members {
+		if member.ID == userID {
+			input.Speaker = member.Nickname
+		} else {
+			input.Members = append(input.Members, member.Nickname)
+		}
+	}
+	for _, shop := range shops {
+		input.Shops = append(input.Shops, shop.Name)
+	}
+
+	usageID, err := s.usageService.ReserveUsage(userID, &groupID, models.AIUsageAskQuestion)
+	if err != nil {
+		return nil, err
+	}
+	translated, err := s.analyzer.TranslateQuestion(ctx, input)
+	// 変換に失敗してもモデルは呼び出しているため記録を残す
+	var usage AITokenUsage
+	if translated != nil {
+		usage = translated.Usage
+	}
+	s.usageService.FinishUsage(ctx, usageID, usage)
+	if err != nil {
+		return nil, err
+	}
+
+	answer := &SpendingAnswer{Question: question, Ignored: []string{}}
+	query := s.buildReceiptQuery(groupID, userID, group.Members, categories, translated, answer)
+	receipts, err := s.receiptRepo.SearchReceipts(query)
+	if err != nil {
+		return nil, err
+	}
+
+	answer.Receipts = receipts
+	if answer.Receipts == nil {
+		answer.Receipts = []models.Receipt{}
+	}
+	answer.Count = len(receipts)
+	answer.Value = aggregateReceipts(receipts, answer.Query.Aggregate)
+	return answer, nil
+}
+
+// buildReceiptQuery AIが変換した検索条件を検証してリポジトリの検索条件にし、適用した条件・適用できなかった条件を answer に設定する
+func (s *spendingQuestionServiceImpl) buildReceiptQuery(groupID uuid.UUID, userID uuid.UUID, members []models.User, categories []string, translated *SpendingQuery, answer *SpendingAnswer) *repository.ReceiptQuery {
+	query := &repository.ReceiptQuery{
+		GroupID:    groupID,
+		Shops:      trimmedValues(translated.Shops),
+		Categories: matchCategories(trimmedValues(translated.Categories), categories),
+		Keyword:    strings.TrimSpace(translated.Keyword),
+	}
+
+	loc := s.now().Location()
+	from, fromErr := time.ParseInLocation("2006-01-02", translated.From, loc)
+	to, toErr := time.ParseInLocation("2006-01-02", translated.To, loc)
+	if fromErr == nil && toErr == nil && to.Before(from) {
+		answer.Ignored = append(answer.Ignored, "period")
+	} else {
+		if fromErr == nil {
+			query.From = &from
+			answer.Query.From = &translated.From
+		}
+		if toErr == nil {
+			end := to.AddDate(0, 0, 1)
+			query.To = &end
+			answer.Query.To = &translated.To
+		}
+	}
+
+	if translated.Payer != "" {
+		if payerID, ok := resolveReceiptTextPayer(translated.Payer, userID, members); ok {
+			query.PayerID = &payerID
+		} else {
+			answer.Ignored = append(answer.Ignored, "payer")
+		}
+	}
+
+	answer.Query.Shops = nonNilStrings(query.Shops)
+	answer.Query.Categories = nonNilStrings(query.Categories)
+	answer.Query.Keyword = query.Keyword
+	answer.Query.PayerID = query.PayerID
+	answer.Query.Aggregate = translated.Aggregate
+	if !isValidSpendingAggregate(answer.Query.Aggregate) {
+		answer.Query.Aggregate = SpendingAggregateSum
+	}
+	return query
+}
+
+// matchCategories 分類をグループで使われている表記にそろえる（大文字・小文字、全角・半角の違いは無視する）
+func matchCategories(values []string, categories []string) []string {
+	matched := make([]string, 0, len(values))
+	for _, v := range values {
+		key := strings.ToLower(norm.NFKC.String(v))
+		for _, c := range categories {
+			if strings.ToLower(norm.NFKC.String(c)) == key {
+				v = c
+				break
+			}
+		}
+		matched = append(matched, v)
+	}
+	return matched
+}
+
+// aggregateReceipts レシートの金額を集計する（レシートがない場合は0）
+func aggregateReceipts(receipts []models.Receipt, aggregate string) int {
+	if len(receipts) == 0 {
+		return 0
+	}
+
+	sum, highest, lowest := 0, receipts[0].Amount, receipts[0].Amount
+	for _, r := range receipts {
+		sum += r.Amount
+		highest = max(highest, r.Amount)
+		lowest = min(lowest, r.Amount)
+	}
+
+	switch aggregate {
+	case SpendingAggregateCount:
+		return len(receipts)
+	case SpendingAggregateAverage:
+		return (sum + len(receipts)/2) / len(receipts)
+	case SpendingAggregateMax:
+		return highest
+	case SpendingAggregateMin:
+		return lowest
+	}
+	return sum
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
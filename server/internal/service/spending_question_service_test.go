@@ -0,0 +1,189 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"receipt/server/internal/models"
+	"receipt/server/internal/service"
+
+	"github.com/google/uuid"
+)
+
+func TestParseSpendingQuestion(t *testing.T) {
+	today := time.Date(2026, 5, 20, 0, 0, 0, 0, time.Local)
+	input := func(question string) *service.SpendingQuestionInput {
+		return &service.SpendingQuestionInput{
+			Question:   question,
+			Today:      today,
+			Speaker:    "Hanako",
+			Members:    []string{"太郎"},
+			Categories: []string{"外食", "食費"},
+			Shops:      []string{"サイゼリヤ"},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		question string
+		from, to string
+		check    func(t *testing.T, q *service.SpendingQuery)
+	}{
+		{
+			name:     "Summer Not Yet Started Uses Last Year",
+			question: "How much did we spend on eating out in summer?",
+			from:     "2025-06-01", to: "2025-08-31",
+			check: func(t *testing.T, q *service.SpendingQuery) {
+				if len(q.Categories) != 1 || q.Categories[0] != "外食" || q.Aggregate != service.SpendingAggregateSum {
+					t.Errorf("unexpected query: %+v", q)
+				}
+			},
+		},
+		{
+			name:     "Last Month Count",
+			question: "先月サイゼリヤに何回行った？",
+			from:     "2026-04-01", to: "2026-04-30",
+			check: func(t *testing.T, q *service.SpendingQuery) {
+				if len(q.Shops) != 1 || q.Shops[0] != "サイゼリヤ" || q.Aggregate != service.SpendingAggregateCount {
+					t.Errorf("unexpected query: %+v", q)
+				}
+			},
+		},
+		{
+			name:     "Month Of Last Year",
+			question: "去年の12月に太郎が払った食費の平均は？",
+			from:     "2025-12-01", to: "2025-12-31",
+			check: func(t *testing.T, q *service.SpendingQuery) {
+				if q.Payer != "太郎" || q.Aggregate != service.SpendingAggregateAverage || len(q.Categories) != 1 || q.Categories[0] != "食費" {
+					t.Errorf("unexpected query: %+v", q)
+				}
+			},
+		},
+		{
+			name:     "Recent Days Paid By Me",
+			question: "What was the most expensive thing I paid in the last 7 days?",
+			from:     "2026-05-14", to: "2026-05-20",
+			check: func(t *testing.T, q *service.SpendingQuery) {
+				if q.Payer != "Hanako" || q.Aggregate != service.SpendingAggregateMax {
+					t.Errorf("unexpected query: %+v", q)
+				}
+			},
+		},
+		{
+			name:     "No Period",
+			question: "How much have we spent in total?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := service.ParseSpendingQuestion(input(tt.question))
+			if q.From != tt.from || q.To != tt.to {
+				t.Errorf("expected %s - %s, got %s - %s", tt.from, tt.to, q.From, q.To)
+			}
+			if tt.check != nil {
+				tt.check(t, q)
+			}
+		})
+	}
+}
+
+func TestSpendingQuestionService(t *testing.T) {
+	groupRepo := newMockGroupRepository()
+	receiptRepo := newMockReceiptRepository()
+	userID := uuid.New()
+	memberID := uuid.New()
+	otherID := uuid.New()
+
+	group := models.Group{Name: "Spending Question Test"}
+	_ = groupRepo.Create(&group)
+	_ = groupRepo.AddMember(&group, &models.User{ID: userID, Nickname: "Hanako"})
+	_ = groupRepo.AddMember(&group, &models.User{ID: memberID, Nickname: "太郎"})
+	groupID := group.ID
+
+	otherGroupID := newReceiptTestGroup(groupRepo, otherID)
+	date := func(s string) time.Time {
+		d, _ := time.ParseInLocation("2006-01-02", s, time.Local)
+		return d
+	}
+	for _, r := range []models.Receipt{
+		{GroupID: groupID, Date: date("2025-07-10"), Shop: "サイゼリヤ", Category: "外食", Item: "夕食", Amount: 3200, PayerID: userID},
+		{GroupID: groupID, Date: date("2025-08-31"), Shop: "スシロー", Category: "外食", Item: "寿司", Amount: 4100, PayerID: memberID},
+		{GroupID: groupID, Date: date("2025-09-01"), Shop: "サイゼリヤ", Category: "外食", Item: "昼食", Amount: 1500, PayerID: userID},
+		{GroupID: groupID, Date: date("2025-07-15"), Shop: "イオン", Category: "食費", Item: "食材", Amount: 5000, PayerID: userID},
+		{GroupID: otherGroupID, Date: date("2025-07-20"), Shop: "サイゼリヤ", Category: "外食", Item: "夕食", Amount: 9999, PayerID: otherID},
+	} {
+		receipt := r
+		_ = receiptRepo.Create(&receipt)
+	}
+
+	var translated *service.SpendingQuery
+	var received *service.SpendingQuestionInput
+	analyzer := &mockAIAnalyzer{
+		questionFunc: func(ctx context.Context, input *service.SpendingQuestionInput) (*service.SpendingQuery, error) {
+			received = input
+			query := *translated
+			return &query, nil
+		},
+	}
+	svc := service.NewSpendingQuestionService(groupRepo, receiptRepo, newMockShopRepository(), newTestAIUsageService(groupRepo, analyzer), analyzer)
+
+	t.Run("Sums Matching Receipts", func(t *testing.T) {
+		translated = &service.SpendingQuery{From: "2025-06-01", To: "2025-08-31", Categories: []string{"外食"}, Aggregate: service.SpendingAggregateSum}
+
+		answer, err := svc.Ask(context.Background(), groupID, userID, "How much did we spend on eating out in summer?")
+		if err != nil {
+			t.Fatalf("Ask failed: %v", err)
+		}
+		if len(received.Categories) != 2 || received.Speaker != "Hanako" {
+			t.Errorf("expected group categories and speaker to be passed, got %+v", received)
+		}
+		if answer.Value != 7300 || answer.Count != 2 || len(answer.Receipts) != 2 {
+			t.Errorf("expected 7300 from 2 receipts, got %d from %d", answer.Value, answer.Count)
+		}
+		if answer.Query.From == nil || *answer.Query.To != "2025-08-31" {
+			t.Errorf("expected applied period to be returned, got %+v", answer.Query)
+		}
+	})
+
+	t.Run("Filters By Payer And Shop", func(t *testing.T) {
+		translated = &service.SpendingQuery{Shops: []string{"サイゼリヤ"}, Payer: "me", Aggregate: service.SpendingAggregateCount}
+
+		answer, err := svc.Ask(context.Background(), groupID, userID, "自分が払ったサイゼリヤは何回？")
+		if err != nil {
+			t.Fatalf("Ask failed: %v", err)
+		}
+		if answer.Value != 2 || answer.Query.PayerID == nil || *answer.Query.PayerID != userID {
+			t.Errorf("expected 2 receipts paid by the user, got %+v", answer)
+		}
+	})
+
+	t.Run("Reports Unapplied Conditions", func(t *testing.T) {
+		translated = &service.SpendingQuery{From: "2025-09-30", To: "2025-06-01", Payer: "次郎", Aggregate: "median"}
+
+		answer, err := svc.Ask(context.Background(), groupID, userID, "次郎はいくら使った？")
+		if err != nil {
+			t.Fatalf("Ask failed: %v", err)
+		}
+		if len(answer.Ignored) != 2 || answer.Ignored[0] != "period" || answer.Ignored[1] != "payer" {
+			t.Errorf("expected period and payer to be ignored, got %v", answer.Ignored)
+		}
+		if answer.Query.Aggregate != service.SpendingAggregateSum || answer.Value != 13800 {
+			t.Errorf("expected the total of the group, got %s %d", answer.Query.Aggregate, answer.Value)
+		}
+	})
+
+	t.Run("Invalid Question", func(t *testing.T) {
+		if _, err := svc.Ask(context.Background(), groupID, userID, " "); !errors.Is(err, service.ErrInvalidSpendingQuestion) {
+			t.Errorf("expected ErrInvalidSpendingQuestion, got %v", err)
+		}
+	})
+
+	t.Run("Requires Group Membership", func(t *testing.T) {
+		if _, err := svc.Ask(context.Background(), groupID, otherID, "How much did we spend?"); !errors.Is(err, service.ErrPermissionDenied) {
+			t.Errorf("expected ErrPermissionDenied, got %v", err)
+		}
+	})
+}
@@ -2,6 +2,9 @@ package service
 
 import (
 	"errors"
+	"sort"
+	"time"
+
 	"receipt/server/internal/models"
 	"receipt/server/internal/repository"
 
@@ -11,14 +14,39 @@ import (
 var (
 	// ErrInvalidSettlementAmount 精算金額が不正な場合のエラー
 	ErrInvalidSettlementAmount = errors.New("settlement amount must be at least 1")
+	// ErrInvalidSettlementRecipient 精算の受取人がグループのメンバーでない場合のエラー
+	ErrInvalidSettlementRecipient = errors.New("settlement recipient must be another member of the group")
+	// ErrSettlementExceedsBalance 累積残高を超える金額で精算しようとした場合のエラー
+	ErrSettlementExceedsBalance = errors.New("settlement amount exceeds the outstanding balance")
 )
 
+// CreateSettlementParams 精算登録用パラメータ
+type CreateSettlementParams struct {
+	GroupID     uuid.UUID
+	Year        int
+	Month       int
+	Amount      int
+	RecipientID *uuid.UUID // 省略時、2人のグループでは相手を受取人とする
+	Cumulative  bool       // true の場合、指定月までの累積残高をまとめて精算する
+}
+
 // MemberSummary メンバーごとの出費集計
 type MemberSummary struct {
 	UserID   uuid.UUID `json:"user_id"`
 	Nickname string    `json:"nickname"`
-	Paid     int       `json:"paid"`  // 実際に支払った合計
-	Share    int       `json:"share"` // 負担すべき合計
+	Paid     int       `json:"paid"`   // 実際に支払った合計
+	Share    int       `json:"share"`  // 負担すべき合計
+	Former   bool      `json:"former"` // 既にグループを退出したメンバー
+}
+
+// MemberBalance メンバーごとの累積残高（月をまたいで繰り越される未精算額）。
+// 残高 = 負担額の合計 - 支払額の合計 - 支払った精算額 + 受け取った精算額。プラスは支払うべき額、マイナスは受け取るべき額
+type MemberBalance struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Nickname    string    `json:"nickname"`
+	CarriedOver int       `json:"carried_over"` // 前月末までの累積残高
+	Balance     int       `json:"balance"`      // 当月末までの累積残高
+	Former      bool      `json:"former"`
 }
 
 // MonthlySummaryResult 月次サマリー集計結果
@@ -26,18 +54,20 @@ type MonthlySummaryResult struct {
 	TotalSpent  int                 `json:"total_spent"`
 	Members     []MemberSummary     `json:"members"`
 	Settlements []models.Settlement `json:"settlements"`
+	Balances    []MemberBalance     `json:"balances"`
 }
 
 // SummaryService 精算計算・月次集計に関するビジネスロジックインターフェース
 type SummaryService interface {
-	GetMonthlySummary(groupID uuid.UUID, year int, month int) (*MonthlySummaryResult, error)
-	CreateSettlement(groupID uuid.UUID, year int, month int, amount int, settledBy uuid.UUID) (*models.Settlement, error)
+	GetMonthlySummary(groupID uuid.UUID, userID uuid.UUID, year int, month int) (*MonthlySummaryResult, error)
+	CreateSettlement(params *CreateSettlementParams, settledBy uuid.UUID) (*models.Settlement, error)
 }
 
 type summaryServiceImpl struct {
 	groupRepo      repository.GroupRepository
 	receiptRepo    repository.ReceiptRepository
 	settlementRepo repository.SettlementRepository
+	reportRepo     repository.ReportRepository
 }
 
 // NewSummaryService SummaryServiceの実装を作成
@@ -45,17 +75,23 @@ func NewSummaryService(
 	groupRepo repository.GroupRepository,
 	receiptRepo repository.ReceiptRepository,
 	settlementRepo repository.SettlementRepository,
+	reportRepo repository.ReportRepository,
 ) SummaryService {
 	return &summaryServiceImpl{
 		groupRepo:      groupRepo,
 		receiptRepo:    receiptRepo,
 		settlementRepo: settlementRepo,
+		reportRepo:     reportRepo,
 	}
 }
 
-func (s *summaryServiceImpl) GetMonthlySummary(groupID uuid.UUID, year int, month int) (*MonthlySummaryResult, error) {
+func (s *summaryServiceImpl) GetMonthlySummary(groupID uuid.UUID, userID uuid.UUID, year int, month int) (*MonthlySummaryResult, error) {
 	group, err := s.groupRepo.GetByIDWithMembers(groupID)
 	if err != nil {
+		return nil, ErrGroupNotFound
+	}
+
+	if _, err := authorizeGroup(s.groupRepo, groupID, userID, PermissionViewGroup); err != nil {
 		return nil, err
 	}
 
@@ -69,92 +105,478 @@ func (s *summaryServiceImpl) GetMonthlySummary(groupID uuid.UUID, year int, mont
 		return nil, err
 	}
 
-	paidMap := make(map[uuid.UUID]int)
-	shareMap := make(map[uuid.UUID]int)
-	totalSpent := 0
+	periods, err := s.groupRepo.GetMembershipPeriods(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	paidMap, shareMap, totalSpent := calculateMemberShares(periods, receipts)
+
+	var memberSummaries []MemberSummary
+	listed := make(map[uuid.UUID]bool)
+	for _, m := range group.Members {
+		listed[m.ID] = true
+		memberSummaries = append(memberSummaries, MemberSummary{
+			UserID:   m.ID,
+			Nickname: m.Nickname,
+			Paid:     paidMap[m.ID],
+			Share:    shareMap[m.ID],
+		})
+	}
+
+	// 退出済みのメンバーも、その月に支払い・負担がある場合は表示する
+	for _, p := range periods {
+		if listed[p.UserID] || (paidMap[p.UserID] == 0 && shareMap[p.UserID] == 0) {
+			continue
+		}
+		listed[p.UserID] = true
+		memberSummaries = append(memberSummaries, MemberSummary{
+			UserID:   p.UserID,
+			Nickname: p.User.Nickname,
+			Paid:     paidMap[p.UserID],
+			Share:    shareMap[p.UserID],
+			Former:   true,
+		})
+	}
+
+	balances, err := s.memberBalances(group, periods, year, month)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MonthlySummaryResult{
+		TotalSpent:  totalSpent,
+		Members:     memberSummaries,
+		Settlements: settlements,
+		Balances:    balances,
+	}, nil
+}
+
+// memberBalances 前月末・当月末時点の累積残高をメンバーごとに計算する
+func (s *summaryServiceImpl) memberBalances(group *models.Group, periods []models.GroupMembershipPeriod, year int, month int) ([]MemberBalance, error) {
+	prevYear, prevMonth := year, month-1
+	if prevMonth == 0 {
+		prevYear, prevMonth = year-1, 12
+	}
+	carriedOver, err := calculateBalances(s.reportRepo, s.receiptRepo, s.settlementRepo, group.ID, periods, prevYear, prevMonth)
+	if err != nil {
+		return nil, err
+	}
+	current, err := calculateBalances(s.reportRepo, s.receiptRepo, s.settlementRepo, group.ID, periods, year, month)
+	if err != nil {
+		return nil, err
+	}
+
+	var balances []MemberBalance
+	listed := make(map[uuid.UUID]bool)
+	for _, m := range group.Members {
+		listed[m.ID] = true
+		balances = append(balances, MemberBalance{
+			UserID:      m.ID,
+			Nickname:    m.Nickname,
+			CarriedOver: carriedOver[m.ID],
+			Balance:     current[m.ID],
+		})
+	}
+
+	// 退出済みでも残高が残っているメンバーは表示する
+	for _, p := range periods {
+		if listed[p.UserID] || (carriedOver[p.UserID] == 0 && current[p.UserID] == 0) {
+			continue
+		}
+		listed[p.UserID] = true
+		balances = append(balances, MemberBalance{
+			UserID:      p.UserID,
+			Nickname:    p.User.Nickname,
+			CarriedOver: carriedOver[p.UserID],
+			Balance:     current[p.UserID],
+			Former:      true,
+		})
+	}
+
+	return balances, nil
+}
+
+func (s *summaryServiceImpl) CreateSettlement(params *CreateSettlementParams, settledBy uuid.UUID) (*models.Settlement, error) {
+	if _, err := authorizeGroup(s.groupRepo, params.GroupID, settledBy, PermissionSettle); err != nil {
+		return nil, err
+	}
+
+	if params.Amount <= 0 {
+		return nil, ErrInvalidSettlementAmount
+	}
+
+	group, err := s.groupRepo.GetByIDWithMembers(params.GroupID)
+	if err != nil {
+		return nil, ErrGroupNotFound
+	}
+
+	recipientID, err := settlementRecipient(group, params.RecipientID, settledBy)
+	if err != nil {
+		return nil, err
+	}
+
+	settlement := models.Settlement{
+		GroupID:     params.GroupID,
+		Year:        params.Year,
+		Month:       params.Month,
+		Amount:      params.Amount,
+		SettledBy:   settledBy,
+		RecipientID: recipientID,
+		Cumulative:  params.Cumulative,
+	}
+
+	if !params.Cumulative {
+		if err := s.settlementRepo.CreateSettlementAndSettleReceipts(&settlement); err != nil {
+			return nil, err
+		}
+		return &settlement, nil
+	}
+
+	// 累積精算は、指定月までの累積残高（支払うべき額）を上限とする
+	periods, err := s.groupRepo.GetMembershipPeriods(params.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	balances, err := calculateBalances(s.reportRepo, s.receiptRepo, s.settlementRepo, params.GroupID, periods, params.Year, params.Month)
+	if err != nil {
+		return nil, err
+	}
+	if params.Amount > balances[settledBy] {
+		return nil, ErrSettlementExceedsBalance
+	}
+
+	// 一部だけの支払いでは残高が残るため、全員の残高が0になった場合のみレシートを精算済みにする
+	settlement.CreatedAt = time.Now()
+	applySettlement(balances, periods, &settlement)
+	if !allSettled(balances) {
+		if err := s.settlementRepo.Create(&settlement); err != nil {
+			return nil, err
+		}
+		return &settlement, nil
+	}
+
+	if err := s.settlementRepo.CreateCumulativeSettlement(&settlement); err != nil {
+		return nil, err
+	}
+	return &settlement, nil
+}
+
+// allSettled 全員の累積残高が0か
+func allSettled(balances map[uuid.UUID]int) bool {
+	for _, balance := range balances {
+		if balance != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// settlementRecipient 精算の受取人を決める。省略時は相手が1人の場合のみその相手とする
+func settlementRecipient(group *models.Group, recipientID *uuid.UUID, settledBy uuid.UUID) (*uuid.UUID, error) {
+	if recipientID != nil {
+		if *recipientID == settledBy {
+			return nil, ErrInvalidSettlementRecipient
+		}
+		for _, m := range group.Members {
+			if m.ID == *recipientID {
+				return recipientID, nil
+			}
+		}
+		return nil, ErrInvalidSettlementRecipient
+	}
+
+	var others []uuid.UUID
+	for _, m := range group.Members {
+		if m.ID != settledBy {
+			others = append(others, m.ID)
+		}
+	}
+	if len(others) == 1 {
+		return &others[0], nil
+	}
+	return nil, nil
+}
+
+// calculateBalances 指定月（精算月）までのレシートと精算から、メンバーごとの累積残高を計算する。
+// レシートは在籍メンバーが変わらない期間ごと、精算は精算者・受取人ごとにSQLで集計する
+func calculateBalances(
+	reportRepo repository.ReportRepository,
+	receiptRepo repository.ReceiptRepository,
+	settlementRepo repository.SettlementRepository,
+	groupID uuid.UUID,
+	periods []models.GroupMembershipPeriod,
+	year int,
+	month int,
+) (map[uuid.UUID]int, error) {
+	balances := make(map[uuid.UUID]int)
+
+	for _, seg := range membershipSegments(periods) {
+		if len(seg.members) == 0 {
+			// その日に誰も在籍していない（グループ作成前の日付などの）レシートは、登録時点のメンバーで1件ずつ計算する
+			receipts, err := receiptRepo.SearchReceipts(&repository.ReceiptQuery{GroupID: groupID, From: seg.from, To: seg.to})
+			if err != nil {
+				return nil, err
+			}
+			var until []models.Receipt
+			for _, r := range receipts {
+				if r.SettlementYear < year || (r.SettlementYear == year && r.SettlementMonth <= month) {
+					until = append(until, r)
+				}
+			}
+			paid, share, _ := calculateMemberShares(periods, until)
+			for id, amount := range share {
+				balances[id] += amount
+			}
+			for id, amount := range paid {
+				balances[id] -= amount
+			}
+			continue
+		}
+
+		if err := addSegmentShares(balances, reportRepo, groupID, seg, year, month); err != nil {
+			return nil, err
+		}
+	}
+
+	totals, err := settlementRepo.GetTotalsByMember(groupID, year, month)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range totals {
+		balances[t.SettledBy] -= t.Total
+		balances[t.RecipientID] += t.Total
+	}
+
+	legacy, err := settlementRepo.GetSettlementsWithoutRecipient(groupID, year, month)
+	if err != nil {
+		return nil, err
+	}
+	for i := range legacy {
+		applySettlement(balances, periods, &legacy[i])
+	}
+
+	return balances, nil
+}
+
+// membershipSegment 在籍しているメンバーが変わらない購入日の期間 [from, to)（nil は上限・下限なし）
+type membershipSegment struct {
+	from    *time.Time
+	to      *time.Time
+	members []uuid.UUID // 参加順
+}
+
+// membershipSegments 在籍期間から、在籍しているメンバーが変わらない購入日の期間に分ける。
+// レシートの日付に在籍していたか（activeMemberIDs）は日単位で判定するため、参加日と退出日の翌日を境界にする
+func membershipSegments(periods []models.GroupMembershipPeriod) []membershipSegment {
+	var bounds []time.Time
+	for _, p := range periods {
+		bounds = append(bounds, startOfLocalDay(p.JoinedAt))
+		if p.LeftAt != nil {
+			bounds = append(bounds, startOfLocalDay(*p.LeftAt).AddDate(0, 0, 1))
+		}
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i].Before(bounds[j]) })
+
+	var segments []membershipSegment
+	var from *time.Time
+	for i := range bounds {
+		if from != nil && !bounds[i].After(*from) {
+			continue
+		}
+		segments = append(segments, membershipSegment{from: from, to: &bounds[i], members: segmentMembers(periods, from)})
+		from = &bounds[i]
+	}
+	return append(segments, membershipSegment{from: from, members: segmentMembers(periods, from)})
+}
+
+// segmentMembers 期間の初日に在籍していたメンバー（下限のない期間は最初の参加日より前のため、誰もいない）
+func segmentMembers(periods []models.GroupMembershipPeriod, from *time.Time) []uuid.UUID {
+	if from == nil {
+		return nil
+	}
+	return membersActiveBetween(periods, *from, from.AddDate(0, 0, 1).Add(-time.Nanosecond))
+}
+
+func startOfLocalDay(t time.Time) time.Time {
+	t = t.In(time.Local)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// addSegmentShares 在籍メンバーが変わらない期間のレシートの支払額・負担額を残高に加える（calculateMemberShares と同じ分け方）
+func addSegmentShares(balances map[uuid.UUID]int, reportRepo repository.ReportRepository, groupID uuid.UUID, seg membershipSegment, year int, month int) error {
+	totals, err := reportRepo.GetShareTotals(groupID, seg.from, seg.to, year, month, len(seg.members))
+	if err != nil {
+		return err
+	}
+
+	// 支払者以外で負担するレシートは、支払者が在籍メンバーに含まれる場合は1人少ない人数で割る
+	splitOthers := make(map[uuid.UUID]bool)
+	for _, t := range totals {
+		balances[t.PayerID] -= t.Total
+
+		switch t.PaymentMethod {
+		case models.PaymentMethodSelf:
+			balances[t.PayerID] += t.Total
+
+		case models.PaymentMethodOther:
+			others := excludeMember(seg.members, t.PayerID)
+			switch {
+			case len(others) == 0:
+				balances[t.PayerID] += t.Total
+			case len(others) == len(seg.members):
+				addSplitShare(balances, others, t.Total, t.Remainder, others[0])
+			case len(others) == 1:
+				balances[others[0]] += t.Total
+			default:
+				splitOthers[t.PayerID] = true
+			}
+
+		default:
+			addSplitShare(balances, seg.members, t.Total, t.Remainder, t.PayerID)
+		}
+	}
+	if len(splitOthers) == 0 {
+		return nil
+	}
+
+	totals, err = reportRepo.GetShareTotals(groupID, seg.from, seg.to, year, month, len(seg.members)-1)
+	if err != nil {
+		return err
+	}
+	for _, t := range totals {
+		if t.PaymentMethod == models.PaymentMethodOther && splitOthers[t.PayerID] {
+			others := excludeMember(seg.members, t.PayerID)
+			addSplitShare(balances, others, t.Total, t.Remainder, others[0])
+		}
+	}
+	return nil
+}
+
+// addSplitShare 合計を均等に負担額に加え、端数（レシートごとの余りの合計）を remainderTo に加える
+func addSplitShare(balances map[uuid.UUID]int, members []uuid.UUID, total int, remainder int, remainderTo uuid.UUID) {
+	for _, id := range members {
+		balances[id] += (total - remainder) / len(members)
+	}
+	balances[remainderTo] += remainder
+}
+
+func excludeMember(members []uuid.UUID, excluded uuid.UUID) []uuid.UUID {
+	var result []uuid.UUID
+	for _, id := range members {
+		if id != excluded {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// applySettlement 精算を残高に反映する。受取人が記録されていない精算は、精算時点の他のメンバーで均等に受け取ったものとする
+func applySettlement(balances map[uuid.UUID]int, periods []models.GroupMembershipPeriod, st *models.Settlement) {
+	balances[st.SettledBy] -= st.Amount
+	if st.RecipientID != nil {
+		balances[*st.RecipientID] += st.Amount
+		return
+	}
+
+	recipients := excludeMember(membersActiveBetween(periods, st.CreatedAt, st.CreatedAt), st.SettledBy)
+	if len(recipients) == 0 {
+		balances[st.SettledBy] += st.Amount
+		return
+	}
+	for i, id := range recipients {
+		balances[id] += st.Amount / len(recipients)
+		if i == 0 {
+			balances[id] += st.Amount % len(recipients)
+		}
+	}
+}
+
+// calculateMemberShares レシートごとの支払方法に従い、メンバーごとの支払額・負担額と合計支出を計算する。
+// 割り勘の対象は、レシートの日付時点でグループに在籍していたメンバー
+func calculateMemberShares(periods []models.GroupMembershipPeriod, receipts []models.Receipt) (paid map[uuid.UUID]int, share map[uuid.UUID]int, total int) {
+	paid = make(map[uuid.UUID]int)
+	share = make(map[uuid.UUID]int)
 
 	for _, r := range receipts {
-		totalSpent += r.Amount
-		paidMap[r.PayerID] += r.Amount
+		total += r.Amount
+		paid[r.PayerID] += r.Amount
+
+		members := activeMemberIDs(periods, r)
 
 		switch r.PaymentMethod {
 		case models.PaymentMethodSelf:
 			// 支払者が全額負担
-			shareMap[r.PayerID] += r.Amount
+			share[r.PayerID] += r.Amount
 
 		case models.PaymentMethodOther:
 			// 支払者以外で均等に負担（2人以上の場合に対応）
-			otherCount := len(group.Members) - 1
-			if otherCount > 0 {
-				sharePerPerson := r.Amount / otherCount
-				remainder := r.Amount % otherCount
-
-				isRemainderAssigned := false
-				for _, m := range group.Members {
-					if m.ID != r.PayerID {
-						shareMap[m.ID] += sharePerPerson
-						if !isRemainderAssigned {
-							shareMap[m.ID] += remainder
-							isRemainderAssigned = true
-						}
-					}
+			var others []uuid.UUID
+			for _, id := range members {
+				if id != r.PayerID {
+					others = append(others, id)
+				}
+			}
+
+			if len(others) > 0 {
+				sharePerPerson := r.Amount / len(others)
+				remainder := r.Amount % len(others)
+
+				for _, id := range others {
+					share[id] += sharePerPerson
 				}
+				share[others[0]] += remainder
 			} else {
 				// 相手がいない場合は支払者が負担
-				shareMap[r.PayerID] += r.Amount
+				share[r.PayerID] += r.Amount
 			}
 
 		case models.PaymentMethodHalf:
 			fallthrough
 		default:
 			// メンバー全員で均等割り
-			numMembers := len(group.Members)
+			numMembers := len(members)
 			if numMembers > 0 {
 				sharePerPerson := r.Amount / numMembers
 				remainder := r.Amount % numMembers
 
-				for _, m := range group.Members {
-					shareMap[m.ID] += sharePerPerson
+				for _, id := range members {
+					share[id] += sharePerPerson
 				}
 				// 端数は支払者が負担
-				shareMap[r.PayerID] += remainder
+				share[r.PayerID] += remainder
+			} else {
+				share[r.PayerID] += r.Amount
 			}
 		}
 	}
 
-	var memberSummaries []MemberSummary
-	for _, m := range group.Members {
-		memberSummaries = append(memberSummaries, MemberSummary{
-			UserID:   m.ID,
-			Nickname: m.Nickname,
-			Paid:     paidMap[m.ID],
-			Share:    shareMap[m.ID],
-		})
-	}
-
-	return &MonthlySummaryResult{
-		TotalSpent:  totalSpent,
-		Members:     memberSummaries,
-		Settlements: settlements,
-	}, nil
+	return paid, share, total
 }
 
-func (s *summaryServiceImpl) CreateSettlement(groupID uuid.UUID, year int, month int, amount int, settledBy uuid.UUID) (*models.Settlement, error) {
-	if amount <= 0 {
-		return nil, ErrInvalidSettlementAmount
-	}
+// activeMemberIDs レシートの日付に在籍していたメンバーを参加順に返す。
+// グループ作成前の日付で登録されたなど、その日に誰も在籍していない場合は登録時点のメンバーを返す
+func activeMemberIDs(periods []models.GroupMembershipPeriod, r models.Receipt) []uuid.UUID {
+	dayStart := time.Date(r.Date.Year(), r.Date.Month(), r.Date.Day(), 0, 0, 0, 0, r.Date.Location())
+	dayEnd := dayStart.AddDate(0, 0, 1).Add(-time.Nanosecond)
 
-	settlement := models.Settlement{
-		GroupID:   groupID,
-		Year:      year,
-		Month:     month,
-		Amount:    amount,
-		SettledBy: settledBy,
+	members := membersActiveBetween(periods, dayStart, dayEnd)
+	if len(members) == 0 {
+		members = membersActiveBetween(periods, r.CreatedAt, r.CreatedAt)
 	}
+	return members
+}
 
-	if err := s.settlementRepo.CreateSettlementAndSettleReceipts(&settlement); err != nil {
-		return nil, err
+func membersActiveBetween(periods []models.GroupMembershipPeriod, from time.Time, to time.Time) []uuid.UUID {
+	var ids []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for i := range periods {
+		p := &periods[i]
+		if seen[p.UserID] || !p.IsActiveBetween(from, to) {
+			continue
+		}
+		seen[p.UserID] = true
+		ids = append(ids, p.UserID)
 	}
-
-	return &settlement, nil
+	return ids
 }
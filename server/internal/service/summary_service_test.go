@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
 	"receipt/server/internal/service"
 
 	"github.com/google/uuid"
@@ -13,6 +14,8 @@ import (
 
 type mockSettlementRepository struct {
 	settlements map[uuid.UUID]*models.Settlement
+	// receiptsSettled レシートをまとめて精算済みにした累積精算の数
+	receiptsSettled int
 }
 
 func newMockSettlementRepository() *mockSettlementRepository {
@@ -44,13 +47,62 @@ func (m *mockSettlementRepository) CreateSettlementAndSettleReceipts(settlement
 	return m.Create(settlement)
 }
 
+func (m *mockSettlementRepository) GetSettlementsByUser(userID uuid.UUID) ([]models.Settlement, error) {
+	var result []models.Settlement
+	for _, settlement := range m.settlements {
+		if settlement.SettledBy == userID {
+			result = append(result, *settlement)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockSettlementRepository) CreateCumulativeSettlement(settlement *models.Settlement) error {
+	m.receiptsSettled++
+	return m.Create(settlement)
+}
+
+func (m *mockSettlementRepository) settledUntil(groupID uuid.UUID, year int, month int) []models.Settlement {
+	var result []models.Settlement
+	for _, settlement := range m.settlements {
+		if settlement.GroupID == groupID && (settlement.Year < year || (settlement.Year == year && settlement.Month <= month)) {
+			result = append(result, *settlement)
+		}
+	}
+	return result
+}
+
+func (m *mockSettlementRepository) GetTotalsByMember(groupID uuid.UUID, year int, month int) ([]repository.SettlementTotal, error) {
+	byMember := make(map[[2]uuid.UUID]int)
+	for _, settlement := range m.settledUntil(groupID, year, month) {
+		if settlement.RecipientID != nil {
+			byMember[[2]uuid.UUID{settlement.SettledBy, *settlement.RecipientID}] += settlement.Amount
+		}
+	}
+	var totals []repository.SettlementTotal
+	for key, total := range byMember {
+		totals = append(totals, repository.SettlementTotal{SettledBy: key[0], RecipientID: key[1], Total: total})
+	}
+	return totals, nil
+}
+
+func (m *mockSettlementRepository) GetSettlementsWithoutRecipient(groupID uuid.UUID, year int, month int) ([]models.Settlement, error) {
+	var result []models.Settlement
+	for _, settlement := range m.settledUntil(groupID, year, month) {
+		if settlement.RecipientID == nil {
+			result = append(result, settlement)
+		}
+	}
+	return result, nil
+}
+
 func TestSummaryService_GetMonthlySummary(t *testing.T) {
 	userRepo := newMockUserRepository()
 	groupRepo := newMockGroupRepository()
 	receiptRepo := newMockReceiptRepository()
 	settlementRepo := newMockSettlementRepository()
 
-	svc := service.NewSummaryService(groupRepo, receiptRepo, settlementRepo)
+	svc := service.NewSummaryService(groupRepo, receiptRepo, settlementRepo, &mockReportRepository{receiptRepo: receiptRepo})
 
 	// テストデータ準備
 	userA := models.User{Email: "usera@example.com", Nickname: "UserA"}
@@ -121,7 +173,7 @@ func TestSummaryService_GetMonthlySummary(t *testing.T) {
 	}
 	_ = receiptRepo.Create(&r4)
 
-	result, err := svc.GetMonthlySummary(group.ID, year, month)
+	result, err := svc.GetMonthlySummary(group.ID, userA.ID, year, month)
 	if err != nil {
 		t.Fatalf("GetMonthlySummary failed: %v", err)
 	}
@@ -161,13 +213,17 @@ func TestSummaryService_CreateSettlement(t *testing.T) {
 	receiptRepo := newMockReceiptRepository()
 	settlementRepo := newMockSettlementRepository()
 
-	svc := service.NewSummaryService(groupRepo, receiptRepo, settlementRepo)
+	svc := service.NewSummaryService(groupRepo, receiptRepo, settlementRepo, &mockReportRepository{receiptRepo: receiptRepo})
 
-	groupID := uuid.New()
 	userID := uuid.New()
+	group := models.Group{Name: "Family", OwnerID: userID}
+	_ = groupRepo.Create(&group)
+	_ = groupRepo.AddMember(&group, &models.User{ID: userID})
+	groupID := group.ID
 
 	t.Run("Success", func(t *testing.T) {
-		settlement, err := svc.CreateSettlement(groupID, 2026, 6, 5000, userID)
+		params := &service.CreateSettlementParams{GroupID: groupID, Year: 2026, Month: 6, Amount: 5000}
+		settlement, err := svc.CreateSettlement(params, userID)
 		if err != nil {
 			t.Fatalf("CreateSettlement failed: %v", err)
 		}
@@ -181,9 +237,262 @@ func TestSummaryService_CreateSettlement(t *testing.T) {
 	})
 
 	t.Run("Invalid Amount", func(t *testing.T) {
-		_, err := svc.CreateSettlement(groupID, 2026, 6, 0, userID)
+		params := &service.CreateSettlementParams{GroupID: groupID, Year: 2026, Month: 6, Amount: 0}
+		_, err := svc.CreateSettlement(params, userID)
 		if !errors.Is(err, service.ErrInvalidSettlementAmount) {
 			t.Errorf("Expected error %v, got %v", service.ErrInvalidSettlementAmount, err)
 		}
 	})
 }
+
+func TestSummaryService_GetMonthlySummary_MembershipPeriods(t *testing.T) {
+	userRepo := newMockUserRepository()
+	groupRepo := newMockGroupRepository()
+	receiptRepo := newMockReceiptRepository()
+	settlementRepo := newMockSettlementRepository()
+
+	svc := service.NewSummaryService(groupRepo, receiptRepo, settlementRepo, &mockReportRepository{receiptRepo: receiptRepo})
+
+	userA := models.User{Email: "usera@example.com", Nickname: "UserA"}
+	_ = userRepo.Create(&userA)
+	userB := models.User{Email: "userb@example.com", Nickname: "UserB"}
+	_ = userRepo.Create(&userB)
+	userC := models.User{Email: "userc@example.com", Nickname: "UserC"}
+	_ = userRepo.Create(&userC)
+
+	group := models.Group{Name: "Family", OwnerID: userA.ID}
+	_ = groupRepo.Create(&group)
+	_ = groupRepo.AddMember(&group, &userA)
+	_ = groupRepo.AddMember(&group, &userB)
+
+	year := 2026
+	month := 6
+	day := func(d int) time.Time { return time.Date(year, time.Month(month), d, 12, 0, 0, 0, time.UTC) }
+	leftAt := day(10)
+
+	// A: 6/1から在籍、B: 6/20に参加、C: 6/1に参加して6/10に退出
+	groupRepo.periods = []models.GroupMembershipPeriod{
+		{GroupID: group.ID, UserID: userA.ID, JoinedAt: day(1), User: userA},
+		{GroupID: group.ID, UserID: userC.ID, JoinedAt: day(1), LeftAt: &leftAt, User: userC},
+		{GroupID: group.ID, UserID: userB.ID, JoinedAt: day(20), User: userB},
+	}
+
+	// 6/5 折半 (900円) -> 在籍中のAとCで450円ずつ
+	r1 := models.Receipt{GroupID: group.ID, UserID: userA.ID, Date: day(5), SettlementYear: year, SettlementMonth: month, Amount: 900, PayerID: userA.ID, PaymentMethod: "half"}
+	_ = receiptRepo.Create(&r1)
+	// 6/25 折半 (1000円) -> 在籍中のAとBで500円ずつ
+	r2 := models.Receipt{GroupID: group.ID, UserID: userA.ID, Date: day(25), SettlementYear: year, SettlementMonth: month, Amount: 1000, PayerID: userA.ID, PaymentMethod: "half"}
+	_ = receiptRepo.Create(&r2)
+	// 6/15 相手負担 (600円) -> 在籍中のA以外はBがまだ参加前・Cが退出後のため支払者Aが負担
+	r3 := models.Receipt{GroupID: group.ID, UserID: userA.ID, Date: day(15), SettlementYear: year, SettlementMonth: month, Amount: 600, PayerID: userA.ID, PaymentMethod: "other"}
+	_ = receiptRepo.Create(&r3)
+
+	result, err := svc.GetMonthlySummary(group.ID, userA.ID, year, month)
+	if err != nil {
+		t.Fatalf("GetMonthlySummary failed: %v", err)
+	}
+
+	summaries := make(map[uuid.UUID]service.MemberSummary)
+	for _, m := range result.Members {
+		summaries[m.UserID] = m
+	}
+
+	if s := summaries[userA.ID]; s.Paid != 2500 || s.Share != 1550 || s.Former {
+		t.Errorf("UserA: Expected Paid=2500, Share=1550, Former=false. Got %+v", s)
+	}
+	if s := summaries[userB.ID]; s.Paid != 0 || s.Share != 500 || s.Former {
+		t.Errorf("UserB: Expected Paid=0, Share=500, Former=false. Got %+v", s)
+	}
+
+	// 退出済みのCも過去の負担分が表示される
+	s, ok := summaries[userC.ID]
+	if !ok {
+		t.Fatalf("Expected former member UserC to be included in the summary")
+	}
+	if s.Share != 450 || !s.Former || s.Nickname != "UserC" {
+		t.Errorf("UserC: Expected Share=450, Former=true, Nickname=UserC. Got %+v", s)
+	}
+}
+
+func TestSummaryService_CumulativeBalance(t *testing.T) {
+	userRepo := newMockUserRepository()
+	groupRepo := newMockGroupRepository()
+	receiptRepo := newMockReceiptRepository()
+	settlementRepo := newMockSettlementRepository()
+
+	svc := service.NewSummaryService(groupRepo, receiptRepo, settlementRepo, &mockReportRepository{receiptRepo: receiptRepo})
+
+	userA := models.User{Email: "usera@example.com", Nickname: "UserA"}
+	_ = userRepo.Create(&userA)
+	userB := models.User{Email: "userb@example.com", Nickname: "UserB"}
+	_ = userRepo.Create(&userB)
+
+	group := models.Group{Name: "Family", OwnerID: userA.ID}
+	_ = groupRepo.Create(&group)
+	_ = groupRepo.AddMember(&group, &userA)
+	_ = groupRepo.AddMember(&group, &userB)
+
+	// 3月: Aが折半で1000円 -> Bは500円の負担
+	march := models.Receipt{GroupID: group.ID, UserID: userA.ID, Date: time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC), SettlementYear: 2026, SettlementMonth: 3, Amount: 1000, PayerID: userA.ID, PaymentMethod: "half"}
+	_ = receiptRepo.Create(&march)
+	// 3月: 受取人が記録されていない過去の精算（BからAへ100円）
+	_ = settlementRepo.Create(&models.Settlement{GroupID: group.ID, Year: 2026, Month: 3, Amount: 100, SettledBy: userB.ID})
+	// 4月: Bが折半で400円 -> Aは200円の負担
+	april := models.Receipt{GroupID: group.ID, UserID: userB.ID, Date: time.Date(2026, 4, 10, 12, 0, 0, 0, time.UTC), SettlementYear: 2026, SettlementMonth: 4, Amount: 400, PayerID: userB.ID, PaymentMethod: "half"}
+	_ = receiptRepo.Create(&april)
+
+	balanceOf := func(result *service.MonthlySummaryResult, userID uuid.UUID) service.MemberBalance {
+		for _, b := range result.Balances {
+			if b.UserID == userID {
+				return b
+			}
+		}
+		t.Fatalf("balance for %s not found", userID)
+		return service.MemberBalance{}
+	}
+
+	t.Run("Carried Over Into April", func(t *testing.T) {
+		result, err := svc.GetMonthlySummary(group.ID, userA.ID, 2026, 4)
+		if err != nil {
+			t.Fatalf("GetMonthlySummary failed: %v", err)
+		}
+
+		// B: 3月末 500 - 100 = 400、4月末 400 - 200 = 200
+		if b := balanceOf(result, userB.ID); b.CarriedOver != 400 || b.Balance != 200 {
+			t.Errorf("UserB: Expected CarriedOver=400, Balance=200. Got %+v", b)
+		}
+		if b := balanceOf(result, userA.ID); b.CarriedOver != -400 || b.Balance != -200 {
+			t.Errorf("UserA: Expected CarriedOver=-400, Balance=-200. Got %+v", b)
+		}
+	})
+
+	t.Run("Cumulative Settlement Exceeds Balance", func(t *testing.T) {
+		params := &service.CreateSettlementParams{GroupID: group.ID, Year: 2026, Month: 4, Amount: 300, Cumulative: true}
+		_, err := svc.CreateSettlement(params, userB.ID)
+		if !errors.Is(err, service.ErrSettlementExceedsBalance) {
+			t.Errorf("Expected error %v, got %v", service.ErrSettlementExceedsBalance, err)
+		}
+	})
+
+	t.Run("Invalid Recipient", func(t *testing.T) {
+		recipient := userB.ID
+		params := &service.CreateSettlementParams{GroupID: group.ID, Year: 2026, Month: 4, Amount: 200, RecipientID: &recipient, Cumulative: true}
+		_, err := svc.CreateSettlement(params, userB.ID)
+		if !errors.Is(err, service.ErrInvalidSettlementRecipient) {
+			t.Errorf("Expected error %v, got %v", service.ErrInvalidSettlementRecipient, err)
+		}
+	})
+
+	t.Run("Partial Cumulative Settlement", func(t *testing.T) {
+		params := &service.CreateSettlementParams{GroupID: group.ID, Year: 2026, Month: 4, Amount: 150, Cumulative: true}
+		settlement, err := svc.CreateSettlement(params, userB.ID)
+		if err != nil {
+			t.Fatalf("CreateSettlement failed: %v", err)
+		}
+		if settlement.RecipientID == nil || *settlement.RecipientID != userA.ID {
+			t.Errorf("Expected recipient to default to the other member")
+		}
+		if settlementRepo.receiptsSettled != 0 {
+			t.Errorf("Expected receipts to stay unsettled while a balance remains")
+		}
+
+		result, _ := svc.GetMonthlySummary(group.ID, userA.ID, 2026, 4)
+		if b := balanceOf(result, userB.ID); b.Balance != 50 {
+			t.Errorf("UserB: Expected Balance=50 after partial settlement, got %d", b.Balance)
+		}
+	})
+
+	t.Run("Cumulative Settlement", func(t *testing.T) {
+		params := &service.CreateSettlementParams{GroupID: group.ID, Year: 2026, Month: 4, Amount: 50, Cumulative: true}
+		if _, err := svc.CreateSettlement(params, userB.ID); err != nil {
+			t.Fatalf("CreateSettlement failed: %v", err)
+		}
+		if settlementRepo.receiptsSettled != 1 {
+			t.Errorf("Expected receipts to be settled once the balance reaches zero")
+		}
+
+		result, _ := svc.GetMonthlySummary(group.ID, userA.ID, 2026, 4)
+		if b := balanceOf(result, userB.ID); b.Balance != 0 {
+			t.Errorf("UserB: Expected Balance=0 after cumulative settlement, got %d", b.Balance)
+		}
+		if b := balanceOf(result, userA.ID); b.Balance != 0 {
+			t.Errorf("UserA: Expected Balance=0 after cumulative settlement, got %d", b.Balance)
+		}
+	})
+}
+
+// 累積残高（SQLで期間ごとに集計）は、月ごとの負担額（レシートごとに計算）の累計と一致する
+func TestSummaryService_BalancesMatchMonthlyShares(t *testing.T) {
+	userRepo := newMockUserRepository()
+	groupRepo := newMockGroupRepository()
+	receiptRepo := newMockReceiptRepository()
+	settlementRepo := newMockSettlementRepository()
+
+	svc := service.NewSummaryService(groupRepo, receiptRepo, settlementRepo, &mockReportRepository{receiptRepo: receiptRepo})
+
+	var users []models.User
+	for _, name := range []string{"UserA", "UserB", "UserC", "UserD"} {
+		u := models.User{Email: name + "@example.com", Nickname: name}
+		_ = userRepo.Create(&u)
+		users = append(users, u)
+	}
+	a, b, c, d := users[0], users[1], users[2], users[3]
+
+	group := models.Group{Name: "Family", OwnerID: a.ID}
+	_ = groupRepo.Create(&group)
+	for i := range users {
+		_ = groupRepo.AddMember(&group, &users[i])
+	}
+	_ = groupRepo.RemoveMember(&group, &c)
+
+	date := func(year int, month int, day int) time.Time {
+		return time.Date(year, time.Month(month), day, 12, 0, 0, 0, time.UTC)
+	}
+	leftAt := date(2026, 3, 5)
+	// A・B・Cが1/1から在籍、Dが2/10に参加、Cが3/5に退出
+	groupRepo.periods = []models.GroupMembershipPeriod{
+		{GroupID: group.ID, UserID: a.ID, JoinedAt: date(2026, 1, 1), User: a},
+		{GroupID: group.ID, UserID: b.ID, JoinedAt: date(2026, 1, 1), User: b},
+		{GroupID: group.ID, UserID: c.ID, JoinedAt: date(2026, 1, 1), LeftAt: &leftAt, User: c},
+		{GroupID: group.ID, UserID: d.ID, JoinedAt: date(2026, 2, 10), User: d},
+	}
+
+	receipts := []struct {
+		date   time.Time
+		payer  models.User
+		amount int
+		method string
+	}{
+		{date(2025, 12, 20), a, 1001, models.PaymentMethodHalf}, // グループ作成前の日付
+		{date(2026, 1, 10), a, 1001, models.PaymentMethodHalf},
+		{date(2026, 1, 12), a, 1003, models.PaymentMethodHalf},
+		{date(2026, 1, 20), b, 1001, models.PaymentMethodOther},
+		{date(2026, 1, 21), b, 502, models.PaymentMethodOther},
+		{date(2026, 2, 10), c, 999, models.PaymentMethodHalf},
+		{date(2026, 2, 11), d, 1001, models.PaymentMethodOther},
+		{date(2026, 2, 12), a, 700, models.PaymentMethodSelf},
+		{date(2026, 3, 5), b, 1234, models.PaymentMethodHalf},
+		{date(2026, 3, 6), a, 1001, models.PaymentMethodOther},
+		{date(2026, 4, 1), c, 555, models.PaymentMethodOther},
+		{date(2026, 4, 2), d, 333, models.PaymentMethodHalf},
+	}
+	for _, r := range receipts {
+		_ = receiptRepo.Create(&models.Receipt{GroupID: group.ID, UserID: r.payer.ID, Date: r.date, SettlementYear: r.date.Year(), SettlementMonth: int(r.date.Month()), Amount: r.amount, PayerID: r.payer.ID, PaymentMethod: r.method})
+	}
+
+	expected := make(map[uuid.UUID]int)
+	for _, ym := range [][2]int{{2025, 12}, {2026, 1}, {2026, 2}, {2026, 3}, {2026, 4}} {
+		result, err := svc.GetMonthlySummary(group.ID, a.ID, ym[0], ym[1])
+		if err != nil {
+			t.Fatalf("GetMonthlySummary failed: %v", err)
+		}
+		for _, m := range result.Members {
+			expected[m.UserID] += m.Share - m.Paid
+		}
+		for _, balance := range result.Balances {
+			if balance.Balance != expected[balance.UserID] {
+				t.Errorf("%d/%d %s: expected balance %d, got %d", ym[0], ym[1], balance.Nickname, expected[balance.UserID], balance.Balance)
+			}
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"receipt/server/internal/models"
 	"receipt/server/internal/repository"
 	"receipt/server/internal/utils"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -13,13 +14,81 @@ var (
 	// ErrInvalidCredentials 認証情報が無効な場合のエラー
 	ErrInvalidCredentials = errors.New("invalid email or password")
 	// ErrUserNotFound ユーザーが見つからない場合のエラー
-	ErrUserNotFound       = errors.New("user not found")
+	ErrUserNotFound = errors.New("user not found")
+	// ErrAccountLocked ログイン失敗が続きアカウントが一時的にロックされている場合のエラー
+	ErrAccountLocked = errors.New("account is temporarily locked")
+	// ErrInvalidTwoFactorCode 2段階認証コードが無効な場合のエラー
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor authentication code")
+	// ErrInvalidChallenge 2段階認証のチャレンジトークンが無効・期限切れの場合のエラー
+	ErrInvalidChallenge = errors.New("invalid or expired login challenge")
+	// ErrTwoFactorAlreadyEnabled 既に2段階認証が有効な場合のエラー
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorNotSetup 2段階認証のセットアップが開始されていない場合のエラー
+	ErrTwoFactorNotSetup = errors.New("two-factor authentication is not set up")
+	// ErrReauthenticationRequired 退会などの操作の前に、パスキー・外部IDプロバイダーでの再ログインが必要な場合のエラー
+	ErrReauthenticationRequired = errors.New("recent authentication is required")
 )
 
+const (
+	// maxFailedLoginAttempts アカウントをロックするまでの連続ログイン失敗回数
+	maxFailedLoginAttempts = 5
+	// accountLockDuration アカウントロックの期間
+	accountLockDuration = 15 * time.Minute
+
+	// totpIssuer 認証アプリに表示される発行者名
+	totpIssuer = "Receipt"
+	// twoFactorChallengePurpose 2段階認証のチャレンジトークンの用途
+	twoFactorChallengePurpose = "totp"
+	// twoFactorChallengeTTL チャレンジトークンの有効期間
+	twoFactorChallengeTTL = 5 * time.Minute
+	// recoveryCodeCount 発行するリカバリーコードの個数
+	recoveryCodeCount = 10
+	// reauthenticationPurpose 再認証トークンの用途
+	reauthenticationPurpose = "reauthentication"
+	// reauthenticationTTL 再認証トークンの有効期間（ログイン直後の操作にのみ使えるよう短くする）
+	reauthenticationTTL = 5 * time.Minute
+)
+
+// LoginResult ログイン結果
+// 2段階認証が有効なユーザーの場合は Token の代わりに ChallengeToken を返し、
+// VerifyTwoFactorLogin でコードを検証した後に Token が発行される。
+// パスキー・外部IDプロバイダーでログインした場合は、パスワードの代わりに退会などの本人確認に使う
+// ReauthenticationToken も返す。
+type LoginResult struct {
+	Token                 string       `json:"token,omitempty"`
+	User                  *models.User `json:"user,omitempty"`
+	TwoFactorRequired     bool         `json:"two_factor_required"`
+	ChallengeToken        string       `json:"challenge_token,omitempty"`
+	ReauthenticationToken string       `json:"reauthentication_token,omitempty"`
+}
+
+// TwoFactorSetup 2段階認証のセットアップ情報
+type TwoFactorSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // QRコードに変換して認証アプリで読み取る
+}
+
+// AccountLockedError アカウントロック中のエラー。ロック解除までの残り時間を保持する
+type AccountLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *AccountLockedError) Error() string {
+	return ErrAccountLocked.Error()
+}
+
+func (e *AccountLockedError) Unwrap() error {
+	return ErrAccountLocked
+}
+
 // UserService ユーザー認証・情報管理に関するビジネスロジックインターフェース
 type UserService interface {
 	Register(email, password, nickname string) error
-	Login(email, password string) (string, *models.User, error)
+	Login(email, password string) (*LoginResult, error)
+	VerifyTwoFactorLogin(challengeToken, code string) (*LoginResult, error)
+	SetupTwoFactor(userID uuid.UUID) (*TwoFactorSetup, error)
+	EnableTwoFactor(userID uuid.UUID, code string) ([]string, error)
+	DisableTwoFactor(userID uuid.UUID, password, code string) error
 	GetMe(userID uuid.UUID) (*models.User, error)
 	UpdateMe(userID uuid.UUID, email, nickname, password string) (*models.User, error)
 }
@@ -48,23 +117,151 @@ func (s *userServiceImpl) Register(email, password, nickname string) error {
 	return s.userRepo.Create(&user)
 }
 
-func (s *userServiceImpl) Login(email, password string) (string, *models.User, error) {
+func (s *userServiceImpl) Login(email, password string) (*LoginResult, error) {
 	user, err := s.userRepo.GetByEmail(email)
 	if err != nil {
 		// 存在しない場合も、パスワード不一致と同様のエラーにする（セキュリティ対策）
-		return "", nil, ErrInvalidCredentials
+		return nil, ErrInvalidCredentials
+	}
+
+	now := time.Now()
+	if err := checkAccountLock(user, now); err != nil {
+		// ロック中はパスワード照合（bcrypt）自体を行わない
+		return nil, err
+	}
+
+	if user.PasswordHash == "" {
+		// 外部IDプロバイダーのみで登録されたユーザーはパスワードでログインできない
+		return nil, ErrInvalidCredentials
 	}
 
 	if !utils.CheckPasswordHash(password, user.PasswordHash) {
-		return "", nil, ErrInvalidCredentials
+		return nil, s.recordLoginFailure(user, now)
+	}
+
+	return beginSecondFactorOrIssueToken(s.userRepo, user)
+}
+
+// beginSecondFactorOrIssueToken 2段階認証が有効なユーザーにはチャレンジトークンを、それ以外には認証トークンを返す
+func beginSecondFactorOrIssueToken(userRepo repository.UserRepository, user *models.User) (*LoginResult, error) {
+	if user.TOTPEnabled {
+		challenge, err := utils.GenerateChallengeToken(user.ID, twoFactorChallengePurpose, twoFactorChallengeTTL)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{TwoFactorRequired: true, ChallengeToken: challenge}, nil
+	}
+
+	return issueLoginToken(userRepo, user)
+}
+
+func (s *userServiceImpl) VerifyTwoFactorLogin(challengeToken, code string) (*LoginResult, error) {
+	userID, err := utils.ParseChallengeToken(challengeToken, twoFactorChallengePurpose)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil || !user.TOTPEnabled {
+		return nil, ErrInvalidChallenge
+	}
+
+	now := time.Now()
+	if err := checkAccountLock(user, now); err != nil {
+		return nil, err
+	}
+
+	ok, err := verifySecondFactor(s.userRepo, user, code, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := s.recordLoginFailure(user, now); !errors.Is(err, ErrInvalidCredentials) {
+			return nil, err
+		}
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	return s.completeLogin(user)
+}
+
+// verifySecondFactor TOTPコードまたはリカバリーコードを検証する
+func verifySecondFactor(userRepo repository.UserRepository, user *models.User, code string, now time.Time) (bool, error) {
+	if step, ok := utils.ValidateTOTPCode(user.TOTPSecret, code, now); ok {
+		// 同じコードの再利用（リプレイ）を防ぐ
+		if step <= user.TOTPLastUsedStep {
+			return false, nil
+		}
+		user.TOTPLastUsedStep = step
+		return true, userRepo.Update(user)
+	}
+
+	return userRepo.UseRecoveryCode(user.ID, utils.HashRecoveryCode(code))
+}
+
+// completeLogin 失敗回数をリセットし、認証トークンを発行する
+func (s *userServiceImpl) completeLogin(user *models.User) (*LoginResult, error) {
+	return issueLoginToken(s.userRepo, user)
+}
+
+// issueLoginToken ログイン失敗回数をリセットし、utils.GenerateToken で認証トークンを発行する。
+// パスワード・パスキーなど、ログイン手段によらず同じトークンを返す。
+func issueLoginToken(userRepo repository.UserRepository, user *models.User) (*LoginResult, error) {
+	if user.FailedLoginCount > 0 || user.LockedUntil != nil {
+		user.FailedLoginCount = 0
+		user.LockedUntil = nil
+		if err := userRepo.Update(user); err != nil {
+			return nil, err
+		}
 	}
 
 	token, err := utils.GenerateToken(user.ID)
 	if err != nil {
-		return "", nil, err
+		return nil, err
+	}
+
+	return &LoginResult{Token: token, User: user}, nil
+}
+
+// withReauthenticationToken 認証トークンを発行したログイン結果に、再認証トークンを付ける。
+// パスワードを持たないユーザーが退会などを行う際の本人確認に使う
+func withReauthenticationToken(result *LoginResult, err error) (*LoginResult, error) {
+	if err != nil || result.Token == "" {
+		return result, err
+	}
+	token, err := utils.GenerateChallengeToken(result.User.ID, reauthenticationPurpose, reauthenticationTTL)
+	if err != nil {
+		return nil, err
+	}
+	result.ReauthenticationToken = token
+	return result, nil
+}
+
+// checkAccountLock アカウントがロック中であれば AccountLockedError を返す
+func checkAccountLock(user *models.User, now time.Time) error {
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		return &AccountLockedError{RetryAfter: user.LockedUntil.Sub(now)}
 	}
+	return nil
+}
 
-	return token, user, nil
+// recordLoginFailure ログイン失敗を記録し、規定回数に達した場合はアカウントをロックする
+func (s *userServiceImpl) recordLoginFailure(user *models.User, now time.Time) error {
+	user.FailedLoginCount++
+	if user.FailedLoginCount >= maxFailedLoginAttempts {
+		lockedUntil := now.Add(accountLockDuration)
+		user.LockedUntil = &lockedUntil
+		user.FailedLoginCount = 0
+	}
+
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	if err := checkAccountLock(user, now); err != nil {
+		return err
+	}
+	return ErrInvalidCredentials
 }
 
 func (s *userServiceImpl) GetMe(userID uuid.UUID) (*models.User, error) {
@@ -101,3 +298,105 @@ func (s *userServiceImpl) UpdateMe(userID uuid.UUID, email, nickname, password s
 
 	return user, nil
 }
+
+func (s *userServiceImpl) SetupTwoFactor(userID uuid.UUID) (*TwoFactorSetup, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	// 有効化はコードの検証（EnableTwoFactor）が成功してから行う
+	user.TOTPSecret = secret
+	user.TOTPLastUsedStep = 0
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+func (s *userServiceImpl) EnableTwoFactor(userID uuid.UUID, code string) ([]string, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotSetup
+	}
+
+	step, ok := utils.ValidateTOTPCode(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	recoveryCodes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, 0, len(recoveryCodes))
+	for _, c := range recoveryCodes {
+		hashes = append(hashes, utils.HashRecoveryCode(c))
+	}
+	if err := s.userRepo.ReplaceRecoveryCodes(user.ID, hashes); err != nil {
+		return nil, err
+	}
+
+	user.TOTPEnabled = true
+	user.TOTPLastUsedStep = step
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	// リカバリーコードの平文はこの時だけ返す
+	return recoveryCodes, nil
+}
+
+// DisableTwoFactor 2段階認証を無効化する。本人確認にはパスワード、または現在のTOTPコード・リカバリーコードを使う
+// （外部IDプロバイダーのみでログインするユーザーはパスワードを持たないため）
+func (s *userServiceImpl) DisableTwoFactor(userID uuid.UUID, password, code string) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	if password != "" {
+		if user.PasswordHash == "" || !utils.CheckPasswordHash(password, user.PasswordHash) {
+			return ErrInvalidCredentials
+		}
+	} else if !user.TOTPEnabled || code == "" {
+		return ErrInvalidCredentials
+	} else {
+		ok, err := verifySecondFactor(s.userRepo, user, code, time.Now())
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+	}
+
+	if err := s.userRepo.DeleteRecoveryCodes(user.ID); err != nil {
+		return err
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastUsedStep = 0
+	return s.userRepo.Update(user)
+}
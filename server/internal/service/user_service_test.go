@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"receipt/server/internal/models"
+	"receipt/server/internal/repository"
 	"receipt/server/internal/service"
 	"receipt/server/internal/utils"
 
@@ -13,12 +14,14 @@ import (
 )
 
 type mockUserRepository struct {
-	users map[uuid.UUID]*models.User
+	users         map[uuid.UUID]*models.User
+	recoveryCodes map[uuid.UUID]map[string]bool // userID -> codeHash -> used
 }
 
 func newMockUserRepository() *mockUserRepository {
 	return &mockUserRepository{
-		users: make(map[uuid.UUID]*models.User),
+		users:         make(map[uuid.UUID]*models.User),
+		recoveryCodes: make(map[uuid.UUID]map[string]bool),
 	}
 }
 
@@ -61,6 +64,38 @@ func (m *mockUserRepository) Update(user *models.User) error {
 	return nil
 }
 
+func (m *mockUserRepository) ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error {
+	codes := make(map[string]bool)
+	for _, h := range codeHashes {
+		codes[h] = false
+	}
+	m.recoveryCodes[userID] = codes
+	return nil
+}
+
+func (m *mockUserRepository) UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error) {
+	used, exists := m.recoveryCodes[userID][codeHash]
+	if !exists || used {
+		return false, nil
+	}
+	m.recoveryCodes[userID][codeHash] = true
+	return true, nil
+}
+
+func (m *mockUserRepository) DeleteRecoveryCodes(userID uuid.UUID) error {
+	delete(m.recoveryCodes, userID)
+	return nil
+}
+
+func (m *mockUserRepository) DeleteAccount(user *models.User, groups repository.AccountGroupChanges) error {
+	if _, exists := m.users[user.ID]; !exists {
+		return errors.New("record not found")
+	}
+	delete(m.users, user.ID)
+	delete(m.recoveryCodes, user.ID)
+	return nil
+}
+
 func TestUserService_Register(t *testing.T) {
 	repo := newMockUserRepository()
 	svc := service.NewUserService(repo)
@@ -105,27 +140,27 @@ func TestUserService_Login(t *testing.T) {
 	_ = svc.Register(email, password, nickname)
 
 	t.Run("Success", func(t *testing.T) {
-		token, user, err := svc.Login(email, password)
+		result, err := svc.Login(email, password)
 		if err != nil {
 			t.Fatalf("Login failed: %v", err)
 		}
-		if token == "" {
+		if result.Token == "" {
 			t.Errorf("Expected token to be generated, got empty string")
 		}
-		if user.Email != email {
-			t.Errorf("Expected user email %s, got %s", email, user.Email)
+		if result.User.Email != email {
+			t.Errorf("Expected user email %s, got %s", email, result.User.Email)
 		}
 	})
 
 	t.Run("Invalid Password", func(t *testing.T) {
-		_, _, err := svc.Login(email, "wrongpassword")
+		_, err := svc.Login(email, "wrongpassword")
 		if !errors.Is(err, service.ErrInvalidCredentials) {
 			t.Errorf("Expected error %v, got %v", service.ErrInvalidCredentials, err)
 		}
 	})
 
 	t.Run("Non-existent User", func(t *testing.T) {
-		_, _, err := svc.Login("notfound@example.com", password)
+		_, err := svc.Login("notfound@example.com", password)
 		if !errors.Is(err, service.ErrInvalidCredentials) {
 			t.Errorf("Expected error %v, got %v", service.ErrInvalidCredentials, err)
 		}
@@ -202,15 +237,199 @@ func TestUserService_UpdateMe(t *testing.T) {
 		}
 
 		// 古いパスワードでログインできないことを確認
-		_, _, err = svc.Login(user.Email, password)
+		_, err = svc.Login(user.Email, password)
 		if !errors.Is(err, service.ErrInvalidCredentials) {
 			t.Errorf("Should not login with old password, but got err: %v", err)
 		}
 
 		// 新しいパスワードでログインできることを確認
-		_, _, err = svc.Login(user.Email, newPassword)
+		_, err = svc.Login(user.Email, newPassword)
 		if err != nil {
 			t.Errorf("Should login with new password, but failed: %v", err)
 		}
 	})
 }
+
+func TestUserService_Login_AccountLock(t *testing.T) {
+	repo := newMockUserRepository()
+	svc := service.NewUserService(repo)
+
+	email := "lock@example.com"
+	password := "securepassword"
+	_ = svc.Register(email, password, "LockUser")
+
+	t.Run("Lock after repeated failures", func(t *testing.T) {
+		var err error
+		for i := 0; i < 5; i++ {
+			_, err = svc.Login(email, "wrongpassword")
+		}
+		var lockedErr *service.AccountLockedError
+		if !errors.As(err, &lockedErr) {
+			t.Fatalf("Expected AccountLockedError, got %v", err)
+		}
+		if !errors.Is(err, service.ErrAccountLocked) {
+			t.Errorf("Expected error %v, got %v", service.ErrAccountLocked, err)
+		}
+		if lockedErr.RetryAfter <= 0 {
+			t.Errorf("Expected positive RetryAfter, got %v", lockedErr.RetryAfter)
+		}
+	})
+
+	t.Run("Correct password is rejected while locked", func(t *testing.T) {
+		_, err := svc.Login(email, password)
+		if !errors.Is(err, service.ErrAccountLocked) {
+			t.Errorf("Expected error %v, got %v", service.ErrAccountLocked, err)
+		}
+	})
+
+	t.Run("Login succeeds after lock expires", func(t *testing.T) {
+		user, _ := repo.GetByEmail(email)
+		expired := time.Now().Add(-time.Minute)
+		user.LockedUntil = &expired
+		_ = repo.Update(user)
+
+		_, err := svc.Login(email, password)
+		if err != nil {
+			t.Fatalf("Login failed after lock expired: %v", err)
+		}
+
+		updated, _ := repo.GetByEmail(email)
+		if updated.LockedUntil != nil || updated.FailedLoginCount != 0 {
+			t.Errorf("Expected lock state to be reset, got LockedUntil=%v FailedLoginCount=%d", updated.LockedUntil, updated.FailedLoginCount)
+		}
+	})
+}
+
+func TestUserService_TwoFactor(t *testing.T) {
+	repo := newMockUserRepository()
+	svc := service.NewUserService(repo)
+
+	email := "totp@example.com"
+	password := "securepassword"
+	_ = svc.Register(email, password, "TotpUser")
+	registeredUser, _ := repo.GetByEmail(email)
+
+	setup, err := svc.SetupTwoFactor(registeredUser.ID)
+	if err != nil {
+		t.Fatalf("SetupTwoFactor failed: %v", err)
+	}
+	if setup.Secret == "" || setup.ProvisioningURI == "" {
+		t.Fatalf("Expected secret and provisioning URI, got %+v", setup)
+	}
+
+	t.Run("Enable with invalid code", func(t *testing.T) {
+		_, err := svc.EnableTwoFactor(registeredUser.ID, "000000x")
+		if !errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			t.Errorf("Expected error %v, got %v", service.ErrInvalidTwoFactorCode, err)
+		}
+	})
+
+	// 有効化時に使ったコードはリプレイ防止で再利用できないため、1ステップ前のコードで有効化する
+	enableCode, _ := utils.GenerateTOTPCode(setup.Secret, time.Now().Add(-utils.TOTPPeriod))
+	recoveryCodes, err := svc.EnableTwoFactor(registeredUser.ID, enableCode)
+	if err != nil {
+		t.Fatalf("EnableTwoFactor failed: %v", err)
+	}
+	if len(recoveryCodes) == 0 {
+		t.Fatalf("Expected recovery codes to be issued")
+	}
+
+	t.Run("Login requires second step", func(t *testing.T) {
+		result, err := svc.Login(email, password)
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+		if !result.TwoFactorRequired || result.ChallengeToken == "" {
+			t.Fatalf("Expected two-factor challenge, got %+v", result)
+		}
+		if result.Token != "" {
+			t.Errorf("Token must not be issued before the second step")
+		}
+
+		_, err = svc.VerifyTwoFactorLogin(result.ChallengeToken, "123456x")
+		if !errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			t.Errorf("Expected error %v, got %v", service.ErrInvalidTwoFactorCode, err)
+		}
+
+		code, _ := utils.GenerateTOTPCode(setup.Secret, time.Now())
+		verified, err := svc.VerifyTwoFactorLogin(result.ChallengeToken, code)
+		if err != nil {
+			t.Fatalf("VerifyTwoFactorLogin failed: %v", err)
+		}
+		if verified.Token == "" {
+			t.Errorf("Expected token after second step")
+		}
+
+		// 同じコードは再利用できない
+		_, err = svc.VerifyTwoFactorLogin(result.ChallengeToken, code)
+		if !errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			t.Errorf("Expected replayed code to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("Recovery code can be used once", func(t *testing.T) {
+		result, _ := svc.Login(email, password)
+
+		if _, err := svc.VerifyTwoFactorLogin(result.ChallengeToken, recoveryCodes[0]); err != nil {
+			t.Fatalf("Recovery code login failed: %v", err)
+		}
+		_, err := svc.VerifyTwoFactorLogin(result.ChallengeToken, recoveryCodes[0])
+		if !errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			t.Errorf("Expected used recovery code to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("Invalid challenge token", func(t *testing.T) {
+		_, err := svc.VerifyTwoFactorLogin("invalid-token", "123456")
+		if !errors.Is(err, service.ErrInvalidChallenge) {
+			t.Errorf("Expected error %v, got %v", service.ErrInvalidChallenge, err)
+		}
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		if err := svc.DisableTwoFactor(registeredUser.ID, "wrongpassword", ""); !errors.Is(err, service.ErrInvalidCredentials) {
+			t.Errorf("Expected error %v, got %v", service.ErrInvalidCredentials, err)
+		}
+		if err := svc.DisableTwoFactor(registeredUser.ID, "", "000000x"); !errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			t.Errorf("Expected error %v, got %v", service.ErrInvalidTwoFactorCode, err)
+		}
+		if err := svc.DisableTwoFactor(registeredUser.ID, password, ""); err != nil {
+			t.Fatalf("DisableTwoFactor failed: %v", err)
+		}
+
+		result, err := svc.Login(email, password)
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+		if result.TwoFactorRequired || result.Token == "" {
+			t.Errorf("Expected single-step login after disabling, got %+v", result)
+		}
+	})
+
+	t.Run("Disable without password", func(t *testing.T) {
+		// 外部IDプロバイダーのみでログインするユーザーはパスワードを持たないため、コードで本人確認する
+		user, _ := repo.GetByID(registeredUser.ID)
+		user.PasswordHash = ""
+		_ = repo.Update(user)
+
+		setup, _ := svc.SetupTwoFactor(user.ID)
+		enableCode, _ := utils.GenerateTOTPCode(setup.Secret, time.Now().Add(-utils.TOTPPeriod))
+		if _, err := svc.EnableTwoFactor(user.ID, enableCode); err != nil {
+			t.Fatalf("EnableTwoFactor failed: %v", err)
+		}
+
+		if err := svc.DisableTwoFactor(user.ID, "", ""); !errors.Is(err, service.ErrInvalidCredentials) {
+			t.Errorf("Expected error %v, got %v", service.ErrInvalidCredentials, err)
+		}
+		if err := svc.DisableTwoFactor(user.ID, "anypassword", ""); !errors.Is(err, service.ErrInvalidCredentials) {
+			t.Errorf("Expected error %v, got %v", service.ErrInvalidCredentials, err)
+		}
+		code, _ := utils.GenerateTOTPCode(setup.Secret, time.Now())
+		if err := svc.DisableTwoFactor(user.ID, "", code); err != nil {
+			t.Fatalf("DisableTwoFactor with TOTP code failed: %v", err)
+		}
+		if user, _ := repo.GetByID(user.ID); user.TOTPEnabled {
+			t.Errorf("Expected two-factor authentication to be disabled")
+		}
+	})
+}
@@ -1,10 +1,12 @@
 package utils
 
 import (
+	"encoding/json"
+	"errors"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -30,3 +32,79 @@ func GenerateToken(userID uuid.UUID) (string, error) {
 	})
 	return token.SignedString(JwtKey)
 }
+
+// ErrInvalidChallengeToken チャレンジトークンが無効・期限切れの場合のエラー
+var ErrInvalidChallengeToken = errors.New("invalid or expired challenge token")
+
+// GenerateChallengeToken 多要素認証の途中段階で使う短命のチャレンジトークンを生成する。
+// user_id クレームを含まないため、通常の認証トークンとしては利用できない。
+func GenerateChallengeToken(userID uuid.UUID, purpose string, ttl time.Duration) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":     userID.String(),
+		"purpose": purpose,
+		"exp":     time.Now().Add(ttl).Unix(),
+	})
+	return token.SignedString(JwtKey)
+}
+
+// ParseChallengeToken チャレンジトークンを検証し、対象ユーザーIDを返す
+func ParseChallengeToken(tokenString, purpose string) (uuid.UUID, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return JwtKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		return uuid.Nil, ErrInvalidChallengeToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != purpose {
+		return uuid.Nil, ErrInvalidChallengeToken
+	}
+
+	sub, _ := claims["sub"].(string)
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return uuid.Nil, ErrInvalidChallengeToken
+	}
+	return userID, nil
+}
+
+// GenerateStateToken 任意のデータを署名付きで埋め込んだ短命の状態トークンを生成する。
+// WebAuthn のセッションデータなど、複数リクエストにまたがる状態をサーバーに保存せずに受け渡すために使う。
+func GenerateStateToken(purpose string, payload interface{}, ttl time.Duration) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"purpose": purpose,
+		"data":    string(data),
+		"exp":     time.Now().Add(ttl).Unix(),
+	})
+	return token.SignedString(JwtKey)
+}
+
+// ParseStateToken 状態トークンを検証し、埋め込まれたデータを payload に復元する
+func ParseStateToken(tokenString, purpose string, payload interface{}) error {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return JwtKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		return ErrInvalidChallengeToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != purpose {
+		return ErrInvalidChallengeToken
+	}
+
+	data, ok := claims["data"].(string)
+	if !ok {
+		return ErrInvalidChallengeToken
+	}
+	if err := json.Unmarshal([]byte(data), payload); err != nil {
+		return ErrInvalidChallengeToken
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package utils
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// RetryAfterSeconds Retry-After ヘッダー用に待ち時間を秒単位（切り上げ、最小1秒）の文字列にする
+func RetryAfterSeconds(d time.Duration) string {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	// shopBranchSuffix 末尾の支店名（例: "7-Eleven 新宿店"、"ローソン(渋谷駅前店)"）
+	shopBranchSuffix = regexp.MustCompile(`(\s+\S*店|\s*\([^()]*店\))$`)
+)
+
+// TrimShopBranch 店舗名の前後の空白と末尾の支店名を取り除く（全角・半角は統一する）
+func TrimShopBranch(name string) string {
+	s := strings.TrimSpace(norm.NFKC.String(name))
+	if trimmed := strings.TrimSpace(shopBranchSuffix.ReplaceAllString(s, "")); trimmed != "" {
+		return trimmed
+	}
+	return s
+}
+
+// NormalizeShopName 店舗名を比較用のキーに正規化する。
+// 全角・半角を統一して小文字にし、支店名・空白・記号を取り除く（例: "７－Ｅｌｅｖｅｎ 新宿店" → "7eleven"）
+func NormalizeShopName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(TrimShopBranch(name)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// MatchShopName 正規化した店舗名が最も長く前方一致する候補の添字を返す（一致しない場合は -1）。
+// 候補も NormalizeShopName で正規化して比較する
+func MatchShopName(name string, candidates []string) int {
+	normalized := NormalizeShopName(name)
+
+	best, bestLen := -1, 0
+	for i, candidate := range candidates {
+		key := NormalizeShopName(candidate)
+		if key == "" || len(key) <= bestLen {
+			continue
+		}
+		if strings.HasPrefix(normalized, key) {
+			best, bestLen = i, len(key)
+		}
+	}
+	return best
+}
@@ -1,8 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"receipt/server/config"
 	"receipt/server/internal/handlers"
@@ -19,27 +27,122 @@ func main() {
 	// .envファイルがある場合は読み込む（ローカル開発用）
 	godotenv.Load()
 
+	// スキーマのマイグレーション（例: ./main migrate up）
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrateCommand(os.Args[2:]))
+	}
+
 	// データベース初期化
 	config.InitDB()
 
+	// 停止のシグナルでバックグラウンドの処理を止め、解析中のジョブを解析待ちに戻す
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// 依存関係の初期化 (DI)
 	userRepo := repository.NewUserRepository(config.DB)
 	userService := service.NewUserService(userRepo)
 	userHandler := handlers.NewUserHandler(userService)
 
+	credentialRepo := repository.NewCredentialRepository(config.DB)
+	passkeyService, err := service.NewPasskeyService(passkeyConfigFromEnv(), userRepo, credentialRepo)
+	if err != nil {
+		panic("failed to configure passkey: " + err.Error())
+	}
+	passkeyHandler := handlers.NewPasskeyHandler(passkeyService)
+
+	identityRepo := repository.NewExternalIdentityRepository(config.DB)
+	oidcService := service.NewOIDCService(service.OIDCConfig{
+		Issuer:       os.Getenv("OIDC_ISSUER"),
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+	}, userRepo, identityRepo)
+	oidcHandler := handlers.NewOIDCHandler(oidcService)
+
 	groupRepo := repository.NewGroupRepository(config.DB)
-	groupService := service.NewGroupService(groupRepo, userRepo)
+	receiptRepo := repository.NewReceiptRepository(config.DB)
+	settlementRepo := repository.NewSettlementRepository(config.DB)
+	reportRepo := repository.NewReportRepository(config.DB)
+	groupService := service.NewGroupService(groupRepo, userRepo, receiptRepo, settlementRepo, reportRepo)
 	groupHandler := handlers.NewGroupHandler(groupService)
 
-	receiptRepo := repository.NewReceiptRepository(config.DB)
-	receiptService := service.NewReceiptService(receiptRepo)
-	aiAnalyzer := service.NewAIAnalyzer(os.Getenv("GOOGLE_API_KEY"))
-	receiptHandler := handlers.NewReceiptHandler(receiptService, aiAnalyzer)
+	// 完全削除が要求されたグループ・復元期限切れのグループを定期的に削除
+	go service.NewGroupPurgeWorker(groupRepo, time.Hour).Run(ctx)
 
-	settlementRepo := repository.NewSettlementRepository(config.DB)
-	summaryService := service.NewSummaryService(groupRepo, receiptRepo, settlementRepo)
+	shopRepo := repository.NewShopRepository(config.DB)
+	shopService := service.NewShopService(shopRepo, groupRepo)
+	shopHandler := handlers.NewShopHandler(shopService)
+
+	receiptService := service.NewReceiptService(receiptRepo, groupRepo, shopRepo)
+	aiConfig := aiAnalyzerConfigFromEnv()
+	aiAnalyzer, err := service.NewAIAnalyzer(aiConfig)
+	if err != nil {
+		panic("failed to configure AI analyzer: " + err.Error())
+	}
+	// 同じ画像の解析結果の再利用と、1日あたりの利用上限（AI_DAILY_USER_LIMIT / AI_DAILY_GROUP_LIMIT）
+	aiUsageRepo := repository.NewAIUsageRepository(config.DB)
+	analysisCacheRepo := repository.NewAnalysisCacheRepository(config.DB)
+	aiCacheTTL := durationFromEnv("AI_CACHE_TTL")
+	aiUsageService := service.NewAIUsageService(aiUsageRepo, analysisCacheRepo, groupRepo, aiAnalyzer, aiQuotaFromEnv(), aiCacheTTL)
+	aiUsageHandler := handlers.NewAIUsageHandler(aiUsageService)
+	go service.NewAnalysisCacheExpiryWorker(analysisCacheRepo, time.Hour).Run(ctx)
+
+	imageProcessor := service.NewReceiptImageProcessor(service.NewImageMagickConverter(os.Getenv("IMAGE_CONVERT_COMMAND")))
+
+	// AI解析の結果は確定するまで下書きとして保存する（RECEIPT_DRAFT_TTL: 保存期間）
+	draftRepo := repository.NewReceiptDraftRepository(config.DB)
+	draftTTL := durationFromEnv("RECEIPT_DRAFT_TTL")
+	// 下書きの確定時に記録した修正から学習し、次回以降の解析結果を補正する
+	feedbackRepo := repository.NewAnalysisFeedbackRepository(config.DB)
+	feedbackService := service.NewAnalysisFeedbackService(feedbackRepo, groupRepo, shopService)
+	feedbackHandler := handlers.NewAnalysisFeedbackHandler(feedbackService)
+	draftService := service.NewReceiptDraftService(draftRepo, feedbackRepo, receiptService, draftTTL)
+	draftHandler := handlers.NewReceiptDraftHandler(draftService)
+	go service.NewReceiptDraftExpiryWorker(draftRepo, time.Hour).Run(ctx)
+
+	receiptHandler := handlers.NewReceiptHandler(receiptService, feedbackService, draftService, aiUsageService, imageProcessor)
+	// 「昨日サイゼリヤで3200円、折半」のような文からのレシートの入力
+	receiptTextService := service.NewReceiptTextService(groupRepo, shopService, aiUsageService, aiAnalyzer)
+	receiptTextHandler := handlers.NewReceiptTextHandler(receiptTextService)
+	// 「夏に外食にいくら使った？」のような質問を検索条件に変換して答える
+	spendingQuestionService := service.NewSpendingQuestionService(groupRepo, receiptRepo, shopRepo, aiUsageService, aiAnalyzer)
+	spendingQuestionHandler := handlers.NewSpendingQuestionHandler(spendingQuestionService)
+
+	// レシート画像の非同期解析（ANALYSIS_WORKERS: 同時に解析する数）
+	analysisJobRepo := repository.NewAnalysisJobRepository(config.DB)
+	analysisJobNotifier := service.NewAnalysisJobNotifier()
+	analysisJobService := service.NewAnalysisJobService(analysisJobRepo, groupRepo, analysisJobNotifier)
+	analysisJobHandler := handlers.NewAnalysisJobHandler(analysisJobService, imageProcessor)
+	analysisWorkers := intFromEnv("ANALYSIS_WORKERS")
+	analysisWorkerPool := service.NewAnalysisWorkerPool(analysisJobRepo, aiUsageService, feedbackService, draftService, analysisJobNotifier, analysisWorkers, aiConfig.Timeout)
+	analysisWorkersDone := make(chan struct{})
+	go func() {
+		defer close(analysisWorkersDone)
+		analysisWorkerPool.Run(ctx)
+	}()
+
+	summaryService := service.NewSummaryService(groupRepo, receiptRepo, settlementRepo, reportRepo)
 	summaryHandler := handlers.NewSummaryHandler(summaryService)
 
+	reportService := service.NewReportService(groupRepo, reportRepo)
+	reportHandler := handlers.NewReportHandler(reportService)
+
+	shopAliasRepo := repository.NewShopAliasRepository(config.DB)
+	insightService := service.NewInsightService(groupRepo, reportRepo, shopAliasRepo)
+	insightHandler := handlers.NewInsightHandler(insightService)
+
+	accountService := service.NewAccountService(userRepo, groupRepo, receiptRepo, settlementRepo)
+	accountHandler := handlers.NewAccountHandler(accountService)
+
+	// ログインのレート制限（RATE_LIMIT_STORE=db で複数台構成でも共有できるDB保存に切り替え）
+	var rateLimitStore repository.RateLimitStore
+	if os.Getenv("RATE_LIMIT_STORE") == "db" {
+		rateLimitStore = repository.NewRateLimitStore(config.DB)
+	} else {
+		rateLimitStore = repository.NewMemoryRateLimitStore()
+	}
+
 	r := gin.Default()
 
 	// CORS設定
@@ -52,7 +155,7 @@ func main() {
 		AllowOrigins:     allowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "Content-Disposition", "Retry-After"},
 		AllowCredentials: true,
 	}))
 
@@ -60,9 +163,25 @@ func main() {
 	auth := r.Group("/auth")
 	{
 		auth.POST("/register", userHandler.Register)
-		auth.POST("/login", userHandler.Login)
+		auth.POST("/login", middleware.LoginRateLimitMiddleware(rateLimitStore, middleware.DefaultLoginRateLimitConfig), userHandler.Login)
+		auth.POST("/login/2fa", middleware.LoginRateLimitMiddleware(rateLimitStore, middleware.DefaultLoginRateLimitConfig), userHandler.VerifyTwoFactor)
 		auth.GET("/me", middleware.AuthMiddleware(), userHandler.GetMe)
 		auth.PUT("/me", middleware.AuthMiddleware(), userHandler.UpdateMe)
+		auth.DELETE("/me", middleware.AuthMiddleware(), accountHandler.DeleteMe)
+		auth.GET("/me/export", middleware.AuthMiddleware(), accountHandler.ExportMe)
+		auth.POST("/me/2fa/setup", middleware.AuthMiddleware(), userHandler.SetupTwoFactor)
+		auth.POST("/me/2fa/enable", middleware.AuthMiddleware(), userHandler.EnableTwoFactor)
+		auth.POST("/me/2fa/disable", middleware.AuthMiddleware(), userHandler.DisableTwoFactor)
+
+		auth.POST("/passkey/login/begin", passkeyHandler.BeginLogin)
+		auth.POST("/passkey/login/finish", middleware.LoginRateLimitMiddleware(rateLimitStore, middleware.DefaultLoginRateLimitConfig), passkeyHandler.FinishLogin)
+		auth.POST("/oidc/begin", oidcHandler.BeginLogin)
+		auth.POST("/oidc/callback", middleware.LoginRateLimitMiddleware(rateLimitStore, middleware.DefaultLoginRateLimitConfig), oidcHandler.FinishLogin)
+
+		auth.GET("/me/passkeys", middleware.AuthMiddleware(), passkeyHandler.GetPasskeys)
+		auth.POST("/me/passkeys/register/begin", middleware.AuthMiddleware(), passkeyHandler.BeginRegistration)
+		auth.POST("/me/passkeys/register/finish", middleware.AuthMiddleware(), passkeyHandler.FinishRegistration)
+		auth.DELETE("/me/passkeys/:id", middleware.AuthMiddleware(), passkeyHandler.DeletePasskey)
 	}
 
 	// レシート関連（認証必須）
@@ -71,20 +190,61 @@ func main() {
 	{
 		api.GET("/receipts", receiptHandler.GetReceipts)
 		api.POST("/receipts", receiptHandler.CreateReceipt)
+		api.POST("/receipts/batch", receiptHandler.CreateReceipts)
 		api.GET("/receipts/:id", receiptHandler.GetReceipt)
 		api.PUT("/receipts/:id", receiptHandler.UpdateReceipt)
 		api.DELETE("/receipts/:id", receiptHandler.DeleteReceipt)
 		api.POST("/receipts/analyze", receiptHandler.AnalyzeReceipt)
+		api.POST("/receipts/parse-text", receiptTextHandler.ParseText)
+
+		api.POST("/analysis-jobs", analysisJobHandler.SubmitJob)
+		api.GET("/analysis-jobs/:id", analysisJobHandler.GetJob)
+		api.GET("/analysis-jobs/:id/events", analysisJobHandler.StreamJob)
+		api.POST("/analysis-batches", analysisJobHandler.SubmitBatch)
+		api.GET("/analysis-batches/:id", analysisJobHandler.GetBatch)
+
+		api.GET("/receipt-drafts", draftHandler.GetDrafts)
+		api.GET("/receipt-drafts/:id", draftHandler.GetDraft)
+		api.GET("/receipt-drafts/:id/image", draftHandler.GetDraftImage)
+		api.POST("/receipt-drafts/:id/confirm", draftHandler.ConfirmDraft)
+		api.DELETE("/receipt-drafts/:id", draftHandler.DeleteDraft)
+
+		api.GET("/ai-usage", aiUsageHandler.GetMyUsage)
 
 		api.GET("/groups", groupHandler.GetMyGroups)
+		api.GET("/groups/deleted", groupHandler.GetDeletedGroups)
 		api.POST("/groups", groupHandler.CreateGroup)
 		api.PUT("/groups/:id", groupHandler.UpdateGroup)
 		api.DELETE("/groups/:id", groupHandler.DeleteGroup)
 		api.POST("/groups/:id/invite", groupHandler.InviteMember)
 		api.DELETE("/groups/:id/members/:userId", groupHandler.RemoveMember)
+		api.PUT("/groups/:id/members/:userId/role", groupHandler.ChangeMemberRole)
+		api.POST("/groups/:id/leave", groupHandler.LeaveGroup)
+		api.POST("/groups/:id/transfer-ownership", groupHandler.TransferOwnership)
+		api.POST("/groups/:id/archive", groupHandler.ArchiveGroup)
+		api.POST("/groups/:id/unarchive", groupHandler.UnarchiveGroup)
+		api.POST("/groups/:id/restore", groupHandler.RestoreGroup)
+		api.POST("/groups/:id/purge", groupHandler.PurgeGroup)
+		api.GET("/groups/:id/insights", insightHandler.GetInsights)
+		api.GET("/groups/:id/analysis-accuracy", feedbackHandler.GetAccuracy)
+		api.GET("/groups/:id/ai-usage", aiUsageHandler.GetGroupUsage)
+		api.POST("/groups/:id/ask", spendingQuestionHandler.Ask)
+		api.GET("/groups/:id/shop-aliases", insightHandler.GetShopAliases)
+		api.POST("/groups/:id/shop-aliases", insightHandler.CreateShopAlias)
+		api.DELETE("/groups/:id/shop-aliases/:aliasId", insightHandler.DeleteShopAlias)
+		api.GET("/groups/:id/shops", shopHandler.GetShops)
+		api.POST("/groups/:id/shops", shopHandler.CreateShop)
+
+		api.PUT("/shops/:id", shopHandler.UpdateShop)
+		api.DELETE("/shops/:id", shopHandler.DeleteShop)
+		api.POST("/shops/:id/aliases", shopHandler.AddShopAlias)
+		api.POST("/shops/:id/merge", shopHandler.MergeShops)
 
 		api.GET("/summary", summaryHandler.GetMonthlySummary)
 		api.POST("/settle", summaryHandler.CreateSettlement)
+
+		api.GET("/reports", reportHandler.GetReport)
+		api.GET("/reports/yearly", reportHandler.GetYearlyReport)
 	}
 
 	// ヘルスチェック
@@ -94,5 +254,98 @@ func main() {
 		})
 	})
 
-	r.Run(":8080")
+	srv := &http.Server{Addr: ":8080", Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panic("failed to start server: " + err.Error())
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Println("shutting down")
+
+	// 処理中のリクエストを待ってから終了する（解析結果のストリームなど終わらない接続は shutdownTimeout で打ち切る）
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("failed to shut down server gracefully: %v", err)
+	}
+	<-analysisWorkersDone
+}
+
+// shutdownTimeout 停止時に処理中のリクエストの完了を待つ時間
+const shutdownTimeout = 10 * time.Second
+
+// passkeyConfigFromEnv 環境変数からパスキー（WebAuthn）の設定を読み込む
+func passkeyConfigFromEnv() service.PasskeyConfig {
+	cfg := service.PasskeyConfig{
+		RPID:          "localhost",
+		RPDisplayName: "Receipt",
+		RPOrigins:     []string{"http://localhost:3000"},
+	}
+	if rpID := os.Getenv("WEBAUTHN_RP_ID"); rpID != "" {
+		cfg.RPID = rpID
+	}
+	if origins := os.Getenv("WEBAUTHN_RP_ORIGINS"); origins != "" {
+		cfg.RPOrigins = strings.Split(origins, ",")
+	} else if origins := os.Getenv("ALLOWED_ORIGINS"); origins != "" {
+		cfg.RPOrigins = strings.Split(origins, ",")
+	}
+	return cfg
+}
+
+// aiAnalyzerConfigFromEnv 環境変数からレシート解析のAIバックエンドの設定を読み込む
+func aiAnalyzerConfigFromEnv() service.AIAnalyzerConfig {
+	cfg := service.AIAnalyzerConfig{
+		Backend:      os.Getenv("AI_BACKEND"),
+		APIKey:       os.Getenv("AI_API_KEY"),
+		BaseURL:      os.Getenv("AI_BASE_URL"),
+		Model:        os.Getenv("AI_MODEL"),
+		Prompt:       os.Getenv("AI_PROMPT"),
+		OCRCommand:   os.Getenv("AI_OCR_COMMAND"),
+		OCRLanguages: os.Getenv("AI_OCR_LANGUAGES"),
+	}
+	// Gemini は従来どおり GOOGLE_API_KEY でも設定できる
+	if cfg.APIKey == "" && (cfg.Backend == "" || cfg.Backend == service.AIBackendGemini) {
+		cfg.APIKey = os.Getenv("GOOGLE_API_KEY")
+	}
+	cfg.Timeout = durationFromEnv("AI_TIMEOUT")
+	return cfg
+}
+
+// aiQuotaFromEnv 環境変数から1日あたりのAIの利用上限を読み込む（未指定の場合は無制限）
+func aiQuotaFromEnv() service.AIQuota {
+	return service.AIQuota{
+		UserDailyLimit:  intFromEnv("AI_DAILY_USER_LIMIT"),
+		GroupDailyLimit: intFromEnv("AI_DAILY_GROUP_LIMIT"),
+	}
+}
+
+// durationFromEnv 環境変数から期間（例: 72h）を読み込む。未指定の場合は0（既定値）を返し、
+// 不正な値の場合は設定の誤りに気付けるよう起動を中止する
+func durationFromEnv(name string) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		panic("invalid " + name + " " + strconv.Quote(value) + ": must be a non-negative duration such as 72h")
+	}
+	return d
+}
+
+// intFromEnv 環境変数から0以上の整数を読み込む。未指定の場合は0（既定値）を返し、
+// 不正な値の場合は設定の誤りに気付けるよう起動を中止する
+func intFromEnv(name string) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		panic("invalid " + name + " " + strconv.Quote(value) + ": must be a non-negative integer")
+	}
+	return n
 }